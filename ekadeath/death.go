@@ -5,11 +5,10 @@
 
 package ekadeath
 
+import "context"
 import "os"
-import "os/signal"
-import "reflect"
-import "syscall"
 import "sync"
+import "time"
 
 // ---------------------------------------------------------------------------- //
 //
@@ -52,76 +51,320 @@ import "sync"
 //
 // ---------------------------------------------------------------------------- //
 //
-// What functions you can use as destructors? Context-independent those, I guess.
-// Only 2 signatures of destructors are allowable:
+// What functions you can use as destructors?
+// Only 3 signatures of destructors are allowable:
 // - func(): no arguments, no returns. Just your callback (or closure).
 // - func(code int): one argument, no returns. Argument is exit code Die called with.
+// - func(ctx context.Context) error: context-aware destructor.
+//   Its context is cancelled when its timeout or shutdown deadline is reached.
+//
+// ---------------------------------------------------------------------------- //
+//
+// Want to remove destructor later (e.g. for short-lived resources)?
+// Use RegNamed() or RegPhaseNamed(), that return a *Handle with Unregister().
+// Names are also used to detect duplicates and are reported by Registered().
+//
+// Want to know what goroutines were doing when service is dying?
+// Use DumpGoroutinesBeforeDie() to log them using ekalog's package logger.
+//
+// Testing shutdown? Use SetExitFunc() to avoid os.Exit() and Reset() after.
+//
+// Long-running goroutines may use Context(), Done() to know that shutdown
+// has been started or may be launched by Track(), thus shutdown waits
+// until they are finished before calling destructors.
+//
+// Shutdown is performed by phases, that are run one by one:
+// PHASE_STOP_ACCEPTING -> PHASE_DRAIN -> PHASE_FLUSH_LOGS -> PHASE_CLOSE_RESOURCES.
+// Use RegPhase() to register destructor for the specified phase
+// with its own timeout. Reg() registers destructors for PHASE_CLOSE_RESOURCES.
+//
+// Context-aware destructors of the same phase are called in parallel.
+// Context-independent destructors of the same phase are called one by one
+// in LIFO order (just as it always has been), in parallel with context-aware ones.
+//
+// The whole shutdown is limited by the deadline (see SetShutdownTimeout()).
+// The destructors that are not finished until their timeout or deadline
+// are abandoned. Each destructor's outcome and duration is logged
+// using ekalog's package logger.
 //
 // ---------------------------------------------------------------------------- //
 //
 // How it works? Very simple!
 // Destructors stored in map[exitCode]destructorToBeCalledWithThatExitCode,
-// and there is goroutine that calls Die(1) if SIGKILL/SIGTERM is occurred.
+// and there is goroutine that calls Die(1) if SIGINT/SIGTERM is occurred.
+// The second signal while shutdown is in progress forces immediate exit.
+// You may choose signals and their exit codes using SetSignals(),
+// or disable signal handling at all using DisableSignalHandling().
+// SIGHUP calls reload hooks registered by RegReload() instead of dying.
 //
 // ---------------------------------------------------------------------------- //
 
 type (
 	DestructorSimple       = func()
 	DestructorWithExitCode = func(code int)
+	DestructorWithContext  = func(ctx context.Context) error
+
+	// Phase is a shutdown phase. Phases are run one by one in the order
+	// of their values. See PHASE_... constants.
+	Phase uint8
+
+	// Handle is a registration of destructors or reload hooks
+	// that allows to unregister them. See RegNamed(), RegPhaseNamed(), RegReload().
+	Handle struct {
+		firstID, lastID uint64 // range of destructors' (hooks') IDs registered by one call
+		isReload        bool   // true if it's reload hooks' registration
+	}
+
+	// DestructorInfo is an info about registered destructor. See Registered().
+	DestructorInfo struct {
+		Name            string        // empty if it's registered w/o name
+		Func            string        // name of destructor's function
+		Phase           Phase         // phase destructor is called at
+		Timeout         time.Duration // 0 if there is no destructor's own timeout
+		BoundToExitCode bool          // true if it's called only for ExitCode
+		ExitCode        int
+	}
 
 	destructorRegistered = struct {
+		id             uint64
+		name           string
 		f              interface{}
 		bindToExitCode int
 		callAnyway     bool
+		phase          Phase
+		timeout        time.Duration
 	}
 )
 
+//noinspection GoSnakeCaseUsage
+const (
+	// PHASE_STOP_ACCEPTING is the 1st shutdown phase: stop accepting
+	// new requests, connections, jobs.
+	PHASE_STOP_ACCEPTING Phase = 1 + iota
+
+	// PHASE_DRAIN is the 2nd shutdown phase: finish requests, jobs in progress.
+	PHASE_DRAIN
+
+	// PHASE_FLUSH_LOGS is the 3rd shutdown phase: flush logs, metrics, traces.
+	PHASE_FLUSH_LOGS
+
+	// PHASE_CLOSE_RESOURCES is the last shutdown phase: close DB connections,
+	// files, etc. Destructors registered by Reg() are called at this phase.
+	PHASE_CLOSE_RESOURCES
+
+	// SHUTDOWN_TIMEOUT_DEFAULT is the default shutdown deadline.
+	// See SetShutdownTimeout().
+	SHUTDOWN_TIMEOUT_DEFAULT = 30 * time.Second
+
+	// RELOAD_TIMEOUT_DEFAULT is the default time each reload hook
+	// must be finished within. See SetReloadTimeout().
+	RELOAD_TIMEOUT_DEFAULT = 30 * time.Second
+)
+
 var (
-	destructors []destructorRegistered
-	mu          sync.Mutex
+	destructors      []destructorRegistered
+	lastDestructorID uint64
+	shutdownTimeout  = SHUTDOWN_TIMEOUT_DEFAULT
+	exitFunc         = os.Exit
+	isDying          bool
+	mu               sync.Mutex
+
+	// dumpGoroutines is true if goroutines must be logged before shutdown.
+	// See DumpGoroutinesBeforeDie().
+	dumpGoroutines bool
+
+	// shutdownCtx is cancelled when shutdown begins. See Context(), Done().
+	shutdownCtx, shutdownCancel = context.WithCancel(context.Background())
+
+	// tracked are the workers launched by Track().
+	tracked = new(trackedGroup)
 )
 
-// Package initialization. Spawns goroutine which can handle SIGKILL, SIGTERM
-// and call then Die(1).
+// Package initialization. Spawns goroutine which can handle SIGINT, SIGTERM
+// and call then Die(1). See SetSignals() to change that behaviour.
 func init() {
-
-	ch := make(chan os.Signal)
-	signal.Notify(ch, os.Interrupt, syscall.SIGKILL, syscall.SIGTERM)
-
-	go func() {
-		_ = <-ch // blocks
-		Die(1)
-	}()
+	initSignalHandling()
 }
 
 // Reg registers destructors to be called when service should be stopped
-// (Die is called or SIGTERM/SIGKILL).
+// (Die is called or SIGINT/SIGTERM).
 //
-// You can use func as destructor
-// if it's type is either DestructorSimple or DestructorWithExitCode.
+// You can use func as destructor if it's type is either DestructorSimple,
+// DestructorWithExitCode or DestructorWithContext.
 //
 // You can use Reg to do:
 // 1. Just reg one or many destructor(s): Reg(foo), Reg(foo1, foo2, foo3).
 // 2. Reg destructor (one or many) to be called for special exitCode only:
 //    Reg(exitCode, foo), Reg(exitCode, foo1, foo2, foo3),
 //    where exitCode should be: int, int8, int16, int32, int64 and the same uint's.
+//
+// Destructors are registered for PHASE_CLOSE_RESOURCES phase w/o their own timeout.
+// Use RegPhase() to specify them.
 func Reg(args ...interface{}) {
+	RegPhase(PHASE_CLOSE_RESOURCES, 0, args...)
+}
+
+// RegPhase is the same as Reg() but registers destructors for the specified
+// shutdown 'phase' with the 'timeout' (ignored if it's not positive)
+// each destructor must be finished within.
+//
+// Context of DestructorWithContext is cancelled when timeout is reached.
+// Other destructors are just abandoned (not waited).
+//
+// There is no-op if 'phase' is not one of PHASE_... constants.
+func RegPhase(phase Phase, timeout time.Duration, args ...interface{}) {
+	_ = regArgs("", phase, timeout, args)
+}
 
-	if l := len(args); l == 0 {
-		return
+// RegNamed is the same as Reg() but destructors are registered with the 'name'
+// and the returned *Handle may be used to unregister them.
+//
+// Returns nil if nothing is registered: there are no valid destructors
+// or the destructor with the same non-empty 'name' is registered already.
+// Empty 'name' disables duplicate detection.
+func RegNamed(name string, args ...interface{}) *Handle {
+	return regArgs(name, PHASE_CLOSE_RESOURCES, 0, args)
+}
+
+// RegPhaseNamed is the same as RegPhase() but destructors are registered
+// with the 'name' and the returned *Handle may be used to unregister them.
+// See RegNamed() for more info.
+func RegPhaseNamed(name string, phase Phase, timeout time.Duration, args ...interface{}) *Handle {
+	return regArgs(name, phase, timeout, args)
+}
 
-	} else if v0 := reflect.ValueOf(args[0]); l == 1 {
-		reg(false, 0, args[0])
+// Unregister unregisters destructors (reload hooks) that are registered
+// by the call that returned h. Reports whether at least one destructor (hook)
+// is unregistered. There is no-op and false is returned if shutdown
+// is in progress already (it's not applied to reload hooks).
+// Nil safe.
+func (h *Handle) Unregister() bool {
 
-	} else if k := v0.Kind(); k >= reflect.Int && k <= reflect.Int64 {
-		reg(true, int(v0.Int()), args[1:]...)
+	if h == nil {
+		return false
+	}
 
-	} else if k >= reflect.Uint && k <= reflect.Uint64 {
-		reg(true, int(v0.Uint()), args[1:]...)
+	if h.isReload {
+		return unregisterReload(h)
+	}
 
-	} else {
-		reg(false, 0, args...)
+	mu.Lock()
+	defer mu.Unlock()
+
+	if isDying {
+		return false
+	}
+
+	unregistered := false
+	kept := destructors[:0]
+
+	for _, d := range destructors {
+		if d.id >= h.firstID && d.id <= h.lastID {
+			unregistered = true
+		} else {
+			kept = append(kept, d)
+		}
 	}
+
+	// Remaining pointers must not keep unregistered destructors alive.
+	for i := len(kept); i < len(destructors); i++ {
+		destructors[i] = destructorRegistered{}
+	}
+
+	destructors = kept
+	return unregistered
+}
+
+// Registered returns info about all registered destructors
+// in the order of their registration.
+func Registered() []DestructorInfo {
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	infos := make([]DestructorInfo, len(destructors))
+	for i, d := range destructors {
+		infos[i] = DestructorInfo{
+			Name:            d.name,
+			Func:            destructorName(d.f),
+			Phase:           d.phase,
+			Timeout:         d.timeout,
+			BoundToExitCode: !d.callAnyway,
+			ExitCode:        d.bindToExitCode,
+		}
+	}
+
+	return infos
+}
+
+// SetExitFunc replaces the function Die() calls at the end
+// (os.Exit by default). Nil restores os.Exit.
+//
+// It's useful for tests: if 'exit' returns, Die() returns too.
+// Use Reset() after such Die() call to be able to use ekadeath again.
+func SetExitFunc(exit func(code int)) {
+
+	if exit == nil {
+		exit = os.Exit
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	exitFunc = exit
+}
+
+// Reset unregisters all destructors and reload hooks and restores ekadeath's state
+// as if Die() has never been called: Context() is not cancelled,
+// shutdown and reload timeouts and exit function are defaults.
+// Shutdown signals are not changed.
+//
+// It's intended to be used in tests only.
+func Reset() {
+
+	resetReload()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	destructors = nil
+	shutdownTimeout = SHUTDOWN_TIMEOUT_DEFAULT
+	exitFunc = os.Exit
+	isDying = false
+	isShutdownRequested = false
+	dumpGoroutines = false
+
+	shutdownCtx, shutdownCancel = context.WithCancel(context.Background())
+	tracked = new(trackedGroup)
+}
+
+// DumpGoroutinesBeforeDie enables (or disables) logging of the states
+// and stacktraces of all goroutines when Die() is called, before shutdown
+// is started (thus, before tracked workers are notified).
+// Goroutines are logged with the warning level using ekalog's package logger
+// (see ekalog.LogGoroutines()) if ekalog package is used (imported) at all.
+func DumpGoroutinesBeforeDie(enable bool) {
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	dumpGoroutines = enable
+}
+
+// SetShutdownTimeout sets the deadline the whole shutdown (all phases)
+// must be done within. Destructors that are not finished until deadline
+// are abandoned and os.Exit() is called.
+// Use 0 to disable deadline. SHUTDOWN_TIMEOUT_DEFAULT is used by default.
+func SetShutdownTimeout(timeout time.Duration) {
+
+	if timeout < 0 {
+		timeout = 0
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	shutdownTimeout = timeout
 }
 
 // Exit is the same as Die(0).
@@ -130,29 +373,66 @@ func Exit() {
 }
 
 // Die calls all registered destructors (in this moment you can't register new)
-// and then shutdowns a service through os.Exit - all goroutines will be forcibly stopped.
+// phase by phase and then shutdowns a service through os.Exit -
+// all goroutines will be forcibly stopped.
 //
 // You can pass one int argument as exit code. In this case only common
 // and associated with specified exit code destructors will be called.
 // By default, exit code is 1.
+//
+// If Die is called when shutdown is already in progress
+// (e.g. from the destructor), it blocks forever.
 func Die(code ...int) {
 
 	mu.Lock()
-	defer mu.Unlock()
+	if isDying {
+		mu.Unlock()
+		select {} // the first Die() call will call os.Exit()
+	}
+	isDying = true
+	registered, timeout, exit := destructors, shutdownTimeout, exitFunc
+	workers, cancelShutdownCtx := tracked, shutdownCancel
+	mustDumpGoroutines := dumpGoroutines
+	mu.Unlock()
+
+	if mustDumpGoroutines {
+		logGoroutines(_LOG_LEVEL_WARNING)
+	}
+
+	// Notify tracked workers and all that use Context(), Done().
+	cancelShutdownCtx()
 
 	exitCode := 1 // default value, could be overwritten by first arg
 	if len(code) > 0 {
 		exitCode = code[0]
 	}
 
-	// Call destructors in reverse order (LIFO)
-	for i := len(destructors) - 1; i >= 0; i-- {
-		if destructors[i].callAnyway || destructors[i].bindToExitCode == exitCode {
-			call(destructors[i].f, exitCode)
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+
+	if waitTrackedWorkers(ctx, workers) {
+		for phase := PHASE_STOP_ACCEPTING; phase <= PHASE_CLOSE_RESOURCES; phase++ {
+			if !runPhase(ctx, registered, phase, exitCode) {
+				break
+			}
 		}
 	}
 
-	os.Exit(exitCode)
+	cancel()
+	exit(exitCode)
+}
+
+// String returns the name of phase.
+func (p Phase) String() string {
+	switch p {
+	case PHASE_STOP_ACCEPTING:  return "stop accepting"
+	case PHASE_DRAIN:           return "drain"
+	case PHASE_FLUSH_LOGS:      return "flush logs"
+	case PHASE_CLOSE_RESOURCES: return "close resources"
+	default:                    return "unknown"
+	}
 }
 
 // RegisteredNum reports how much destructors are registered.
@@ -163,50 +443,3 @@ func RegisteredNum() int {
 
 	return len(destructors)
 }
-
-// reg registers each function from destructorsToBeRegistered as destructor
-// that will be called anyway if hasExitCodeBind is false (exitCode is ignored this way)
-// or will be called if Die with passed exitCode is called if hasExitCodeBind is true.
-func reg(hasExitCodeBind bool, exitCode int, destructorsToBeRegistered ...interface{}) {
-
-	for _, destructor := range destructorsToBeRegistered {
-		if !valid(destructor) {
-			continue
-		}
-		destructors = append(destructors, destructorRegistered{
-			f:              destructor,
-			bindToExitCode: exitCode,
-			callAnyway:     !hasExitCodeBind,
-		})
-	}
-}
-
-// valid reports whether d is valid destructor:
-// - it's type either DestructorSimple or DestructorWithExitCode,
-// - it's value is not nil.
-func valid(d interface{}) bool {
-	switch d.(type) {
-
-	case DestructorSimple:
-		return d.(DestructorSimple) != nil
-
-	case DestructorWithExitCode:
-		return d.(DestructorWithExitCode) != nil
-
-	default:
-		return false
-	}
-}
-
-// call calls d with no passing arguments if d is DestructorSimple,
-// or passing exitCode if d is DestructorWithExitCode.
-func call(d interface{}, exitCode int) {
-	switch d.(type) {
-
-	case DestructorSimple:
-		d.(DestructorSimple)()
-
-	case DestructorWithExitCode:
-		d.(DestructorWithExitCode)(exitCode)
-	}
-}
@@ -0,0 +1,71 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekadeath
+
+import (
+	"context"
+)
+
+// Context returns a context that is cancelled when shutdown begins
+// (Die() is called or shutdown signal is received),
+// before any destructor is called.
+//
+// Use it as a parent context for long-running operations
+// that must be stopped when service is going to die.
+func Context() context.Context {
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	return shutdownCtx
+}
+
+// Done returns a channel that is closed when shutdown begins.
+// It's the same as Context().Done(). Useful for select loops:
+//
+// 		for {
+// 		    select {
+// 		    case <-ekadeath.Done():
+// 		        return
+// 		    case job := <-jobs:
+// 		        process(job)
+// 		    }
+// 		}
+//
+func Done() <-chan struct{} {
+	return Context().Done()
+}
+
+// Track launches 'worker' in a new goroutine passing Context() to it.
+// When shutdown begins, Context() is cancelled and the shutdown sequence
+// waits until all tracked workers are returned (but not longer than
+// shutdown deadline, see SetShutdownTimeout()) and only then
+// destructors are called.
+//
+// If shutdown is in progress already, 'worker' is launched anyway
+// (with cancelled context), but it's not waited.
+// There is no-op if 'worker' is nil.
+func Track(worker func(ctx context.Context)) {
+
+	if worker == nil {
+		return
+	}
+
+	mu.Lock()
+	ctx, workers := shutdownCtx, tracked
+	isTracked := !isDying
+	if isTracked {
+		workers.add()
+	}
+	mu.Unlock()
+
+	go func() {
+		if isTracked {
+			defer workers.done()
+		}
+		worker(ctx)
+	}()
+}
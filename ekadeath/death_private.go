@@ -0,0 +1,369 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekadeath
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/qioalice/ekago/v2/internal/ekafield"
+	"github.com/qioalice/ekago/v2/internal/ekaletter"
+)
+
+type (
+	// trackedGroup is a group of workers launched by Track().
+	trackedGroup struct {
+		wg  sync.WaitGroup
+		num int32 // number of running workers, atomic
+	}
+
+	// destructorOutcome is a result of calling one destructor.
+	destructorOutcome struct {
+		name     string
+		phase    Phase
+		duration time.Duration
+		err      error
+	}
+)
+
+//noinspection GoSnakeCaseUsage
+const (
+	// Log levels destructors' outcomes are logged with.
+	// They are the same as ekalog.LEVEL_DEBUG, ekalog.LEVEL_ERROR.
+	_LOG_LEVEL_DEBUG   uint8 = 50
+	_LOG_LEVEL_WARNING uint8 = 70
+	_LOG_LEVEL_ERROR   uint8 = 80
+)
+
+// regArgs parses 'args' as Reg() does and registers destructors
+// with the 'name' for the 'phase' with the 'timeout'.
+// Returns nil if nothing is registered.
+func regArgs(name string, phase Phase, timeout time.Duration, args []interface{}) *Handle {
+
+	if phase < PHASE_STOP_ACCEPTING || phase > PHASE_CLOSE_RESOURCES || len(args) == 0 {
+		return nil
+	}
+
+	if timeout < 0 {
+		timeout = 0
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if isDying {
+		return nil
+	}
+
+	if name != "" {
+		for _, d := range destructors {
+			if d.name == name {
+				return nil
+			}
+		}
+	}
+
+	firstID := lastDestructorID + 1
+
+	if v0 := reflect.ValueOf(args[0]); len(args) == 1 {
+		reg(name, phase, timeout, false, 0, args[0])
+
+	} else if k := v0.Kind(); k >= reflect.Int && k <= reflect.Int64 {
+		reg(name, phase, timeout, true, int(v0.Int()), args[1:]...)
+
+	} else if k >= reflect.Uint && k <= reflect.Uint64 {
+		reg(name, phase, timeout, true, int(v0.Uint()), args[1:]...)
+
+	} else {
+		reg(name, phase, timeout, false, 0, args...)
+	}
+
+	if firstID > lastDestructorID {
+		return nil
+	}
+
+	return &Handle{firstID: firstID, lastID: lastDestructorID}
+}
+
+// reg registers each function from destructorsToBeRegistered as destructor
+// that will be called anyway if hasExitCodeBind is false (exitCode is ignored this way)
+// or will be called if Die with passed exitCode is called if hasExitCodeBind is true.
+//
+// Requirements:
+// 'mu' must be locked.
+func reg(
+
+	name string,
+	phase Phase,
+	timeout time.Duration,
+	hasExitCodeBind bool,
+	exitCode int,
+	destructorsToBeRegistered ...interface{},
+
+) {
+
+	for _, destructor := range destructorsToBeRegistered {
+		if !valid(destructor) {
+			continue
+		}
+		lastDestructorID++
+		destructors = append(destructors, destructorRegistered{
+			id:             lastDestructorID,
+			name:           name,
+			f:              destructor,
+			bindToExitCode: exitCode,
+			callAnyway:     !hasExitCodeBind,
+			phase:          phase,
+			timeout:        timeout,
+		})
+	}
+}
+
+// valid reports whether d is valid destructor:
+// - it's type either DestructorSimple, DestructorWithExitCode or DestructorWithContext,
+// - it's value is not nil.
+func valid(d interface{}) bool {
+	switch d.(type) {
+
+	case DestructorSimple:
+		return d.(DestructorSimple) != nil
+
+	case DestructorWithExitCode:
+		return d.(DestructorWithExitCode) != nil
+
+	case DestructorWithContext:
+		return d.(DestructorWithContext) != nil
+
+	default:
+		return false
+	}
+}
+
+// call calls d with no passing arguments if d is DestructorSimple,
+// passing exitCode if d is DestructorWithExitCode
+// or passing ctx if d is DestructorWithContext.
+// A panic is recovered and returned as an error.
+func call(ctx context.Context, d interface{}, exitCode int) (err error) {
+
+	defer func() {
+		if panicObj := recover(); panicObj != nil {
+			err = fmt.Errorf("destructor panicked: %v", panicObj)
+		}
+	}()
+
+	switch d.(type) {
+
+	case DestructorSimple:
+		d.(DestructorSimple)()
+
+	case DestructorWithExitCode:
+		d.(DestructorWithExitCode)(exitCode)
+
+	case DestructorWithContext:
+		err = d.(DestructorWithContext)(ctx)
+	}
+
+	return err
+}
+
+// runPhase calls destructors from 'registered' that are registered for 'phase'
+// and 'exitCode' and waits until they are done (or 'ctx' is cancelled).
+//
+// Context-aware destructors are called in parallel, others are called one by one
+// in LIFO order, in parallel with the context-aware ones.
+//
+// Returns false if 'ctx' is cancelled (shutdown deadline is reached)
+// and next phases must not be run.
+func runPhase(
+
+	ctx context.Context,
+	registered []destructorRegistered,
+	phase Phase,
+	exitCode int,
+
+) bool {
+
+	var (
+		withContext []destructorRegistered
+		sequential  []destructorRegistered
+	)
+
+	for i := len(registered) - 1; i >= 0; i-- {
+		d := registered[i]
+		if d.phase != phase || !d.callAnyway && d.bindToExitCode != exitCode {
+			continue
+		}
+		if _, ok := d.f.(DestructorWithContext); ok {
+			withContext = append(withContext, d)
+		} else {
+			sequential = append(sequential, d)
+		}
+	}
+
+	total := len(withContext) + len(sequential)
+	if total == 0 {
+		return ctx.Err() == nil
+	}
+
+	// Buffered, thus abandoned destructors won't block their goroutines forever.
+	outcomes := make(chan destructorOutcome, total)
+
+	for _, d := range withContext {
+		go func(d destructorRegistered) {
+			outcomes <- invoke(ctx, d, exitCode)
+		}(d)
+	}
+
+	if len(sequential) > 0 {
+		go func() {
+			for _, d := range sequential {
+				outcomes <- invoke(ctx, d, exitCode)
+			}
+		}()
+	}
+
+	for i := 0; i < total; i++ {
+		select {
+		case outcome := <-outcomes:
+			logOutcome(outcome)
+
+		case <-ctx.Done():
+			logw(_LOG_LEVEL_ERROR, "ekadeath: Shutdown deadline is reached. Exiting.",
+				ekafield.String("phase", phase.String()),
+				ekafield.Int("abandoned", total-i),
+			)
+			return false
+		}
+	}
+
+	return ctx.Err() == nil
+}
+
+// add marks one more worker launched by Track() as running.
+func (g *trackedGroup) add() {
+	g.wg.Add(1)
+	atomic.AddInt32(&g.num, 1)
+}
+
+// done marks one worker launched by Track() as finished.
+func (g *trackedGroup) done() {
+	atomic.AddInt32(&g.num, -1)
+	g.wg.Done()
+}
+
+// waitTrackedWorkers waits until all workers from 'g' are finished
+// or 'ctx' is cancelled. Returns false in the last case.
+//
+// Requirements:
+// Shutdown is started, thus no worker can be added to 'g' anymore.
+func waitTrackedWorkers(ctx context.Context, g *trackedGroup) bool {
+
+	done := make(chan struct{})
+	start := time.Now()
+
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		if duration := time.Since(start); duration > time.Millisecond {
+			logw(_LOG_LEVEL_DEBUG, "ekadeath: Tracked workers are done.",
+				ekafield.Duration("duration", duration))
+		}
+		return true
+
+	case <-ctx.Done():
+		logw(_LOG_LEVEL_ERROR, "ekadeath: Shutdown deadline is reached "+
+			"while waiting for tracked workers. Exiting.",
+			ekafield.Int32("abandoned", atomic.LoadInt32(&g.num)))
+		return false
+	}
+}
+
+// invoke calls destructor 'd' and waits until it's done or its timeout
+// (if it has) or 'ctx' is cancelled.
+func invoke(ctx context.Context, d destructorRegistered, exitCode int) destructorOutcome {
+
+	if d.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.timeout)
+		defer cancel()
+	}
+
+	outcome := destructorOutcome{
+		name:  d.name,
+		phase: d.phase,
+	}
+	if outcome.name == "" {
+		outcome.name = destructorName(d.f)
+	}
+
+	done := make(chan error, 1)
+	start := time.Now()
+
+	go func() {
+		done <- call(ctx, d.f, exitCode)
+	}()
+
+	select {
+	case outcome.err = <-done:
+	case <-ctx.Done():
+		outcome.err = ctx.Err()
+	}
+
+	outcome.duration = time.Since(start)
+	return outcome
+}
+
+// destructorName returns the name of destructor's function.
+func destructorName(d interface{}) string {
+
+	if f := runtime.FuncForPC(reflect.ValueOf(d).Pointer()); f != nil {
+		return f.Name()
+	}
+	return "<unknown>"
+}
+
+// logOutcome logs destructor's outcome: Debug level if it's succeeded,
+// Error level otherwise.
+func logOutcome(outcome destructorOutcome) {
+
+	fields := []ekafield.Field{
+		ekafield.String("destructor", outcome.name),
+		ekafield.String("phase", outcome.phase.String()),
+		ekafield.Duration("duration", outcome.duration),
+	}
+
+	if outcome.err == nil {
+		logw(_LOG_LEVEL_DEBUG, "ekadeath: Destructor is done.", fields...)
+	} else {
+		fields = append(fields, ekafield.String("error", outcome.err.Error()))
+		logw(_LOG_LEVEL_ERROR, "ekadeath: Destructor is failed.", fields...)
+	}
+}
+
+// logw logs 'message' with 'fields' using ekalog's package logger,
+// if ekalog package is used (imported) at all.
+func logw(level uint8, message string, fields ...ekafield.Field) {
+	if ekaletter.BridgeLogwMessage != nil {
+		ekaletter.BridgeLogwMessage(level, message, fields)
+	}
+}
+
+// logGoroutines logs all goroutines using ekalog's package logger,
+// if ekalog package is used (imported) at all.
+func logGoroutines(level uint8) {
+	if ekaletter.BridgeLogGoroutines != nil {
+		ekaletter.BridgeLogGoroutines(level)
+	}
+}
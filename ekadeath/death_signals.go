@@ -0,0 +1,106 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekadeath
+
+import (
+	"os"
+	"time"
+)
+
+// SetSignals replaces the signals that trigger shutdown (Die() call)
+// by the 'signals', where map's value is the exit code Die() is called with
+// for that signal. By default they are SIGINT, SIGTERM with exit code 1.
+//
+// The second of these signals that is received while shutdown
+// is in progress forces immediate exit w/o waiting for destructors
+// (with the exit code of the second signal).
+//
+// Signal handling is enabled again if it's been disabled by
+// DisableSignalHandling(). Empty 'signals' is the same as DisableSignalHandling().
+func SetSignals(signals map[os.Signal]int) {
+
+	sigMu.Lock()
+	defer sigMu.Unlock()
+
+	shutdownSignals = make(map[os.Signal]int, len(signals))
+	for sig, exitCode := range signals {
+		if sig != nil {
+			shutdownSignals[sig] = exitCode
+		}
+	}
+
+	subscribe()
+}
+
+// DisableSignalHandling disables the automatic signal handling.
+// Default Go's behaviour for these signals is restored,
+// Die() is not called for them anymore.
+//
+// Reload hooks (see RegReload()) are not called for SIGHUP too.
+func DisableSignalHandling() {
+
+	sigMu.Lock()
+	defer sigMu.Unlock()
+
+	shutdownSignals = nil
+	reloadDisabled = true
+
+	subscribe()
+}
+
+// RegReload registers hooks that are called (one by one, in the order
+// of registration) when SIGHUP is received, instead of dying.
+// It's useful to reload configs, reopen log files, etc.
+// The returned *Handle may be used to unregister them.
+//
+// Hooks are called in the separate goroutine, so they do not block
+// the handling of shutdown signals. If a hook is not finished within
+// the reload timeout (see SetReloadTimeout()), it's abandoned
+// and the next one is called. SIGHUPs that are received while hooks are called
+// lead to one more call of all hooks after that.
+//
+// SIGHUP is handled only if there is at least one reload hook
+// (otherwise default Go's behaviour is kept, that is exit).
+// Nil hooks are ignored. Returns nil if there is no valid hook.
+func RegReload(hooks ...func()) *Handle {
+
+	sigMu.Lock()
+	defer sigMu.Unlock()
+
+	firstID := lastReloadHookID + 1
+
+	for _, hook := range hooks {
+		if hook != nil {
+			lastReloadHookID++
+			reloadHooks = append(reloadHooks, reloadHook{id: lastReloadHookID, f: hook})
+		}
+	}
+
+	if firstID > lastReloadHookID {
+		return nil
+	}
+
+	reloadDisabled = false
+	subscribe()
+
+	return &Handle{firstID: firstID, lastID: lastReloadHookID, isReload: true}
+}
+
+// SetReloadTimeout sets the time each reload hook (see RegReload())
+// must be finished within. A hook that is not finished in time is abandoned
+// and the next one is called. Use 0 to wait hooks w/o timeout.
+// RELOAD_TIMEOUT_DEFAULT is used by default.
+func SetReloadTimeout(timeout time.Duration) {
+
+	if timeout < 0 {
+		timeout = 0
+	}
+
+	sigMu.Lock()
+	defer sigMu.Unlock()
+
+	reloadTimeout = timeout
+}
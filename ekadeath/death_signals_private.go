@@ -0,0 +1,233 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekadeath
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/qioalice/ekago/v2/internal/ekafield"
+)
+
+type (
+	// reloadHook is a hook registered by RegReload().
+	reloadHook struct {
+		id uint64
+		f  func()
+	}
+)
+
+var (
+	// shutdownSignals are the signals that trigger shutdown,
+	// and exit codes Die() is called with.
+	shutdownSignals map[os.Signal]int
+
+	// reloadHooks are hooks that are called when SIGHUP is received.
+	reloadHooks      []reloadHook
+	lastReloadHookID uint64
+
+	// reloadTimeout is the time each reload hook must be finished within.
+	reloadTimeout = RELOAD_TIMEOUT_DEFAULT
+
+	// reloadCh wakes up the goroutine that calls reload hooks.
+	// Buffered, SIGHUPs that are received while hooks are called are coalesced.
+	reloadCh = make(chan struct{}, 1)
+
+	// reloadDisabled is true if SIGHUP must not be handled
+	// even if there are reload hooks.
+	reloadDisabled bool
+
+	// sigCh is a channel the signals are received through.
+	// Buffered, because signal.Notify() does not block sending to it
+	// and the second signal must not be lost.
+	sigCh = make(chan os.Signal, 2)
+
+	// sigMu protects all signal handling's variables above.
+	sigMu sync.Mutex
+
+	// isShutdownRequested is true if shutdown signal has been received.
+	// It's protected by 'mu', not 'sigMu'.
+	isShutdownRequested bool
+)
+
+// initSignalHandling subscribes to the default signals
+// and spawns goroutine that handles them.
+func initSignalHandling() {
+
+	shutdownSignals = map[os.Signal]int{
+		os.Interrupt:    1,
+		syscall.SIGTERM: 1,
+	}
+
+	subscribe()
+	go handleSignals()
+	go handleReloads()
+}
+
+// subscribe (re)subscribes 'sigCh' to the current set of signals.
+//
+// Requirements:
+// 'sigMu' must be locked.
+func subscribe() {
+
+	signal.Stop(sigCh)
+
+	signals := make([]os.Signal, 0, len(shutdownSignals)+1)
+	for sig := range shutdownSignals {
+		signals = append(signals, sig)
+	}
+	if len(reloadHooks) > 0 && !reloadDisabled {
+		signals = append(signals, syscall.SIGHUP)
+	}
+
+	if len(signals) > 0 {
+		signal.Notify(sigCh, signals...)
+	}
+}
+
+// handleSignals receives signals from 'sigCh' and handles them:
+// wakes up reload hooks' goroutine for SIGHUP, calls Die() for the shutdown signals
+// or exits immediately if it's the second shutdown signal.
+func handleSignals() {
+
+	for sig := range sigCh {
+
+		sigMu.Lock()
+		exitCode, isShutdown := shutdownSignals[sig]
+		isReload := sig == syscall.SIGHUP && len(reloadHooks) > 0 && !reloadDisabled && !isShutdown
+		sigMu.Unlock()
+
+		switch {
+		case isReload:
+			select {
+			case reloadCh <- struct{}{}:
+			default:
+				// hooks will be called anyway
+			}
+
+		case !isShutdown:
+			// signal has been unsubscribed, but it was already in the channel
+
+		case requestShutdown():
+			// Die() in another goroutine, because the second signal
+			// must be handled while shutdown is in progress.
+			go Die(exitCode)
+
+		default:
+			mu.Lock()
+			exit := exitFunc
+			mu.Unlock()
+			exit(exitCode)
+		}
+	}
+}
+
+// handleReloads calls reload hooks each time it's woken up by handleSignals().
+func handleReloads() {
+
+	for range reloadCh {
+
+		sigMu.Lock()
+		hooks := append([]reloadHook(nil), reloadHooks...)
+		timeout := reloadTimeout
+		sigMu.Unlock()
+
+		for _, hook := range hooks {
+			callReloadHook(hook.f, timeout)
+		}
+	}
+}
+
+// callReloadHook calls 'hook' and waits until it's done or 'timeout'
+// is reached (0 means no timeout). Abandoned hook is logged.
+func callReloadHook(hook func(), timeout time.Duration) {
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		hook()
+	}()
+
+	if timeout == 0 {
+		<-done
+		return
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+	case <-timer.C:
+		logw(_LOG_LEVEL_WARNING, "ekadeath: Reload hook is not finished in time, abandoned.",
+			ekafield.String("hook", destructorName(hook)),
+			ekafield.Duration("timeout", timeout))
+	}
+}
+
+// unregisterReload unregisters reload hooks that are registered
+// by the RegReload() call that returned h. Unsubscribes from SIGHUP
+// if there is no more reload hooks.
+func unregisterReload(h *Handle) bool {
+
+	sigMu.Lock()
+	defer sigMu.Unlock()
+
+	unregistered := false
+	kept := reloadHooks[:0]
+
+	for _, hook := range reloadHooks {
+		if hook.id >= h.firstID && hook.id <= h.lastID {
+			unregistered = true
+		} else {
+			kept = append(kept, hook)
+		}
+	}
+
+	for i := len(kept); i < len(reloadHooks); i++ {
+		reloadHooks[i] = reloadHook{}
+	}
+
+	reloadHooks = kept
+	if unregistered {
+		subscribe()
+	}
+
+	return unregistered
+}
+
+// resetReload unregisters all reload hooks and restores reload timeout.
+func resetReload() {
+
+	sigMu.Lock()
+	defer sigMu.Unlock()
+
+	reloadHooks = nil
+	reloadTimeout = RELOAD_TIMEOUT_DEFAULT
+
+	subscribe()
+}
+
+// requestShutdown marks that shutdown is requested by the signal.
+// Returns false if shutdown has been requested or started already.
+//
+// Die() could be not started yet when the second signal is received,
+// thus 'isDying' is not enough.
+func requestShutdown() bool {
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if isDying || isShutdownRequested {
+		return false
+	}
+
+	isShutdownRequested = true
+	return true
+}
@@ -0,0 +1,322 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekadeath_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/qioalice/ekago/v2/ekadeath"
+	"github.com/qioalice/ekago/v2/internal/ekaletter"
+
+	"github.com/stretchr/testify/require"
+)
+
+// inSubprocess reports whether the test is run as a subprocess by runSubprocess().
+func inSubprocess() bool {
+	return os.Getenv("EKADEATH_TEST_SUBPROCESS") == "1"
+}
+
+// runSubprocess runs the test 'name' in a separate process (because it calls
+// os.Exit() through ekadeath.Die()), returning its stdout and exit code.
+func runSubprocess(t *testing.T, name string) (string, int) {
+
+	cmd := exec.Command(os.Args[0], "-test.run=^"+name+"$")
+	cmd.Env = append(os.Environ(), "EKADEATH_TEST_SUBPROCESS=1")
+
+	out, err := cmd.Output()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return string(out), exitErr.ExitCode()
+	}
+	require.NoError(t, err)
+	return string(out), 0
+}
+
+func TestDie_Phases(t *testing.T) {
+
+	if inSubprocess() {
+		ekadeath.SetShutdownTimeout(time.Second)
+
+		say := func(s string) func() {
+			return func() { fmt.Println(s) }
+		}
+
+		ekadeath.Reg(say("close 1"))
+		ekadeath.Reg(say("close 2"))
+		ekadeath.Reg(3, say("close for 3"))
+		ekadeath.Reg(4, say("close for 4"))
+		ekadeath.RegPhase(ekadeath.PHASE_FLUSH_LOGS, 0, say("flush"))
+		ekadeath.RegPhase(ekadeath.PHASE_STOP_ACCEPTING, 0, say("stop"))
+
+		// Both are waiting for each other, thus they must be called in parallel.
+		ch1, ch2 := make(chan struct{}), make(chan struct{})
+		ekadeath.RegPhase(ekadeath.PHASE_DRAIN, 0, func(ctx context.Context) error {
+			close(ch1)
+			<-ch2
+			fmt.Println("drain")
+			return nil
+		})
+		ekadeath.RegPhase(ekadeath.PHASE_DRAIN, 0, func(ctx context.Context) error {
+			close(ch2)
+			<-ch1
+			return nil
+		})
+
+		ekadeath.Die(3)
+	}
+
+	out, code := runSubprocess(t, "TestDie_Phases")
+	require.Equal(t, 3, code)
+	require.Equal(t, "stop\ndrain\nflush\nclose for 3\nclose 2\nclose 1\n", out)
+}
+
+func TestDie_Timeouts(t *testing.T) {
+
+	if inSubprocess() {
+		ekadeath.SetShutdownTimeout(300 * time.Millisecond)
+
+		ekadeath.RegPhase(ekadeath.PHASE_DRAIN, 50*time.Millisecond, func(ctx context.Context) error {
+			<-ctx.Done()
+			fmt.Println("destructor timeout:", ctx.Err())
+			return ctx.Err()
+		})
+		ekadeath.RegPhase(ekadeath.PHASE_FLUSH_LOGS, 0, func() {
+			time.Sleep(time.Hour) // hung
+		})
+		ekadeath.Reg(func() {
+			fmt.Println("must not be called, deadline is reached")
+		})
+
+		ekadeath.Die(5)
+	}
+
+	start := time.Now()
+	out, code := runSubprocess(t, "TestDie_Timeouts")
+
+	require.Equal(t, 5, code)
+	require.Less(t, int64(time.Since(start)), int64(10*time.Second))
+	require.Equal(t, "destructor timeout: "+context.DeadlineExceeded.Error(), strings.TrimSpace(out))
+}
+
+// sendSignal sends 'sig' to the current process.
+func sendSignal(t *testing.T, sig os.Signal) {
+
+	p, err := os.FindProcess(os.Getpid())
+	require.NoError(t, err)
+	require.NoError(t, p.Signal(sig))
+}
+
+func TestRegReload(t *testing.T) {
+
+	ekadeath.SetReloadTimeout(100 * time.Millisecond)
+	defer ekadeath.SetReloadTimeout(ekadeath.RELOAD_TIMEOUT_DEFAULT)
+
+	// Blocked hook must not prevent the next ones from being called.
+	unblock := make(chan struct{})
+	defer close(unblock)
+
+	blockedHandle := ekadeath.RegReload(func() {
+		<-unblock
+	})
+	defer blockedHandle.Unregister()
+
+	reloaded := make(chan struct{}, 1)
+	handle := ekadeath.RegReload(func() {
+		select {
+		case reloaded <- struct{}{}:
+		default:
+		}
+	})
+	require.NotNil(t, handle)
+	defer handle.Unregister()
+
+	sendSignal(t, syscall.SIGHUP)
+
+	select {
+	case <-reloaded:
+	case <-time.After(5 * time.Second):
+		t.Fatal("reload hook is not called")
+	}
+
+	require.Nil(t, ekadeath.RegReload(nil))
+	require.True(t, handle.Unregister())
+	require.False(t, handle.Unregister())
+}
+
+func TestReset_ReloadHooks(t *testing.T) {
+
+	handle := ekadeath.RegReload(func() {})
+	require.NotNil(t, handle)
+
+	ekadeath.Reset()
+	require.False(t, handle.Unregister())
+}
+
+func TestSetSignals(t *testing.T) {
+
+	if inSubprocess() {
+		ekadeath.SetSignals(map[os.Signal]int{syscall.SIGUSR1: 7})
+		ekadeath.Reg(func(code int) {
+			fmt.Println("destructor", code)
+		})
+
+		sendSignal(t, syscall.SIGUSR1)
+		time.Sleep(5 * time.Second)
+		return
+	}
+
+	out, code := runSubprocess(t, "TestSetSignals")
+	require.Equal(t, 7, code)
+	require.Equal(t, "destructor 7\n", out)
+}
+
+func TestSecondSignalForcesExit(t *testing.T) {
+
+	if inSubprocess() {
+		ekadeath.SetShutdownTimeout(0)
+
+		called := make(chan struct{})
+		ekadeath.Reg(func() {
+			close(called)
+			time.Sleep(time.Hour) // hung
+		})
+
+		sendSignal(t, syscall.SIGTERM)
+		<-called
+		fmt.Println("hung")
+		sendSignal(t, syscall.SIGINT)
+		time.Sleep(5 * time.Second)
+		return
+	}
+
+	start := time.Now()
+	out, code := runSubprocess(t, "TestSecondSignalForcesExit")
+
+	require.Equal(t, 1, code)
+	require.Equal(t, "hung\n", out)
+	require.Less(t, int64(time.Since(start)), int64(5*time.Second))
+}
+
+func TestTrack(t *testing.T) {
+
+	if inSubprocess() {
+		ekadeath.SetShutdownTimeout(time.Second)
+
+		ekadeath.Reg(func() {
+			fmt.Println("destructor")
+		})
+
+		started := make(chan struct{})
+		ekadeath.Track(func(ctx context.Context) {
+			close(started)
+			<-ctx.Done()
+			time.Sleep(50 * time.Millisecond)
+			fmt.Println("worker")
+		})
+		<-started
+
+		go func() {
+			<-ekadeath.Done()
+			fmt.Println("done")
+		}()
+
+		ekadeath.Die(0)
+	}
+
+	out, code := runSubprocess(t, "TestTrack")
+	require.Equal(t, 0, code)
+	require.Equal(t, "done\nworker\ndestructor\n", out)
+}
+
+func TestRegNamed(t *testing.T) {
+
+	ekadeath.Reset()
+	defer ekadeath.Reset()
+
+	var calls []string
+	exitCode := -1
+
+	ekadeath.SetExitFunc(func(code int) { exitCode = code })
+
+	h1 := ekadeath.RegNamed("db", func() { calls = append(calls, "db") })
+	require.NotNil(t, h1)
+	require.Nil(t, ekadeath.RegNamed("db", func() {}), "duplicate")
+	require.Nil(t, ekadeath.RegNamed("invalid", "not a func"))
+
+	h2 := ekadeath.RegPhaseNamed("tmp", ekadeath.PHASE_DRAIN, time.Second, 2,
+		func() { calls = append(calls, "tmp 1") },
+		func() { calls = append(calls, "tmp 2") },
+	)
+	require.NotNil(t, h2)
+
+	ekadeath.Reg(func() { calls = append(calls, "unnamed") })
+
+	registered := ekadeath.Registered()
+	require.Len(t, registered, 4)
+	require.Equal(t, "db", registered[0].Name)
+	require.Equal(t, "tmp", registered[1].Name)
+	require.Equal(t, ekadeath.PHASE_DRAIN, registered[1].Phase)
+	require.Equal(t, time.Second, registered[1].Timeout)
+	require.True(t, registered[1].BoundToExitCode)
+	require.Equal(t, 2, registered[1].ExitCode)
+	require.Equal(t, "", registered[3].Name)
+	require.Contains(t, registered[3].Func, "TestRegNamed")
+
+	require.True(t, h1.Unregister())
+	require.False(t, h1.Unregister())
+	require.False(t, (*ekadeath.Handle)(nil).Unregister())
+	require.Equal(t, 3, ekadeath.RegisteredNum())
+
+	ekadeath.Die(2)
+
+	require.Equal(t, 2, exitCode)
+	require.Equal(t, []string{"tmp 2", "tmp 1", "unnamed"}, calls)
+	require.Error(t, ekadeath.Context().Err())
+
+	// Nothing can be registered or unregistered while (after) dying.
+	require.Nil(t, ekadeath.RegNamed("late", func() {}))
+	require.False(t, h2.Unregister())
+
+	ekadeath.Reset()
+	require.Zero(t, ekadeath.RegisteredNum())
+	require.NoError(t, ekadeath.Context().Err())
+}
+
+func TestDumpGoroutinesBeforeDie(t *testing.T) {
+
+	ekadeath.Reset()
+	defer ekadeath.Reset()
+
+	// ekalog is not imported here, its bridge is replaced instead.
+	var calls []string
+	bridgeBak := ekaletter.BridgeLogGoroutines
+	ekaletter.BridgeLogGoroutines = func(uint8) { calls = append(calls, "dump") }
+	defer func() { ekaletter.BridgeLogGoroutines = bridgeBak }()
+
+	ekadeath.Reg(func() { calls = append(calls, "destructor") })
+	ekadeath.SetExitFunc(func(int) {})
+
+	ekadeath.Die(0)
+	require.Equal(t, []string{"destructor"}, calls)
+
+	ekadeath.Reset()
+	ekadeath.Reg(func() { calls = append(calls, "destructor") })
+	ekadeath.SetExitFunc(func(int) {})
+	ekadeath.DumpGoroutinesBeforeDie(true)
+
+	calls = nil
+	ekadeath.Die(0)
+	require.Equal(t, []string{"dump", "destructor"}, calls)
+}
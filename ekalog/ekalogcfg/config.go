@@ -0,0 +1,187 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+// Package ekalogcfg allows to build the whole ekalog's logging setup
+// from the config document instead of code.
+//
+// It's a separate package, because config's validation errors are reported
+// as *ekaerr.Error objects, but ekaerr package depends on ekalog already.
+//
+// The config document is JSON:
+//
+// 		{
+// 		    "levels": [
+// 		        {"value": 55, "name": "Notice"}
+// 		    ],
+// 		    "outputs": [
+// 		        {
+// 		            "encoder": {
+// 		                "type": "console",
+// 		                "format": "{{c}}{{l}} {{t}}{{c/0}} {{m}}",
+// 		                "colors": {"warning": "c/fg:ascii:33/b"}
+// 		            },
+// 		            "min_level": "debug",
+// 		            "drop_all_fields": true,
+// 		            "destinations": ["stdout"]
+// 		        },
+// 		        {
+// 		            "encoder": {"type": "json", "indent": 0},
+// 		            "min_level": "notice",
+// 		            "stacktrace_min_level": "error",
+// 		            "stack_frames": "only_marked",
+// 		            "key_mapping": {"time": "@timestamp", "level": "log.level"},
+// 		            "destinations": ["file:/var/log/myservice.log", "tcp://127.0.0.1:5170"]
+// 		        }
+// 		    ]
+// 		}
+//
+// Each output is one CommonIntegrator's output (see CommonIntegrator.WriteTo()).
+// Destinations are:
+// - "stdout", "stderr",
+// - "file:<path>": file is opened for appending, created if not exists,
+// - "tcp://<host:port>", "udp://<host:port>", "unix://<path>": socket.
+//
+// Levels might be specified by their names (case insensitive,
+// including names are registered by "levels" section) or by their numbers.
+package ekalogcfg
+
+import (
+	"io"
+	"time"
+
+	"github.com/qioalice/ekago/v2/ekaerr"
+)
+
+type (
+	// Config is the root of config document.
+	Config struct {
+
+		// Levels are custom log levels (or overwritten names of standard ones).
+		// They are registered before outputs are built,
+		// so their names may be used in the outputs section.
+		Levels []LevelConfig `json:"levels"`
+
+		// Outputs are CommonIntegrator's outputs. At least one is required.
+		Outputs []OutputConfig `json:"outputs"`
+	}
+
+	// LevelConfig is a custom log level. See ekalog.RegisterLevelName(),
+	// ekalog.MarkLevelAsFatal().
+	LevelConfig struct {
+		Value uint8  `json:"value"`
+		Name  string `json:"name"`
+		Fatal bool   `json:"fatal"`
+	}
+
+	// OutputConfig is one CommonIntegrator's output.
+	OutputConfig struct {
+		Encoder EncoderConfig `json:"encoder"`
+
+		// MinLevel is a level name or number. See CommonIntegrator.WithMinLevel().
+		// Debug by default.
+		MinLevel string `json:"min_level"`
+
+		// MinLevelForStackTrace is a level name or number.
+		// See CommonIntegrator.WithMinLevelForStackTrace(). Debug by default.
+		MinLevelForStackTrace string `json:"stacktrace_min_level"`
+
+		// Fields, ExcludeFields, DropAllFields describe which fields are kept.
+		// Only one of them may be used. See CommonIntegrator.WithFields(),
+		// CommonIntegrator.WithoutFields().
+		Fields        []string `json:"fields"`
+		ExcludeFields []string `json:"exclude_fields"`
+		DropAllFields bool     `json:"drop_all_fields"`
+
+		// StackFrames is "all" (default), "none" or "only_marked".
+		// See CommonIntegrator.WithStackFrames().
+		StackFrames string `json:"stack_frames"`
+
+		// KeyMapping is the key renaming rules.
+		// See CommonIntegrator.WithKeyMapping().
+		KeyMapping map[string]string `json:"key_mapping"`
+
+		// Destinations are where encoded log entries are written to.
+		// At least one is required.
+		Destinations []string `json:"destinations"`
+	}
+
+	// EncoderConfig describes an encoder of output.
+	EncoderConfig struct {
+
+		// Type is "console" (default) or "json".
+		Type string `json:"type"`
+
+		// Format is console encoder's format string.
+		// See ekalog.CI_ConsoleEncoder.SetFormat().
+		Format string `json:"format"`
+
+		// Colors are console encoder's level name -> color rules.
+		// See ekalog.CI_ConsoleEncoder.SetColorFor().
+		Colors map[string]string `json:"colors"`
+
+		// Indent is JSON encoder's indentation. See ekalog.CI_JSONEncoder.SetIndent().
+		Indent int `json:"indent"`
+	}
+)
+
+// FromConfig reads config document from 'r', validates it, builds
+// the ekalog.CommonIntegrator and replaces the default ekalog's logger's
+// integrator by that.
+//
+// Returns an error if config is malformed or invalid or if some destination
+// can not be opened. Nothing is changed in that case.
+//
+// It's safe to call it again while something is logged: the integrator
+// is installed by the first successful call and then only the integrator
+// it proxies to is replaced. Because of that "levels" section is applied
+// by the first successful call only (registering level names is not thread-safe).
+func FromConfig(r io.Reader) *ekaerr.Error {
+
+	cfg, err := decode(r)
+	if err.IsNotNil() {
+		return err.Throw()
+	}
+
+	if err = apply(cfg, true); err.IsNotNil() {
+		return err.Throw()
+	}
+
+	return nil
+}
+
+// FromFile is the same as FromConfig() but reads config document from file
+// at the 'path'.
+func FromFile(path string) *ekaerr.Error {
+	return fromFile(path, true).Throw()
+}
+
+// WatchFile is the same as FromFile() but also checks each 'interval'
+// (1 second if it's not positive) whether the config file at the 'path'
+// has been changed and applies it again if it so (hot reload).
+//
+// If the changed config can not be applied, the error is logged
+// and the previous logging setup is kept.
+//
+// The hot reload is safe to be done while something is logged.
+// But "levels" section is applied only by the first load,
+// because registering level names is not thread-safe.
+//
+// Returns a function that stops watching.
+// Returned error is an error of the first FromFile() call
+// (there is no watching if it's not nil).
+func WatchFile(path string, interval time.Duration) (stop func(), err *ekaerr.Error) {
+
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	w := &watcher{path: path, interval: interval, stop: make(chan struct{})}
+	if err = w.reload(true); err.IsNotNil() {
+		return nil, err.Throw()
+	}
+
+	go w.run()
+	return w.close, nil
+}
@@ -0,0 +1,530 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekalogcfg
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/qioalice/ekago/v2/ekaerr"
+	"github.com/qioalice/ekago/v2/ekalog"
+	"github.com/qioalice/ekago/v2/ekasys"
+
+	"github.com/json-iterator/go"
+)
+
+type (
+	// watcher is a config file's watcher. See WatchFile().
+	watcher struct {
+		path     string
+		interval time.Duration
+		modTime  time.Time
+		size     int64
+		stop     chan struct{}
+		stopOnce sync.Once
+	}
+
+	// switchableIntegrator is an ekalog.Integrator that just proxies all calls
+	// to the integrator built by the last applied config.
+	//
+	// ekalog.ReplaceIntegrator() is not thread-safe and must not be called
+	// while something is logged. So switchableIntegrator is installed once
+	// by FromConfig() and then only its internal integrator is replaced
+	// at the hot reload.
+	switchableIntegrator struct {
+		v atomic.Value // *generation
+	}
+
+	// generation is the integrator built by some applied config
+	// and destinations it has opened.
+	//
+	// When the next config is applied, the generation is retired:
+	// it waits until all in-flight writes are done and only then
+	// syncs the integrator and closes the destinations.
+	// Writes that are started after that are redirected to the next generation.
+	generation struct {
+		integrator ekalog.Integrator
+		closers    []io.Closer
+		mu         sync.RWMutex // read locked while the integrator is used
+		isRetired  bool
+	}
+)
+
+var (
+	// mu makes config applying sequential.
+	mu sync.Mutex
+
+	// current is the integrator that is installed as default logger's one.
+	current = new(switchableIntegrator)
+
+	// isInstalled reports whether current has been installed
+	// as default logger's integrator already. Protected by mu.
+	isInstalled bool
+
+	// jsonAPI is used to decode config document. Unknown fields are errors,
+	// to report typos in the config.
+	jsonAPI = jsoniter.Config{
+		EscapeHTML:             false,
+		DisallowUnknownFields:  true,
+		ValidateJsonRawMessage: true,
+	}.Froze()
+)
+
+// decode decodes config document from 'r'.
+func decode(r io.Reader) (*Config, *ekaerr.Error) {
+
+	cfg := new(Config)
+	if legacyErr := jsonAPI.NewDecoder(r).Decode(cfg); legacyErr != nil {
+		return nil, ekaerr.IllegalFormat.
+			Wrap(legacyErr, "ekalogcfg: failed to decode config").
+			Throw()
+	}
+
+	return cfg, nil
+}
+
+// apply validates 'cfg', builds ekalog.CommonIntegrator based on that
+// and makes it current. If 'install' is true and switchableIntegrator
+// has not been installed as default logger's integrator yet, also registers
+// custom levels and installs it. It's done only once, because neither
+// ekalog.ReplaceIntegrator() nor levels registering is thread-safe.
+// Retires the integrator built by the previous applied config (see generation).
+//
+// Nothing is changed if error is returned.
+func apply(cfg *Config, install bool) *ekaerr.Error {
+
+	if err := validateLevels(cfg.Levels); err.IsNotNil() {
+		return err.Throw()
+	}
+
+	if len(cfg.Outputs) == 0 {
+		return ekaerr.IllegalArgument.
+			New("ekalogcfg: at least one output is required", "path", "outputs").
+			Throw()
+	}
+
+	var (
+		integrator = new(ekalog.CommonIntegrator)
+		opened     []io.Closer
+	)
+
+	for i := range cfg.Outputs {
+		var err *ekaerr.Error
+		if opened, err = buildOutput(integrator, cfg, i, opened); err.IsNotNil() {
+			closeAll(opened)
+			return err.Throw()
+		}
+	}
+
+	if !ekalog.BuildIntegrator(integrator) {
+		closeAll(opened)
+		return ekaerr.InternalError.
+			New("ekalogcfg: failed to build integrator").
+			Throw()
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	prev, _ := current.v.Load().(*generation)
+	current.v.Store(&generation{integrator: integrator, closers: opened})
+
+	if install && !isInstalled {
+		isInstalled = true
+		for _, level := range cfg.Levels {
+			ekalog.RegisterLevelName(ekalog.Level(level.Value), level.Name)
+			if level.Fatal {
+				ekalog.MarkLevelAsFatal(ekalog.Level(level.Value))
+			}
+		}
+		ekalog.ReplaceIntegrator(current)
+	}
+
+	if prev != nil {
+		prev.retire()
+	}
+
+	return nil
+}
+
+// validateLevels checks that custom levels are named.
+func validateLevels(levels []LevelConfig) *ekaerr.Error {
+
+	for i, level := range levels {
+		if strings.TrimSpace(level.Name) == "" {
+			return ekaerr.IllegalArgument.
+				New("ekalogcfg: level's name must be not empty",
+					"path", fmt.Sprintf("levels[%d].name", i)).
+				Throw()
+		}
+	}
+
+	return nil
+}
+
+// buildOutput adds i-th output of 'cfg' to 'integrator',
+// appending opened destinations to 'opened' and returning it.
+func buildOutput(
+
+	integrator *ekalog.CommonIntegrator,
+	cfg *Config,
+	i int,
+	opened []io.Closer,
+
+) ([]io.Closer, *ekaerr.Error) {
+
+	output := cfg.Outputs[i]
+	path := fmt.Sprintf("outputs[%d]", i)
+
+	encoder, err := buildEncoder(output.Encoder, cfg.Levels, path+".encoder")
+	if err.IsNotNil() {
+		return opened, err.Throw()
+	}
+
+	minLevel, err := parseLevel(output.MinLevel, cfg.Levels, path+".min_level")
+	if err.IsNotNil() {
+		return opened, err.Throw()
+	}
+
+	stMinLevel, err := parseLevel(output.MinLevelForStackTrace, cfg.Levels, path+".stacktrace_min_level")
+	if err.IsNotNil() {
+		return opened, err.Throw()
+	}
+
+	integrator.
+		WithEncoder(encoder).
+		WithMinLevel(minLevel).
+		WithMinLevelForStackTrace(stMinLevel)
+
+	usedRules := 0
+	for _, used := range []bool{
+		len(output.Fields) > 0, len(output.ExcludeFields) > 0, output.DropAllFields,
+	} {
+		if used {
+			usedRules++
+		}
+	}
+
+	switch {
+	case usedRules > 1:
+		return opened, ekaerr.IllegalArgument.
+			New("ekalogcfg: only one of fields, exclude_fields, drop_all_fields may be used",
+				"path", path).
+			Throw()
+	case len(output.Fields) > 0:
+		integrator.WithFields(output.Fields...)
+	case len(output.ExcludeFields) > 0:
+		integrator.WithoutFields(output.ExcludeFields...)
+	case output.DropAllFields:
+		integrator.WithoutFields()
+	}
+
+	switch strings.ToLower(output.StackFrames) {
+	case "", "all":
+		integrator.WithStackFrames(ekalog.CI_STACK_FRAMES_ALL)
+	case "none":
+		integrator.WithStackFrames(ekalog.CI_STACK_FRAMES_NONE)
+	case "only_marked":
+		integrator.WithStackFrames(ekalog.CI_STACK_FRAMES_ONLY_MARKED)
+	default:
+		return opened, ekaerr.IllegalArgument.
+			New("ekalogcfg: unknown stack frames mode",
+				"path", path+".stack_frames", "value", output.StackFrames).
+			Throw()
+	}
+
+	if len(output.KeyMapping) > 0 {
+		integrator.WithKeyMapping(output.KeyMapping)
+	}
+
+	if len(output.Destinations) == 0 {
+		return opened, ekaerr.IllegalArgument.
+			New("ekalogcfg: at least one destination is required",
+				"path", path+".destinations").
+			Throw()
+	}
+
+	for j, destination := range output.Destinations {
+		w, closer, err := openDestination(destination)
+		if err.IsNotNil() {
+			return opened, err.
+				AddFields("path", fmt.Sprintf("%s.destinations[%d]", path, j)).
+				Throw()
+		}
+		if closer != nil {
+			opened = append(opened, closer)
+		}
+		integrator.WriteTo(w)
+	}
+
+	return opened, nil
+}
+
+// buildEncoder creates and freezes an encoder described by 'cfg'.
+func buildEncoder(cfg EncoderConfig, levels []LevelConfig, path string) (ekalog.CI_Encoder, *ekaerr.Error) {
+
+	switch strings.ToLower(cfg.Type) {
+
+	case "", "console":
+		if cfg.Indent != 0 {
+			return nil, ekaerr.IllegalArgument.
+				New("ekalogcfg: indent is not supported by console encoder",
+					"path", path+".indent").
+				Throw()
+		}
+		encoder := new(ekalog.CI_ConsoleEncoder)
+		if cfg.Format != "" {
+			encoder.SetFormat(cfg.Format)
+		}
+		for levelName, color := range cfg.Colors {
+			level, err := parseLevel(levelName, levels, path+".colors")
+			if err.IsNotNil() {
+				return nil, err.Throw()
+			}
+			encoder.SetColorFor(level, color)
+		}
+		return encoder.FreezeAndGetEncoder(), nil
+
+	case "json":
+		switch {
+		case cfg.Format != "" || len(cfg.Colors) > 0:
+			return nil, ekaerr.IllegalArgument.
+				New("ekalogcfg: format and colors are not supported by JSON encoder",
+					"path", path).
+				Throw()
+		case cfg.Indent < 0:
+			return nil, ekaerr.IllegalArgument.
+				New("ekalogcfg: indent must be not negative",
+					"path", path+".indent", "value", cfg.Indent).
+				Throw()
+		}
+		return new(ekalog.CI_JSONEncoder).SetIndent(cfg.Indent).FreezeAndGetEncoder(), nil
+
+	default:
+		return nil, ekaerr.IllegalArgument.
+			New("ekalogcfg: unknown encoder type",
+				"path", path+".type", "value", cfg.Type).
+			Throw()
+	}
+}
+
+// parseLevel returns a Level that is represented by 's': level's name
+// (case insensitive, including names from 'levels') or level's number.
+// Empty string is ekalog.LEVEL_DEBUG.
+func parseLevel(s string, levels []LevelConfig, path string) (ekalog.Level, *ekaerr.Error) {
+
+	if s = strings.TrimSpace(s); s == "" {
+		return ekalog.LEVEL_DEBUG, nil
+	}
+
+	if n, legacyErr := strconv.ParseUint(s, 10, 8); legacyErr == nil {
+		return ekalog.Level(n), nil
+	}
+
+	for _, level := range levels {
+		if strings.EqualFold(s, level.Name) {
+			return ekalog.Level(level.Value), nil
+		}
+	}
+
+	for i := 0; i <= 0xFF; i++ {
+		if strings.EqualFold(s, ekalog.Level(i).String()) {
+			return ekalog.Level(i), nil
+		}
+	}
+
+	return 0, ekaerr.IllegalArgument.
+		New("ekalogcfg: unknown level", "path", path, "value", s).
+		Throw()
+}
+
+// openDestination opens a destination described by 's'.
+// Returned io.Closer is nil if destination must not be closed (stdout, stderr).
+func openDestination(s string) (io.Writer, io.Closer, *ekaerr.Error) {
+
+	switch {
+
+	case s == "stdout":
+		return ekasys.Stdout(), nil, nil
+
+	case s == "stderr":
+		return ekasys.Stderr(), nil, nil
+
+	case strings.HasPrefix(s, "file:"):
+		path := strings.TrimPrefix(s, "file:")
+		if path == "" {
+			return nil, nil, ekaerr.IllegalArgument.
+				New("ekalogcfg: file path must be not empty", "value", s).
+				Throw()
+		}
+		f, legacyErr := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if legacyErr != nil {
+			return nil, nil, ekaerr.InitializationFailed.
+				Wrap(legacyErr, "ekalogcfg: failed to open file destination", "value", s).
+				Throw()
+		}
+		return f, f, nil
+	}
+
+	if i := strings.Index(s, "://"); i != -1 {
+		network, address := s[:i], s[i+3:]
+		switch network {
+		case "tcp", "tcp4", "tcp6", "udp", "udp4", "udp6", "unix", "unixgram":
+			conn, legacyErr := net.DialTimeout(network, address, 5*time.Second)
+			if legacyErr != nil {
+				return nil, nil, ekaerr.ServiceUnavailable.
+					Wrap(legacyErr, "ekalogcfg: failed to connect to socket destination", "value", s).
+					Throw()
+			}
+			return conn, conn, nil
+		}
+	}
+
+	return nil, nil, ekaerr.IllegalArgument.
+		New("ekalogcfg: unknown destination", "value", s).
+		Throw()
+}
+
+// fromFile is FromFile() and FromConfig() implementation.
+// See apply() for 'install' meaning.
+func fromFile(path string, install bool) *ekaerr.Error {
+
+	f, legacyErr := os.Open(path)
+	if legacyErr != nil {
+		return ekaerr.InitializationFailed.
+			Wrap(legacyErr, "ekalogcfg: failed to open config file", "path", path).
+			Throw()
+	}
+	defer f.Close()
+
+	cfg, err := decode(f)
+	if err.IsNotNil() {
+		return err.AddFields("path", path).Throw()
+	}
+
+	if err = apply(cfg, install); err.IsNotNil() {
+		return err.AddFields("path", path).Throw()
+	}
+
+	return nil
+}
+
+// closeAll closes all 'closers' ignoring errors.
+func closeAll(closers []io.Closer) {
+	for _, closer := range closers {
+		_ = closer.Close()
+	}
+}
+
+// reload applies config file again if it has been changed since last reload.
+// See apply() for 'install' meaning.
+func (w *watcher) reload(install bool) *ekaerr.Error {
+
+	fi, legacyErr := os.Stat(w.path)
+	if legacyErr != nil {
+		return ekaerr.InitializationFailed.
+			Wrap(legacyErr, "ekalogcfg: failed to stat config file", "path", w.path).
+			Throw()
+	}
+
+	if fi.ModTime().Equal(w.modTime) && fi.Size() == w.size {
+		return nil
+	}
+
+	// Even if config can not be applied, do not try to apply it again
+	// until it's changed.
+	w.modTime, w.size = fi.ModTime(), fi.Size()
+
+	if err := fromFile(w.path, install); err.IsNotNil() {
+		return err.Throw()
+	}
+
+	return nil
+}
+
+// run checks config file each w.interval until w.close() is called.
+func (w *watcher) run() {
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := w.reload(false); err.IsNotNil() {
+				err.LogAsError("ekalogcfg: failed to reload config, previous one is kept")
+			}
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// close stops watching. Might be called many times.
+func (w *watcher) close() {
+	w.stopOnce.Do(func() { close(w.stop) })
+}
+
+// retire marks the generation as retired, waiting for in-flight writes,
+// then syncs its integrator and closes its destinations.
+func (g *generation) retire() {
+
+	g.mu.Lock()
+	g.isRetired = true
+	g.mu.Unlock()
+
+	_ = g.integrator.Sync()
+	closeAll(g.closers)
+}
+
+// get returns the generation built by the last applied config.
+func (si *switchableIntegrator) get() *generation {
+	return si.v.Load().(*generation)
+}
+
+// acquire returns the generation built by the last applied config
+// which is read locked and is not retired. Call g.mu.RUnlock() when it's done.
+func (si *switchableIntegrator) acquire() *generation {
+	for {
+		g := si.get()
+		if g.mu.RLock(); !g.isRetired {
+			return g
+		}
+		// The next generation is already stored, try it.
+		g.mu.RUnlock()
+	}
+}
+
+func (si *switchableIntegrator) Write(entry *ekalog.Entry) {
+	g := si.acquire()
+	defer g.mu.RUnlock()
+	g.integrator.Write(entry)
+}
+
+func (si *switchableIntegrator) MinLevelEnabled() ekalog.Level {
+	return si.get().integrator.MinLevelEnabled()
+}
+
+func (si *switchableIntegrator) MinLevelForStackTrace() ekalog.Level {
+	return si.get().integrator.MinLevelForStackTrace()
+}
+
+func (si *switchableIntegrator) Sync() error {
+	g := si.acquire()
+	defer g.mu.RUnlock()
+	return g.integrator.Sync()
+}
+
+func (si *switchableIntegrator) IsAsync() bool {
+	return si.get().integrator.IsAsync()
+}
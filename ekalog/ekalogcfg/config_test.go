@@ -0,0 +1,183 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekalogcfg_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/qioalice/ekago/v2/ekalog"
+	"github.com/qioalice/ekago/v2/ekalog/ekalogcfg"
+
+	"github.com/stretchr/testify/require"
+)
+
+func readFile(t *testing.T, path string) string {
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestFromConfig(t *testing.T) {
+
+	dir := t.TempDir()
+	consolePath := filepath.Join(dir, "console.log")
+	jsonPath := filepath.Join(dir, "json.log")
+
+	cfg := fmt.Sprintf(`{
+		"levels": [{"value": 65, "name": "Notice"}],
+		"outputs": [
+			{
+				"encoder": {"type": "console", "format": "{{l}}|{{m}}"},
+				"min_level": "warning",
+				"drop_all_fields": true,
+				"destinations": ["file:%s"]
+			},
+			{
+				"encoder": {"type": "json"},
+				"min_level": "notice",
+				"key_mapping": {"time": "@timestamp", "level": "log.level"},
+				"destinations": ["file:%s"]
+			}
+		]
+	}`, consolePath, jsonPath)
+
+	require.Nil(t, ekalogcfg.FromConfig(strings.NewReader(cfg)))
+	require.Equal(t, "Notice", ekalog.Level(65).String())
+
+	ekalog.Info("info is skipped")
+	ekalog.Warn("warning", "field", 42)
+
+	console := readFile(t, consolePath)
+	require.NotContains(t, console, "info is skipped")
+	require.Contains(t, console, "Warning|warning")
+	require.NotContains(t, console, "42")
+
+	json := readFile(t, jsonPath)
+	require.NotContains(t, json, "info is skipped")
+	require.Contains(t, json, `"@timestamp"`)
+	require.Contains(t, json, `"log.level":"Warning"`)
+}
+
+func TestFromConfig_Invalid(t *testing.T) {
+
+	for name, cfg := range map[string]string{
+		"malformed":           `{"outputs": [`,
+		"unknown field":       `{"outputs": [{"destinations": ["stdout"], "min_levle": "info"}]}`,
+		"no outputs":          `{"outputs": []}`,
+		"no destinations":     `{"outputs": [{"min_level": "info"}]}`,
+		"unknown level":       `{"outputs": [{"min_level": "verbose", "destinations": ["stdout"]}]}`,
+		"unknown encoder":     `{"outputs": [{"encoder": {"type": "yaml"}, "destinations": ["stdout"]}]}`,
+		"json with format":    `{"outputs": [{"encoder": {"type": "json", "format": "{{m}}"}, "destinations": ["stdout"]}]}`,
+		"unknown destination": `{"outputs": [{"destinations": ["ftp://localhost"]}]}`,
+		"fields conflict":     `{"outputs": [{"fields": ["a"], "drop_all_fields": true, "destinations": ["stdout"]}]}`,
+		"unnamed level":       `{"levels": [{"value": 66}], "outputs": [{"destinations": ["stdout"]}]}`,
+	} {
+		require.NotNil(t, ekalogcfg.FromConfig(strings.NewReader(cfg)), name)
+	}
+}
+
+func TestFromConfig_ReloadUnderLoad(t *testing.T) {
+
+	dir := t.TempDir()
+	paths := []string{filepath.Join(dir, "first.log"), filepath.Join(dir, "second.log")}
+
+	apply := func(path string) {
+		cfg := fmt.Sprintf(`{"outputs": [{
+			"encoder": {"format": "{{m}}"},
+			"destinations": ["file:%s"]
+		}]}`, path)
+		require.Nil(t, ekalogcfg.FromConfig(strings.NewReader(cfg)))
+	}
+
+	apply(paths[0])
+
+	var (
+		wg     sync.WaitGroup
+		stop   = make(chan struct{})
+		logged = make([]int, 4)
+	)
+
+	for i := range logged {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					ekalog.Info("entry")
+					logged[i]++
+				}
+			}
+		}(i)
+	}
+
+	// Old destinations must not be closed while something is written to them.
+	for i := 0; i < 10; i++ {
+		apply(paths[(i+1)%2])
+		time.Sleep(time.Millisecond)
+	}
+
+	close(stop)
+	wg.Wait()
+	require.NoError(t, ekalog.SyncThis())
+
+	expected, written := 0, 0
+	for i := range logged {
+		expected += logged[i]
+	}
+	for _, path := range paths {
+		written += strings.Count(readFile(t, path), "entry")
+	}
+	require.Equal(t, expected, written)
+}
+
+func TestWatchFile(t *testing.T) {
+
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.json")
+	firstPath := filepath.Join(dir, "first.log")
+	secondPath := filepath.Join(dir, "second.log")
+
+	writeConfig := func(destination string) {
+		cfg := fmt.Sprintf(`{"outputs": [{
+			"encoder": {"format": "{{m}}"},
+			"destinations": ["file:%s"]
+		}]}`, destination)
+		require.NoError(t, ioutil.WriteFile(cfgPath, []byte(cfg), 0644))
+	}
+
+	writeConfig(firstPath)
+
+	stop, err := ekalogcfg.WatchFile(cfgPath, 10*time.Millisecond)
+	require.Nil(t, err)
+	defer stop()
+
+	ekalog.Info("first")
+	require.Contains(t, readFile(t, firstPath), "first")
+
+	writeConfig(secondPath)
+
+	// Make sure modification time is changed even on coarse-grained filesystems.
+	future := time.Now().Add(time.Second)
+	require.NoError(t, os.Chtimes(cfgPath, future, future))
+
+	require.Eventually(t, func() bool {
+		ekalog.Info("second")
+		_, statErr := os.Stat(secondPath)
+		return statErr == nil && strings.Contains(readFile(t, secondPath), "second")
+	}, 2*time.Second, 20*time.Millisecond)
+
+	require.NotContains(t, readFile(t, firstPath), "second")
+}
@@ -6,7 +6,11 @@
 package ekalog
 
 import (
+	"io"
 	"math"
+	"os"
+	"path/filepath"
+	"runtime/debug"
 	"strconv"
 	"strings"
 	"time"
@@ -55,9 +59,22 @@ type (
 		// at least as more as 'formatParts' required).
 		formatParts []_CICE_FormatPart
 
+		colorMapRaw map[Level]string // level -> color verb, see SetColorFor()
 		colorMap    map[Level]string // map of default colors for each level
 		colorMapMax int              // max used len of ASCII color encoded seq.
 
+		// colorSupport is what colors are written, see SetColorSupport().
+		// Colors of color verbs are degraded to it.
+		colorSupport      ekasys.ColorSupport
+		isColorSupportSet bool
+
+		appName    string // substitution of "{{app}}" verb, see SetAppInfo()
+		appVersion string // substitution of "{{version}}" verb, see SetAppInfo()
+
+		timeLoc *time.Location // location time verbs are encoded in, see SetTimeLocation()
+
+		sourceContext int // number of source lines around marked frames, see SetSourceContext()
+
 		// Sum of: len of just text parts + predicted len of log's parts.
 		minimumBufferLen int
 
@@ -74,9 +91,13 @@ type (
 	//   (like "\033[01;03;38;05;144m");
 	// - for other 'typ' variants, 'value' is empty, 'cause it's log's verbs
 	// and they're runtime calculated entities.
+	// 'wm' is not nil if verb's text must be aligned or truncated.
+	// 'tf' is not nil for 'typ' == '_CICE_FPT_VERB_TIME'.
 	_CICE_FormatPart struct {
 		typ   _CICE_FormatPartType
 		value string
+		wm    *widthModifier
+		tf    *timeFormat
 	}
 
 	// _CICE_FormatPartType is a special type of _CICE_FormatPart's field 'typ' that contains
@@ -94,6 +115,10 @@ type (
 		afterNewLine         string
 		afterNewLineForError string
 		itemsPerLine         int16
+
+		// Keys of fields that are written by named field verbs ("{{f/=<key>}}").
+		// They are not written again as a part of fields verb.
+		pulled map[string]struct{}
 	}
 
 	_CICE_BodyFormat struct {
@@ -140,7 +165,11 @@ const (
 	_CICE_FPT_VERB_LEVEL           _CICE_FormatPartType = 0x0C
 	_CICE_FPT_VERB_STACKTRACE      _CICE_FormatPartType = 0x1A
 	_CICE_FPT_VERB_FIELDS          _CICE_FormatPartType = 0x2A
+	_CICE_FPT_VERB_FIELD           _CICE_FormatPartType = 0x2B
 	_CICE_FPT_VERB_CALLER          _CICE_FormatPartType = 0x3A
+	_CICE_FPT_VERB_GOROUTINE_ID    _CICE_FormatPartType = 0x4A
+	_CICE_FPT_VERB_ERROR_ID        _CICE_FormatPartType = 0x5A
+	_CICE_FPT_VERB_ERROR_CLASS     _CICE_FormatPartType = 0x5B
 
 	// Common Integrator Console Encoder Level Format (CICE LF)
 	// type constants.
@@ -151,20 +180,6 @@ const (
 	_CICE_LF_FULL_NORMAL           _CICE_FormatPartType = 4
 	_CICE_LF_FULL_UPPER_CASE       _CICE_FormatPartType = 5
 
-	// Common Integrator Console Encoder Time Format (CICE TF)
-	// type constants.
-
-	_CICE_TF_TIMESTAMP             _CICE_FormatPartType = 1
-	_CICE_TF_ANSIC                 _CICE_FormatPartType = 2
-	_CICE_TF_UNIXDATE              _CICE_FormatPartType = 3
-	_CICE_TF_RUBYDATE              _CICE_FormatPartType = 4
-	_CICE_TF_RFC822                _CICE_FormatPartType = 5
-	_CICE_TF_RFC822_Z              _CICE_FormatPartType = 6
-	_CICE_TF_RFC850                _CICE_FormatPartType = 7
-	_CICE_TF_RFC1123               _CICE_FormatPartType = 8
-	_CICE_TF_RFC1123_Z             _CICE_FormatPartType = 9
-	_CICE_TF_RFC3339               _CICE_FormatPartType = 10
-
 	// Common Integrator Console Encoder Caller Format (CICE CF)
 	// type constants.
 
@@ -221,6 +236,13 @@ var (
 	cevtMessage    = []string{"message", "body", "m", "b"}
 	cevtFields     = []string{"fields", "f"}
 	cevtStacktrace = []string{"stacktrace", "s"}
+	cevtPID        = []string{"pid"}
+	cevtHostname   = []string{"hostname", "host"}
+	cevtGoroutine  = []string{"goroutine", "gid"}
+	cevtAppName    = []string{"app"}
+	cevtAppVersion = []string{"version", "ver"}
+	cevtErrorID    = []string{"error_id", "eid"}
+	cevtErrorClass = []string{"error_class", "eclass"}
 )
 
 var (
@@ -255,21 +277,80 @@ func (ce *CI_ConsoleEncoder) SetFormat(newFormat string) *CI_ConsoleEncoder {
 	return ce
 }
 
+// SetAppInfo sets app's name and version that will be used as a replace
+// for "{{app}}" and "{{version}}" verbs from the 'format' string
+// that is set by SetFormat() func.
+//
+// If they are not set, the name of executable file and the version
+// of main module (if it's known) are used.
+func (ce *CI_ConsoleEncoder) SetAppInfo(name, version string) *CI_ConsoleEncoder {
+
+	ce.appName = strings.TrimSpace(name)
+	ce.appVersion = strings.TrimSpace(version)
+	return ce
+}
+
+// SetTimeLocation sets the location time verbs will be encoded in
+// (e.g. time.UTC). Entry's time is encoded as is (in local time) if it's not set.
+func (ce *CI_ConsoleEncoder) SetTimeLocation(loc *time.Location) *CI_ConsoleEncoder {
+	ce.timeLoc = loc
+	return ce
+}
+
+// SetSourceContext enables printing of source code lines under those
+// stacktrace's frames, attached error's messages or fields are bound to.
+// The line frame points to and up to 'lines' lines before and after it
+// are printed if source file exists locally. Disabled by default (0).
+func (ce *CI_ConsoleEncoder) SetSourceContext(lines int) *CI_ConsoleEncoder {
+
+	if lines >= 0 {
+		ce.sourceContext = lines
+	}
+	return ce
+}
+
+// SetColors enables or disables colors. If colors are disabled,
+// all color verbs from the 'format' string are ignored.
+// Enabled colors are the same as SetColorSupport(ekasys.COLOR_SUPPORT_TRUECOLOR).
+func (ce *CI_ConsoleEncoder) SetColors(enabled bool) *CI_ConsoleEncoder {
+	if enabled {
+		return ce.SetColorSupport(ekasys.COLOR_SUPPORT_TRUECOLOR)
+	}
+	return ce.SetColorSupport(ekasys.COLOR_SUPPORT_NONE)
+}
+
+// SetColorsAutoFor sets colors that are supported by 'w', like if 'w'
+// is a terminal (TTY) and what colors it supports.
+// NO_COLOR, FORCE_COLOR, TERM, COLORTERM env vars are respected.
+// See ekasys.ColorSupportFor() for more info.
+//
+// Keep in mind, encoder's colors are the same for all writers the encoded
+// entries are written to. Use CommonIntegrator.WithColorsAuto() to degrade
+// colors for each writer separately.
+func (ce *CI_ConsoleEncoder) SetColorsAutoFor(w io.Writer) *CI_ConsoleEncoder {
+	return ce.SetColorSupport(ekasys.ColorSupportFor(w))
+}
+
+// SetColorSupport sets what colors are written by color verbs.
+// Colors that are not supported are degraded to the closest supported ones
+// (24-bit hex, rgb colors -> xterm256 colors -> 16 basic colors)
+// or are not written at all for ekasys.COLOR_SUPPORT_NONE.
+//
+// ekasys.COLOR_SUPPORT_256 is used by default.
+func (ce *CI_ConsoleEncoder) SetColorSupport(support ekasys.ColorSupport) *CI_ConsoleEncoder {
+	ce.colorSupport, ce.isColorSupportSet = support, true
+	return ce
+}
+
 // SetColorFor sets color what will be used as a replace for level-depended
 // color verb from the 'format' string that is set by SetFormat() func.
 func (ce *CI_ConsoleEncoder) SetColorFor(level Level, color string) *CI_ConsoleEncoder {
 
-	if ce.colorMap == nil {
-		ce.colorMap = make(map[Level]string)
-	}
-
-	if encodedColor := ce.rvColorHelper(color); encodedColor != "" {
-		ce.colorMap[level] = encodedColor
-		if l := len(encodedColor); ce.colorMapMax < l {
-			ce.colorMapMax = l
-		}
+	if ce.colorMapRaw == nil {
+		ce.colorMapRaw = make(map[Level]string)
 	}
 
+	ce.colorMapRaw[level] = color
 	return ce
 }
 
@@ -299,6 +380,21 @@ func (ce *CI_ConsoleEncoder) doBuild() *CI_ConsoleEncoder {
 		ce.format = _CICE_DEFAULT_FORMAT
 	}
 
+	if ce.appName == "" && len(os.Args) > 0 {
+		ce.appName = filepath.Base(os.Args[0])
+	}
+	if ce.appVersion == "" {
+		if buildInfo, ok := debug.ReadBuildInfo(); ok && buildInfo.Main.Version != "(devel)" {
+			ce.appVersion = buildInfo.Main.Version
+		}
+	}
+
+	if !ce.isColorSupportSet {
+		ce.colorSupport = ekasys.COLOR_SUPPORT_256
+	}
+
+	ce.buildColorMap()
+
 	// start parsing ce.format
 	// all parsing loops are for-range based (because there is UTF-8 support)
 	// (yes, you can use not only ASCII parts in your format string,
@@ -352,12 +448,17 @@ func (ce *CI_ConsoleEncoder) uniteJustTextVerbs() *CI_ConsoleEncoder {
 	return ce
 }
 
-// setStandardParts saves standard colors for standard log levels
-// if they has not been set yet.
-func (ce *CI_ConsoleEncoder) setStandardParts() *CI_ConsoleEncoder {
+// buildColorMap encodes colors that are set by SetColorFor()
+// and standard colors for standard log levels if they has not been set,
+// according with the supported colors.
+func (ce *CI_ConsoleEncoder) buildColorMap() *CI_ConsoleEncoder {
+
+	ce.colorMap = make(map[Level]string)
 
-	if ce.colorMap == nil {
-		ce.colorMap = make(map[Level]string)
+	for level, color := range ce.colorMapRaw {
+		if encodedColor := ce.rvColorHelper(color); encodedColor != "" {
+			ce.colorMap[level] = encodedColor
+		}
 	}
 
 	if ce.colorMap[LEVEL_DEBUG] == "" {
@@ -380,6 +481,18 @@ func (ce *CI_ConsoleEncoder) setStandardParts() *CI_ConsoleEncoder {
 		ce.colorMap[LEVEL_FATAL] = ce.rvColorHelper(_CICE_SC_FATAL)
 	}
 
+	for _, encodedColor := range ce.colorMap {
+		if l := len(encodedColor); ce.colorMapMax < l {
+			ce.colorMapMax = l
+		}
+	}
+
+	return ce
+}
+
+// setStandardParts saves standard parts if they has not been set yet.
+func (ce *CI_ConsoleEncoder) setStandardParts() *CI_ConsoleEncoder {
+
 	if !ce.cf.isSet {
 		ce.cf.isDefault = true
 	}
@@ -451,19 +564,49 @@ func (ce *CI_ConsoleEncoder) parseFirstVerb(format string) (rest string) {
 // formatted verb.
 func (ce *CI_ConsoleEncoder) rv(verb string) (predictedLen int) {
 
+	// it guarantees that "verb" starts from "{{",
+	// so we can remove leading "{{" and trailing "}}"
+	verb = verb[2 : len(verb)-2]
+
+	// width modifiers may be used with any verb except color one,
+	// extract them to not to confuse verbs' resolvers
+	verb, wm := parseWidthModifiers(verb)
+
 	// applyOnce is a helper func to avoid many if-else statements in tht switch below.
 	applyOnce := func(isSet *bool, fallback, applicator func(string) int, verb string) int {
 		if !*isSet {
 			*isSet = true
-			return applicator(verb)
+			return ce.rvWidth(wm, applicator, verb)
 		} else {
 			return fallback(verb)
 		}
 	}
 
-	// it guarantees that "verb" starts from "{{",
-	// so we can remove leading "{{" and trailing "}}"
-	switch verb = verb[2 : len(verb)-2]; {
+	switch {
+
+	// Verbs below must be checked before others,
+	// because they may start with the same letters as short verbs do.
+
+	case hpm(verb, cevtPID):
+		return ce.rvJustText(wm.applyToText(strconv.Itoa(ekasys.PosixCachedPid())))
+
+	case hpm(verb, cevtHostname):
+		return ce.rvJustText(wm.applyToText(ekasys.CachedHostname()))
+
+	case hpm(verb, cevtGoroutine):
+		return ce.rvWidth(wm, ce.rvGoroutineID, verb)
+
+	case hpm(verb, cevtAppName):
+		return ce.rvJustText(wm.applyToText(ce.appName))
+
+	case hpm(verb, cevtAppVersion):
+		return ce.rvJustText(wm.applyToText(ce.appVersion))
+
+	case hpm(verb, cevtErrorID):
+		return ce.rvWidth(wm, ce.rvErrorID, verb)
+
+	case hpm(verb, cevtErrorClass):
+		return ce.rvWidth(wm, ce.rvErrorClass, verb)
 
 	case hpm(verb, cevtCaller):
 		return applyOnce(&ce.cf.isSet, ce.rvJustText, ce.rvCaller, verb)
@@ -472,14 +615,17 @@ func (ce *CI_ConsoleEncoder) rv(verb string) (predictedLen int) {
 		return ce.rvColor(verb)
 
 	case hpm(verb, cevtLevel):
-		return ce.rvLevel(verb)
+		return ce.rvWidth(wm, ce.rvLevel, verb)
 
 	case hpm(verb, cevtTime):
-		return ce.rvTime(verb)
+		return ce.rvWidth(wm, ce.rvTime, verb)
 
 	case hpm(verb, cevtMessage):
 		return applyOnce(&ce.bf.isSet, ce.rvJustText, ce.rvBody, verb)
 
+	case hpm(verb, cevtFields) && ce.isNamedFieldVerb(verb):
+		return ce.rvWidth(wm, ce.rvField, verb)
+
 	case hpm(verb, cevtFields):
 		return applyOnce(&ce.ff.isSet, ce.rvJustText, ce.rvFields, verb)
 
@@ -492,6 +638,28 @@ func (ce *CI_ConsoleEncoder) rv(verb string) (predictedLen int) {
 	}
 }
 
+// rvWidth calls 'resolver' for 'verb' and binds width modifiers 'wm'
+// to the verb's format part if 'resolver' has added it.
+// Returns predicted len that is adjusted according with 'wm'.
+func (ce *CI_ConsoleEncoder) rvWidth(
+
+	wm *widthModifier,
+	resolver func(verb string) (predictedLen int),
+	verb string,
+
+) (predictedLen int) {
+
+	n := len(ce.formatParts)
+	predictedLen = resolver(verb)
+
+	if wm == nil || len(ce.formatParts) != n+1 {
+		return predictedLen
+	}
+
+	ce.formatParts[n].wm = wm
+	return wm.predictedLen(predictedLen)
+}
+
 // rvHelper is a part of "resolve verb" functions but moreover it's a helper.
 // This function literally have no recognition algorithm but splits 'verb'
 // to verb parts (ignoring first part, assuming that its verb's name) and then
@@ -571,43 +739,40 @@ func (ce *CI_ConsoleEncoder) rvLevel(verb string) (predictedLen int) {
 }
 
 // rvTime is a part of "resolve verb" functions.
+// rvTime parses 'verb' as Entry's timestamp and anyway indicates that
+// here will be stored Entry's timestamp.
 //
+// Verb's argument is the name of predefined time format (like "RFC3339",
+// "RFC3339_MS", "UNIX_MS"), strftime's layout (like "%Y-%m-%d %H:%M:%S.%L")
+// or Go time layout (like "2006-01-02 15:04:05.000").
+// See parseTimeFormat() for more info.
+//
+// The format is parsed only once, here, thus there is no overhead at the runtime.
 func (ce *CI_ConsoleEncoder) rvTime(verb string) (predictedLen int) {
 
-	format := _CICE_DEFAULT_TIME_FORMAT
-	formattedTime := _CICE_FormatPartType(0)
+	tf := parseTimeFormat("", _CICE_DEFAULT_TIME_FORMAT)
 
 	(*CI_ConsoleEncoder)(nil).rvHelper(verb, func(verbPart string) (continue_ bool) {
-		if verbPart = strings.TrimSpace(format); verbPart != "" {
-			switch predefined := strings.ToUpper(verbPart); predefined {
-			case "UNIX", "TIMESTAMP":     formattedTime = _CICE_TF_TIMESTAMP
-			case "ANSIC":                 formattedTime = _CICE_TF_ANSIC
-			case "UNIXDATE", "UNIX_DATE": formattedTime = _CICE_TF_UNIXDATE
-			case "RUBYDATE", "RUBY_DATE": formattedTime = _CICE_TF_RUBYDATE
-			case "RFC822":                formattedTime = _CICE_TF_RFC822
-			case "RFC822Z":               formattedTime = _CICE_TF_RFC822_Z
-			case "RFC850":                formattedTime = _CICE_TF_RFC850
-			case "RFC1123":               formattedTime = _CICE_TF_RFC1123
-			case "RFC1123Z":              formattedTime = _CICE_TF_RFC1123_Z
-			case "RFC3339":               formattedTime = _CICE_TF_RFC3339
-			default:                      format = verbPart
-			}
-		}
+		tf = parseTimeFormat(verbPart, _CICE_DEFAULT_TIME_FORMAT)
 		return false // only first time verb is allowed and will be parsed
 	})
 
 	ce.formatParts = append(ce.formatParts, _CICE_FormatPart{
-		typ:   _CICE_FPT_VERB_TIME | (formattedTime << 8),
-		value: format,
+		typ: _CICE_FPT_VERB_TIME,
+		tf:  &tf,
 	})
 
-	return len(format) + 10 // stock for some weekdays
+	return tf.predictedLen()
 }
 
 //
 // ""
 func (ce *CI_ConsoleEncoder) rvColor(verb string) (predictedLen int) {
 
+	if ce.colorSupport == ekasys.COLOR_SUPPORT_NONE {
+		return 0
+	}
+
 	if idx := strings.IndexByte(verb, _CICE_VERB_SEPARATOR); idx == -1 {
 		ce.formatParts = append(ce.formatParts, _CICE_FormatPart{
 			typ: _CICE_FPT_VERB_COLOR_FOR_LEVEL,
@@ -626,8 +791,9 @@ func (ce *CI_ConsoleEncoder) rvColor(verb string) (predictedLen int) {
 	}
 }
 
-//
-func (_ *CI_ConsoleEncoder) rvColorHelper(colorVerb string) string {
+// rvColorHelper returns bash escape sequence of the color described
+// by 'colorVerb' degraded to the supported colors.
+func (ce *CI_ConsoleEncoder) rvColorHelper(colorVerb string) string {
 
 	cb := colorBuilder{}
 	cb.init()
@@ -636,7 +802,7 @@ func (_ *CI_ConsoleEncoder) rvColorHelper(colorVerb string) string {
 		return cb.parseEntity(verbPart)
 	})
 
-	return cb.encode()
+	return cb.encode(ce.colorSupport)
 }
 
 // rvBody is a part of "resolve verb" functions.
@@ -814,6 +980,74 @@ func (ce *CI_ConsoleEncoder) rvStacktrace(verb string) (predictedLen int) {
 	return 2048
 }
 
+// rvGoroutineID is a part of "resolve verb" functions.
+// rvGoroutineID indicates that here will be stored an ID of goroutine
+// that encodes the log Entry (it's the goroutine log message is written from,
+// because CommonIntegrator encodes entries synchronously).
+func (ce *CI_ConsoleEncoder) rvGoroutineID(_ string) (predictedLen int) {
+
+	ce.formatParts = append(ce.formatParts, _CICE_FormatPart{
+		typ: _CICE_FPT_VERB_GOROUTINE_ID,
+	})
+
+	return 8
+}
+
+// rvErrorID is a part of "resolve verb" functions.
+// rvErrorID indicates that here will be stored an ID of attached Error
+// if it's presented.
+func (ce *CI_ConsoleEncoder) rvErrorID(_ string) (predictedLen int) {
+
+	ce.formatParts = append(ce.formatParts, _CICE_FormatPart{
+		typ: _CICE_FPT_VERB_ERROR_ID,
+	})
+
+	return 36 // UUID's length
+}
+
+// rvErrorClass is a part of "resolve verb" functions.
+// rvErrorClass indicates that here will be stored a class name of attached Error
+// if it's presented.
+func (ce *CI_ConsoleEncoder) rvErrorClass(_ string) (predictedLen int) {
+
+	ce.formatParts = append(ce.formatParts, _CICE_FormatPart{
+		typ: _CICE_FPT_VERB_ERROR_CLASS,
+	})
+
+	return 32
+}
+
+// isNamedFieldVerb reports whether 'verb' is a named field verb ("{{f/=<key>}}")
+// and not a fields verb with the arguments.
+func (_ *CI_ConsoleEncoder) isNamedFieldVerb(verb string) bool {
+
+	idx := strings.IndexByte(verb, _CICE_VERB_SEPARATOR)
+	return idx != -1 && idx+2 < len(verb) && verb[idx+1] == '='
+}
+
+// rvField is a part of "resolve verb" functions.
+// rvField indicates that here will be stored the value of field with the key
+// from 'verb' ("{{f/=<key>}}"). Entry's fields are looked up first,
+// then the fields of attached Error.
+//
+// The field is written only here then, fields verb skips it.
+func (ce *CI_ConsoleEncoder) rvField(verb string) (predictedLen int) {
+
+	key := verb[strings.IndexByte(verb, _CICE_VERB_SEPARATOR)+2:]
+
+	if ce.ff.pulled == nil {
+		ce.ff.pulled = make(map[string]struct{})
+	}
+	ce.ff.pulled[key] = struct{}{}
+
+	ce.formatParts = append(ce.formatParts, _CICE_FormatPart{
+		typ:   _CICE_FPT_VERB_FIELD,
+		value: key,
+	})
+
+	return 32
+}
+
 //
 func (ce *CI_ConsoleEncoder) encode(e *Entry) []byte {
 
@@ -823,6 +1057,8 @@ func (ce *CI_ConsoleEncoder) encode(e *Entry) []byte {
 	allowEmpty := e.LogLetter.Items.Flags.TestAll(FLAG_INTEGRATOR_IGNORE_EMPTY_PARTS)
 
 	for _, part := range ce.formatParts {
+		start := len(buf)
+
 		switch part.typ.Type() {
 
 		case _CICE_FPT_VERB_JUST_TEXT:       buf = ce.encodeJustText(buf, part)
@@ -833,6 +1069,10 @@ func (ce *CI_ConsoleEncoder) encode(e *Entry) []byte {
 		case _CICE_FPT_VERB_LEVEL:           buf = ce.encodeLevel(buf, part, e)
 		case _CICE_FPT_VERB_STACKTRACE:      buf = ce.encodeStacktrace(buf, e, allowEmpty)
 		case _CICE_FPT_VERB_CALLER:          buf = ce.encodeCaller(buf, e)
+		case _CICE_FPT_VERB_FIELD:           buf = ce.encodeField(buf, part, e)
+		case _CICE_FPT_VERB_GOROUTINE_ID:    buf = ce.encodeGoroutineID(buf)
+		case _CICE_FPT_VERB_ERROR_ID:        buf = ce.encodeError(buf, part, e)
+		case _CICE_FPT_VERB_ERROR_CLASS:     buf = ce.encodeError(buf, part, e)
 
 		case _CICE_FPT_VERB_FIELDS:
 			buf = ce.encodeFields(buf, e.LogLetter.SystemFields, allowEmpty, false)
@@ -841,6 +1081,10 @@ func (ce *CI_ConsoleEncoder) encode(e *Entry) []byte {
 			}
 			buf = ce.encodeFields(buf, e.LogLetter.Items.Fields, allowEmpty, false)
 		}
+
+		if part.wm != nil {
+			buf = part.wm.apply(buf, start)
+		}
 	}
 
 	return buf
@@ -870,23 +1114,7 @@ func (ce *CI_ConsoleEncoder) encodeLevel(to []byte, fp _CICE_FormatPart, e *Entr
 //
 func (ce *CI_ConsoleEncoder) encodeTime(e *Entry, fp _CICE_FormatPart, to []byte) []byte {
 
-	formattedTime := ""
-
-	switch fp.typ.Data() {
-	case _CICE_TF_TIMESTAMP: formattedTime = strconv.FormatInt(e.Time.Unix(), 10)
-	case _CICE_TF_ANSIC:     formattedTime = e.Time.Format(time.ANSIC)
-	case _CICE_TF_UNIXDATE:  formattedTime = e.Time.Format(time.UnixDate)
-	case _CICE_TF_RUBYDATE:  formattedTime = e.Time.Format(time.RubyDate)
-	case _CICE_TF_RFC822:    formattedTime = e.Time.Format(time.RFC822)
-	case _CICE_TF_RFC822_Z:  formattedTime = e.Time.Format(time.RFC822Z)
-	case _CICE_TF_RFC850:    formattedTime = e.Time.Format(time.RFC850)
-	case _CICE_TF_RFC1123:   formattedTime = e.Time.Format(time.RFC1123)
-	case _CICE_TF_RFC1123_Z: formattedTime = e.Time.Format(time.RFC1123Z)
-	case _CICE_TF_RFC3339:   formattedTime = e.Time.Format(time.RFC3339)
-	default:                 formattedTime = e.Time.Format(fp.value)
-	}
-
-	return bufw(to, formattedTime)
+	return fp.tf.appendTo(bufgr(to, fp.tf.predictedLen()), e.Time, ce.timeLoc)
 }
 
 // easy case because ASCII sequence already generated at the rvColor method.
@@ -941,7 +1169,7 @@ func (ce *CI_ConsoleEncoder) encodeCaller(to []byte, e *Entry) []byte {
 	case len(e.LogLetter.StackTrace) > 0:
 		frame = e.LogLetter.StackTrace[0]
 
-	case e.ErrLetter != nil:
+	case e.ErrLetter != nil && len(e.ErrLetter.StackTrace) > 0:
 		frame = e.ErrLetter.StackTrace[0]
 
 	default:
@@ -951,6 +1179,68 @@ func (ce *CI_ConsoleEncoder) encodeCaller(to []byte, e *Entry) []byte {
 	return ce.encodeStackFrame(to, frame, nil, false)
 }
 
+//
+func (ce *CI_ConsoleEncoder) encodeGoroutineID(to []byte) []byte {
+	return bufw(to, strconv.FormatUint(ekasys.GoroutineID(), 10))
+}
+
+// encodeError writes attached Error's ID or class name depends on fp's type.
+func (ce *CI_ConsoleEncoder) encodeError(to []byte, fp _CICE_FormatPart, e *Entry) []byte {
+
+	if e.ErrLetter == nil {
+		return to
+	}
+
+	kind := ekafield.Kind(ekafield.KIND_SYS_TYPE_EKAERR_UUID)
+	if fp.typ.Type() == _CICE_FPT_VERB_ERROR_CLASS {
+		kind = ekafield.KIND_SYS_TYPE_EKAERR_CLASS_NAME
+	}
+
+	for i, n := 0, len(e.ErrLetter.SystemFields); i < n; i++ {
+		if e.ErrLetter.SystemFields[i].Kind.BaseType() == kind {
+			return bufw(to, e.ErrLetter.SystemFields[i].SValue)
+		}
+	}
+
+	return to
+}
+
+// encodeField writes the value of field with the key fp.value.
+// Entry's fields are looked up first, then the fields of attached Error.
+// Strings are written w/o quotes.
+func (ce *CI_ConsoleEncoder) encodeField(to []byte, fp _CICE_FormatPart, e *Entry) []byte {
+
+	field := ce.lookupField(e.LogLetter.Items.Fields, fp.value)
+	if field == nil && e.ErrLetter != nil {
+		for item := e.ErrLetter.Items; item != nil && field == nil; item = item.Next() {
+			field = ce.lookupField(item.Fields, fp.value)
+		}
+	}
+
+	switch {
+	case field == nil:
+		return to
+
+	case field.Kind.BaseType() == ekafield.KIND_TYPE_STRING:
+		return bufw(to, field.SValue)
+
+	default:
+		return ce.encodeFieldValue(to, field)
+	}
+}
+
+// lookupField returns a pointer to the field with the 'key' from 'fields'
+// or nil if there is no such field.
+func (_ *CI_ConsoleEncoder) lookupField(fields []ekafield.Field, key string) *ekafield.Field {
+
+	for i, n := 0, len(fields); i < n; i++ {
+		if fields[i].Key == key {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
 //
 func (ce *CI_ConsoleEncoder) encodeFields(
 
@@ -965,6 +1255,10 @@ func (ce *CI_ConsoleEncoder) encodeFields(
 		return to
 	}
 
+	// All fields may be skipped (empty, system, pulled by named field verbs).
+	// Nothing must be written then, even wrappers.
+	start := len(to)
+
 	if !isErrors && ce.ff.beforeFields != "" {
 		to = bufw(to, ce.ff.beforeFields)
 	}
@@ -1008,6 +1302,8 @@ func (ce *CI_ConsoleEncoder) encodeFields(
 		// https://en.wikipedia.org/wiki/Short-circuit_evaluation
 		if (!allowEmpty && fields[i].IsZero()) || strings.HasPrefix(fields[i].Key, "sys.") {
 			continue
+		} else if _, isPulled := ce.ff.pulled[fields[i].Key]; isPulled && !isErrors {
+			continue
 		} else {
 			writtenFieldIdx++
 		}
@@ -1026,90 +1322,98 @@ func (ce *CI_ConsoleEncoder) encodeFields(
 		}
 
 		// write value
+		to = ce.encodeFieldValue(to, &fields[i])
 
-		// ----- SYSTEM FIELDS -----
+		// write after value
+		if ce.ff.afterValue != "" {
+			to = bufw(to, ce.ff.afterValue)
+		}
 
-		if fields[i].Kind.IsSystem() {
-			switch fields[i].Kind.BaseType() {
+	} // end loop of fields
 
-			case ekafield.KIND_SYS_TYPE_EKAERR_UUID, ekafield.KIND_SYS_TYPE_EKAERR_CLASS_NAME,
-			ekafield.KIND_SYS_TYPE_EKAERR_PUBLIC_MESSAGE:
-				to = bufw(to, `"`)
-				to = bufw(to, fields[i].SValue)
-				to = bufw(to, `"`)
+	if writtenFieldIdx == 0 {
+		return to[:start]
+	}
 
-			case ekafield.KIND_SYS_TYPE_EKAERR_CLASS_ID:
-				to = bufw(to, strconv.FormatInt(fields[i].IValue, 10))
+	// remove last after value
+	if ce.ff.afterValue != "" {
+		to = to[:len(to)-len(ce.ff.afterValue)]
+	}
 
-			default:
-				to = bufw(to, `"<unsupported system field>"`)
-			}
-			goto END_FIELD_PROCESSING
-		}
+	if !isErrors && ce.ff.afterFields != "" {
+		to = bufw(to, ce.ff.afterFields)
+	}
 
-		// ----- NIL FIELDS -----
+	return to
+}
 
-		if fields[i].Kind.IsNil() {
-			to = bufw(to, "null")
-			goto END_FIELD_PROCESSING
-		}
+// encodeFieldValue writes the value of 'field'.
+func (_ *CI_ConsoleEncoder) encodeFieldValue(to []byte, field *ekafield.Field) []byte {
 
-		// ----- ARRAY FIELDS -----
-		// todo
+	// ----- SYSTEM FIELDS -----
 
-		// ----- BASE TYPE FIELDS -----
+	if field.Kind.IsSystem() {
+		switch field.Kind.BaseType() {
 
-		switch fields[i].Kind.BaseType() {
+		case ekafield.KIND_SYS_TYPE_EKAERR_UUID, ekafield.KIND_SYS_TYPE_EKAERR_CLASS_NAME,
+		ekafield.KIND_SYS_TYPE_EKAERR_PUBLIC_MESSAGE:
+			to = bufw(to, `"`)
+			to = bufw(to, field.SValue)
+			to = bufw(to, `"`)
 
-		case ekafield.KIND_TYPE_BOOL:
-			if fields[i].IValue != 0 {
-				to = bufw(to, "true")
-			} else {
-				to = bufw(to, "false")
-			}
+		case ekafield.KIND_SYS_TYPE_EKAERR_CLASS_ID:
+			to = bufw(to, strconv.FormatInt(field.IValue, 10))
 
-		case ekafield.KIND_TYPE_INT,
-		ekafield.KIND_TYPE_INT_8, ekafield.KIND_TYPE_INT_16,
-		ekafield.KIND_TYPE_INT_32, ekafield.KIND_TYPE_INT_64:
-			to = bufw(to, strconv.FormatInt(fields[i].IValue, 10))
+		default:
+			to = bufw(to, `"<unsupported system field>"`)
+		}
+		return to
+	}
 
-		case ekafield.KIND_TYPE_UINT,
-		ekafield.KIND_TYPE_UINT_8, ekafield.KIND_TYPE_UINT_16,
-		ekafield.KIND_TYPE_UINT_32, ekafield.KIND_TYPE_UINT_64:
-			to = bufw(to, strconv.FormatUint(uint64(fields[i].IValue), 10))
+	// ----- NIL FIELDS -----
 
-		case ekafield.KIND_TYPE_FLOAT_32:
-			f := float64(math.Float32frombits(uint32(fields[i].IValue)))
-			to = bufw(to, strconv.FormatFloat(f, 'f', 2, 32))
+	if field.Kind.IsNil() {
+		return bufw(to, "null")
+	}
 
-		case ekafield.KIND_TYPE_FLOAT_64:
-			f := float64(math.Float32frombits(uint32(fields[i].IValue)))
-			to = bufw(to, strconv.FormatFloat(f, 'f', 2, 64))
+	// ----- ARRAY FIELDS -----
+	// todo
 
-		case ekafield.KIND_TYPE_STRING:
-			to = bufw(to, `"`)
-			to = bufw(to, fields[i].SValue)
-			to = bufw(to, `"`)
+	// ----- BASE TYPE FIELDS -----
 
-		default:
+	switch field.Kind.BaseType() {
+
+	case ekafield.KIND_TYPE_BOOL:
+		if field.IValue != 0 {
+			to = bufw(to, "true")
+		} else {
+			to = bufw(to, "false")
 		}
 
-	END_FIELD_PROCESSING:
+	case ekafield.KIND_TYPE_INT,
+	ekafield.KIND_TYPE_INT_8, ekafield.KIND_TYPE_INT_16,
+	ekafield.KIND_TYPE_INT_32, ekafield.KIND_TYPE_INT_64:
+		to = bufw(to, strconv.FormatInt(field.IValue, 10))
 
-		// write after value
-		if ce.ff.afterValue != "" {
-			to = bufw(to, ce.ff.afterValue)
-		}
+	case ekafield.KIND_TYPE_UINT,
+	ekafield.KIND_TYPE_UINT_8, ekafield.KIND_TYPE_UINT_16,
+	ekafield.KIND_TYPE_UINT_32, ekafield.KIND_TYPE_UINT_64:
+		to = bufw(to, strconv.FormatUint(uint64(field.IValue), 10))
 
-	} // end loop of fields
+	case ekafield.KIND_TYPE_FLOAT_32:
+		f := float64(math.Float32frombits(uint32(field.IValue)))
+		to = bufw(to, strconv.FormatFloat(f, 'f', 2, 32))
 
-	// remove last after value
-	if ce.ff.afterValue != "" {
-		to = to[:len(to)-len(ce.ff.afterValue)]
-	}
+	case ekafield.KIND_TYPE_FLOAT_64:
+		f := float64(math.Float32frombits(uint32(field.IValue)))
+		to = bufw(to, strconv.FormatFloat(f, 'f', 2, 64))
 
-	if !isErrors && ce.ff.afterFields != "" {
-		to = bufw(to, ce.ff.afterFields)
+	case ekafield.KIND_TYPE_STRING:
+		to = bufw(to, `"`)
+		to = bufw(to, field.SValue)
+		to = bufw(to, `"`)
+
+	default:
 	}
 
 	return to
@@ -1205,6 +1509,11 @@ func (ce *CI_ConsoleEncoder) encodeStackFrame(
 		to = bufw(to, frame.DoFormat())
 	}
 
+	if ce.sourceContext > 0 && letterItem != nil &&
+		(letterItem.Message != "" || len(letterItem.Fields) > 0) {
+		to = ce.encodeSourceContext(to, frame.SourceContext(ce.sourceContext))
+	}
+
 	if letterItem != nil {
 		to = bufw(to, "\n")
 
@@ -1230,3 +1539,44 @@ func (ce *CI_ConsoleEncoder) encodeStackFrame(
 
 	return to
 }
+
+// encodeSourceContext writes source code lines under the stack frame
+// (each line starts from the new line), marking the current one:
+//
+// 		   41 | prev()
+// 		>  42 | current()
+// 		   43 | next()
+//
+func (ce *CI_ConsoleEncoder) encodeSourceContext(to []byte, lines []ekasys.SourceLine) []byte {
+
+	if len(lines) == 0 {
+		return to
+	}
+
+	lineNumWidth := len(strconv.Itoa(lines[len(lines)-1].Line))
+
+	for _, line := range lines {
+		to = bufw(to, "\n")
+
+		if ce.ff.afterNewLineForError != "" {
+			to = bufw(to, ce.ff.afterNewLineForError)
+		}
+
+		if line.IsCurrent {
+			to = bufw(to, "> ")
+		} else {
+			to = bufw(to, "  ")
+		}
+
+		lineNum := strconv.Itoa(line.Line)
+		for i := len(lineNum); i < lineNumWidth; i++ {
+			to = bufw(to, " ")
+		}
+
+		to = bufw(to, lineNum)
+		to = bufw(to, " | ")
+		to = bufw(to, line.Text)
+	}
+
+	return to
+}
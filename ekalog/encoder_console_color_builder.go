@@ -10,6 +10,8 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/qioalice/ekago/v2/ekasys"
+
 	"github.com/qioalice/ekago/v2/internal/3rdparty/xtermcolor"
 )
 
@@ -32,6 +34,11 @@ type (
 		// -2 if 'not set, use those one that was used' (not included to SGR)
 		bg, fg int16
 
+		// 24-bit colors, if they are specified by hex or rgb.
+		// A == 255 if color is set (bg, fg contains the closest xterm256 color then).
+		// They are used instead of bg, fg if truecolor is supported.
+		bgRGB, fgRGB color.RGBA
+
 		// 0 - 'not set, use those one that was used' (not included to SGR)
 		// 1 - enable (included to SGR (01/03/04))
 		// -1 - disable (included to SGR (22/23/24))
@@ -45,6 +52,7 @@ type (
 //
 func (cb *colorBuilder) init() {
 	cb.bg, cb.fg = -2, -2
+	cb.bgRGB, cb.fgRGB = color.RGBA{}, color.RGBA{}
 	cb.bold, cb.italic, cb.underline = 0, 0, 0
 }
 
@@ -94,21 +102,27 @@ func (cb *colorBuilder) parseEntity(verbPart string) (parsed bool) {
 	// TODO: Add supporting of color's literals like "red", "pink", "blue", etc.
 
 	// what's kind of color? default is fg
-	var colorDestination *int16
+	var (
+		colorDestination *int16
+		rgbDestination   *color.RGBA
+	)
 	switch {
 
 	case strings.HasPrefix(verbPart, "BG:"):
-		colorDestination = &cb.bg
+		colorDestination, rgbDestination = &cb.bg, &cb.bgRGB
 		verbPart = strings.TrimSpace(verbPart[3:])
 
 	case strings.HasPrefix(verbPart, "FG:"): // already defaulted
-		colorDestination = &cb.fg
+		colorDestination, rgbDestination = &cb.fg, &cb.fgRGB
 		verbPart = strings.TrimSpace(verbPart[3:])
 
 	default:
-		colorDestination = &cb.fg
+		colorDestination, rgbDestination = &cb.fg, &cb.fgRGB
 	}
 
+	// 24-bit color is reset, until it's specified by the current verb's part.
+	*rgbDestination = color.RGBA{}
+
 	// handle special easy cases cases
 	switch {
 	case len(verbPart) == 0:
@@ -122,7 +136,7 @@ func (cb *colorBuilder) parseEntity(verbPart string) (parsed bool) {
 
 	case verbPart[0] == '#':
 		// easy case if it's explicit hex
-		return cb.parseHexTo(verbPart[1:], colorDestination)
+		return cb.parseHexTo(verbPart[1:], colorDestination, rgbDestination)
 	}
 
 	// maybe default ASCII seq?
@@ -137,21 +151,21 @@ func (cb *colorBuilder) parseEntity(verbPart string) (parsed bool) {
 	// okay, maybe easy rgb/rgba?
 	switch {
 	case strings.HasPrefix(verbPart, "RGB:"):
-		return cb.parseRgbTo(verbPart[4:], colorDestination)
+		return cb.parseRgbTo(verbPart[4:], colorDestination, rgbDestination)
 
 	case strings.HasPrefix(verbPart, "RGBA:"):
-		return cb.parseRgbTo(verbPart[5:], colorDestination)
+		return cb.parseRgbTo(verbPart[5:], colorDestination, rgbDestination)
 
 	case strings.HasPrefix(verbPart, "RGB(") && verbPart[len(verbPart)-1] == ')':
-		return cb.parseRgbTo(verbPart[4:len(verbPart)-1], colorDestination)
+		return cb.parseRgbTo(verbPart[4:len(verbPart)-1], colorDestination, rgbDestination)
 
 	case strings.HasPrefix(verbPart, "RGBA(") && verbPart[len(verbPart)-1] == ')':
-		return cb.parseRgbTo(verbPart[4:len(verbPart)-1], colorDestination)
+		return cb.parseRgbTo(verbPart[4:len(verbPart)-1], colorDestination, rgbDestination)
 	}
 
 	// okay maybe rgb by comma?
 	if commas := strings.Count(verbPart, ","); commas >= 3 && commas <= 4 {
-		return cb.parseRgbTo(verbPart, colorDestination)
+		return cb.parseRgbTo(verbPart, colorDestination, rgbDestination)
 	}
 
 	// believe it's just XTerm 256 colors code
@@ -187,7 +201,13 @@ func (_ *colorBuilder) parseX256To(verbPart string, destination *int16) (parsed
 }
 
 //
-func (_ *colorBuilder) parseHexTo(verbPart string, destination *int16) (parsed bool) {
+func (_ *colorBuilder) parseHexTo(
+
+	verbPart string,
+	destination *int16,
+	rgbDestination *color.RGBA,
+
+) (parsed bool) {
 	// --- REMINDER! 1ST ARGUMENT IS ALWAYS UPPER CASED! ---
 
 	switch verbPart = strings.TrimSpace(verbPart); len(verbPart) {
@@ -211,14 +231,26 @@ func (_ *colorBuilder) parseHexTo(verbPart string, destination *int16) (parsed b
 		return false
 	}
 
-	termColor, err := xtermcolor.FromHexStr(verbPart)
-	*destination = int16(termColor)
+	v, err := strconv.ParseUint(verbPart, 16, 24)
+	if err != nil {
+		return false
+	}
+
+	rgb := color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 255}
+	*destination = int16(xtermcolor.FromColor(rgb))
+	*rgbDestination = rgb
 
-	return err == nil
+	return true
 }
 
 //
-func (_ *colorBuilder) parseRgbTo(verbPart string, destination *int16) (parsed bool) {
+func (_ *colorBuilder) parseRgbTo(
+
+	verbPart string,
+	destination *int16,
+	rgbDestination *color.RGBA,
+
+) (parsed bool) {
 	// --- REMINDER! 1ST ARGUMENT IS ALWAYS UPPER CASED! ---
 
 	rgbParts := strings.Split(strings.TrimSpace(verbPart), ",")
@@ -242,12 +274,19 @@ func (_ *colorBuilder) parseRgbTo(verbPart string, destination *int16) (parsed b
 
 	rgb := color.RGBA{R: uint8(r), G: uint8(g), B: uint8(b), A: 255}
 	*destination = int16(xtermcolor.FromColor(rgb))
+	*rgbDestination = rgb
 
 	return true
 }
 
-//
-func (cb *colorBuilder) encode() string {
+// encode returns bash escape sequence of the built color, degraded
+// to the 'support' (e.g. 24-bit colors are replaced by the closest xterm256 ones
+// if truecolor is not supported). Returns an empty string for COLOR_SUPPORT_NONE.
+func (cb *colorBuilder) encode(support ekasys.ColorSupport) string {
+
+	if support == ekasys.COLOR_SUPPORT_NONE {
+		return ""
+	}
 
 	if cb.bg == 100 {
 		return "\033[0m"
@@ -280,44 +319,88 @@ func (cb *colorBuilder) encode() string {
 		out += "24;" // disable underline
 	}
 
-	switch /* FOREGROUND COLOR */ {
-	case cb.fg == -2:
-		// do nothing, use those one that was used
-	case cb.fg == -1:
-		// set foreground to term default
-		out += "39;"
-	case cb.fg&(1<<14) != 0:
-		// first 16 ASCII sys colors
-		cb.fg &^= 1 << 14
-		if cb.fg >= 40 && cb.fg <= 47 || cb.fg >= 100 {
-			cb.fg -= 10
-		}
-		out += strconv.Itoa(int(cb.fg)) + ";"
-	default:
-		out += "38;5;" + strconv.Itoa(int(cb.fg)) + ";"
+	out += cb.encodeColor(cb.fg, cb.fgRGB, false, support)
+	out += cb.encodeColor(cb.bg, cb.bgRGB, true, support)
+
+	if out[len(out)-1] != ';' {
+		return "" // all values are default and ignored
 	}
 
-	switch /* BACKGROUND COLOR */ {
-	case cb.bg == -2:
+	out = out[:len(out)-1] + "m"
+	return out
+}
+
+// encodeColor returns SGR parameters (with trailing ";") of foreground
+// or background color (if 'isBg' is true) 'c' (or 'rgb' if it's set),
+// degraded to the 'support'.
+func (_ *colorBuilder) encodeColor(
+
+	c int16,
+	rgb color.RGBA,
+	isBg bool,
+	support ekasys.ColorSupport,
+
+) string {
+
+	var offset int // 0 for fg, 10 for bg (SGR 30 -> 40, 38 -> 48, 39 -> 49)
+	if isBg {
+		offset = 10
+	}
+
+	switch {
+	case c == -2:
 		// do nothing, use those one that was used
-	case cb.bg == -1:
-		// set background to term default
-		out += "49;"
-	case cb.bg&(1<<14) != 0:
+		return ""
+
+	case c == -1:
+		// set to term default
+		return strconv.Itoa(39+offset) + ";"
+
+	case c&(1<<14) != 0:
 		// first 16 ASCII sys colors
-		cb.bg &^= 1 << 14
-		if cb.bg < 40 || cb.bg >= 90 && cb.bg <= 97 {
-			cb.bg += 10
+		c &^= 1 << 14
+		if c >= 40 && c <= 47 || c >= 100 {
+			c -= 10
 		}
-		out += strconv.Itoa(int(cb.fg)) + ";"
+		return strconv.Itoa(int(c)+offset) + ";"
+
+	case rgb.A == 255 && support >= ekasys.COLOR_SUPPORT_TRUECOLOR:
+		return strconv.Itoa(38+offset) + ";2;" +
+			strconv.Itoa(int(rgb.R)) + ";" +
+			strconv.Itoa(int(rgb.G)) + ";" +
+			strconv.Itoa(int(rgb.B)) + ";"
+
+	case support >= ekasys.COLOR_SUPPORT_256:
+		return strconv.Itoa(38+offset) + ";5;" + strconv.Itoa(int(c)) + ";"
+
 	default:
-		out += "48;5;" + strconv.Itoa(int(cb.bg)) + ";"
+		return strconv.Itoa(colorXterm256To16(int(c))+offset) + ";"
 	}
+}
 
-	if out[len(out)-1] != ';' {
-		return "" // all values are default and ignored
+// colorXterm256To16 returns SGR foreground code (30-37, 90-97)
+// of the basic color that is the closest to the xterm256 color 'n'.
+func colorXterm256To16(n int) int {
+
+	if n < 0 || n > 255 {
+		return 39
 	}
 
-	out = out[:len(out)-1] + "m"
-	return out
+	if n >= 16 {
+		n = colorXterm16Palette.Index(xtermcolor.Colors[n])
+	}
+
+	if n < 8 {
+		return 30 + n
+	}
+	return 90 + n - 8
 }
+
+// colorRGBTo16 returns SGR foreground code (30-37, 90-97)
+// of the basic color that is the closest to 'rgb'.
+func colorRGBTo16(rgb color.RGBA) int {
+	return colorXterm256To16(colorXterm16Palette.Index(rgb))
+}
+
+// colorXterm16Palette is the palette of the first 16 (basic) xterm256 colors.
+var colorXterm16Palette = color.Palette(xtermcolor.Colors[:16])
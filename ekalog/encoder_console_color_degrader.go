@@ -0,0 +1,120 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekalog
+
+import (
+	"bytes"
+	"image/color"
+	"strconv"
+
+	"github.com/qioalice/ekago/v2/ekasys"
+
+	"github.com/qioalice/ekago/v2/internal/3rdparty/xtermcolor"
+)
+
+// degradeColors returns 'src' with ANSI SGR escape sequences (colors)
+// degraded to the 'support':
+//
+//   - ekasys.COLOR_SUPPORT_NONE: all SGR sequences are removed,
+//   - ekasys.COLOR_SUPPORT_16: xterm256 and 24-bit colors are replaced
+//     by the closest basic colors,
+//   - ekasys.COLOR_SUPPORT_256: 24-bit colors are replaced by the closest
+//     xterm256 colors.
+//
+// Returns 'src' itself if there is nothing to degrade, a new slice otherwise.
+func degradeColors(src []byte, support ekasys.ColorSupport) []byte {
+
+	if support >= ekasys.COLOR_SUPPORT_TRUECOLOR || bytes.IndexByte(src, '\033') == -1 {
+		return src
+	}
+
+	dst := make([]byte, 0, len(src))
+
+	for i := 0; i < len(src); {
+
+		n := (*widthModifier)(nil).escapeSequenceLen(src[i:])
+		if n == 0 {
+			dst = append(dst, src[i])
+			i++
+			continue
+		}
+
+		switch sequence := src[i : i+n]; {
+
+		case sequence[n-1] != 'm':
+			// not SGR sequence, keep it as is
+			dst = append(dst, sequence...)
+
+		case support == ekasys.COLOR_SUPPORT_NONE:
+			// drop it
+
+		default:
+			dst = append(dst, "\033["...)
+			dst = degradeSGRParams(dst, sequence[2:n-1], support)
+			dst = append(dst, 'm')
+		}
+
+		i += n
+	}
+
+	return dst
+}
+
+// degradeSGRParams writes SGR parameters 'params' (like "01;38;2;255;0;0")
+// degraded to the 'support' to 'dst', returning it.
+func degradeSGRParams(dst, params []byte, support ekasys.ColorSupport) []byte {
+
+	parts := bytes.Split(params, []byte{';'})
+
+	for i := 0; i < len(parts); i++ {
+
+		offset := -1 // 0 for fg, 10 for bg if it's extended color
+		switch string(parts[i]) {
+		case "38":
+			offset = 0
+		case "48":
+			offset = 10
+		}
+
+		var degraded string
+		switch {
+
+		case offset == -1 || i+1 >= len(parts):
+			// not an extended color, keep as is
+
+		case string(parts[i+1]) == "5" && i+2 < len(parts) && support < ekasys.COLOR_SUPPORT_256:
+			n, _ := strconv.Atoi(string(parts[i+2]))
+			degraded = strconv.Itoa(colorXterm256To16(n) + offset)
+			i += 2
+
+		case string(parts[i+1]) == "2" && i+4 < len(parts):
+			r, _ := strconv.Atoi(string(parts[i+2]))
+			g, _ := strconv.Atoi(string(parts[i+3]))
+			b, _ := strconv.Atoi(string(parts[i+4]))
+			rgb := color.RGBA{R: uint8(r), G: uint8(g), B: uint8(b), A: 255}
+
+			if support >= ekasys.COLOR_SUPPORT_256 {
+				degraded = strconv.Itoa(38+offset) + ";5;" +
+					strconv.Itoa(int(xtermcolor.FromColor(rgb)))
+			} else {
+				degraded = strconv.Itoa(colorRGBTo16(rgb) + offset)
+			}
+			i += 4
+		}
+
+		if len(dst) > 0 && dst[len(dst)-1] != '[' {
+			dst = append(dst, ';')
+		}
+
+		if degraded != "" {
+			dst = append(dst, degraded...)
+		} else {
+			dst = append(dst, parts[i]...)
+		}
+	}
+
+	return dst
+}
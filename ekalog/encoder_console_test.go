@@ -0,0 +1,226 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekalog_test
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qioalice/ekago/v2/ekaerr"
+	"github.com/qioalice/ekago/v2/ekalog"
+	"github.com/qioalice/ekago/v2/ekasys"
+
+	"github.com/stretchr/testify/require"
+)
+
+// consoleOutput replaces ekalog's integrator by the one, that writes log entries
+// encoded by 'ce' to the returned buffer.
+func consoleOutput(ce *ekalog.CI_ConsoleEncoder) *bytes.Buffer {
+
+	b := new(bytes.Buffer)
+	ekalog.ReplaceIntegrator(new(ekalog.CommonIntegrator).
+		WithEncoder(ce.FreezeAndGetEncoder()).
+		WriteTo(b),
+	)
+	return b
+}
+
+func TestCI_ConsoleEncoder_ProcessVerbs(t *testing.T) {
+
+	hostname, _ := os.Hostname()
+
+	b := consoleOutput(new(ekalog.CI_ConsoleEncoder).
+		SetFormat("{{pid}}|{{host}}|{{app}}|{{version}}|{{gid}}").
+		SetAppInfo("myapp", "v1.2.3"),
+	)
+
+	ekalog.Info("test")
+
+	parts := strings.Split(b.String(), "|")
+	require.Len(t, parts, 5)
+
+	require.Equal(t, strconv.Itoa(os.Getpid()), parts[0])
+	require.Equal(t, hostname, parts[1])
+	require.Equal(t, "myapp", parts[2])
+	require.Equal(t, "v1.2.3", parts[3])
+
+	gid, err := strconv.ParseUint(parts[4], 10, 64)
+	require.NoError(t, err)
+	require.NotZero(t, gid)
+}
+
+func TestCI_ConsoleEncoder_NamedFields(t *testing.T) {
+
+	b := consoleOutput(new(ekalog.CI_ConsoleEncoder).
+		SetFormat("{{c/fg:ascii:32}}{{f/=request_id}}{{c/0}} {{m}} {{f/?^[/v=/e,/*0/?$]}} {{f/=missing}}<"),
+	)
+
+	ekalog.Info("test", "request_id", "abc", "user", 42)
+
+	require.Equal(t, "\033[32mabc\033[0m test [user=42] <", b.String())
+
+	// All fields are pulled, fields verb must write nothing, even wrappers.
+	b = consoleOutput(new(ekalog.CI_ConsoleEncoder).
+		SetFormat("{{m}} rid={{f/=request_id}}{{f/?^ [/?$]/e, }}"),
+	)
+
+	ekalog.Info("hello world", "request_id", "abc")
+	require.Equal(t, "hello world rid=abc", b.String())
+
+	// Fields verb's modifiers are not field names.
+	b = consoleOutput(new(ekalog.CI_ConsoleEncoder).
+		SetFormat("{{f/=c}}|{{f/E-}}"),
+	)
+
+	ekalog.Info("", "a", 1, "b", 2, "c", 3)
+	require.Equal(t, "3|a1-b2", b.String())
+}
+
+func TestCI_ConsoleEncoder_ErrorVerbs(t *testing.T) {
+
+	b := consoleOutput(new(ekalog.CI_ConsoleEncoder).
+		SetFormat("{{eclass}}|{{eid}}|{{f/=in_error}}"),
+	)
+
+	ekalog.Info("w/o error")
+	require.Equal(t, "||", b.String())
+
+	b.Reset()
+	markedError().LogAsError()
+
+	parts := strings.Split(b.String(), "|")
+	require.Len(t, parts, 3)
+	require.Equal(t, ekaerr.IllegalArgument.FullName(), parts[0])
+	require.Len(t, parts[1], 36)
+	require.Equal(t, "1", parts[2])
+}
+
+func TestCI_ConsoleEncoder_WidthModifiers(t *testing.T) {
+
+	b := consoleOutput(new(ekalog.CI_ConsoleEncoder).
+		SetFormat("[{{l/w:-8}}][{{l/S/w:5}}][{{m/w:-6/trunc}}][{{f/=user/max:2}}][{{app/w:4/trunc}}]").
+		SetAppInfo("myapp", ""),
+	)
+
+	ekalog.Info("привет, мир", "user", "John")
+	require.Equal(t, "[Info    ][  INF][приве…][Jo][mya…]", b.String())
+
+	b.Reset()
+	ekalog.Info("hi", "user", "J")
+	require.Equal(t, "[Info    ][  INF][hi    ][J][mya…]", b.String())
+}
+
+func TestCI_ConsoleEncoder_WidthModifiersColors(t *testing.T) {
+
+	b := consoleOutput(new(ekalog.CI_ConsoleEncoder).
+		SetFormat("[{{m/w:4/trunc}}]"),
+	)
+
+	ekalog.Info("\033[31mred\033[0m")
+	require.Equal(t, "[ \033[31mred\033[0m]", b.String())
+
+	b.Reset()
+	ekalog.Info("\033[31mredder\033[0m")
+	require.Equal(t, "[\033[31mred…\033[0m]", b.String())
+}
+
+func TestCI_ConsoleEncoder_TimeFormats(t *testing.T) {
+
+	loc := time.FixedZone("UTC+3", 3*60*60)
+
+	b := consoleOutput(new(ekalog.CI_ConsoleEncoder).
+		SetFormat("{{t/RFC3339_MS}}|{{t/%Y-%m-%d %H:%M:%S.%f}}|{{t/15:04}}|{{t/unix_ms}}|{{t/ UNIX }}").
+		SetTimeLocation(loc),
+	)
+
+	before := time.Now()
+	ekalog.Info("test")
+	after := time.Now()
+
+	parts := strings.Split(b.String(), "|")
+	require.Len(t, parts, 5)
+
+	parsed, err := time.Parse("2006-01-02T15:04:05.000Z07:00", parts[0])
+	require.NoError(t, err)
+	require.True(t, strings.HasSuffix(parts[0], "+03:00"))
+	require.WithinDuration(t, before, parsed, time.Second)
+
+	parsed, err = time.ParseInLocation("2006-01-02 15:04:05.000000", parts[1], loc)
+	require.NoError(t, err)
+	require.False(t, parsed.Before(before.Truncate(time.Microsecond)))
+	require.False(t, parsed.After(after))
+
+	require.Equal(t, parsed.Format("15:04"), parts[2])
+
+	ms, err := strconv.ParseInt(parts[3], 10, 64)
+	require.NoError(t, err)
+	require.True(t, ms >= before.UnixNano()/1e6 && ms <= after.UnixNano()/1e6)
+
+	sec, err := strconv.ParseInt(parts[4], 10, 64)
+	require.NoError(t, err)
+	require.True(t, sec >= before.Unix() && sec <= after.Unix())
+}
+
+func TestCI_ConsoleEncoder_SourceContext(t *testing.T) {
+
+	b := consoleOutput(new(ekalog.CI_ConsoleEncoder).
+		SetFormat("{{m}}{{s}}").
+		SetSourceContext(2),
+	)
+
+	markedError().LogAsError()
+
+	require.Contains(t, b.String(), `AddFields("in_error", 1).`)
+	require.Contains(t, b.String(), "> ")
+	require.Contains(t, b.String(), " | ")
+}
+
+func TestCI_ConsoleEncoder_ColorsDisabled(t *testing.T) {
+
+	b := consoleOutput(new(ekalog.CI_ConsoleEncoder).
+		SetFormat("{{c}}{{l}}{{c/0}} {{c/fg:ascii:32}}{{m}}{{c/0}}").
+		SetColors(false),
+	)
+
+	ekalog.Info("test")
+	require.Equal(t, "Info test", b.String())
+
+	t.Setenv("FORCE_COLOR", "")
+	b = consoleOutput(new(ekalog.CI_ConsoleEncoder).
+		SetFormat("{{c/fg:ascii:32}}{{m}}").
+		SetColorsAutoFor(new(bytes.Buffer)),
+	)
+
+	ekalog.Info("test")
+	require.Equal(t, "test", b.String())
+}
+
+func TestCI_ConsoleEncoder_ColorSupport(t *testing.T) {
+
+	tests := []struct {
+		support  ekasys.ColorSupport
+		expected string
+	}{
+		{ekasys.COLOR_SUPPORT_TRUECOLOR, "\033[38;2;255;0;0mtest"},
+		{ekasys.COLOR_SUPPORT_256, "\033[38;5;9mtest"},
+		{ekasys.COLOR_SUPPORT_16, "\033[91mtest"},
+		{ekasys.COLOR_SUPPORT_NONE, "test"},
+	}
+
+	for _, test := range tests {
+		b := consoleOutput(new(ekalog.CI_ConsoleEncoder).
+			SetFormat("{{c/fg:#ff0000}}{{m}}").
+			SetColorSupport(test.support),
+		)
+
+		ekalog.Info("test")
+		require.Equal(t, test.expected, b.String(), test.support.String())
+	}
+}
@@ -0,0 +1,245 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekalog
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type (
+	// widthModifier is a helper to align console encoder's verbs by columns.
+	//
+	// Objects of this type are instantiated at the verbs parsing,
+	// their fields are filled by modifiers provided at the verb:
+	//
+	//   - "w:<int>": fixed width. Positive value aligns verb's text to the right,
+	//     negative to the left (like fmt's "%5s" and "%-5s"). Text is padded
+	//     by spaces if it's shorter. Longer text is kept as is w/o "trunc".
+	//   - "max:<int>": text is truncated if it's longer.
+	//   - "trunc": text that is longer than width is truncated with "…" at the end.
+	//
+	// Width is measured in runes, ANSI escape sequences are not counted
+	// and are never truncated (so colors are reset correctly even if text is cut).
+	widthModifier struct {
+		width int  // 0 if not set, < 0 if text must be aligned to the left
+		max   int  // 0 if not set
+		trunc bool // true if text must be truncated up to width with ellipsis
+	}
+)
+
+//noinspection GoSnakeCaseUsage
+const (
+	_CICE_WM_ELLIPSIS string = "…"
+)
+
+// parseWidthModifiers extracts width modifiers from 'verb'
+// returning a verb w/o them and parsed modifiers (nil if there are no modifiers).
+// The first verb's part (verb's name) is never treated as a modifier.
+func parseWidthModifiers(verb string) (string, *widthModifier) {
+
+	idx := strings.IndexByte(verb, _CICE_VERB_SEPARATOR)
+	if idx == -1 ||
+		!strings.Contains(verb[idx:], "/w:") &&
+			!strings.Contains(verb[idx:], "/max:") &&
+			!strings.Contains(verb[idx:], "/trunc") {
+		return verb, nil
+	}
+
+	var (
+		wm    widthModifier
+		found bool
+		parts = strings.Split(verb[idx+1:], string(_CICE_VERB_SEPARATOR))
+		kept  = parts[:0]
+	)
+
+	for _, part := range parts {
+		switch {
+		case part == "trunc":
+			wm.trunc, found = true, true
+
+		case strings.HasPrefix(part, "w:") && wm.parseInt(part[2:], &wm.width):
+			found = true
+
+		case strings.HasPrefix(part, "max:") && wm.parseInt(part[4:], &wm.max) && wm.max > 0:
+			found = true
+
+		default:
+			kept = append(kept, part)
+		}
+	}
+
+	if !found {
+		return verb, nil
+	}
+
+	if len(kept) == 0 {
+		return verb[:idx], &wm
+	}
+	return verb[:idx+1] + strings.Join(kept, string(_CICE_VERB_SEPARATOR)), &wm
+}
+
+//
+func (_ *widthModifier) parseInt(s string, to *int) bool {
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return false
+	}
+	*to = v
+	return true
+}
+
+// limit returns max count of runes text may have. 0 means no limit.
+func (wm *widthModifier) limit() int {
+
+	limit := wm.max
+	if wm.trunc && wm.width != 0 {
+		width := wm.width
+		if width < 0 {
+			width = -width
+		}
+		if limit == 0 || width < limit {
+			limit = width
+		}
+	}
+	return limit
+}
+
+// predictedLen returns predicted len of verb's text after modifiers are applied,
+// if 'predictedLen' is the predicted len of verb's text w/o modifiers.
+func (wm *widthModifier) predictedLen(predictedLen int) int {
+
+	if limit := wm.limit(); limit > 0 && limit*utf8.UTFMax < predictedLen {
+		predictedLen = limit * utf8.UTFMax
+	}
+
+	width := wm.width
+	if width < 0 {
+		width = -width
+	}
+	if predictedLen < width {
+		predictedLen = width
+	}
+
+	return predictedLen + len(_CICE_WM_ELLIPSIS)
+}
+
+// applyToText returns 'text' with applied modifiers. Nil safe.
+func (wm *widthModifier) applyToText(text string) string {
+	if wm == nil {
+		return text
+	}
+	return string(wm.apply([]byte(text), 0))
+}
+
+// apply applies modifiers to the text 'buf[start:]' returning modified 'buf'.
+func (wm *widthModifier) apply(buf []byte, start int) []byte {
+
+	runes, cut, lastKept := wm.measure(buf[start:], wm.limit())
+
+	if cut != -1 {
+		// Text must be truncated. Cut it, but keep ANSI escape sequences
+		// that are after the cut position.
+		if wm.trunc {
+			// ellipsis takes place of the last kept rune
+			cut = lastKept
+		}
+		tail := wm.escapeSequences(buf[start+cut:])
+		buf = buf[:start+cut]
+		if wm.trunc {
+			buf = append(buf, _CICE_WM_ELLIPSIS...)
+		}
+		buf = append(buf, tail...)
+		runes = wm.limit()
+	}
+
+	width := wm.width
+	if width < 0 {
+		width = -width
+	}
+
+	pad := width - runes
+	if pad <= 0 {
+		return buf
+	}
+
+	end := len(buf)
+	for i := 0; i < pad; i++ {
+		buf = append(buf, ' ')
+	}
+
+	if wm.width > 0 {
+		// align to the right: move text, fill the beginning by spaces
+		copy(buf[start+pad:], buf[start:end])
+		for i := start; i < start+pad; i++ {
+			buf[i] = ' '
+		}
+	}
+
+	return buf
+}
+
+// measure returns a number of runes in 'text' (ANSI escape sequences excluded),
+// the byte index 'text' must be cut at to contain 'limit' runes
+// (or -1 if 'text' is not longer than 'limit' or 'limit' is 0)
+// and the byte index of the last rune that is kept after cut.
+func (wm *widthModifier) measure(text []byte, limit int) (runes, cut, lastKept int) {
+
+	cut = -1
+	for i := 0; i < len(text); {
+
+		if n := wm.escapeSequenceLen(text[i:]); n > 0 {
+			i += n
+			continue
+		}
+
+		if limit > 0 && runes == limit && cut == -1 {
+			cut = i
+		}
+		if limit > 0 && runes == limit-1 {
+			lastKept = i
+		}
+
+		_, size := utf8.DecodeRune(text[i:])
+		i += size
+		runes++
+	}
+
+	return runes, cut, lastKept
+}
+
+// escapeSequences returns only ANSI escape sequences from 'text'.
+func (wm *widthModifier) escapeSequences(text []byte) []byte {
+
+	var sequences []byte
+	for i := 0; i < len(text); {
+		if n := wm.escapeSequenceLen(text[i:]); n > 0 {
+			sequences = append(sequences, text[i:i+n]...)
+			i += n
+		} else {
+			_, size := utf8.DecodeRune(text[i:])
+			i += size
+		}
+	}
+	return sequences
+}
+
+// escapeSequenceLen returns the len of ANSI CSI escape sequence
+// (like "\033[01;31m") that 'text' starts with or 0 if it's not.
+func (_ *widthModifier) escapeSequenceLen(text []byte) int {
+
+	if len(text) < 2 || text[0] != '\033' || text[1] != '[' {
+		return 0
+	}
+
+	for i := 2; i < len(text); i++ {
+		if text[i] >= 0x40 && text[i] <= 0x7E {
+			return i + 1
+		}
+	}
+	return 0
+}
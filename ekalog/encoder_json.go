@@ -51,6 +51,21 @@ type (
 		// You may set this value using SetIndent() method.
 		indent int
 
+		// timeFormat is a RAW time format that is set by SetTimeFormat() method.
+		// It's parsed to 'tf' at the first FreezeAndGetEncoder() call.
+		timeFormat string
+		tf         timeFormat
+
+		// sourceContext is a number of source code lines before and after
+		// the line of stacktrace's frames, attached error's messages
+		// or fields are bound to, that must be encoded as "source" array.
+		// You may set this value using SetSourceContext() method.
+		sourceContext int
+
+		// timeLoc is a location Entry's time is encoded in.
+		// You may set this value using SetTimeLocation() method.
+		timeLoc *time.Location
+
 		// api is jsoniter's API object.
 		// Created at the first FreezeAndGetEncoder() call for object.
 		// Won't be called twice. Only one.
@@ -74,6 +89,46 @@ func (je *CI_JSONEncoder) SetIndent(num int) *CI_JSONEncoder {
 	return je
 }
 
+// SetTimeFormat sets the format Entry's time will be encoded with.
+// It might be:
+//
+//   - the name of predefined format (case insensitive), like "RFC3339",
+//     "RFC3339_MS", "RFC3339_US", "UnixDate", "Kitchen", etc,
+//   - "UNIX", "UNIX_MS", "UNIX_US", "UNIX_NS": UNIX timestamp
+//     with the specified precision. It's encoded as JSON number, not string,
+//   - strftime's layout, if it contains "%", like "%Y-%m-%d %H:%M:%S.%L",
+//   - Go time layout otherwise, like "2006-01-02 15:04:05.000".
+//
+// The format is parsed only once, at the FreezeAndGetEncoder() call,
+// thus there is no overhead at the runtime. time.UnixDate is used by default.
+func (je *CI_JSONEncoder) SetTimeFormat(format string) *CI_JSONEncoder {
+	je.timeFormat = format
+	return je
+}
+
+// SetTimeLocation sets the location Entry's time will be encoded in
+// (e.g. time.UTC). Entry's time is encoded as is (in local time) if it's not set.
+func (je *CI_JSONEncoder) SetTimeLocation(loc *time.Location) *CI_JSONEncoder {
+	je.timeLoc = loc
+	return je
+}
+
+// SetSourceContext enables encoding of source code lines of those
+// stacktrace's frames, attached error's messages or fields are bound to.
+// The line frame points to and up to 'lines' lines before and after it
+// are encoded as "source" array if source file exists locally:
+//
+// 		"source": [{"line": 41, "text": "..."}, {"line": 42, "text": "...", "current": true}]
+//
+// Disabled by default (0).
+func (je *CI_JSONEncoder) SetSourceContext(lines int) *CI_JSONEncoder {
+
+	if lines >= 0 {
+		je.sourceContext = lines
+	}
+	return je
+}
+
 // FreezeAndGetEncoder builds current CI_JSONEncoder if it has not built yet
 // returning a function (has an alias CI_Encoder) that can be used at the
 // CommonIntegrator.WithEncoder() call while initializing.
@@ -94,6 +149,8 @@ func (je *CI_JSONEncoder) doBuild() *CI_JSONEncoder {
 		return je
 	}
 
+	je.tf = parseTimeFormat(je.timeFormat, time.UnixDate)
+
 	je.api = jsoniter.Config{
 		IndentionStep:                 je.indent,
 		MarshalFloatWith6Digits:       true,
@@ -127,7 +184,7 @@ func (je *CI_JSONEncoder) encode(e *Entry) []byte {
 	s.WriteMore()
 
 	wasAdded :=
-		je.encodeFields(s, allowEmpty, e.LogLetter.Items.Fields)
+		je.encodeFields(s, e.key("fields"), allowEmpty, e.LogLetter.Items.Fields)
 	if wasAdded {
 		s.WriteMore()
 	}
@@ -156,54 +213,80 @@ func (je *CI_JSONEncoder) encode(e *Entry) []byte {
 	return copied
 }
 
+// encodeTime encodes 't' as JSON string using je.tf right into the s's buffer.
+func (je *CI_JSONEncoder) encodeTime(s *jsoniter.Stream, t time.Time) {
+
+	buf := append(s.Buffer(), '"')
+	start := len(buf)
+	buf = je.tf.appendTo(buf, t, je.timeLoc)
+
+	// Only strftime's or Go's layout literals may require escaping.
+	// It's rare, so there is no problem to allocate memory for that case.
+	for _, c := range buf[start:] {
+		if c < 0x20 || c == '"' || c == '\\' {
+			s.SetBuffer(buf[:start-1])
+			s.WriteString(string(buf[start:]))
+			return
+		}
+	}
+
+	s.SetBuffer(append(buf, '"'))
+}
+
 // encodeBase encodes e's level, timestamp, message to s.
 func (je *CI_JSONEncoder) encodeBase(s *jsoniter.Stream, e *Entry, allowEmpty bool) {
 
-	s.WriteObjectField("level")
+	s.WriteObjectField(e.key("level"))
 	s.WriteString(e.Level.String())
 	s.WriteMore()
 
-	s.WriteObjectField("level_value")
+	s.WriteObjectField(e.key("level_value"))
 	s.WriteUint8(uint8(e.Level))
 	s.WriteMore()
 
-	s.WriteObjectField("time")
-	s.WriteString(e.Time.Format(time.UnixDate))
+	s.WriteObjectField(e.key("time"))
+	if je.tf.epoch != 0 {
+		s.SetBuffer(je.tf.appendTo(s.Buffer(), e.Time, nil))
+	} else {
+		je.encodeTime(s, e.Time)
+	}
 
 	if e.ErrLetter != nil {
 		s.WriteMore()
-		je.encodeError(s, e.ErrLetter, allowEmpty)
+		je.encodeError(s, e, allowEmpty)
 	}
 
 	if len(e.LogLetter.Items.Message) > 0 || allowEmpty {
 
 		s.WriteMore()
-		s.WriteObjectField("message")
+		s.WriteObjectField(e.key("message"))
 		s.WriteString(e.LogLetter.Items.Message)
 	}
 }
 
 //
-func (je *CI_JSONEncoder) encodeError(s *jsoniter.Stream, errLetter *ekaletter.Letter, allowEmpty bool) {
+func (je *CI_JSONEncoder) encodeError(s *jsoniter.Stream, e *Entry, allowEmpty bool) {
+
+	errLetter := e.ErrLetter
 
 	for i, n := 0, len(errLetter.SystemFields); i < n; i++ {
 		switch errLetter.SystemFields[i].BaseType() {
 
 		case ekafield.KIND_SYS_TYPE_EKAERR_UUID:
-			s.WriteObjectField("error_id")
+			s.WriteObjectField(e.key("error_id"))
 			s.WriteString(errLetter.SystemFields[i].SValue)
 
 		case ekafield.KIND_SYS_TYPE_EKAERR_CLASS_ID:
-			s.WriteObjectField("error_class_id")
+			s.WriteObjectField(e.key("error_class_id"))
 			s.WriteInt64(errLetter.SystemFields[i].IValue)
 
 		case ekafield.KIND_SYS_TYPE_EKAERR_CLASS_NAME:
-			s.WriteObjectField("error_class_name")
+			s.WriteObjectField(e.key("error_class_name"))
 			s.WriteString(errLetter.SystemFields[i].SValue)
 
 		case ekafield.KIND_SYS_TYPE_EKAERR_PUBLIC_MESSAGE:
 			if publicMessage := errLetter.SystemFields[i].SValue; len(publicMessage) > 0 || allowEmpty {
-				s.WriteObjectField("error_public_message")
+				s.WriteObjectField(e.key("error_public_message"))
 				s.WriteString(publicMessage)
 				if i < n-1 {
 					s.WriteMore()
@@ -237,7 +320,7 @@ func (je *CI_JSONEncoder) encodeStacktrace(
 		return false
 	}
 
-	s.WriteObjectField("stacktrace")
+	s.WriteObjectField(e.key("stacktrace"))
 
 	letterItem := (*ekaletter.LetterItem)(nil)
 	letterItemIdx := int16(0)
@@ -296,6 +379,10 @@ func (je *CI_JSONEncoder) encodeStackFrame(
 	s.WriteString(frame.Format[frame.FormatFullPathOffset:])
 
 	if letterItem != nil {
+		if je.sourceContext > 0 &&
+			(len(letterItem.Message) > 0 || len(letterItem.Fields) > 0) {
+			je.encodeSourceContext(s, frame.SourceContext(je.sourceContext))
+		}
 		if len(letterItem.Message) > 0 || allowEmpty {
 			s.WriteMore()
 			s.WriteObjectField("message")
@@ -303,7 +390,7 @@ func (je *CI_JSONEncoder) encodeStackFrame(
 		}
 		if len(letterItem.Fields) > 0 || allowEmpty {
 			s.WriteMore()
-			je.encodeFields(s, allowEmpty, letterItem.Fields)
+			je.encodeFields(s, "fields", allowEmpty, letterItem.Fields)
 		}
 	}
 
@@ -311,7 +398,7 @@ func (je *CI_JSONEncoder) encodeStackFrame(
 }
 
 // JsonEncodeFields is JSON encoding helper that encodes 'fields' as JSON array,
-// adding it to the JSON document's root with the key "fields".
+// adding it to the JSON document's root with the 'key' (usually "fields").
 //
 // Puts JSON encoded data into 's' stream,
 // doing nothing if 'fields' is empty and 'allowEmpty' is false.
@@ -320,6 +407,7 @@ func (je *CI_JSONEncoder) encodeStackFrame(
 func (je *CI_JSONEncoder) encodeFields(
 
 	s *jsoniter.Stream,
+	key string,
 	allowEmpty bool,
 	fields []ekafield.Field,
 
@@ -331,7 +419,7 @@ func (je *CI_JSONEncoder) encodeFields(
 		return false
 	}
 
-	s.WriteObjectField("fields")
+	s.WriteObjectField(key)
 
 	if emptySet {
 		s.WriteEmptyArray()
@@ -390,4 +478,41 @@ func (je *CI_JSONEncoder) encodeField(
 	}
 
 	s.WriteObjectEnd()
-}
\ No newline at end of file
+}
+
+// encodeSourceContext writes source code lines as "source" JSON array
+// of objects. Does nothing if 'lines' is empty.
+func (je *CI_JSONEncoder) encodeSourceContext(s *jsoniter.Stream, lines []ekasys.SourceLine) {
+
+	if len(lines) == 0 {
+		return
+	}
+
+	s.WriteMore()
+	s.WriteObjectField("source")
+	s.WriteArrayStart()
+
+	for i, line := range lines {
+		s.WriteObjectStart()
+
+		s.WriteObjectField("line")
+		s.WriteInt(line.Line)
+		s.WriteMore()
+
+		s.WriteObjectField("text")
+		s.WriteString(line.Text)
+
+		if line.IsCurrent {
+			s.WriteMore()
+			s.WriteObjectField("current")
+			s.WriteTrue()
+		}
+
+		s.WriteObjectEnd()
+		if i < len(lines)-1 {
+			s.WriteMore()
+		}
+	}
+
+	s.WriteArrayEnd()
+}
@@ -0,0 +1,104 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekalog_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/qioalice/ekago/v2/ekalog"
+
+	"github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+)
+
+// jsonTime logs an entry using JSON encoder 'je' and returns
+// encoded "time" value.
+func jsonTime(t *testing.T, je *ekalog.CI_JSONEncoder) interface{} {
+
+	var b bytes.Buffer
+	ekalog.ReplaceIntegrator(new(ekalog.CommonIntegrator).
+		WithEncoder(je.FreezeAndGetEncoder()).
+		WriteTo(&b),
+	)
+
+	ekalog.Info("test")
+
+	var decoded map[string]interface{}
+	require.NoError(t, jsoniter.Unmarshal(b.Bytes(), &decoded))
+	return decoded["time"]
+}
+
+func TestCI_JSONEncoder_TimeFormat(t *testing.T) {
+
+	before := time.Now()
+
+	encoded := jsonTime(t, new(ekalog.CI_JSONEncoder).
+		SetTimeFormat("RFC3339_US").
+		SetTimeLocation(time.UTC),
+	)
+	require.IsType(t, "", encoded)
+
+	parsed, err := time.Parse(time.RFC3339Nano, encoded.(string))
+	require.NoError(t, err)
+	require.Equal(t, time.UTC, parsed.Location())
+	require.WithinDuration(t, before, parsed, time.Second)
+
+	encoded = jsonTime(t, new(ekalog.CI_JSONEncoder).SetTimeFormat("%d.%m.%Y"))
+	require.Equal(t, time.Now().Format("02.01.2006"), encoded)
+
+	// strftime's literals are written as is, even if they look like Go's layout.
+	encoded = jsonTime(t, new(ekalog.CI_JSONEncoder).
+		SetTimeFormat("(day 1 of %A) PM-site %%Y %H:%M:%S.%L").
+		SetTimeLocation(time.UTC),
+	)
+	now := time.Now().UTC()
+	require.Regexp(t, `^\(day 1 of `+now.Format("Monday")+`\) PM-site %Y \d\d:\d\d:\d\d\.\d{3}$`, encoded)
+
+	encoded = jsonTime(t, new(ekalog.CI_JSONEncoder).
+		SetTimeFormat(`"%Y"%n`).
+		SetTimeLocation(time.UTC),
+	)
+	require.Equal(t, `"`+now.Format("2006")+`"`+"\n", encoded)
+
+	encoded = jsonTime(t, new(ekalog.CI_JSONEncoder).SetTimeFormat("UNIX_NS"))
+	require.IsType(t, float64(0), encoded)
+	require.InDelta(t, float64(before.UnixNano()), encoded, float64(time.Second))
+
+	encoded = jsonTime(t, new(ekalog.CI_JSONEncoder))
+	_, err = time.Parse(time.UnixDate, encoded.(string))
+	require.NoError(t, err)
+}
+
+func TestCI_JSONEncoder_SourceContext(t *testing.T) {
+
+	var b bytes.Buffer
+	ekalog.ReplaceIntegrator(new(ekalog.CommonIntegrator).
+		WithEncoder(new(ekalog.CI_JSONEncoder).SetSourceContext(2).FreezeAndGetEncoder()).
+		WriteTo(&b),
+	)
+
+	markedError().LogAsError()
+
+	var decoded struct {
+		Stacktrace []struct {
+			Source []struct {
+				Line    int    `json:"line"`
+				Text    string `json:"text"`
+				Current bool   `json:"current"`
+			} `json:"source"`
+		} `json:"stacktrace"`
+	}
+	require.NoError(t, jsoniter.Unmarshal(b.Bytes(), &decoded))
+	require.NotEmpty(t, decoded.Stacktrace)
+
+	source := decoded.Stacktrace[0].Source
+	require.Len(t, source, 5)
+	require.True(t, source[2].Current)
+	require.Equal(t, source[0].Line+2, source[2].Line)
+	require.Contains(t, b.String(), `AddFields(\"in_error\", 1).`)
+}
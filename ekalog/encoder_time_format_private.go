@@ -0,0 +1,223 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekalog
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// timeFormat is a parsed time format, that is used by encoders
+	// to encode Entry's timestamp.
+	//
+	// It's parsed once, at the encoder's building, by parseTimeFormat().
+	timeFormat struct {
+
+		// epoch is a precision of UNIX timestamp (time.Second, time.Millisecond,
+		// time.Microsecond or time.Nanosecond) if time must be encoded
+		// as UNIX timestamp or 0 otherwise.
+		epoch time.Duration
+
+		// layout is Go time layout if epoch == 0 and strftime is empty.
+		layout string
+
+		// strftime is compiled strftime's layout if it has been used.
+		strftime []timeStrftimeElem
+	}
+
+	// timeStrftimeElem is a strftime layout's element: either a conversion
+	// specification that is formatted by Go's time layout or a literal
+	// that is written as is.
+	timeStrftimeElem struct {
+		layout  string // Go time layout of the conversion specification
+		lit     string // literal, if layout is empty
+		trimDot bool   // whether the leading '.' of fractional seconds must be removed
+	}
+)
+
+var (
+	// timePredefinedFormats are the names of predefined time formats
+	// (upper cased) that may be used instead of layouts.
+	timePredefinedFormats = map[string]timeFormat{
+		"UNIX":         {epoch: time.Second},
+		"TIMESTAMP":    {epoch: time.Second},
+		"UNIX_MS":      {epoch: time.Millisecond},
+		"UNIXMS":       {epoch: time.Millisecond},
+		"TIMESTAMP_MS": {epoch: time.Millisecond},
+		"UNIX_US":      {epoch: time.Microsecond},
+		"UNIXUS":       {epoch: time.Microsecond},
+		"TIMESTAMP_US": {epoch: time.Microsecond},
+		"UNIX_NS":      {epoch: time.Nanosecond},
+		"UNIXNS":       {epoch: time.Nanosecond},
+		"TIMESTAMP_NS": {epoch: time.Nanosecond},
+		"ANSIC":        {layout: time.ANSIC},
+		"UNIXDATE":     {layout: time.UnixDate},
+		"UNIX_DATE":    {layout: time.UnixDate},
+		"RUBYDATE":     {layout: time.RubyDate},
+		"RUBY_DATE":    {layout: time.RubyDate},
+		"RFC822":       {layout: time.RFC822},
+		"RFC822Z":      {layout: time.RFC822Z},
+		"RFC850":       {layout: time.RFC850},
+		"RFC1123":      {layout: time.RFC1123},
+		"RFC1123Z":     {layout: time.RFC1123Z},
+		"RFC3339":      {layout: time.RFC3339},
+		"RFC3339_MS":   {layout: "2006-01-02T15:04:05.000Z07:00"},
+		"RFC3339MILLI": {layout: "2006-01-02T15:04:05.000Z07:00"},
+		"RFC3339_US":   {layout: "2006-01-02T15:04:05.000000Z07:00"},
+		"RFC3339MICRO": {layout: "2006-01-02T15:04:05.000000Z07:00"},
+		"RFC3339NANO":  {layout: time.RFC3339Nano},
+		"KITCHEN":      {layout: time.Kitchen},
+		"STAMP":        {layout: time.Stamp},
+		"STAMPMILLI":   {layout: time.StampMilli},
+		"STAMPMICRO":   {layout: time.StampMicro},
+		"STAMPNANO":    {layout: time.StampNano},
+	}
+
+	// timeStrftimeDirectives are strftime's conversion specifications
+	// and their Go layout's equivalents.
+	timeStrftimeDirectives = map[byte]timeStrftimeElem{
+		'a': {layout: "Mon"},
+		'A': {layout: "Monday"},
+		'b': {layout: "Jan"},
+		'h': {layout: "Jan"},
+		'B': {layout: "January"},
+		'c': {layout: "Mon Jan _2 15:04:05 2006"},
+		'd': {layout: "02"},
+		'D': {layout: "01/02/06"},
+		'e': {layout: "_2"},
+		'F': {layout: "2006-01-02"},
+		'H': {layout: "15"},
+		'I': {layout: "03"},
+		'j': {layout: "002"},
+		'm': {layout: "01"},
+		'M': {layout: "04"},
+		'p': {layout: "PM"},
+		'R': {layout: "15:04"},
+		'S': {layout: "05"},
+		'T': {layout: "15:04:05"},
+		'y': {layout: "06"},
+		'Y': {layout: "2006"},
+		'z': {layout: "-0700"},
+		'Z': {layout: "MST"},
+		'L': {layout: ".000", trimDot: true},       // milliseconds, use as "%S.%L"
+		'f': {layout: ".000000", trimDot: true},    // microseconds, use as "%S.%f"
+		'N': {layout: ".000000000", trimDot: true}, // nanoseconds, use as "%S.%N"
+		'n': {lit: "\n"},
+		't': {lit: "\t"},
+		'%': {lit: "%"},
+	}
+)
+
+// parseTimeFormat parses 'format' that might be:
+//
+//   - the name of predefined format (case insensitive), like "RFC3339",
+//     "RFC3339_MS", "UNIX", "UNIX_MS" (see timePredefinedFormats),
+//   - strftime's layout, if it contains "%", like "%Y-%m-%d %H:%M:%S.%L",
+//   - Go time layout otherwise, like "2006-01-02 15:04:05.000".
+//
+// If 'format' is empty, 'defaultLayout' is used.
+func parseTimeFormat(format, defaultLayout string) timeFormat {
+
+	switch format = strings.TrimSpace(format); {
+
+	case format == "":
+		return timeFormat{layout: defaultLayout}
+
+	case strings.IndexByte(format, '%') != -1:
+		return timeFormat{strftime: compileStrftime(format)}
+	}
+
+	if predefined, ok := timePredefinedFormats[strings.ToUpper(format)]; ok {
+		return predefined
+	}
+
+	return timeFormat{layout: format}
+}
+
+// compileStrftime compiles strftime's layout to the elements.
+// All bytes except conversion specifications are literals,
+// they are written as is. Unknown conversion specifications are literals too.
+func compileStrftime(format string) []timeStrftimeElem {
+
+	var (
+		elems []timeStrftimeElem
+		lit   strings.Builder
+	)
+
+	flushLit := func() {
+		if lit.Len() > 0 {
+			elems = append(elems, timeStrftimeElem{lit: lit.String()})
+			lit.Reset()
+		}
+	}
+
+	for i := 0; i < len(format); i++ {
+		if format[i] == '%' && i+1 < len(format) {
+			if elem, ok := timeStrftimeDirectives[format[i+1]]; ok {
+				i++
+				if elem.layout == "" {
+					lit.WriteString(elem.lit)
+					continue
+				}
+				flushLit()
+				elems = append(elems, elem)
+				continue
+			}
+		}
+		lit.WriteByte(format[i])
+	}
+
+	flushLit()
+	return elems
+}
+
+// appendTo encodes 't' (converted to 'loc' if it's not nil) using tf
+// and writes the result to 'to', returning it.
+func (tf timeFormat) appendTo(to []byte, t time.Time, loc *time.Location) []byte {
+
+	if tf.epoch != 0 {
+		return strconv.AppendInt(to, t.UnixNano()/int64(tf.epoch), 10)
+	}
+
+	if loc != nil {
+		t = t.In(loc)
+	}
+
+	if tf.strftime == nil {
+		return t.AppendFormat(to, tf.layout)
+	}
+
+	for _, elem := range tf.strftime {
+		switch {
+		case elem.layout == "":
+			to = append(to, elem.lit...)
+		case elem.trimDot:
+			n := len(to)
+			to = t.AppendFormat(to, elem.layout)
+			to = append(to[:n], to[n+1:]...)
+		default:
+			to = t.AppendFormat(to, elem.layout)
+		}
+	}
+
+	return to
+}
+
+// predictedLen returns predicted len of encoded time.
+func (tf timeFormat) predictedLen() int {
+
+	if tf.epoch != 0 {
+		return 19 // len of UNIX timestamp in nanoseconds
+	}
+
+	n := len(tf.layout)
+	for _, elem := range tf.strftime {
+		n += len(elem.layout) + len(elem.lit)
+	}
+	return n + 10 // stock for some weekdays
+}
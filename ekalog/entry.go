@@ -50,6 +50,11 @@ type (
 
 		needSetFinalizer bool
 
+		// keyMapping is a CommonIntegrator's output's key renaming rules
+		// the encoder must apply. Set only while Entry is being encoded.
+		// See CommonIntegrator.WithKeyMapping() and Entry.key().
+		keyMapping map[string]string
+
 		//ssf  int // skip stack frames
 		//ssfp int // skip stack frames private ("3" by default)
 		//
@@ -103,3 +103,13 @@ func (e *Entry) addStacktrace() (this *Entry) {
 
 	return e
 }
+
+// key returns the key 'defaultKey' must be renamed to at the encoding
+// (according with CommonIntegrator's output's key mapping) or 'defaultKey'
+// itself if it must not be renamed.
+func (e *Entry) key(defaultKey string) string {
+	if newKey, ok := e.keyMapping[defaultKey]; ok {
+		return newKey
+	}
+	return defaultKey
+}
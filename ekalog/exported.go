@@ -50,17 +50,50 @@ import (
 // unnecessary copying and calls internal parts directly.
 // ------------------------------------------------------------------------- //
 
+// ReplaceIntegrator replaces the default package logger's integrator
+// with 'newIntegrator'. It might be either one of predefined integrators
+// (CommonIntegrator, NetIntegrator) or your own Integrator implementation.
 //
+// Predefined integrators are built (finalized, started) by this call
+// (see BuildIntegrator()). Nothing is replaced if 'newIntegrator' is nil
+// or it can not be built (e.g. CommonIntegrator w/o any output).
+//
+// If the replaced integrator is NetIntegrator, it's stopped (see NetIntegrator.Stop()).
+//
+// WARNING! ReplaceIntegrator is not thread-safe and must not be called
+// concurrently with logging.
 func ReplaceIntegrator(newIntegrator Integrator) {
 
-	if ekaclike.TakeRealAddr(newIntegrator) == nil {
-		return
-	}
-	if ci, ok := newIntegrator.(*CommonIntegrator); !(ok && ci != nil && ci.tryToBuild()) {
+	if !BuildIntegrator(newIntegrator) {
 		return
 	}
 
+	prevIntegrator := baseLogger.integrator
 	baseLogger.setIntegrator(newIntegrator)
+
+	// Replaced NetIntegrator must not keep its goroutine and ekadeath's destructor.
+	// Draining may take a while, so it's done w/o blocking the caller.
+	if ni, ok := prevIntegrator.(*NetIntegrator); ok && Integrator(ni) != newIntegrator {
+		go func() { _ = ni.Stop() }()
+	}
+}
+
+// BuildIntegrator finalizes 'integrator' the same way ReplaceIntegrator() does
+// (predefined integrators like CommonIntegrator, NetIntegrator must be built
+// before they can be used), but does not replace default logger's integrator.
+// Useful when your own Integrator proxies calls to some predefined one.
+//
+// Returns false if 'integrator' is nil or can not be built.
+func BuildIntegrator(integrator Integrator) bool {
+
+	if ekaclike.TakeRealAddr(integrator) == nil {
+		return false
+	}
+	if bi, ok := integrator.(buildableIntegrator); ok && !bi.tryToBuild() {
+		return false
+	}
+
+	return true
 }
 
 // SyncThis forces to flush all default package logger's integrator's buffer
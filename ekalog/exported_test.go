@@ -0,0 +1,50 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekalog_test
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/qioalice/ekago/v2/ekalog"
+
+	"github.com/stretchr/testify/require"
+)
+
+type tCustomIntegrator struct {
+	messages []string
+}
+
+func (ci *tCustomIntegrator) MinLevelEnabled() ekalog.Level       { return ekalog.LEVEL_DEBUG }
+func (ci *tCustomIntegrator) MinLevelForStackTrace() ekalog.Level { return ekalog.LEVEL_ERROR }
+func (ci *tCustomIntegrator) Sync() error                         { return nil }
+func (ci *tCustomIntegrator) IsAsync() bool                       { return false }
+
+func (ci *tCustomIntegrator) Write(entry *ekalog.Entry) {
+	ci.messages = append(ci.messages, entry.LogLetter.Items.Message)
+}
+
+func TestReplaceIntegrator(t *testing.T) {
+
+	custom := new(tCustomIntegrator)
+	ekalog.ReplaceIntegrator(custom)
+
+	ekalog.Info("to custom integrator")
+	require.Equal(t, []string{"to custom integrator"}, custom.messages)
+
+	// Nil and not buildable integrators are ignored.
+	ekalog.ReplaceIntegrator(nil)
+	ekalog.ReplaceIntegrator((*tCustomIntegrator)(nil))
+	ekalog.ReplaceIntegrator(new(ekalog.CommonIntegrator))
+	require.False(t, ekalog.BuildIntegrator(new(ekalog.CommonIntegrator)))
+
+	ekalog.Info("still custom")
+	require.Equal(t, []string{"to custom integrator", "still custom"}, custom.messages)
+
+	ekalog.ReplaceIntegrator(new(ekalog.CommonIntegrator).WriteTo(ioutil.Discard))
+	ekalog.Info("not to custom integrator")
+	require.Len(t, custom.messages, 2)
+}
@@ -0,0 +1,72 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekalog
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// LogGoroutines logs the states and stacktraces of all goroutines with 'level'
+// using package's default logger. See Logger.LogGoroutines() for more info.
+func LogGoroutines(level Level) *Logger {
+	return baseLogger.LogGoroutines(level)
+}
+
+// LogGoroutines logs the states and stacktraces of all goroutines with 'level'.
+//
+// Goroutines that have the same state and stacktrace and are spawned
+// by the same 'go' statement are grouped (like panicparse does).
+// The one log entry is written for each group, with group's stacktrace
+// and fields: number of goroutines, their IDs, state, wait duration
+// and "created by" frame. Bigger groups are logged first.
+//
+// Keep in mind, integrators may drop stacktraces of entries
+// with low levels (see CommonIntegrator.WithMinLevelForStackTrace()).
+// Fatal 'level' does not lead to Die() call.
+func (l *Logger) LogGoroutines(level Level) (this *Logger) {
+	return l.logGoroutines(level)
+}
+
+// LogGoroutinesOnSignal starts logging of all goroutines (as LogGoroutines()
+// does) with 'level' each time when one of 'signals' is received.
+// SIGQUIT is used if 'signals' are not provided.
+//
+// Keep in mind, default Go's behaviour for these signals is overwritten:
+// SIGQUIT does not print goroutines to stderr and does not exit anymore.
+// Calling it again replaces signals and level. Use StopLogGoroutinesOnSignal()
+// to restore default behaviour.
+func LogGoroutinesOnSignal(level Level, signals ...os.Signal) {
+
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGQUIT}
+	}
+
+	goroutinesSigMu.Lock()
+	defer goroutinesSigMu.Unlock()
+
+	if goroutinesSigCh == nil {
+		goroutinesSigCh = make(chan os.Signal, 1)
+		go handleGoroutinesSignals(goroutinesSigCh)
+	}
+
+	goroutinesSigLevel = level
+	signal.Stop(goroutinesSigCh)
+	signal.Notify(goroutinesSigCh, signals...)
+}
+
+// StopLogGoroutinesOnSignal stops logging of all goroutines
+// that has been started by LogGoroutinesOnSignal().
+func StopLogGoroutinesOnSignal() {
+
+	goroutinesSigMu.Lock()
+	defer goroutinesSigMu.Unlock()
+
+	if goroutinesSigCh != nil {
+		signal.Stop(goroutinesSigCh)
+	}
+}
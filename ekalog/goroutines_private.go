@@ -0,0 +1,123 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekalog
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qioalice/ekago/v2/ekasys"
+	"github.com/qioalice/ekago/v2/internal/ekafield"
+	"github.com/qioalice/ekago/v2/internal/ekaletter"
+)
+
+//noinspection GoSnakeCaseUsage
+const (
+	// _GOROUTINES_IDS_MAX is a max number of goroutines' IDs
+	// that are logged for the one group by Logger.LogGoroutines().
+	_GOROUTINES_IDS_MAX = 64
+)
+
+var (
+	// goroutinesSigCh is a channel signals LogGoroutinesOnSignal() subscribed to
+	// are received through. Nil if LogGoroutinesOnSignal() has never been called.
+	goroutinesSigCh chan os.Signal
+
+	// goroutinesSigLevel is a level goroutines are logged with
+	// when signal is received.
+	goroutinesSigLevel Level
+
+	// goroutinesSigMu protects goroutinesSigCh, goroutinesSigLevel.
+	goroutinesSigMu sync.Mutex
+)
+
+// logGoroutines is a private part of LogGoroutines().
+func (l *Logger) logGoroutines(lvl Level) *Logger {
+
+	if !(l.IsValid() && l.levelEnabled(lvl)) {
+		return l
+	}
+
+	groups := ekasys.GroupGoroutines(ekasys.GetGoroutines())
+
+	for i, n := 0, len(groups); i < n; i++ {
+
+		workTempEntry := l.entry.clone()
+
+		workTempEntry.Level = lvl
+		workTempEntry.Time = time.Now()
+		workTempEntry.LogLetter.Items.Message = "Goroutines dump (" +
+			strconv.Itoa(i+1) + "/" + strconv.Itoa(n) + "): " +
+			strconv.Itoa(groups[i].Count()) + " goroutine(s) [" + groups[i].State + "]"
+		workTempEntry.LogLetter.StackTrace = groups[i].StackTrace
+
+		ekaletter.ParseTo(workTempEntry.LogLetter.Items, nil, goroutinesGroupFields(&groups[i]), true)
+
+		l.integrator.Write(workTempEntry)
+
+		if !l.integrator.IsAsync() {
+			releaseEntry(workTempEntry)
+		}
+	}
+
+	return l
+}
+
+// goroutinesGroupFields returns the log entry's fields that describes 'group'.
+func goroutinesGroupFields(group *ekasys.GoroutineGroup) []ekafield.Field {
+
+	ids := group.IDs
+	if len(ids) > _GOROUTINES_IDS_MAX {
+		ids = ids[:_GOROUTINES_IDS_MAX]
+	}
+
+	encodedIDs := make([]string, len(ids), len(ids)+1)
+	for i, id := range ids {
+		encodedIDs[i] = strconv.FormatUint(id, 10)
+	}
+	if len(ids) < len(group.IDs) {
+		encodedIDs = append(encodedIDs, "...")
+	}
+
+	fields := []ekafield.Field{
+		ekafield.Int("goroutines_count", group.Count()),
+		ekafield.String("goroutines_ids", strings.Join(encodedIDs, ", ")),
+		ekafield.String("goroutines_state", group.State),
+	}
+
+	if group.MaxWait > 0 {
+		fields = append(fields, ekafield.Duration("goroutines_wait", group.MaxWait))
+	}
+
+	if group.CreatedBy.Function != "" {
+		fields = append(fields, ekafield.String("goroutines_created_by",
+			group.CreatedBy.Function+" ("+group.CreatedBy.File+":"+
+				strconv.Itoa(group.CreatedBy.Line)+")"))
+	}
+
+	return fields
+}
+
+// handleGoroutinesSignals logs goroutines each time signal is received by 'ch'.
+func handleGoroutinesSignals(ch chan os.Signal) {
+
+	for range ch {
+		goroutinesSigMu.Lock()
+		level := goroutinesSigLevel
+		goroutinesSigMu.Unlock()
+
+		baseLogger.logGoroutines(level)
+	}
+}
+
+// logGoroutinesBridge is the same as baseLogger.LogGoroutines().
+// It's used by ekadeath package to log goroutines before die.
+func logGoroutinesBridge(level uint8) {
+	baseLogger.logGoroutines(Level(level))
+}
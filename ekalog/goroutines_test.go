@@ -0,0 +1,43 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekalog_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qioalice/ekago/v2/ekalog"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCI_LogGoroutines(t *testing.T) {
+
+	var b bytes.Buffer
+	ekalog.ReplaceIntegrator(new(ekalog.CommonIntegrator).
+		WithEncoder(new(ekalog.CI_JSONEncoder).FreezeAndGetEncoder()).
+		WithMinLevelForStackTrace(ekalog.LEVEL_DEBUG).
+		WriteTo(&b),
+	)
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	for i := 0; i < 3; i++ {
+		go func() { <-stop }()
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	ekalog.LogGoroutines(ekalog.LEVEL_DEBUG)
+
+	require.Contains(t, b.String(), "Goroutines dump")
+	require.Contains(t, b.String(), `"goroutines_count"`)
+	require.Contains(t, b.String(), `"goroutines_created_by"`)
+	require.Contains(t, b.String(), "TestCI_LogGoroutines")
+	require.True(t, strings.Count(b.String(), `"stacktrace"`) > 1)
+}
@@ -23,4 +23,8 @@ func init() {
 	// Initialize the gate's functions to link ekalog <-> ekaerr packages.
 	ekaletter.BridgeLogErr2 = logErr
 	ekaletter.BridgeLogwErr2 = logErrw
+
+	// Initialize the gate's function to log destructors' outcomes in ekadeath.
+	ekaletter.BridgeLogwMessage = logMessagew
+	ekaletter.BridgeLogGoroutines = logGoroutinesBridge
 }
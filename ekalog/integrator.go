@@ -47,3 +47,10 @@ type Integrator interface {
 	// If you not sure, return 'true'. It's more secure but slower.
 	IsAsync() bool
 }
+
+// buildableIntegrator is an Integrator that must be "built" (finalized, started)
+// before it can be used. All predefined integrators are buildable.
+type buildableIntegrator interface {
+	Integrator
+	tryToBuild() (wasBuilt bool)
+}
@@ -8,6 +8,7 @@ package ekalog
 import (
 	"io"
 
+	"github.com/qioalice/ekago/v2/ekasys"
 	"github.com/qioalice/ekago/v2/ekatyp"
 
 	"github.com/qioalice/ekago/v2/internal/ekaclike"
@@ -24,6 +25,8 @@ type (
 	// 2. You can define different encoders to different 'io.Writer's.
 	// 3. You can specify different minimum enabled levels for different 'io.Writer's.
 	// 4. You can specify different minimum levels for stacktrace for different 'io.Writer's.
+	// 5. You can specify different set of fields, error's stack frames
+	//    and key names for different 'io.Writer's.
 	//
 	// Yes. You can do something like this:
 	// - Handle all entries, encode them to JSON, and write to 'os.Stdout' and 'file1';
@@ -47,6 +50,21 @@ type (
 	// And there is!
 	// Now you have 'ig' object and it is your integrator.
 	//
+	// Different destinations may need different shapes of log entries.
+	// E.g. stdout should get a short form without fields, while the file
+	// should get everything. Use WithFields(), WithoutFields(), WithStackFrames(),
+	// WithKeyMapping() for that:
+	// 		ig = ig.WithEncoder(consoleEncoder).
+	// 		        WithoutFields().                            // all fields are dropped
+	// 		        WithStackFrames(CI_STACK_FRAMES_ONLY_MARKED).
+	// 		        WriteTo(os.Stdout).
+	// 		        WithEncoder(jsonEncoder).
+	// 		        WithKeyMapping(map[string]string{
+	// 		            "time":  "@timestamp",
+	// 		            "level": "log.level",
+	// 		        }).
+	// 		        WriteTo(file)
+	//
 	// Guess you noticed that you must pass something to WithEncoder() method.
 	// You must create an encoder, specify its behaviour and pass them into.
 	//
@@ -79,8 +97,39 @@ type (
 		stml Level       // minimum level starting with stacktrace must be added to the entry
 		enc  CI_Encoder  // func that encoders 'Entry' object to '[]byte'
 		dest []io.Writer // slice of 'io.Writer's, log entry will be written to
+
+		// shaper transforms log entry before it's encoded (fields filtering,
+		// error's stack frames dropping, key renaming). Nil if entry
+		// must be encoded as is.
+		shaper *_CI_OutputShaper
+
+		// colorsAuto is true if color support must be detected for next
+		// registered writers (see WithColorsAuto()). Then colors is a slice
+		// of detected color supports parallel to dest. Nil if there are no
+		// writers colors of encoded log entry must be degraded for.
+		colorsAuto bool
+		colors     []ekasys.ColorSupport
+	}
+
+	// _CI_OutputShaper is a _CI_Output part that describes how log entry must
+	// be transformed before it will be encoded by the _CI_Output's encoder.
+	_CI_OutputShaper struct {
+		include     map[string]struct{} // if not nil, only these fields are kept
+		exclude     map[string]struct{} // these fields are dropped
+		excludeAll  bool                // all fields are dropped
+		stackFrames CI_StackFramesMode  // what to do with error's stack frames
+		keys        map[string]string   // key renaming, old -> new
+
+		// frameFilter is applied to both of log's and error's stacktraces
+		// if hasFrameFilter is true. Otherwise ekasys.DefaultStackFrameFilter() is.
+		frameFilter    *ekasys.StackFrameFilter
+		hasFrameFilter bool
 	}
 
+	// CI_StackFramesMode describes what CommonIntegrator's output must do with
+	// the attached ekaerr.Error's stack frames. See CI_STACK_FRAMES_... constants.
+	CI_StackFramesMode uint8
+
 	// CI_Encoder is the Common Integrator's Encoder and it's an alias
 	// to the function that takes an one log message (as Entry type),
 	// encodes it (by some rules) and returns the encoded data as RAW bytes.
@@ -98,6 +147,21 @@ type (
 	}
 )
 
+//noinspection GoSnakeCaseUsage
+const (
+	// CI_STACK_FRAMES_ALL means that all error's stack frames are kept. Default.
+	CI_STACK_FRAMES_ALL CI_StackFramesMode = 0
+
+	// CI_STACK_FRAMES_NONE means that error's stack frames are dropped.
+	// Keep in mind, messages and fields are attached to the error's stack frames
+	// are dropped too. Error's ID, class and public message are kept.
+	CI_STACK_FRAMES_NONE CI_StackFramesMode = 1
+
+	// CI_STACK_FRAMES_ONLY_MARKED means that only those error's stack frames
+	// are kept, which are marked (have FLAG_MARKED_LETTER_ITEM flag).
+	CI_STACK_FRAMES_ONLY_MARKED CI_StackFramesMode = 2
+)
+
 // MinLevelEnabled returns minimum level an Integrator will handle Logger's Entries with.
 // E.g. if minimum level is LEVEL_WARNING then LEVEL_DEBUG, LEVEL_INFO logs will be dropped.
 func (bi *CommonIntegrator) MinLevelEnabled() Level {
@@ -125,19 +189,45 @@ func (bi *CommonIntegrator) Write(entry *Entry) {
 
 	for _, output := range bi.output {
 
+		if output.ml > entry.Level {
+			continue
+		}
+
 		// maybe we must remove stacktrace?
 		logStacktraceBak := entry.LogLetter.StackTrace
 		if output.stml > entry.Level {
 			entry.LogLetter.StackTrace = nil
 		}
 
+		// maybe we must reshape entry?
+		var shaperBak _CI_OutputShaperBackup
+		shaper := ciShaperOrDefault(output.shaper)
+		if shaper != nil {
+			shaperBak = shaper.apply(entry)
+		}
+
 		encodedEntry := output.enc(entry)
 
+		if shaper != nil {
+			shaper.restore(entry, shaperBak)
+		}
+
 		// restore stacktrace
 		entry.LogLetter.StackTrace = logStacktraceBak
 
-		for _, destination := range output.dest {
-			_, _ = destination.Write(encodedEntry)
+		// maybe some destinations don't support colors (or all of them)?
+		var degraded [ekasys.COLOR_SUPPORT_TRUECOLOR][]byte
+
+		for i, destination := range output.dest {
+			data := encodedEntry
+			if i < len(output.colors) && output.colors[i] < ekasys.COLOR_SUPPORT_TRUECOLOR {
+				support := output.colors[i]
+				if degraded[support] == nil {
+					degraded[support] = degradeColors(encodedEntry, support)
+				}
+				data = degraded[support]
+			}
+			_, _ = destination.Write(data)
 		}
 	}
 }
@@ -252,6 +342,133 @@ func (bi *CommonIntegrator) WithMinLevelForStackTrace(minLevel Level) *CommonInt
 	return bi
 }
 
+// WithFields marks that only fields with passed keys will be kept for next
+// registered writers by WriteTo() method. Both of log's and attached error's
+// fields are filtered. Overwrites previous WithFields(), WithoutFields() calls.
+//
+// Calling w/o arguments means that all fields are kept (default behaviour).
+func (bi *CommonIntegrator) WithFields(keys ...string) *CommonIntegrator {
+
+	if bi == nil {
+		return nil
+	}
+
+	shaper := bi.getShaper()
+	shaper.include, shaper.exclude, shaper.excludeAll = nil, nil, false
+
+	if len(keys) > 0 {
+		shaper.include = ciKeysSet(keys)
+	}
+
+	return bi
+}
+
+// WithoutFields marks that fields with passed keys will be dropped for next
+// registered writers by WriteTo() method. Both of log's and attached error's
+// fields are filtered. Overwrites previous WithFields(), WithoutFields() calls.
+//
+// Calling w/o arguments means that all fields will be dropped.
+func (bi *CommonIntegrator) WithoutFields(keys ...string) *CommonIntegrator {
+
+	if bi == nil {
+		return nil
+	}
+
+	shaper := bi.getShaper()
+	shaper.include, shaper.exclude, shaper.excludeAll = nil, nil, len(keys) == 0
+
+	if len(keys) > 0 {
+		shaper.exclude = ciKeysSet(keys)
+	}
+
+	return bi
+}
+
+// WithStackFrames changes what to do with the attached error's stack frames
+// for next registered writers by WriteTo() method.
+// See CI_STACK_FRAMES_... constants.
+func (bi *CommonIntegrator) WithStackFrames(mode CI_StackFramesMode) *CommonIntegrator {
+
+	if bi == nil {
+		return nil
+	}
+
+	switch mode {
+	case CI_STACK_FRAMES_ALL, CI_STACK_FRAMES_NONE, CI_STACK_FRAMES_ONLY_MARKED:
+		bi.getShaper().stackFrames = mode
+	}
+
+	return bi
+}
+
+// WithStackFrameFilter marks that both of log's and attached error's stacktraces
+// will be filtered by 'f' for next registered writers by WriteTo() method.
+// Frames, error's messages or fields are attached to, are never dropped.
+//
+// Overrides ekasys.DefaultStackFrameFilter(). Pass nil to disable filtering
+// for next registered writers at all, even if default filter is set.
+func (bi *CommonIntegrator) WithStackFrameFilter(f *ekasys.StackFrameFilter) *CommonIntegrator {
+
+	if bi == nil {
+		return nil
+	}
+
+	shaper := bi.getShaper()
+	shaper.frameFilter, shaper.hasFrameFilter = f, true
+
+	return bi
+}
+
+// WithColorsAuto marks that color support must be detected for each of next
+// registered writers by WriteTo() method (see ekasys.ColorSupportFor())
+// and encoded log entries' colors must be degraded to that level
+// before they are written (truecolor -> 256 -> 16 -> no colors).
+//
+// So, you can use the same encoder for a terminal and a file:
+// the file will get log entries w/o ANSI escape sequences.
+func (bi *CommonIntegrator) WithColorsAuto() *CommonIntegrator {
+
+	if bi == nil {
+		return nil
+	}
+
+	if len(bi.output) == 0 {
+		// only in that case bi.idx == 0,
+		// it was a direct call WithColorsAuto(), even w/o WithEncoder() before.
+		bi.WithEncoder(nil) // then here will no SEGFAULT
+	}
+
+	bi.output[bi.idx].colorsAuto = true
+	return bi
+}
+
+// WithKeyMapping makes keys (that are keys of 'mapping') to be renamed to
+// their values in 'mapping' for next registered writers by WriteTo() method.
+// Both of field's keys and encoder's keys (like "time", "level", "message"
+// of CI_JSONEncoder) are renamed. Overwrites previous WithKeyMapping() call.
+//
+// E.g. for ELK you may want:
+// 		WithKeyMapping(map[string]string{"time": "@timestamp", "level": "log.level"})
+func (bi *CommonIntegrator) WithKeyMapping(mapping map[string]string) *CommonIntegrator {
+
+	if bi == nil {
+		return nil
+	}
+
+	var keys map[string]string
+	if len(mapping) > 0 {
+		keys = make(map[string]string, len(mapping))
+		for from, to := range mapping {
+			if from != "" && to != "" {
+				keys[from] = to
+			}
+		}
+	}
+
+	bi.getShaper().keys = keys
+	return bi
+}
+
 // WriteTo registers all passed 'io.Writer's as logger's destinations.
 // All previous WithEncoder(), WithMinLevel() calls will be applied to these writers.
 func (bi *CommonIntegrator) WriteTo(writers ...io.Writer) *CommonIntegrator {
@@ -284,8 +501,19 @@ func (bi *CommonIntegrator) WriteTo(writers ...io.Writer) *CommonIntegrator {
 		bi.WithEncoder(nil) // otherwise there will be SEGFAULT
 	}
 
-	bi.output[bi.idx].dest =
-		append(bi.output[bi.idx].dest, writers...)
+	output := &bi.output[bi.idx]
+
+	if output.colorsAuto {
+		// previously registered writers of this output are left as is
+		for len(output.colors) < len(output.dest) {
+			output.colors = append(output.colors, ekasys.COLOR_SUPPORT_TRUECOLOR)
+		}
+		for _, writer := range writers {
+			output.colors = append(output.colors, ekasys.ColorSupportFor(writer))
+		}
+	}
+
+	output.dest = append(output.dest, writers...)
 
 	return bi
 }
@@ -314,6 +542,12 @@ func (bi *CommonIntegrator) tryToBuild() (wasBuilt bool) {
 	bi.oll = Level(0xFF)
 	bi.stll = Level(0xFF)
 
+	for i := range bi.output {
+		if bi.output[i].shaper.isNoop() {
+			bi.output[i].shaper = nil
+		}
+	}
+
 	for _, output := range bi.output {
 		if output.ml < bi.oll {
 			bi.oll = output.ml
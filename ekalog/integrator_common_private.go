@@ -0,0 +1,296 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekalog
+
+import (
+	"github.com/qioalice/ekago/v2/ekasys"
+	"github.com/qioalice/ekago/v2/internal/ekafield"
+	"github.com/qioalice/ekago/v2/internal/ekaletter"
+)
+
+type (
+	// _CI_OutputShaperBackup contains Entry's parts that are replaced by
+	// _CI_OutputShaper.apply() and must be restored after entry is encoded.
+	_CI_OutputShaperBackup struct {
+		logFields  []ekafield.Field
+		logStack   ekasys.StackTrace
+		errLetter  *ekaletter.Letter
+		keyMapping map[string]string
+	}
+)
+
+var (
+	// ciDefaultShaper is a _CI_OutputShaper that is used for outputs
+	// w/o their own shapers, when ekasys.DefaultStackFrameFilter() is set.
+	// Never changed.
+	ciDefaultShaper _CI_OutputShaper
+)
+
+// ciShaperOrDefault returns 's' if it's not nil, or ciDefaultShaper
+// if ekasys.DefaultStackFrameFilter() is set, or nil otherwise.
+func ciShaperOrDefault(s *_CI_OutputShaper) *_CI_OutputShaper {
+
+	if s == nil && !ekasys.DefaultStackFrameFilter().IsNoop() {
+		return &ciDefaultShaper
+	}
+	return s
+}
+
+// getShaper returns _CI_OutputShaper of the current output being registered,
+// creating both of them if it's necessary.
+func (bi *CommonIntegrator) getShaper() *_CI_OutputShaper {
+
+	if len(bi.output) == 0 {
+		// only in that case bi.idx == 0,
+		// it was a direct call, even w/o WithEncoder() before.
+		bi.WithEncoder(nil) // then here will no SEGFAULT
+	}
+
+	if bi.output[bi.idx].shaper == nil {
+		bi.output[bi.idx].shaper = new(_CI_OutputShaper)
+	}
+
+	return bi.output[bi.idx].shaper
+}
+
+// ciKeysSet returns a set of not empty 'keys'.
+func ciKeysSet(keys []string) map[string]struct{} {
+
+	set := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		if key != "" {
+			set[key] = struct{}{}
+		}
+	}
+	return set
+}
+
+// isNoop reports whether s does not transform log entries at all.
+// Nil safe.
+func (s *_CI_OutputShaper) isNoop() bool {
+	return s == nil || !s.hasFieldRules() &&
+		s.stackFrames == CI_STACK_FRAMES_ALL && !s.hasFrameFilter
+}
+
+// hasFieldRules reports whether s filters or renames fields.
+func (s *_CI_OutputShaper) hasFieldRules() bool {
+	return s.include != nil || s.exclude != nil || s.excludeAll || len(s.keys) > 0
+}
+
+// apply transforms 'e' according with s's rules, returning those e's parts
+// that has been replaced and must be restored by restore() after encoding.
+//
+// Neither of log's fields slice, nor attached error's letter are modified.
+// They are replaced by their modified copies instead, because the same
+// *Entry is encoded for each output.
+func (s *_CI_OutputShaper) apply(e *Entry) (bak _CI_OutputShaperBackup) {
+
+	bak.logFields = e.LogLetter.Items.Fields
+	bak.logStack = e.LogLetter.StackTrace
+	bak.errLetter = e.ErrLetter
+	bak.keyMapping = e.keyMapping
+
+	e.keyMapping = s.keys
+
+	if s.hasFieldRules() {
+		e.LogLetter.Items.Fields = s.shapeFields(e.LogLetter.Items.Fields)
+	}
+
+	filter := s.stackFrameFilter()
+
+	if e.ErrLetter == nil {
+		if !filter.IsNoop() {
+			e.LogLetter.StackTrace, _ = filter.Apply(e.LogLetter.StackTrace, nil)
+		}
+		return bak
+	}
+
+	if !s.hasFieldRules() && s.stackFrames == CI_STACK_FRAMES_ALL && filter.IsNoop() {
+		return bak
+	}
+
+	errLetter := *e.ErrLetter
+	e.ErrLetter = &errLetter
+
+	switch s.stackFrames {
+
+	case CI_STACK_FRAMES_NONE:
+		errLetter.StackTrace = nil
+		errLetter.Items = nil
+
+	case CI_STACK_FRAMES_ONLY_MARKED:
+		// Error's items may be bound to the log's stacktrace
+		// if error has no its own one. Filter that stacktrace then.
+		if len(errLetter.StackTrace) == 0 && len(e.LogLetter.StackTrace) > 0 {
+			e.LogLetter.StackTrace, errLetter.Items =
+				s.shapeItems(errLetter.Items, e.LogLetter.StackTrace, true)
+		} else {
+			errLetter.StackTrace, errLetter.Items =
+				s.shapeItems(errLetter.Items, errLetter.StackTrace, true)
+		}
+
+	default:
+		_, errLetter.Items = s.shapeItems(errLetter.Items, nil, false)
+	}
+
+	if !filter.IsNoop() {
+		// Error's items may be bound to the log's stacktrace
+		// if error has no its own one. Protect its frames then.
+		if len(errLetter.StackTrace) == 0 && len(e.LogLetter.StackTrace) > 0 {
+			e.LogLetter.StackTrace, errLetter.Items =
+				s.filterItems(filter, errLetter.Items, e.LogLetter.StackTrace)
+		} else {
+			e.LogLetter.StackTrace, _ = filter.Apply(e.LogLetter.StackTrace, nil)
+			errLetter.StackTrace, errLetter.Items =
+				s.filterItems(filter, errLetter.Items, errLetter.StackTrace)
+		}
+	}
+
+	return bak
+}
+
+// stackFrameFilter returns ekasys.StackFrameFilter, that must be applied
+// to the stacktraces: s's own or default one.
+func (s *_CI_OutputShaper) stackFrameFilter() *ekasys.StackFrameFilter {
+
+	if s.hasFrameFilter {
+		return s.frameFilter
+	}
+	return ekasys.DefaultStackFrameFilter()
+}
+
+// restore restores e's parts from 'bak' that has been replaced by apply().
+func (s *_CI_OutputShaper) restore(e *Entry, bak _CI_OutputShaperBackup) {
+
+	e.LogLetter.Items.Fields = bak.logFields
+	e.LogLetter.StackTrace = bak.logStack
+	e.ErrLetter = bak.errLetter
+	e.keyMapping = bak.keyMapping
+}
+
+// shapeItems returns a copy of 'items' linked list, with filtered and renamed
+// fields. If 'onlyMarked' is true, only marked items are kept and a new
+// stacktrace that contains only their stack frames is returned too.
+func (s *_CI_OutputShaper) shapeItems(
+
+	items *ekaletter.LetterItem,
+	stacktrace ekasys.StackTrace,
+	onlyMarked bool,
+
+) (newStacktrace ekasys.StackTrace, newItems *ekaletter.LetterItem) {
+
+	var last *ekaletter.LetterItem
+
+	for item := items; item != nil; item = item.Next() {
+
+		copied := new(ekaletter.LetterItem)
+		*copied = *item
+		ekaletter.SetNextItem(copied, nil)
+
+		if onlyMarked {
+			idx := item.StackFrameIdx()
+			if !item.Flags.TestAll(ekaletter.FLAG_MARKED_LETTER_ITEM) ||
+				idx < 0 || int(idx) >= len(stacktrace) {
+				continue
+			}
+			newStacktrace = append(newStacktrace, stacktrace[idx])
+			ekaletter.SetStackFrameIdx(copied, int16(len(newStacktrace)-1))
+		}
+
+		if s.hasFieldRules() {
+			copied.Fields = s.shapeFields(item.Fields)
+		}
+
+		if last == nil {
+			newItems = copied
+		} else {
+			ekaletter.SetNextItem(last, copied)
+		}
+		last = copied
+	}
+
+	return newStacktrace, newItems
+}
+
+// filterItems returns 'stacktrace' filtered by 'filter' and a copy of 'items'
+// linked list, bound to the filtered stacktrace's frames.
+// Frames, items are bound to, are never dropped.
+func (s *_CI_OutputShaper) filterItems(
+
+	filter *ekasys.StackFrameFilter,
+	items *ekaletter.LetterItem,
+	stacktrace ekasys.StackTrace,
+
+) (newStacktrace ekasys.StackTrace, newItems *ekaletter.LetterItem) {
+
+	bound := make(map[int]struct{})
+	for item := items; item != nil; item = item.Next() {
+		bound[int(item.StackFrameIdx())] = struct{}{}
+	}
+
+	newStacktrace, mapping := filter.Apply(stacktrace, func(idx int) bool {
+		_, ok := bound[idx]
+		return ok
+	})
+
+	if mapping == nil {
+		return newStacktrace, items
+	}
+
+	var last *ekaletter.LetterItem
+
+	for item := items; item != nil; item = item.Next() {
+
+		copied := new(ekaletter.LetterItem)
+		*copied = *item
+		ekaletter.SetNextItem(copied, nil)
+
+		if idx := int(item.StackFrameIdx()); idx >= 0 && idx < len(mapping) {
+			ekaletter.SetStackFrameIdx(copied, int16(mapping[idx]))
+		}
+
+		if last == nil {
+			newItems = copied
+		} else {
+			ekaletter.SetNextItem(last, copied)
+		}
+		last = copied
+	}
+
+	return newStacktrace, newItems
+}
+
+// shapeFields returns a new slice of 'fields', that contains only allowed fields
+// with renamed keys.
+func (s *_CI_OutputShaper) shapeFields(fields []ekafield.Field) []ekafield.Field {
+
+	if s.excludeAll || len(fields) == 0 {
+		return nil
+	}
+
+	shaped := make([]ekafield.Field, 0, len(fields))
+
+	for i, n := 0, len(fields); i < n; i++ {
+
+		if s.include != nil {
+			if _, ok := s.include[fields[i].Key]; !ok {
+				continue
+			}
+		}
+		if s.exclude != nil {
+			if _, ok := s.exclude[fields[i].Key]; ok {
+				continue
+			}
+		}
+
+		shaped = append(shaped, fields[i])
+		if newKey, ok := s.keys[fields[i].Key]; ok {
+			shaped[len(shaped)-1].Key = newKey
+		}
+	}
+
+	return shaped
+}
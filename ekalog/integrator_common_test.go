@@ -0,0 +1,200 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekalog_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/qioalice/ekago/v2/ekaerr"
+	"github.com/qioalice/ekago/v2/ekalog"
+	"github.com/qioalice/ekago/v2/ekasys"
+
+	"github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+)
+
+// markedError returns an error, which first stack frame is marked
+// (because error is created with a message) and the second is not.
+func markedError() *ekaerr.Error {
+	return ekaerr.IllegalArgument.
+		New("marked").
+		AddFields("in_error", 1).
+		Throw().
+		AddMessage("not marked")
+}
+
+func TestCommonIntegrator_FieldsFiltering(t *testing.T) {
+
+	var all, short, only, without bytes.Buffer
+	jsonEncoder := new(ekalog.CI_JSONEncoder).FreezeAndGetEncoder()
+
+	ekalog.ReplaceIntegrator(new(ekalog.CommonIntegrator).
+		WithEncoder(jsonEncoder).
+		WriteTo(&all).
+		WithEncoder(jsonEncoder).
+		WithoutFields().
+		WriteTo(&short).
+		WithEncoder(jsonEncoder).
+		WithFields("keep").
+		WriteTo(&only).
+		WithEncoder(jsonEncoder).
+		WithoutFields("drop").
+		WriteTo(&without),
+	)
+
+	ekalog.Info("test", "keep", 1, "drop", 2, "other", 3)
+
+	require.Contains(t, all.String(), `"keep"`)
+	require.Contains(t, all.String(), `"drop"`)
+	require.Contains(t, all.String(), `"other"`)
+
+	require.NotContains(t, short.String(), `"fields"`)
+
+	require.Contains(t, only.String(), `"keep"`)
+	require.NotContains(t, only.String(), `"drop"`)
+	require.NotContains(t, only.String(), `"other"`)
+
+	require.Contains(t, without.String(), `"keep"`)
+	require.NotContains(t, without.String(), `"drop"`)
+	require.Contains(t, without.String(), `"other"`)
+}
+
+func TestCommonIntegrator_KeyMapping(t *testing.T) {
+
+	var b bytes.Buffer
+
+	ekalog.ReplaceIntegrator(new(ekalog.CommonIntegrator).
+		WithEncoder(new(ekalog.CI_JSONEncoder).FreezeAndGetEncoder()).
+		WithKeyMapping(map[string]string{
+			"time":       "@timestamp",
+			"level":      "log.level",
+			"request_id": "trace.id",
+		}).
+		WriteTo(&b),
+	)
+
+	ekalog.Info("test", "request_id", "abc")
+
+	var decoded map[string]interface{}
+	require.NoError(t, jsoniter.Unmarshal(b.Bytes(), &decoded))
+
+	require.Contains(t, decoded, "@timestamp")
+	require.Contains(t, decoded, "log.level")
+	require.NotContains(t, decoded, "time")
+	require.NotContains(t, decoded, "level")
+
+	require.Contains(t, b.String(), `"trace.id"`)
+	require.NotContains(t, b.String(), `"request_id"`)
+}
+
+func TestCommonIntegrator_StackFrames(t *testing.T) {
+
+	var all, none, marked bytes.Buffer
+	jsonEncoder := new(ekalog.CI_JSONEncoder).FreezeAndGetEncoder()
+
+	// The output w/o shaping is the last one, to make sure
+	// the attached error is left untouched after shaping.
+	ekalog.ReplaceIntegrator(new(ekalog.CommonIntegrator).
+		WithEncoder(jsonEncoder).
+		WithStackFrames(ekalog.CI_STACK_FRAMES_NONE).
+		WriteTo(&none).
+		WithEncoder(jsonEncoder).
+		WithStackFrames(ekalog.CI_STACK_FRAMES_ONLY_MARKED).
+		WriteTo(&marked).
+		WithEncoder(jsonEncoder).
+		WriteTo(&all),
+	)
+
+	markedError().LogAsError()
+
+	require.Contains(t, all.String(), "not marked")
+	require.Contains(t, all.String(), `"marked"`)
+
+	require.NotContains(t, none.String(), `"stacktrace"`)
+	require.Contains(t, none.String(), `"error_id"`)
+
+	require.NotContains(t, marked.String(), "not marked")
+	require.Contains(t, marked.String(), `"marked"`)
+	require.Equal(t, 1, strings.Count(marked.String(), `"func"`))
+}
+
+func TestCommonIntegrator_StackFrameFilter(t *testing.T) {
+
+	var filtered, all bytes.Buffer
+	jsonEncoder := new(ekalog.CI_JSONEncoder).FreezeAndGetEncoder()
+
+	ekasys.SetDefaultStackFrameFilter(new(ekasys.StackFrameFilter).
+		DropPackages("github.com/qioalice/ekago/v2/ekalog_test."))
+	defer ekasys.SetDefaultStackFrameFilter(nil)
+
+	// The first output uses default filter, the second one disables it.
+	ekalog.ReplaceIntegrator(new(ekalog.CommonIntegrator).
+		WithEncoder(jsonEncoder).
+		WithMinLevelForStackTrace(ekalog.LEVEL_ERROR).
+		WriteTo(&filtered).
+		WithEncoder(jsonEncoder).
+		WithStackFrameFilter(nil).
+		WithMinLevelForStackTrace(ekalog.LEVEL_ERROR).
+		WriteTo(&all),
+	)
+
+	ekalog.Error("log")
+
+	require.NotContains(t, filtered.String(), "TestCommonIntegrator_StackFrameFilter")
+	require.Contains(t, all.String(), "TestCommonIntegrator_StackFrameFilter")
+
+	filtered.Reset()
+	all.Reset()
+
+	markedError().LogAsError()
+
+	// Frames, error's messages are attached to, are kept.
+	require.Contains(t, filtered.String(), `"marked"`)
+	require.Contains(t, filtered.String(), "not marked")
+	require.Contains(t, filtered.String(), "markedError")
+	require.Equal(t,
+		strings.Count(all.String(), `"func"`), strings.Count(filtered.String(), `"func"`))
+}
+
+func TestCommonIntegrator_WithColorsAuto(t *testing.T) {
+
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("FORCE_COLOR", "")
+
+	var colored, auto bytes.Buffer
+	consoleEncoder := new(ekalog.CI_ConsoleEncoder).
+		SetFormat("{{c/fg:#ff0000}}{{m}}{{c/0}}").
+		SetColorSupport(ekasys.COLOR_SUPPORT_TRUECOLOR).
+		FreezeAndGetEncoder()
+
+	// The same encoder, but colors are degraded for the second writer only.
+	ekalog.ReplaceIntegrator(new(ekalog.CommonIntegrator).
+		WithEncoder(consoleEncoder).
+		WriteTo(&colored).
+		WithColorsAuto().
+		WriteTo(&auto),
+	)
+
+	ekalog.Info("test")
+
+	require.Equal(t, "\033[38;2;255;0;0mtest\033[0m", colored.String())
+	require.Equal(t, "test", auto.String())
+
+	colored.Reset()
+	auto.Reset()
+
+	t.Setenv("FORCE_COLOR", "2")
+	ekalog.ReplaceIntegrator(new(ekalog.CommonIntegrator).
+		WithEncoder(consoleEncoder).
+		WithColorsAuto().
+		WriteTo(&auto),
+	)
+
+	ekalog.Info("test")
+	require.Equal(t, "\033[38;5;9mtest\033[0m", auto.String())
+}
@@ -0,0 +1,366 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekalog
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/qioalice/ekago/v2/ekadeath"
+)
+
+// noinspection GoSnakeCaseUsage
+type (
+	// NetIntegrator is the implementation of Integrator interface
+	// that ships encoded log entries to some remote log collector.
+	//
+	// Supported transports are:
+	// - TCP: each entry is written to the persistent TCP connection
+	//   framed either by the trailing '\n' or by 4 bytes big endian length prefix.
+	//   See WithFraming() and NET_FRAMING_... constants.
+	// - UDP: each entry is sent as separate datagram.
+	// - HTTP (HTTPS): entries are grouped to the batches and sent by POST request
+	//   as NDJSON (newline delimited JSON) document. Use CI_JSONEncoder then.
+	//
+	// Entries are encoded at the Write() call (so the Entry is not held by
+	// NetIntegrator after Write() returns), saved to the bounded in-memory buffer
+	// and then shipped by the separate internal goroutine.
+	//
+	// When the remote collector is not available the internal goroutine
+	// reconnects with exponential backoff and jitter, while new entries are
+	// accumulated in the buffer. When the buffer overflows, the entries are
+	// written to the on-disk spool file (if it has been set by WithSpool())
+	// or dropped otherwise (see DroppedCount()).
+	//
+	// Entries the remote collector rejects because of their content
+	// (HTTP 400, 413, 415, 422 responses, too large UDP datagram) are not retried
+	// but dropped as well. All other errors are retried.
+	//
+	// Sync() waits until all pending entries are acknowledged:
+	// - TCP, UDP: successfully written to the socket,
+	// - HTTP: the collector responded with 2xx status code.
+	//
+	// NetIntegrator registers itself in ekadeath package, so the pending entries
+	// will be drained (at least an attempt will be done) before the service is shut down.
+	// Stop() does the same right now and unregisters NetIntegrator from ekadeath.
+	// It's called automatically when NetIntegrator is replaced by ReplaceIntegrator().
+	//
+	// How to use? Just like CommonIntegrator:
+	//
+	// 		ni := new(NetIntegrator).
+	// 		        WithEncoder(new(CI_JSONEncoder).FreezeAndGetEncoder()).
+	// 		        WithMinLevel(LEVEL_INFO).
+	// 		        WithFraming(NET_FRAMING_LENGTH_PREFIXED).
+	// 		        WithSpool("/var/spool/myservice.log.spool").
+	// 		        ConnectTo("tcp", "logs.example.com:5170")
+	// 		ReplaceIntegrator(ni)
+	//
+	// All With...() methods must be called before NetIntegrator is started
+	// (by ReplaceIntegrator() call). They are no-op after that.
+	NetIntegrator struct {
+
+		// Configuration part.
+		// Sets by With...() methods, frozen after start() has been called.
+
+		ml      Level      // minimum level log entry should have to be processed
+		stml    Level      // minimum level starting with stacktrace must be added to the entry
+		enc     CI_Encoder // func that encodes 'Entry' object to '[]byte'
+		network string     // "tcp", "udp", "http" or "https"
+		address string     // host:port for TCP, UDP, URL for HTTP
+		framing NetFraming // TCP framing
+
+		bufferSize  int           // max bytes in the in-memory buffer
+		spoolPath   string        // path to the on-disk spool file, empty if disabled
+		backoffMin  time.Duration // first reconnect delay
+		backoffMax  time.Duration // max reconnect delay
+		dialTimeout time.Duration // timeout of connect, write, HTTP request
+		syncTimeout time.Duration // max time Sync() may wait
+		batchSize   int           // max entries in one HTTP batch
+		flushEvery  time.Duration // how often partially filled HTTP batch is sent
+
+		httpClient *http.Client
+
+		// Runtime part.
+
+		mu           sync.Mutex
+		pending      [][]byte // in-memory buffer, oldest first
+		pendingBytes int      // sum of len() of 'pending' items
+		spoolCount   int      // how much not shipped entries are in the spool file now
+		spoolOffset  int64    // how much bytes at the spool file's beginning are shipped already
+		spool        *os.File // opened spool file, nil if disabled or not opened yet
+		droppedCount uint64   // how much entries has been dropped because of overflow
+		isStarted    bool
+		isStopped    bool
+		stopErr      error // why not all entries were shipped at stop, read it after 'done'
+
+		deathHandle *ekadeath.Handle // drain destructor, unregistered by Stop()
+
+		wakeUp chan struct{}   // signals that there is new pending entries
+		syncs  chan chan error // Sync() requests
+		stop   chan struct{}   // closed when the integrator is stopped
+		done   chan struct{}   // closed when the internal goroutine is finished
+
+		conn netConn // current connection (TCP, UDP), nil if disconnected
+	}
+
+	// NetFraming is a type of framing the log entries are written to the
+	// TCP stream with.
+	NetFraming uint8
+)
+
+// noinspection GoSnakeCaseUsage
+const (
+	// NET_FRAMING_NEWLINE means that each entry is written as is,
+	// and '\n' is appended if entry is not ended with it.
+	NET_FRAMING_NEWLINE NetFraming = 0
+
+	// NET_FRAMING_LENGTH_PREFIXED means that each entry is prepended
+	// with 4 bytes length of entry (big endian).
+	NET_FRAMING_LENGTH_PREFIXED NetFraming = 1
+)
+
+// MinLevelEnabled returns minimum level an Integrator will handle Logger's Entries with.
+func (ni *NetIntegrator) MinLevelEnabled() Level {
+	return ni.ml
+}
+
+// MinLevelForStackTrace returns a minimum level starting with a Logger's Entry
+// must generate and attach a stacktrace.
+func (ni *NetIntegrator) MinLevelForStackTrace() Level {
+	return ni.stml
+}
+
+// Write encodes log entry and saves encoded data to the internal buffer
+// the data will be shipped from to the remote collector.
+func (ni *NetIntegrator) Write(entry *Entry) {
+
+	// maybe we must remove stacktrace?
+	logStacktraceBak := entry.LogLetter.StackTrace
+	if ni.stml > entry.Level {
+		entry.LogLetter.StackTrace = nil
+	}
+
+	// maybe we must filter stack frames?
+	var shaperBak _CI_OutputShaperBackup
+	shaper := ciShaperOrDefault(nil)
+	if shaper != nil {
+		shaperBak = shaper.apply(entry)
+	}
+
+	encodedEntry := ni.enc(entry)
+
+	if shaper != nil {
+		shaper.restore(entry, shaperBak)
+	}
+
+	// restore stacktrace
+	entry.LogLetter.StackTrace = logStacktraceBak
+
+	if len(encodedEntry) > 0 {
+		ni.enqueue(encodedEntry)
+	}
+}
+
+// Sync waits until all pending log entries are acknowledged by the remote
+// collector. Returns an error if it's not possible to do during the sync timeout
+// (see WithTimeouts()) or if NetIntegrator is not started or already stopped.
+func (ni *NetIntegrator) Sync() error {
+
+	ni.mu.Lock()
+	isRunning := ni.isStarted && !ni.isStopped
+	ni.mu.Unlock()
+
+	if !isRunning {
+		return fmt.Errorf("ekalog.NetIntegrator: not running")
+	}
+
+	req := make(chan error, 1)
+	select {
+	case ni.syncs <- req:
+	case <-ni.done:
+		return fmt.Errorf("ekalog.NetIntegrator: stopped")
+	}
+
+	select {
+	case err := <-req:
+		return err
+	case <-ni.done:
+		return fmt.Errorf("ekalog.NetIntegrator: stopped")
+	}
+}
+
+// IsAsync always returns false. Despite the fact NetIntegrator ships entries
+// asynchronously, each Entry is encoded at the Write() call and is not held
+// after that. So it's safe to reuse Entry right after Write() is returned.
+func (ni *NetIntegrator) IsAsync() bool {
+	return false
+}
+
+// Stop stops the internal shipping goroutine, making last attempts to ship
+// pending entries until the sync timeout is reached (see WithTimeouts()),
+// and unregisters NetIntegrator from ekadeath package.
+// Entries that are written after Stop() is called are dropped.
+//
+// Returns the same error Sync() does if not all entries have been shipped.
+// It's safe to call Stop() many times. Nil safe.
+func (ni *NetIntegrator) Stop() error {
+
+	if ni == nil {
+		return nil
+	}
+
+	ni.mu.Lock()
+	isStarted, deathHandle := ni.isStarted, ni.deathHandle
+	ni.deathHandle = nil
+	ni.mu.Unlock()
+
+	if !isStarted {
+		return nil
+	}
+
+	err := ni.shutdown(context.Background())
+	deathHandle.Unregister()
+
+	return err
+}
+
+// DroppedCount returns how much log entries has been dropped because of
+// the buffer overflow and unavailable (or disabled) spool file,
+// because the remote collector rejected them
+// or because they have been written after Stop() call.
+func (ni *NetIntegrator) DroppedCount() uint64 {
+	ni.mu.Lock()
+	defer ni.mu.Unlock()
+	return ni.droppedCount
+}
+
+// WithEncoder sets an encoder all log entries will be encoded with.
+// By default, the JSON encoder is used.
+func (ni *NetIntegrator) WithEncoder(enc CI_Encoder) *NetIntegrator {
+	if ni.canBeConfigured() && enc != nil {
+		ni.enc = enc
+	}
+	return ni
+}
+
+// WithMinLevel changes minimum level log's Entry to be processed.
+func (ni *NetIntegrator) WithMinLevel(minLevel Level) *NetIntegrator {
+	if ni.canBeConfigured() {
+		ni.ml = minLevel
+	}
+	return ni
+}
+
+// WithMinLevelForStackTrace changes a minimum level log's Entry stacktrace being
+// generated for.
+func (ni *NetIntegrator) WithMinLevelForStackTrace(minLevel Level) *NetIntegrator {
+	if ni.canBeConfigured() {
+		ni.stml = minLevel
+	}
+	return ni
+}
+
+// WithFraming sets the way log entries are separated in the TCP stream.
+// Does not impact on UDP and HTTP transports.
+func (ni *NetIntegrator) WithFraming(framing NetFraming) *NetIntegrator {
+	if ni.canBeConfigured() &&
+		(framing == NET_FRAMING_NEWLINE || framing == NET_FRAMING_LENGTH_PREFIXED) {
+		ni.framing = framing
+	}
+	return ni
+}
+
+// WithBufferSize sets how much bytes of encoded log entries may be kept
+// in the memory while the remote collector is unavailable.
+// By default it's 4 MB.
+func (ni *NetIntegrator) WithBufferSize(bytes int) *NetIntegrator {
+	if ni.canBeConfigured() && bytes > 0 {
+		ni.bufferSize = bytes
+	}
+	return ni
+}
+
+// WithSpool enables on-disk spool file log entries will be written to
+// when the in-memory buffer overflows. The spool will be drained
+// when the remote collector becomes available. It's read by chunks
+// of the batch size (see WithBatch()) but no more than the buffer size bytes,
+// so the spool file may be much bigger than the available memory.
+func (ni *NetIntegrator) WithSpool(path string) *NetIntegrator {
+	if ni.canBeConfigured() && path != "" {
+		ni.spoolPath = path
+	}
+	return ni
+}
+
+// WithBackoff sets the range of reconnect delays.
+// The delay starts from 'min' and doubles for each next failed attempt
+// until it reaches 'max'. The random jitter is applied to each delay.
+// By default it's [100ms..30s].
+func (ni *NetIntegrator) WithBackoff(min, max time.Duration) *NetIntegrator {
+	if ni.canBeConfigured() && min > 0 && max >= min {
+		ni.backoffMin, ni.backoffMax = min, max
+	}
+	return ni
+}
+
+// WithTimeouts sets the timeout of one network operation (connect, write,
+// HTTP request) and the max time Sync() may wait for the acknowledgement.
+// By default they are 5s and 10s.
+func (ni *NetIntegrator) WithTimeouts(operation, sync time.Duration) *NetIntegrator {
+	if ni.canBeConfigured() {
+		if operation > 0 {
+			ni.dialTimeout = operation
+		}
+		if sync > 0 {
+			ni.syncTimeout = sync
+		}
+	}
+	return ni
+}
+
+// WithBatch sets the max number of entries in one HTTP batch
+// and how often not fully filled batch must be sent anyway.
+// Does not impact on TCP and UDP transports. By default it's 100 and 1s.
+func (ni *NetIntegrator) WithBatch(size int, flushEvery time.Duration) *NetIntegrator {
+	if ni.canBeConfigured() {
+		if size > 0 {
+			ni.batchSize = size
+		}
+		if flushEvery > 0 {
+			ni.flushEvery = flushEvery
+		}
+	}
+	return ni
+}
+
+// WithHTTPClient sets the HTTP client that will be used for HTTP transport.
+func (ni *NetIntegrator) WithHTTPClient(client *http.Client) *NetIntegrator {
+	if ni.canBeConfigured() && client != nil {
+		ni.httpClient = client
+	}
+	return ni
+}
+
+// ConnectTo sets the remote collector's address. 'network' might be:
+// - "tcp", "tcp4", "tcp6": 'address' is "host:port",
+// - "udp", "udp4", "udp6": 'address' is "host:port",
+// - "http", "https": 'address' is the URL batches will be POSTed to.
+//
+// The connection is not established right now,
+// but when NetIntegrator will be started by ReplaceIntegrator().
+func (ni *NetIntegrator) ConnectTo(network, address string) *NetIntegrator {
+	if ni.canBeConfigured() && address != "" {
+		switch network {
+		case "tcp", "tcp4", "tcp6", "udp", "udp4", "udp6", "http", "https":
+			ni.network, ni.address = network, address
+		}
+	}
+	return ni
+}
@@ -0,0 +1,753 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekalog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/qioalice/ekago/v2/ekadeath"
+)
+
+type (
+	// netConn is an alias to net.Conn, a connection NetIntegrator writes
+	// TCP, UDP log entries to.
+	netConn = net.Conn
+
+	// netSyncWaiter is a Sync() request that waits until all pending entries
+	// are acknowledged.
+	netSyncWaiter struct {
+		resp     chan error
+		deadline time.Time
+	}
+
+	// netPermanentError is an error that can not be fixed by retrying,
+	// because the remote collector rejects the entries themselves
+	// (HTTP 400, 413, etc., UDP datagram is too large). Such entries are dropped.
+	netPermanentError struct {
+		err error
+	}
+)
+
+var (
+	// errNetSyncTimeout is returned by NetIntegrator.Sync() if pending entries
+	// could not be acknowledged during the sync timeout.
+	errNetSyncTimeout = errors.New("ekalog.NetIntegrator: sync timeout, remote collector is not available")
+)
+
+// noinspection GoSnakeCaseUsage
+const (
+	_NET_DEFAULT_BUFFER_SIZE  = 4 << 20
+	_NET_DEFAULT_BACKOFF_MIN  = 100 * time.Millisecond
+	_NET_DEFAULT_BACKOFF_MAX  = 30 * time.Second
+	_NET_DEFAULT_DIAL_TIMEOUT = 5 * time.Second
+	_NET_DEFAULT_SYNC_TIMEOUT = 10 * time.Second
+	_NET_DEFAULT_BATCH_SIZE   = 100
+	_NET_DEFAULT_FLUSH_EVERY  = 1 * time.Second
+
+	// _NET_SPOOL_COMPACT_MIN is how much bytes of shipped entries the spool file
+	// must have at least to be compacted while it's still being shipped.
+	_NET_SPOOL_COMPACT_MIN = 1 << 20
+)
+
+// canBeConfigured reports whether NetIntegrator still may be configured
+// (it's not nil and not started yet).
+func (ni *NetIntegrator) canBeConfigured() bool {
+	if ni == nil {
+		return false
+	}
+	ni.mu.Lock()
+	defer ni.mu.Unlock()
+	return !ni.isStarted
+}
+
+// isHTTP reports whether HTTP transport is used.
+func (ni *NetIntegrator) isHTTP() bool {
+	return ni.network == "http" || ni.network == "https"
+}
+
+// isUDP reports whether UDP transport is used.
+func (ni *NetIntegrator) isUDP() bool {
+	return ni.network == "udp" || ni.network == "udp4" || ni.network == "udp6"
+}
+
+// tryToBuild applies default values to not configured parameters,
+// starts the internal shipping goroutine and registers the drain destructor
+// in the ekadeath package.
+//
+// Returns 'false' only if ni == nil or the remote collector's address is not set.
+// Returns 'true' if NetIntegrator is already started.
+func (ni *NetIntegrator) tryToBuild() (wasBuilt bool) {
+
+	if ni == nil {
+		return false
+	}
+
+	ni.mu.Lock()
+	defer ni.mu.Unlock()
+
+	switch {
+	case ni.isStarted:
+		return true
+	case ni.address == "":
+		return false
+	}
+
+	if ni.enc == nil {
+		ni.enc = defaultJSONEncoder
+	}
+	if ni.bufferSize == 0 {
+		ni.bufferSize = _NET_DEFAULT_BUFFER_SIZE
+	}
+	if ni.backoffMin == 0 {
+		ni.backoffMin, ni.backoffMax = _NET_DEFAULT_BACKOFF_MIN, _NET_DEFAULT_BACKOFF_MAX
+	}
+	if ni.dialTimeout == 0 {
+		ni.dialTimeout = _NET_DEFAULT_DIAL_TIMEOUT
+	}
+	if ni.syncTimeout == 0 {
+		ni.syncTimeout = _NET_DEFAULT_SYNC_TIMEOUT
+	}
+	if ni.batchSize == 0 {
+		ni.batchSize = _NET_DEFAULT_BATCH_SIZE
+	}
+	if ni.flushEvery == 0 {
+		ni.flushEvery = _NET_DEFAULT_FLUSH_EVERY
+	}
+	if ni.httpClient == nil {
+		ni.httpClient = &http.Client{Timeout: ni.dialTimeout}
+	}
+
+	ni.wakeUp = make(chan struct{}, 1)
+	ni.syncs = make(chan chan error)
+	ni.stop = make(chan struct{})
+	ni.done = make(chan struct{})
+
+	ni.initSpoolCount()
+	ni.isStarted = true
+
+	go ni.loop()
+	ni.deathHandle = ekadeath.RegPhaseNamed(
+		fmt.Sprintf("ekalog.NetIntegrator(%p)", ni), ekadeath.PHASE_FLUSH_LOGS, 0, ni.shutdown)
+
+	return true
+}
+
+// enqueue saves 'data' to the in-memory buffer, or to the spool file
+// if buffer is overflowed, or drops it if spool is disabled or not available.
+// Wakes up the internal goroutine then.
+func (ni *NetIntegrator) enqueue(data []byte) {
+
+	// The encoder may reuse its buffers. We can not rely on that.
+	data = append(data[:0:0], data...)
+
+	ni.mu.Lock()
+	switch {
+
+	case ni.isStopped:
+		ni.droppedCount++
+
+	// If spool is not empty, the entries must go to the spool anyway
+	// (to keep the original order).
+	case ni.spoolCount == 0 && ni.pendingBytes+len(data) <= ni.bufferSize:
+		ni.pending = append(ni.pending, data)
+		ni.pendingBytes += len(data)
+
+	case ni.spoolPath != "" && ni.spoolWrite([][]byte{data}) == nil:
+		ni.spoolCount++
+
+	default:
+		ni.droppedCount++
+	}
+	ni.mu.Unlock()
+
+	select {
+	case ni.wakeUp <- struct{}{}:
+	default:
+	}
+}
+
+// loop is the internal goroutine that ships pending entries to the remote
+// collector, reconnects with backoff if it's necessary and answers Sync() requests.
+func (ni *NetIntegrator) loop() {
+
+	defer close(ni.done)
+
+	var (
+		attempt    = 0
+		inBackoff  = false
+		force      = false
+		spooled    [][]byte // entries have been taken from spool but not sent yet
+		waiters    []netSyncWaiter
+		flushTimer = time.NewTicker(ni.flushEvery)
+		retryTimer = time.NewTimer(time.Hour)
+	)
+
+	defer flushTimer.Stop()
+	retryTimer.Stop()
+
+	for {
+		if !inBackoff {
+			var err error
+			if spooled, err = ni.flush(spooled, force || len(waiters) > 0); err != nil {
+				inBackoff = true
+				retryTimer.Reset(ni.backoff(attempt))
+				attempt++
+			} else {
+				attempt = 0
+				waiters = ni.answerWaiters(waiters, nil, false)
+			}
+			force = false
+		}
+
+		waiters = ni.answerWaiters(waiters, errNetSyncTimeout, true)
+
+		select {
+		case <-ni.wakeUp:
+		case <-flushTimer.C:
+			force = true
+		case <-retryTimer.C:
+			inBackoff = false
+		case resp := <-ni.syncs:
+			waiters = append(waiters, netSyncWaiter{
+				resp:     resp,
+				deadline: time.Now().Add(ni.syncTimeout),
+			})
+		case <-ni.stop:
+			ni.drain(spooled, waiters)
+			return
+		}
+	}
+}
+
+// drain makes last attempts to ship all pending entries until the sync timeout
+// is reached, answers all Sync() waiters and closes connection and spool file.
+func (ni *NetIntegrator) drain(spooled [][]byte, waiters []netSyncWaiter) {
+
+	var (
+		err      error
+		deadline = time.Now().Add(ni.syncTimeout)
+	)
+
+	for attempt := 0; ; attempt++ {
+		if spooled, err = ni.flush(spooled, true); err == nil {
+			break
+		}
+		delay := ni.backoff(attempt)
+		if time.Now().Add(delay).After(deadline) {
+			break
+		}
+		time.Sleep(delay)
+	}
+
+	// Not shipped spooled entries are still in the spool file.
+	// They will be shipped at the next start.
+	if err != nil {
+		err = fmt.Errorf("ekalog.NetIntegrator: stopped, not all entries were shipped: %w", err)
+	}
+
+	ni.answerWaiters(waiters, err, false)
+	ni.stopErr = err
+
+	if ni.conn != nil {
+		_ = ni.conn.Close()
+		ni.conn = nil
+	}
+
+	ni.mu.Lock()
+	if ni.spool != nil {
+		_ = ni.spoolCompact()
+	}
+	if ni.spool != nil {
+		_ = ni.spool.Close()
+		ni.spool = nil
+	}
+	ni.mu.Unlock()
+}
+
+// shutdown is the ekadeath's destructor. Stops the internal goroutine
+// and waits until it's done (draining pending entries).
+// Returns an error if not all entries have been shipped.
+func (ni *NetIntegrator) shutdown(ctx context.Context) error {
+
+	ni.mu.Lock()
+	wasStopped := ni.isStopped
+	ni.isStopped = true
+	ni.mu.Unlock()
+
+	if !wasStopped {
+		close(ni.stop)
+	}
+
+	select {
+	case <-ni.done:
+		return ni.stopErr
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(ni.syncTimeout + ni.dialTimeout):
+		return errNetSyncTimeout
+	}
+}
+
+// answerWaiters sends 'err' to the Sync() waiters and returns the rest of them.
+// If 'onlyExpired' is true, only those waiters which deadline is reached
+// are answered.
+func (ni *NetIntegrator) answerWaiters(
+
+	waiters []netSyncWaiter,
+	err error,
+	onlyExpired bool,
+
+) []netSyncWaiter {
+
+	now := time.Now()
+	rest := waiters[:0]
+
+	for _, waiter := range waiters {
+		if onlyExpired && now.Before(waiter.deadline) {
+			rest = append(rest, waiter)
+			continue
+		}
+		waiter.resp <- err // buffered chan, never blocks
+	}
+
+	return rest
+}
+
+// backoff returns a delay before the next reconnect attempt:
+// exponential growth with equal jitter, in the [backoffMin..backoffMax] range.
+func (ni *NetIntegrator) backoff(attempt int) time.Duration {
+
+	d := ni.backoffMax
+	if attempt < 30 {
+		if d2 := ni.backoffMin << uint(attempt); d2 < d {
+			d = d2
+		}
+	}
+
+	half := int64(d / 2)
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+// flush ships entries in the following order: 'spooled' (those entries,
+// that were read from the spool file earlier), in-memory buffered entries,
+// spool file's entries. Spooled entries are removed from the spool file
+// only after they are shipped. Returns not shipped spooled entries and an error
+// if something goes wrong.
+//
+// HTTP batch that is not fully filled is sent only if 'force' is true.
+func (ni *NetIntegrator) flush(spooled [][]byte, force bool) ([][]byte, error) {
+
+	for {
+		if len(spooled) > 0 {
+			sent, err := ni.send(spooled)
+			ni.mu.Lock()
+			ni.spoolCommit(spooled[:sent])
+			ni.mu.Unlock()
+			for i := 0; i < sent; i++ {
+				spooled[i] = nil
+			}
+			if spooled = spooled[sent:]; err != nil {
+				return spooled, err
+			}
+			continue
+		}
+
+		ni.mu.Lock()
+		batch := ni.pending
+		if ni.isHTTP() && len(batch) > ni.batchSize {
+			batch = batch[:ni.batchSize]
+		}
+		if len(batch) == 0 && ni.spoolCount > 0 {
+			spooled, _ = ni.spoolRead()
+		}
+		ni.mu.Unlock()
+
+		switch {
+		case len(spooled) > 0:
+			continue
+		case len(batch) == 0:
+			return nil, nil
+		case ni.isHTTP() && len(batch) < ni.batchSize && !force:
+			return nil, nil
+		}
+
+		sent, err := ni.send(batch)
+
+		ni.mu.Lock()
+		for i := 0; i < sent; i++ {
+			ni.pendingBytes -= len(ni.pending[i])
+			ni.pending[i] = nil
+		}
+		ni.pending = ni.pending[sent:]
+		ni.mu.Unlock()
+
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// send ships 'batch' to the remote collector using configured transport.
+// Returns how much entries has been processed: successfully shipped or dropped
+// because of permanent error (see netPermanentError). The returned error
+// is always retryable.
+func (ni *NetIntegrator) send(batch [][]byte) (sent int, err error) {
+
+	if ni.isHTTP() {
+		for sent < len(batch) {
+			n := len(batch) - sent
+			if n > ni.batchSize {
+				n = ni.batchSize
+			}
+			if err = ni.sendHTTP(batch[sent : sent+n]); err != nil {
+				if !isNetPermanentError(err) {
+					return sent, err
+				}
+				ni.drop(n)
+			}
+			sent += n
+		}
+		return sent, nil
+	}
+
+	if ni.conn == nil {
+		if ni.conn, err = net.DialTimeout(ni.network, ni.address, ni.dialTimeout); err != nil {
+			ni.conn = nil
+			return 0, err
+		}
+	}
+
+	var frame []byte
+	for _, data := range batch {
+		frame = ni.frame(frame[:0], data)
+
+		_ = ni.conn.SetWriteDeadline(time.Now().Add(ni.dialTimeout))
+		if _, err = ni.conn.Write(frame); err != nil {
+			if ni.isUDP() && errors.Is(err, syscall.EMSGSIZE) {
+				ni.drop(1)
+				sent++
+				continue
+			}
+			_ = ni.conn.Close()
+			ni.conn = nil
+			return sent, err
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+// frame appends framed 'data' to 'to' according with transport and framing
+// NetIntegrator is configured with.
+func (ni *NetIntegrator) frame(to, data []byte) []byte {
+
+	switch {
+	case ni.isUDP():
+		return append(to, data...)
+
+	case ni.framing == NET_FRAMING_LENGTH_PREFIXED:
+		var l [4]byte
+		binary.BigEndian.PutUint32(l[:], uint32(len(data)))
+		return append(append(to, l[:]...), data...)
+
+	default:
+		to = append(to, data...)
+		if len(data) == 0 || data[len(data)-1] != '\n' {
+			to = append(to, '\n')
+		}
+		return to
+	}
+}
+
+// sendHTTP POSTs 'batch' as NDJSON document and checks that response is 2xx.
+// Returns netPermanentError if the remote collector rejects the batch
+// because of its content.
+func (ni *NetIntegrator) sendHTTP(batch [][]byte) error {
+
+	var body bytes.Buffer
+	for _, data := range batch {
+		body.Write(data)
+		if len(data) == 0 || data[len(data)-1] != '\n' {
+			body.WriteByte('\n')
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ni.dialTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ni.address, &body)
+	if err != nil {
+		return netPermanentError{err}
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	resp, err := ni.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	switch resp.StatusCode {
+
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge,
+		http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity:
+		return netPermanentError{
+			fmt.Errorf("ekalog.NetIntegrator: remote collector rejected batch: %s", resp.Status)}
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("ekalog.NetIntegrator: remote collector responded %s", resp.Status)
+	}
+
+	return nil
+}
+
+// drop counts 'n' entries that are dropped because of permanent error.
+func (ni *NetIntegrator) drop(n int) {
+	ni.mu.Lock()
+	ni.droppedCount += uint64(n)
+	ni.mu.Unlock()
+}
+
+// Error returns the message of underlying error.
+func (e netPermanentError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e netPermanentError) Unwrap() error {
+	return e.err
+}
+
+// isNetPermanentError reports whether 'err' is netPermanentError
+// and the entries that are failed with it must not be retried.
+func isNetPermanentError(err error) bool {
+	var permanentErr netPermanentError
+	return errors.As(err, &permanentErr)
+}
+
+// spoolOpen opens spool file if it's not opened yet.
+// Requires ni.mu to be locked.
+func (ni *NetIntegrator) spoolOpen() error {
+	if ni.spool != nil {
+		return nil
+	}
+	f, err := os.OpenFile(ni.spoolPath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	ni.spool = f
+	return nil
+}
+
+// spoolWrite appends length prefixed 'entries' to the spool file.
+// Requires ni.mu to be locked.
+func (ni *NetIntegrator) spoolWrite(entries [][]byte) error {
+
+	if err := ni.spoolOpen(); err != nil {
+		return err
+	}
+
+	_, err := ni.spool.Write(spoolEncode(nil, entries))
+	return err
+}
+
+// spoolRead reads the first not shipped entries from the spool file,
+// but no more than ni.batchSize entries and no more than ni.bufferSize bytes
+// (at least one entry is read anyway), so the memory usage does not depend on
+// how big the spool file has become. Cuts off the corrupted tail (if any)
+// of the spool file if it's reached. Requires ni.mu to be locked.
+func (ni *NetIntegrator) spoolRead() ([][]byte, error) {
+
+	var (
+		entries [][]byte
+		size    = 0
+	)
+
+	err := ni.spoolScan(func(l int) bool {
+		return len(entries) < ni.batchSize && (len(entries) == 0 || size+l <= ni.bufferSize)
+	}, func(data []byte) {
+		entries = append(entries, data)
+		size += len(data)
+	})
+
+	return entries, err
+}
+
+// spoolScan reads the spool file's entries starting from the first not shipped,
+// calling 'cb' for each entry until 'next' returns false for the entry's length.
+// If 'cb' is nil, entries are skipped w/o reading them into the memory.
+// Cuts off the corrupted tail (if any) of the spool file if it's reached.
+// Requires ni.mu to be locked.
+func (ni *NetIntegrator) spoolScan(next func(l int) bool, cb func(data []byte)) error {
+
+	if err := ni.spoolOpen(); err != nil {
+		return err
+	}
+
+	if _, err := ni.spool.Seek(ni.spoolOffset, io.SeekStart); err != nil {
+		return err
+	}
+
+	var (
+		r      = bufio.NewReader(ni.spool)
+		offset = ni.spoolOffset
+		l      [4]byte
+		err    error
+	)
+
+	for {
+		if _, err = io.ReadFull(r, l[:]); err != nil {
+			break
+		}
+		n := int(binary.BigEndian.Uint32(l[:]))
+		if !next(n) {
+			return nil
+		}
+		if cb == nil {
+			_, err = r.Discard(n)
+		} else {
+			data := make([]byte, n)
+			if _, err = io.ReadFull(r, data); err == nil {
+				cb(data)
+			}
+		}
+		if err != nil {
+			break
+		}
+		offset += int64(4 + n)
+	}
+
+	switch err {
+	case io.EOF:
+		return nil
+	case io.ErrUnexpectedEOF:
+		// Entries are written under ni.mu, so the incomplete one
+		// may be only a trace of the crash.
+		return ni.spool.Truncate(offset)
+	default:
+		return err
+	}
+}
+
+// spoolCommit marks 'entries' (the first not shipped spool file's entries)
+// as shipped. Truncates the spool file when all its entries are shipped,
+// or compacts it if shipped entries take more than a half of it
+// (the spool file may be long to be shipped after the collector's outage).
+// Requires ni.mu to be locked.
+func (ni *NetIntegrator) spoolCommit(entries [][]byte) {
+
+	if len(entries) == 0 {
+		return
+	}
+
+	for _, data := range entries {
+		ni.spoolOffset += int64(4 + len(data))
+	}
+
+	if ni.spoolCount -= len(entries); ni.spoolCount > 0 {
+		if ni.spoolOffset >= _NET_SPOOL_COMPACT_MIN {
+			if fi, err := ni.spool.Stat(); err == nil && ni.spoolOffset*2 >= fi.Size() {
+				_ = ni.spoolCompact()
+			}
+		}
+		return
+	}
+
+	ni.spoolCount = 0
+	if ni.spool.Truncate(0) == nil {
+		ni.spoolOffset = 0
+	}
+}
+
+// spoolCompact removes already shipped entries from the spool file
+// by replacing it with the new one that contains only not shipped entries.
+// The spool file is closed then (it's reopened when it's required).
+// Requires ni.mu to be locked.
+func (ni *NetIntegrator) spoolCompact() error {
+
+	if ni.spoolOffset == 0 {
+		return nil
+	}
+
+	if err := ni.spoolOpen(); err != nil {
+		return err
+	}
+
+	if _, err := ni.spool.Seek(ni.spoolOffset, io.SeekStart); err != nil {
+		return err
+	}
+
+	tmpPath := ni.spoolPath + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	// The spool file's tail has been checked by initSpoolCount() already
+	// and next entries are written under ni.mu, so it can be copied as is.
+	if _, err = io.Copy(tmp, ni.spool); err == nil {
+		err = tmp.Close()
+	} else {
+		_ = tmp.Close()
+	}
+
+	// Rename is atomic, so a crash can not lose the entries.
+	if err == nil {
+		err = os.Rename(tmpPath, ni.spoolPath)
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	_ = ni.spool.Close()
+	ni.spool = nil
+	ni.spoolOffset = 0
+
+	return nil
+}
+
+// initSpoolCount counts entries are already stored in the spool file
+// (from the previous run) w/o reading them into the memory.
+// Requires ni.mu to be locked.
+func (ni *NetIntegrator) initSpoolCount() {
+
+	if ni.spoolPath == "" {
+		return
+	}
+
+	count := 0
+	if ni.spoolScan(func(_ int) bool { count++; return true }, nil) == nil {
+		ni.spoolCount = count
+	}
+}
+
+// spoolEncode appends length prefixed 'entries' to 'to'.
+func spoolEncode(to []byte, entries [][]byte) []byte {
+	for _, data := range entries {
+		var l [4]byte
+		binary.BigEndian.PutUint32(l[:], uint32(len(data)))
+		to = append(append(to, l[:]...), data...)
+	}
+	return to
+}
@@ -0,0 +1,402 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekalog_test
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/qioalice/ekago/v2/ekadeath"
+	"github.com/qioalice/ekago/v2/ekalog"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetIntegrator_TCPNewline(t *testing.T) {
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	lines := make(chan string, 16)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	ni := new(ekalog.NetIntegrator).
+		WithMinLevel(ekalog.LEVEL_DEBUG).
+		WithTimeouts(time.Second, 2*time.Second).
+		ConnectTo("tcp", ln.Addr().String())
+
+	ekalog.ReplaceIntegrator(ni)
+
+	ekalog.Info("first")
+	ekalog.Info("second")
+
+	require.NoError(t, ekalog.SyncThis())
+
+	require.Contains(t, <-lines, "first")
+	require.Contains(t, <-lines, "second")
+}
+
+func TestNetIntegrator_TCPLengthPrefixed(t *testing.T) {
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	frames := make(chan string, 16)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			var l [4]byte
+			if _, err := io.ReadFull(conn, l[:]); err != nil {
+				return
+			}
+			frame := make([]byte, binary.BigEndian.Uint32(l[:]))
+			if _, err := io.ReadFull(conn, frame); err != nil {
+				return
+			}
+			frames <- string(frame)
+		}
+	}()
+
+	ni := new(ekalog.NetIntegrator).
+		WithMinLevel(ekalog.LEVEL_DEBUG).
+		WithFraming(ekalog.NET_FRAMING_LENGTH_PREFIXED).
+		WithTimeouts(time.Second, 2*time.Second).
+		ConnectTo("tcp", ln.Addr().String())
+
+	ekalog.ReplaceIntegrator(ni)
+
+	ekalog.Warn("length prefixed")
+	require.NoError(t, ekalog.SyncThis())
+
+	require.Contains(t, <-frames, "length prefixed")
+}
+
+func TestNetIntegrator_HTTPBatchAndRetry(t *testing.T) {
+
+	var (
+		mu       sync.Mutex
+		requests = 0
+		received []string
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		// The first request fails, NetIntegrator must retry it.
+		if requests++; requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		require.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+		body, _ := ioutil.ReadAll(r.Body)
+		received = append(received, strings.Split(strings.TrimSpace(string(body)), "\n")...)
+	}))
+	defer srv.Close()
+
+	ni := new(ekalog.NetIntegrator).
+		WithMinLevel(ekalog.LEVEL_DEBUG).
+		WithBackoff(10*time.Millisecond, 50*time.Millisecond).
+		WithBatch(10, 50*time.Millisecond).
+		WithTimeouts(time.Second, 2*time.Second).
+		ConnectTo("http", srv.URL)
+
+	ekalog.ReplaceIntegrator(ni)
+
+	ekalog.Info("one")
+	ekalog.Info("two")
+	ekalog.Info("three")
+
+	require.NoError(t, ekalog.SyncThis())
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	require.Len(t, received, 3)
+	require.Contains(t, received[0], "one")
+	require.Contains(t, received[2], "three")
+}
+
+func TestNetIntegrator_HTTPPermanentError(t *testing.T) {
+
+	var (
+		mu       sync.Mutex
+		requests = 0
+		received []string
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		requests++
+		body, _ := ioutil.ReadAll(r.Body)
+
+		// Rejected batch must not be retried.
+		if strings.Contains(string(body), "too large") {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		received = append(received, strings.TrimSpace(string(body)))
+	}))
+	defer srv.Close()
+
+	ni := new(ekalog.NetIntegrator).
+		WithMinLevel(ekalog.LEVEL_DEBUG).
+		WithBackoff(10*time.Millisecond, 50*time.Millisecond).
+		WithBatch(1, 50*time.Millisecond).
+		WithTimeouts(time.Second, 2*time.Second).
+		ConnectTo("http", srv.URL)
+
+	ekalog.ReplaceIntegrator(ni)
+
+	ekalog.Info("one")
+	ekalog.Info("too large")
+	ekalog.Info("three")
+
+	require.NoError(t, ekalog.SyncThis())
+	require.Equal(t, uint64(1), ni.DroppedCount())
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	require.Equal(t, 3, requests)
+	require.Len(t, received, 2)
+	require.Contains(t, received[0], "one")
+	require.Contains(t, received[1], "three")
+}
+
+func TestNetIntegrator_SyncTimeout(t *testing.T) {
+
+	// Take a free port and release it, so nobody listens it.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	ni := new(ekalog.NetIntegrator).
+		WithMinLevel(ekalog.LEVEL_DEBUG).
+		WithBackoff(10*time.Millisecond, 20*time.Millisecond).
+		WithBatch(0, 50*time.Millisecond).
+		WithTimeouts(100*time.Millisecond, 200*time.Millisecond).
+		ConnectTo("tcp", addr)
+
+	ekalog.ReplaceIntegrator(ni)
+
+	ekalog.Info("never shipped")
+	require.Error(t, ekalog.SyncThis())
+	require.Equal(t, uint64(0), ni.DroppedCount())
+}
+
+func TestNetIntegrator_Stop(t *testing.T) {
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = io.Copy(ioutil.Discard, conn)
+	}()
+
+	ni := new(ekalog.NetIntegrator).
+		WithMinLevel(ekalog.LEVEL_DEBUG).
+		WithTimeouts(time.Second, 2*time.Second).
+		ConnectTo("tcp", ln.Addr().String())
+
+	isRegistered := func() bool {
+		name := fmt.Sprintf("ekalog.NetIntegrator(%p)", ni)
+		for _, info := range ekadeath.Registered() {
+			if info.Name == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	require.True(t, ekalog.BuildIntegrator(ni))
+	require.True(t, isRegistered())
+
+	require.NoError(t, ni.Stop())
+	require.NoError(t, ni.Stop())
+	require.False(t, isRegistered())
+	require.Error(t, ni.Sync())
+
+	ekalog.ReplaceIntegrator(ni)
+	ekalog.Info("dropped")
+	require.Equal(t, uint64(1), ni.DroppedCount())
+}
+
+func TestNetIntegrator_Spool(t *testing.T) {
+
+	spoolPath := filepath.Join(t.TempDir(), "net.spool")
+
+	// Take a free port and release it, so nobody listens it.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	ni := new(ekalog.NetIntegrator).
+		WithMinLevel(ekalog.LEVEL_DEBUG).
+		WithBufferSize(1).
+		WithSpool(spoolPath).
+		WithBackoff(10*time.Millisecond, 20*time.Millisecond).
+		WithTimeouts(100*time.Millisecond, 200*time.Millisecond).
+		ConnectTo("tcp", addr)
+
+	ekalog.ReplaceIntegrator(ni)
+
+	ekalog.Info("spooled 1")
+	ekalog.Info("spooled 2")
+
+	// Failed attempts to ship spooled entries must not remove them from the spool.
+	require.Error(t, ekalog.SyncThis())
+	require.Error(t, ni.Stop())
+	require.Equal(t, uint64(0), ni.DroppedCount())
+
+	spool, err := ioutil.ReadFile(spoolPath)
+	require.NoError(t, err)
+	require.Contains(t, string(spool), "spooled 1")
+	require.Contains(t, string(spool), "spooled 2")
+
+	// The next start ships them.
+	ln, err = net.Listen("tcp", addr)
+	require.NoError(t, err)
+	defer ln.Close()
+
+	lines := make(chan string, 16)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	ni = new(ekalog.NetIntegrator).
+		WithMinLevel(ekalog.LEVEL_DEBUG).
+		WithSpool(spoolPath).
+		WithTimeouts(time.Second, 2*time.Second).
+		ConnectTo("tcp", addr)
+
+	ekalog.ReplaceIntegrator(ni)
+	require.NoError(t, ekalog.SyncThis())
+
+	require.Contains(t, <-lines, "spooled 1")
+	require.Contains(t, <-lines, "spooled 2")
+
+	require.NoError(t, ni.Stop())
+	spool, err = ioutil.ReadFile(spoolPath)
+	require.NoError(t, err)
+	require.Empty(t, spool)
+}
+
+func TestNetIntegrator_SpoolBig(t *testing.T) {
+
+	spoolPath := filepath.Join(t.TempDir(), "net.spool")
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	ni := new(ekalog.NetIntegrator).
+		WithMinLevel(ekalog.LEVEL_DEBUG).
+		WithBufferSize(1).
+		WithSpool(spoolPath).
+		WithTimeouts(100*time.Millisecond, 100*time.Millisecond).
+		ConnectTo("tcp", addr)
+
+	ekalog.ReplaceIntegrator(ni)
+
+	// Many batches and enough bytes to compact the spool file
+	// while it's being shipped.
+	const N = 300
+	padding := strings.Repeat("x", 8<<10)
+	for i := 0; i < N; i++ {
+		ekalog.Info(fmt.Sprintf("spooled %d %s", i, padding))
+	}
+	_ = ni.Stop()
+
+	ln, err = net.Listen("tcp", addr)
+	require.NoError(t, err)
+	defer ln.Close()
+
+	lines := make(chan string, N)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		scanner := bufio.NewScanner(conn)
+		scanner.Buffer(nil, 1<<20)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	ni = new(ekalog.NetIntegrator).
+		WithMinLevel(ekalog.LEVEL_DEBUG).
+		WithBufferSize(64 << 10).
+		WithBatch(16, 0).
+		WithSpool(spoolPath).
+		WithTimeouts(time.Second, 5*time.Second).
+		ConnectTo("tcp", addr)
+
+	ekalog.ReplaceIntegrator(ni)
+	require.NoError(t, ekalog.SyncThis())
+
+	// All entries must be shipped once and in the original order.
+	for i := 0; i < N; i++ {
+		require.Contains(t, <-lines, fmt.Sprintf("spooled %d x", i))
+	}
+
+	require.NoError(t, ni.Stop())
+	spool, err := ioutil.ReadFile(spoolPath)
+	require.NoError(t, err)
+	require.Empty(t, spool)
+
+	_, err = ioutil.ReadFile(spoolPath + ".tmp")
+	require.Error(t, err)
+}
@@ -7,11 +7,11 @@ package ekalog
 
 import (
 	"fmt"
-	"os"
 	"time"
 	"unsafe"
 
 	"github.com/qioalice/ekago/v2/ekadeath"
+	"github.com/qioalice/ekago/v2/ekasys"
 	"github.com/qioalice/ekago/v2/internal/ekafield"
 	"github.com/qioalice/ekago/v2/internal/ekaletter"
 )
@@ -165,8 +165,9 @@ func (l *Logger) log(
 		releaseEntry(workTempEntry)
 	}
 
+	// workTempEntry may be released already, so its level must not be used.
 	switch {
-	case workTempEntry.Level.mustDie():
+	case lvl.mustDie():
 		ekadeath.Die()
 	}
 
@@ -203,7 +204,10 @@ func logErrw(logger unsafe.Pointer, level uint8, errLetter *ekaletter.Letter, me
 // initBaseLogger performs a baseLogger initialization.
 func initBaseLogger() {
 
-	defaultConsoleEncoder = new(CI_ConsoleEncoder).FreezeAndGetEncoder()
+	// Colors are written only if stdout is a terminal (or forced by env vars).
+	defaultConsoleEncoder = new(CI_ConsoleEncoder).
+		SetColorsAutoFor(ekasys.Stdout()).
+		FreezeAndGetEncoder()
 	defaultJSONEncoder = new(CI_JSONEncoder).FreezeAndGetEncoder()
 
 	_ = defaultConsoleEncoder
@@ -213,8 +217,14 @@ func initBaseLogger() {
 		WithEncoder(defaultConsoleEncoder).
 		WithMinLevel(LEVEL_DEBUG).
 		WithMinLevelForStackTrace(LEVEL_WARNING).
-		WriteTo(os.Stdout)
+		WriteTo(ekasys.Stdout())
 
 	entry := acquireEntry()
 	baseLogger = new(Logger).setIntegrator(integrator).setEntry(entry)
 }
+
+// logMessagew is just the same as baseLogger.log() but only for explicit fields.
+// It's used by ekadeath package to log destructors' outcomes.
+func logMessagew(level uint8, message string, fields []ekafield.Field) {
+	baseLogger.log(Level(level), message, nil, nil, fields)
+}
@@ -0,0 +1,165 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekasys
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+type (
+	// Goroutine is an one goroutine's state and stacktrace
+	// at the moment of goroutines dump (see GetGoroutines()).
+	Goroutine struct {
+		ID    uint64 // goroutine's ID
+		State string // like "running", "chan receive", "select", "IO wait"
+
+		// WaitDuration is how long goroutine is blocked. Go runtime reports it
+		// with a minute precision and only if it's blocked for 1 minute at least.
+		WaitDuration time.Duration
+
+		LockedToThread bool // true if goroutine is locked to the OS thread
+
+		// CreatedBy is a stack frame of the 'go' statement goroutine is spawned by,
+		// and CreatedByID is an ID of goroutine that spawned it
+		// (0 if it's unknown). Both are empty for the main goroutine.
+		CreatedBy   StackFrame
+		CreatedByID uint64
+
+		StackTrace StackTrace
+	}
+
+	// GoroutineGroup is a group of goroutines that have the same state,
+	// the same stacktrace and are spawned by the same 'go' statement.
+	// See GroupGoroutines().
+	GoroutineGroup struct {
+		State      string
+		StackTrace StackTrace
+		CreatedBy  StackFrame
+
+		IDs     []uint64      // IDs of goroutines in the group, in ascending order
+		MinWait time.Duration // min of goroutines' Goroutine.WaitDuration
+		MaxWait time.Duration // max of goroutines' Goroutine.WaitDuration
+	}
+)
+
+//noinspection GoSnakeCaseUsage
+const (
+	// GOROUTINES_DUMP_MAX_SIZE is a max size of goroutines dump (in bytes)
+	// GetGoroutines() requests from the Go runtime. The rest is truncated.
+	GOROUTINES_DUMP_MAX_SIZE = 64 << 20
+)
+
+// GetGoroutines returns the states and stacktraces of all goroutines
+// (including current one), parsing runtime.Stack()'s output.
+//
+// It's expensive: the world is stopped while runtime dumps goroutines.
+func GetGoroutines() []Goroutine {
+	return ParseGoroutines(getGoroutinesDump())
+}
+
+// ParseGoroutines parses 'dump' that is runtime.Stack()'s output
+// (or the output Go prints when panic occurred or SIGQUIT received)
+// returning parsed goroutines. Unknown lines are skipped.
+func ParseGoroutines(dump []byte) []Goroutine {
+
+	var (
+		goroutines []Goroutine
+		current    *Goroutine
+		lines      = strings.Split(string(dump), "\n")
+	)
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], "\r")
+
+		if g, ok := parseGoroutineHeader(line); ok {
+			goroutines = append(goroutines, g)
+			current = &goroutines[len(goroutines)-1]
+			continue
+		}
+
+		if current == nil || line == "" || line[0] == '\t' ||
+			strings.HasPrefix(line, "...") {
+			continue
+		}
+
+		// It's a function's line, the next one must be its file's line.
+		frame := StackFrame{}
+		if i+1 < len(lines) && strings.HasPrefix(lines[i+1], "\t") {
+			frame.File, frame.Line = parseGoroutineFileLine(lines[i+1])
+			i++
+		}
+
+		if strings.HasPrefix(line, "created by ") {
+			frame.Function, current.CreatedByID = parseGoroutineCreatedBy(line)
+			current.CreatedBy = frame
+		} else {
+			frame.Function = parseGoroutineFunction(line)
+			current.StackTrace = append(current.StackTrace, frame)
+		}
+	}
+
+	return goroutines
+}
+
+// GroupGoroutines groups 'goroutines' that have the same state, stacktrace
+// and "created by" frame. Groups with more goroutines are placed first.
+// Wait durations are not the part of group's key.
+func GroupGoroutines(goroutines []Goroutine) []GoroutineGroup {
+
+	var (
+		groups  []GoroutineGroup
+		indexes = make(map[string]int)
+	)
+
+	for i := range goroutines {
+		g := &goroutines[i]
+		key := goroutineGroupKey(g)
+
+		idx, found := indexes[key]
+		if !found {
+			idx = len(groups)
+			indexes[key] = idx
+			groups = append(groups, GoroutineGroup{
+				State:      g.State,
+				StackTrace: g.StackTrace,
+				CreatedBy:  g.CreatedBy,
+				MinWait:    g.WaitDuration,
+				MaxWait:    g.WaitDuration,
+			})
+		}
+
+		group := &groups[idx]
+		group.IDs = append(group.IDs, g.ID)
+
+		if g.WaitDuration < group.MinWait {
+			group.MinWait = g.WaitDuration
+		}
+		if g.WaitDuration > group.MaxWait {
+			group.MaxWait = g.WaitDuration
+		}
+	}
+
+	for i := range groups {
+		ids := groups[i].IDs
+		sort.Slice(ids, func(a, b int) bool { return ids[a] < ids[b] })
+	}
+
+	sort.SliceStable(groups, func(a, b int) bool {
+		if len(groups[a].IDs) != len(groups[b].IDs) {
+			return len(groups[a].IDs) > len(groups[b].IDs)
+		}
+		return groups[a].IDs[0] < groups[b].IDs[0]
+	})
+
+	return groups
+}
+
+// Count returns a number of goroutines in the group.
+func (g GoroutineGroup) Count() int {
+	return len(g.IDs)
+}
@@ -0,0 +1,146 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekasys
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// getGoroutinesDump returns runtime.Stack()'s output for all goroutines,
+// growing buffer until the whole dump fits it (or the max size is reached).
+func getGoroutinesDump() []byte {
+
+	for size := 64 << 10; ; size <<= 1 {
+		buf := make([]byte, size)
+		if n := runtime.Stack(buf, true); n < size || size >= GOROUTINES_DUMP_MAX_SIZE {
+			return buf[:n]
+		}
+	}
+}
+
+// parseGoroutineHeader parses goroutine's header line like
+// "goroutine 18 [chan receive, 2 minutes, locked to thread]:".
+func parseGoroutineHeader(line string) (g Goroutine, ok bool) {
+
+	const prefix = "goroutine "
+	if !strings.HasPrefix(line, prefix) || !strings.HasSuffix(line, "]:") {
+		return g, false
+	}
+
+	line = line[len(prefix):]
+
+	idEnd := strings.IndexByte(line, ' ')
+	stateStart := strings.IndexByte(line, '[')
+	if idEnd == -1 || stateStart == -1 {
+		return g, false
+	}
+
+	id, err := strconv.ParseUint(line[:idEnd], 10, 64)
+	if err != nil {
+		return g, false
+	}
+
+	g.ID = id
+	parts := strings.Split(line[stateStart+1:len(line)-2], ", ")
+	g.State = parts[0]
+
+	for _, part := range parts[1:] {
+		switch {
+		case part == "locked to thread":
+			g.LockedToThread = true
+
+		case strings.HasSuffix(part, " minutes"):
+			if minutes, err := strconv.Atoi(strings.TrimSuffix(part, " minutes")); err == nil {
+				g.WaitDuration = time.Duration(minutes) * time.Minute
+			}
+		}
+	}
+
+	return g, true
+}
+
+// parseGoroutineFunction returns function's name from the stack frame's line,
+// like "main.(*T).foo(0xc000010000, {0x1, 0x2})" w/o arguments.
+func parseGoroutineFunction(line string) string {
+
+	if !strings.HasSuffix(line, ")") {
+		return line
+	}
+
+	depth := 0
+	for i := len(line) - 1; i >= 0; i-- {
+		switch line[i] {
+		case ')':
+			depth++
+		case '(':
+			if depth--; depth == 0 {
+				return line[:i]
+			}
+		}
+	}
+
+	return line
+}
+
+// parseGoroutineCreatedBy parses the line like
+// "created by main.foo in goroutine 7" returning function and goroutine's ID.
+func parseGoroutineCreatedBy(line string) (function string, id uint64) {
+
+	function = strings.TrimPrefix(line, "created by ")
+
+	if idx := strings.LastIndex(function, " in goroutine "); idx != -1 {
+		id, _ = strconv.ParseUint(function[idx+len(" in goroutine "):], 10, 64)
+		function = function[:idx]
+	}
+
+	return parseGoroutineFunction(function), id
+}
+
+// parseGoroutineFileLine parses stack frame's file line like
+// "\t/path/to/file.go:42 +0x1d".
+func parseGoroutineFileLine(line string) (file string, lineNum int) {
+
+	line = strings.TrimSpace(line)
+	if idx := strings.LastIndex(line, " +0x"); idx != -1 {
+		line = line[:idx]
+	}
+
+	idx := strings.LastIndexByte(line, ':')
+	if idx == -1 {
+		return line, 0
+	}
+
+	lineNum, _ = strconv.Atoi(line[idx+1:])
+	return line[:idx], lineNum
+}
+
+// goroutineGroupKey returns a key goroutines are grouped by.
+func goroutineGroupKey(g *Goroutine) string {
+
+	var b strings.Builder
+
+	b.WriteString(g.State)
+	for _, frame := range g.StackTrace {
+		b.WriteByte('|')
+		b.WriteString(frame.Function)
+		b.WriteByte(' ')
+		b.WriteString(frame.File)
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(frame.Line))
+	}
+
+	b.WriteString("|created by ")
+	b.WriteString(g.CreatedBy.Function)
+	b.WriteByte(' ')
+	b.WriteString(g.CreatedBy.File)
+	b.WriteByte(':')
+	b.WriteString(strconv.Itoa(g.CreatedBy.Line))
+
+	return b.String()
+}
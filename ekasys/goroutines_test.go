@@ -0,0 +1,95 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekasys_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qioalice/ekago/v2/ekasys"
+
+	"github.com/stretchr/testify/require"
+)
+
+const goroutinesTestDump = `goroutine 1 [running]:
+main.main()
+	/home/user/project/main.go:10 +0x1d
+
+goroutine 7 [chan receive, 3 minutes]:
+main.worker(0xc000010000, {0x1, 0x2})
+	/home/user/project/worker.go:20 +0x45
+created by main.main in goroutine 1
+	/home/user/project/main.go:8 +0x25
+
+goroutine 9 [chan receive, 5 minutes, locked to thread]:
+main.worker(0xc000010008, {0x3, 0x4})
+	/home/user/project/worker.go:20 +0x45
+created by main.main in goroutine 1
+	/home/user/project/main.go:8 +0x25
+
+goroutine 12 [select]:
+main.(*Server).loop(...)
+	/home/user/project/server.go:33
+...additional frames elided...
+created by main.main
+	/home/user/project/main.go:9 +0x30
+`
+
+func TestParseGoroutines(t *testing.T) {
+
+	goroutines := ekasys.ParseGoroutines([]byte(goroutinesTestDump))
+	require.Len(t, goroutines, 4)
+
+	require.Equal(t, uint64(1), goroutines[0].ID)
+	require.Equal(t, "running", goroutines[0].State)
+	require.Equal(t, "main.main", goroutines[0].StackTrace[0].Function)
+	require.Equal(t, "/home/user/project/main.go", goroutines[0].StackTrace[0].File)
+	require.Equal(t, 10, goroutines[0].StackTrace[0].Line)
+	require.Empty(t, goroutines[0].CreatedBy.Function)
+
+	g := goroutines[2]
+	require.Equal(t, uint64(9), g.ID)
+	require.Equal(t, "chan receive", g.State)
+	require.Equal(t, 5*time.Minute, g.WaitDuration)
+	require.True(t, g.LockedToThread)
+	require.Equal(t, "main.worker", g.StackTrace[0].Function)
+	require.Equal(t, "main.main", g.CreatedBy.Function)
+	require.Equal(t, 8, g.CreatedBy.Line)
+	require.Equal(t, uint64(1), g.CreatedByID)
+
+	g = goroutines[3]
+	require.Equal(t, "main.(*Server).loop", g.StackTrace[0].Function)
+	require.Equal(t, 33, g.StackTrace[0].Line)
+	require.Equal(t, uint64(0), g.CreatedByID)
+
+	groups := ekasys.GroupGoroutines(goroutines)
+	require.Len(t, groups, 3)
+	require.Equal(t, []uint64{7, 9}, groups[0].IDs)
+	require.Equal(t, 2, groups[0].Count())
+	require.Equal(t, 3*time.Minute, groups[0].MinWait)
+	require.Equal(t, 5*time.Minute, groups[0].MaxWait)
+	require.Equal(t, []uint64{1}, groups[1].IDs)
+}
+
+func TestGetGoroutines(t *testing.T) {
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	for i := 0; i < 3; i++ {
+		go func() { <-stop }()
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	var found bool
+	for _, group := range ekasys.GroupGoroutines(ekasys.GetGoroutines()) {
+		if group.State == "chan receive" && group.Count() >= 3 &&
+			group.CreatedBy.Function != "" {
+			found = true
+		}
+	}
+	require.True(t, found)
+}
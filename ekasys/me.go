@@ -6,13 +6,44 @@
 package ekasys
 
 import (
+	"bytes"
 	"os"
+	"runtime"
+	"strconv"
 )
 
 var (
 	posixCachedUid = uint32(os.Getuid())
 	posixCachedGid = uint32(os.Getgid())
+	posixCachedPid = os.Getpid()
+
+	cachedHostname, _ = os.Hostname()
 )
 
 func PosixCachedUid() uint32 { return posixCachedUid }
 func PosixCachedGid() uint32 { return posixCachedGid }
+func PosixCachedPid() int    { return posixCachedPid }
+
+// CachedHostname returns the hostname reported by the kernel at the start
+// of the program or an empty string if it could not be obtained.
+func CachedHostname() string { return cachedHostname }
+
+// GoroutineID returns the ID of the goroutine it's called from.
+//
+// There is no legal way to get it in Go, so it's parsed from the header
+// of current goroutine's stack, like "goroutine 42 [running]:".
+// It's kinda slow (about a microsecond), do not use it in hot paths.
+// Returns 0 if ID could not be parsed.
+func GoroutineID() uint64 {
+
+	var buf [64]byte
+	header := buf[:runtime.Stack(buf[:], false)]
+
+	header = bytes.TrimPrefix(header, []byte("goroutine "))
+	if idx := bytes.IndexByte(header, ' '); idx != -1 {
+		header = header[:idx]
+	}
+
+	id, _ := strconv.ParseUint(string(header), 10, 64)
+	return id
+}
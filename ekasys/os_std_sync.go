@@ -10,11 +10,47 @@ import (
 )
 
 type (
+	// IStdSynced is a synchronized writer to the process' standard output
+	// or error stream. It's safe for concurrent use and implements
+	// ekatyp.WriteSyncer.
+	//
+	// Lock() and Unlock() allow to make a multi-write atomic section:
+	// writes from other goroutines are blocked until Unlock() is called,
+	// but writes from the goroutine that holds the lock are not.
+	//
+	// 		stdout := ekasys.Stdout()
+	// 		stdout.Lock()
+	// 		_, _ = stdout.Write(header)
+	// 		_, _ = stdout.Write(body)
+	// 		stdout.Unlock()
+	//
+	// Lock() may be called more than once by the same goroutine,
+	// each call must be followed by Unlock() then.
 	IStdSynced interface {
 		io.Writer
+
+		// Sync commits the written data to the stable storage.
+		// Errors, that are returned for terminals and pipes
+		// (they can not be synced), are ignored.
+		Sync() error
+
+		Lock()
+		Unlock()
+
+		// Fd returns the file descriptor of the stream.
+		Fd() uintptr
+
+		// IsTerminal reports whether the stream is a terminal (TTY).
+		IsTerminal() bool
 	}
 )
 
+// Stdout returns the synchronized writer to the process' standard output.
 func Stdout() IStdSynced {
 	return stdout
 }
+
+// Stderr returns the synchronized writer to the process' standard error stream.
+func Stderr() IStdSynced {
+	return stderr
+}
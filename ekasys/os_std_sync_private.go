@@ -6,29 +6,108 @@
 package ekasys
 
 import (
+	"errors"
 	"os"
 	"sync"
+	"sync/atomic"
+	"syscall"
 )
 
 type (
+	// stdSynced is the IStdSynced implementation.
 	stdSynced struct {
-		f *os.File
-		sync.Mutex
+		f  *os.File
+		mu sync.Mutex
+
+		// owner is the ID of goroutine that holds the lock acquired by Lock(),
+		// 0 if there is no such goroutine. Accessed atomically.
+		owner uint64
+
+		// depth is a number of Lock() calls by the owner.
+		// Accessed only by the owner.
+		depth int
+
+		isTerminal bool // cached IsTerminal() result
 	}
 )
 
 var (
 	stdout *stdSynced
+	stderr *stdSynced
 )
 
+//
 func (ss *stdSynced) Write(b []byte) (n int, err error) {
-	ss.Lock()
+
+	// Goroutine's ID is obtained only if multi-write atomic section is in progress.
+	if owner := atomic.LoadUint64(&ss.owner); owner != 0 && owner == GoroutineID() {
+		return ss.f.Write(b)
+	}
+
+	ss.mu.Lock()
 	n, err = ss.f.Write(b)
-	ss.Unlock()
+	ss.mu.Unlock()
 	return n, err
 }
 
+//
+func (ss *stdSynced) Sync() error {
+
+	ss.Lock()
+	err := ss.f.Sync()
+	ss.Unlock()
+
+	// Terminals, pipes can not be synced.
+	if errors.Is(err, syscall.EINVAL) || errors.Is(err, syscall.ENOTSUP) {
+		return nil
+	}
+	return err
+}
+
+//
+func (ss *stdSynced) Lock() {
+
+	gid := GoroutineID()
+	if atomic.LoadUint64(&ss.owner) == gid {
+		ss.depth++
+		return
+	}
+
+	ss.mu.Lock()
+	atomic.StoreUint64(&ss.owner, gid)
+	ss.depth = 1
+}
+
+//
+func (ss *stdSynced) Unlock() {
+
+	if ss.depth--; ss.depth > 0 {
+		return
+	}
+
+	atomic.StoreUint64(&ss.owner, 0)
+	ss.mu.Unlock()
+}
+
+//
+func (ss *stdSynced) Fd() uintptr {
+	return ss.f.Fd()
+}
+
+//
+func (ss *stdSynced) IsTerminal() bool {
+	return ss.isTerminal
+}
+
+// newStdSynced returns a new stdSynced object for 'f'.
+func newStdSynced(f *os.File) *stdSynced {
+	return &stdSynced{
+		f:          f,
+		isTerminal: IsTerminal(f.Fd()),
+	}
+}
+
 func initStdoutSynced() {
-	stdout = new(stdSynced)
-	stdout.f = os.Stdout
+	stdout = newStdSynced(os.Stdout)
+	stderr = newStdSynced(os.Stderr)
 }
@@ -0,0 +1,100 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekasys_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/qioalice/ekago/v2/ekasys"
+	"github.com/qioalice/ekago/v2/ekatyp"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStdSynced_Lock(t *testing.T) {
+
+	require.Implements(t, (*ekatyp.WriteSyncer)(nil), ekasys.Stderr())
+
+	stdout := ekasys.Stdout()
+	require.Equal(t, os.Stdout.Fd(), stdout.Fd())
+
+	stdout.Lock()
+	stdout.Lock() // reentrant
+	_, err := stdout.Write(nil)
+	require.NoError(t, err)
+	stdout.Unlock()
+
+	written := make(chan struct{})
+	go func() {
+		_, _ = stdout.Write(nil)
+		close(written)
+	}()
+
+	select {
+	case <-written:
+		t.Fatal("Write() from another goroutine is not blocked by Lock()")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	stdout.Unlock()
+	<-written
+
+	require.NoError(t, stdout.Sync())
+}
+
+func TestColorsEnabledFor(t *testing.T) {
+
+	f, err := ioutil.TempFile("", "ekasys")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	require.False(t, ekasys.IsTerminal(f.Fd()))
+	require.False(t, ekasys.IsTerminalWriter(f))
+	require.False(t, ekasys.IsTerminalWriter(new(bytes.Buffer)))
+
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("FORCE_COLOR", "")
+	require.False(t, ekasys.ColorsEnabledFor(f))
+
+	t.Setenv("FORCE_COLOR", "1")
+	require.True(t, ekasys.ColorsEnabledFor(f))
+
+	t.Setenv("FORCE_COLOR", "false")
+	require.False(t, ekasys.ColorsEnabledFor(f))
+
+	t.Setenv("FORCE_COLOR", "1")
+	t.Setenv("NO_COLOR", "1")
+	require.False(t, ekasys.ColorsEnabledFor(f))
+}
+
+func TestColorSupportByEnv(t *testing.T) {
+
+	tests := []struct {
+		term, colorTerm string
+		expected        ekasys.ColorSupport
+	}{
+		{"xterm", "truecolor", ekasys.COLOR_SUPPORT_TRUECOLOR},
+		{"xterm-direct", "", ekasys.COLOR_SUPPORT_TRUECOLOR},
+		{"xterm-256color", "", ekasys.COLOR_SUPPORT_256},
+		{"xterm", "", ekasys.COLOR_SUPPORT_16},
+		{"dumb", "", ekasys.COLOR_SUPPORT_NONE},
+	}
+
+	for _, test := range tests {
+		t.Setenv("TERM", test.term)
+		t.Setenv("COLORTERM", test.colorTerm)
+		require.Equal(t, test.expected, ekasys.ColorSupportByEnv(), test.term)
+	}
+
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("FORCE_COLOR", "3")
+	require.Equal(t, ekasys.COLOR_SUPPORT_TRUECOLOR, ekasys.ColorSupportFor(new(bytes.Buffer)))
+}
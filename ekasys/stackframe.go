@@ -31,6 +31,10 @@ type StackFrame struct {
 
 	// FormatFullPathOffset is the index of "<full_package_path>" in Format field.
 	FormatFullPathOffset int
+
+	// sourceFile is the original (untrimmed) path of the source file
+	// if File has been trimmed by StackFrameFilter. Empty otherwise.
+	sourceFile string
 }
 
 // DoFormat generates formatted string representation of the current stack frame,
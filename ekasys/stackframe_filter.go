@@ -0,0 +1,249 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekasys
+
+import (
+	"go/build"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"unsafe"
+)
+
+// StackFrameFilter is a policy of what StackTrace's frames must be dropped
+// and how remaining ones must be simplified before they are shown.
+//
+// The zero value (or nil) is a valid filter that keeps stacktrace as is.
+// Use builder methods to describe a policy:
+//
+// 		f := new(ekasys.StackFrameFilter).
+// 			DropGoRoot().
+// 			DropVendor().
+// 			CollapseRecursion().
+// 			TrimGOPATH()
+//
+// Filter must not be changed after it's passed to someone (e.g. ekalog).
+type StackFrameFilter struct {
+	dropPackages []string // function name prefixes of frames that must be dropped
+	dropFiles    []string // file path prefixes of frames that must be dropped
+	dropVendor   bool     // drop frames that are in vendor directories
+	collapse     bool     // collapse consecutive frames of the same function
+	trimRoots    []string // file path prefixes that must be trimmed
+}
+
+//noinspection GoSnakeCaseUsage
+const (
+	// STACK_FRAME_FILTER_EKAGO_PACKAGE is a function name prefix of frames
+	// that belongs to ekago's packages. Used by StackFrameFilter.DropEkago().
+	STACK_FRAME_FILTER_EKAGO_PACKAGE = "github.com/qioalice/ekago/"
+)
+
+var (
+	// stackFrameFilterDefault is a StackFrameFilter that is used by those
+	// who have no their own one. Atomic (*StackFrameFilter).
+	stackFrameFilterDefault unsafe.Pointer
+)
+
+// SetDefaultStackFrameFilter sets 'f' as a StackFrameFilter that is applied
+// to all stacktraces that are shown by those, who has not their own filter
+// (e.g. ekalog's CommonIntegrator's outputs w/o WithStackFrameFilter()).
+// Pass nil to disable default filtering.
+func SetDefaultStackFrameFilter(f *StackFrameFilter) {
+	atomic.StorePointer(&stackFrameFilterDefault, unsafe.Pointer(f))
+}
+
+// DefaultStackFrameFilter returns StackFrameFilter that has been set by
+// SetDefaultStackFrameFilter() or nil.
+func DefaultStackFrameFilter() *StackFrameFilter {
+	return (*StackFrameFilter)(atomic.LoadPointer(&stackFrameFilterDefault))
+}
+
+// DropPackages marks that frames which function belongs to the packages
+// with passed prefixes (like "github.com/foo/bar/") must be dropped.
+func (f *StackFrameFilter) DropPackages(prefixes ...string) *StackFrameFilter {
+	if f != nil {
+		for _, prefix := range prefixes {
+			if prefix != "" {
+				f.dropPackages = append(f.dropPackages, prefix)
+			}
+		}
+	}
+	return f
+}
+
+// DropEkago marks that frames of ekago's packages must be dropped.
+func (f *StackFrameFilter) DropEkago() *StackFrameFilter {
+	return f.DropPackages(STACK_FRAME_FILTER_EKAGO_PACKAGE)
+}
+
+// DropGoRoot marks that frames of Go standard library
+// (which files are under GOROOT) must be dropped.
+func (f *StackFrameFilter) DropGoRoot() *StackFrameFilter {
+	if f != nil {
+		if goRoot := runtime.GOROOT(); goRoot != "" {
+			f.dropFiles = append(f.dropFiles, stackFrameFilterDir(goRoot, "src"))
+		}
+	}
+	return f
+}
+
+// DropVendor marks that frames which files are in "vendor" directories
+// must be dropped.
+func (f *StackFrameFilter) DropVendor() *StackFrameFilter {
+	if f != nil {
+		f.dropVendor = true
+	}
+	return f
+}
+
+// CollapseRecursion marks that consecutive frames of the same function
+// (recursive calls) must be collapsed to the one (the deepest) frame.
+func (f *StackFrameFilter) CollapseRecursion() *StackFrameFilter {
+	if f != nil {
+		f.collapse = true
+	}
+	return f
+}
+
+// TrimPaths marks that passed roots (like project's root directory) must be
+// trimmed from the frames' file paths, making them relative.
+func (f *StackFrameFilter) TrimPaths(roots ...string) *StackFrameFilter {
+	if f != nil {
+		for _, root := range roots {
+			if root != "" {
+				f.trimRoots = append(f.trimRoots, stackFrameFilterDir(root))
+			}
+		}
+	}
+	return f
+}
+
+// TrimGOPATH marks that GOPATH's source and module cache directories must be
+// trimmed from the frames' file paths, making them look like import paths
+// ("github.com/foo/bar@v1.0.0/file.go").
+func (f *StackFrameFilter) TrimGOPATH() *StackFrameFilter {
+	if f != nil {
+		for _, goPath := range filepath.SplitList(build.Default.GOPATH) {
+			if goPath != "" {
+				f.trimRoots = append(f.trimRoots,
+					stackFrameFilterDir(goPath, "pkg", "mod"),
+					stackFrameFilterDir(goPath, "src"))
+			}
+		}
+	}
+	return f
+}
+
+// TrimWorkingDir marks that the current working directory must be trimmed
+// from the frames' file paths. Does nothing if it can't be determined.
+func (f *StackFrameFilter) TrimWorkingDir() *StackFrameFilter {
+	if wd, err := os.Getwd(); err == nil {
+		return f.TrimPaths(wd)
+	}
+	return f
+}
+
+// IsNoop reports whether f keeps stacktraces as is. Nil safe.
+func (f *StackFrameFilter) IsNoop() bool {
+	return f == nil || len(f.dropPackages) == 0 && len(f.dropFiles) == 0 &&
+		!f.dropVendor && !f.collapse && len(f.trimRoots) == 0
+}
+
+// Apply returns a new StackTrace that is 's' filtered by f's policy
+// and a mapping of 's' frames' indexes to the indexes in the returned StackTrace
+// (-1 for dropped frames). 's' is not modified.
+//
+// If 'protected' is not nil, it's called for each frame's index and frames
+// it returns true for are never dropped (e.g. frames, messages are bound to).
+//
+// Returns 's' itself and nil mapping if f is noop. Nil safe.
+func (f *StackFrameFilter) Apply(
+
+	s StackTrace,
+	protected func(idx int) bool,
+
+) (StackTrace, []int) {
+
+	if f.IsNoop() || len(s) == 0 {
+		return s, nil
+	}
+
+	var (
+		filtered = make(StackTrace, 0, len(s))
+		mapping  = make([]int, len(s))
+	)
+
+	for i := range s {
+		mapping[i] = -1
+
+		isProtected := protected != nil && protected(i)
+		if !isProtected && f.mustBeDropped(s, i) {
+			continue
+		}
+
+		frame := s[i]
+		if file := f.trim(frame.File); file != frame.File {
+			if frame.sourceFile == "" {
+				frame.sourceFile = frame.File // SourceContext() reads it
+			}
+			frame.File = file
+			frame.Format = "" // must be regenerated
+		}
+
+		mapping[i] = len(filtered)
+		filtered = append(filtered, frame)
+	}
+
+	return filtered, mapping
+}
+
+// mustBeDropped reports whether 's[idx]' frame must be dropped.
+func (f *StackFrameFilter) mustBeDropped(s StackTrace, idx int) bool {
+
+	frame := &s[idx]
+
+	for _, prefix := range f.dropPackages {
+		if strings.HasPrefix(frame.Function, prefix) {
+			return true
+		}
+	}
+
+	file := filepath.ToSlash(frame.File)
+	for _, prefix := range f.dropFiles {
+		if strings.HasPrefix(file, prefix) {
+			return true
+		}
+	}
+
+	if f.dropVendor && strings.Contains(file, "/vendor/") {
+		return true
+	}
+
+	// The deepest frame of the recursive calls is kept (it's the first one),
+	// all next frames of the same function are dropped.
+	return f.collapse && idx > 0 && frame.Function != "" &&
+		s[idx-1].Function == frame.Function
+}
+
+// trim returns 'file' w/o the first matched root f's trim roots.
+func (f *StackFrameFilter) trim(file string) string {
+
+	slashed := filepath.ToSlash(file)
+	for _, root := range f.trimRoots {
+		if strings.HasPrefix(slashed, root) && len(slashed) > len(root) {
+			return slashed[len(root):]
+		}
+	}
+	return file
+}
+
+// stackFrameFilterDir returns a slashed directory path made from 'elems'
+// that ends with slash.
+func stackFrameFilterDir(elems ...string) string {
+	return strings.TrimSuffix(filepath.ToSlash(filepath.Join(elems...)), "/") + "/"
+}
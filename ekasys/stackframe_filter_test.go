@@ -0,0 +1,62 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekasys_test
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/qioalice/ekago/v2/ekasys"
+
+	"github.com/stretchr/testify/require"
+)
+
+func filterTestFrame(function, file string) ekasys.StackFrame {
+	return ekasys.StackFrame{Frame: runtime.Frame{Function: function, File: file, Line: 1}}
+}
+
+func TestStackFrameFilter_Apply(t *testing.T) {
+
+	s := ekasys.StackTrace{
+		filterTestFrame("main.recursive", "/home/user/project/main.go"),
+		filterTestFrame("main.recursive", "/home/user/project/main.go"),
+		filterTestFrame("main.recursive", "/home/user/project/main.go"),
+		filterTestFrame("github.com/foo/bar.Do", "/home/user/project/vendor/github.com/foo/bar/bar.go"),
+		filterTestFrame("github.com/qioalice/ekago/v2/ekaerr.(*Error).Throw", "/go/pkg/mod/ekaerr/error.go"),
+		filterTestFrame("main.main", "/home/user/project/main.go"),
+	}
+
+	filter := new(ekasys.StackFrameFilter).
+		DropEkago().
+		DropVendor().
+		CollapseRecursion().
+		TrimPaths("/home/user/project")
+
+	filtered, mapping := filter.Apply(s, func(idx int) bool { return idx == 2 })
+
+	require.Equal(t, []int{0, -1, 1, -1, -1, 2}, mapping)
+	require.Len(t, filtered, 3)
+	require.Equal(t, "main.go", filtered[0].File)
+	require.Equal(t, "main.main", filtered[2].Function)
+
+	// source stacktrace is untouched
+	require.Equal(t, "/home/user/project/main.go", s[0].File)
+
+	filtered, mapping = (*ekasys.StackFrameFilter)(nil).Apply(s, nil)
+	require.Nil(t, mapping)
+	require.Len(t, filtered, len(s))
+}
+
+func TestStackFrameFilter_DropGoRoot(t *testing.T) {
+
+	s := ekasys.GetStackTrace(0, -1)
+	filtered, _ := new(ekasys.StackFrameFilter).DropGoRoot().Apply(s, nil)
+
+	require.NotEmpty(t, filtered)
+	for _, frame := range filtered {
+		require.NotContains(t, frame.Function, "testing.")
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekasys
+
+import (
+	"bytes"
+	"container/list"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// SourceLine is an one line of source code, StackFrame points to
+// or that is around it. See StackFrame.SourceContext() for more info.
+type SourceLine struct {
+	Line      int    // line number, starting from 1
+	Text      string // line's text w/o trailing line break
+	IsCurrent bool   // true if it's the line StackFrame points to
+}
+
+//noinspection GoSnakeCaseUsage
+const (
+	// SOURCE_FILE_MAX_SIZE is a max size of source file (in bytes)
+	// that can be loaded by StackFrame.SourceContext().
+	// Bigger files are treated as unavailable.
+	SOURCE_FILE_MAX_SIZE = 4 << 20
+
+	// SOURCE_CACHE_MAX_SIZE is a max total size of source files (in bytes)
+	// that are cached by StackFrame.SourceContext().
+	// Least recently used files are evicted from the cache when it's exceeded.
+	SOURCE_CACHE_MAX_SIZE = 32 << 20
+)
+
+type (
+	// cachedSourceFile is a source file's lines that has been loaded
+	// by StackFrame.SourceContext(). Nil lines means that file is unavailable.
+	cachedSourceFile struct {
+		path  string
+		lines []string
+		size  int // approximate memory usage
+	}
+)
+
+var (
+	// cachedSourceFiles is a cache of source files, the values are
+	// cachedSourceFilesLRU's elements. Most recently used files are at the front.
+	cachedSourceFiles     = make(map[string]*list.Element)
+	cachedSourceFilesLRU  = list.New()
+	cachedSourceFilesSize = 0
+	cachedSourceFilesMu   sync.Mutex
+)
+
+// SourceContext returns the line of source code the current stack frame
+// points to and up to 'around' lines before and after it.
+//
+// Source file is loaded only if it exists locally (file is read
+// by the StackFrame.File path, or by the original one if it's been trimmed
+// by StackFrameFilter). Loaded files are cached (see SOURCE_CACHE_MAX_SIZE).
+// Returns nil if source file is unavailable or 'around' < 0.
+func (f *StackFrame) SourceContext(around int) []SourceLine {
+
+	path := f.sourceFile
+	if path == "" {
+		path = f.File
+	}
+
+	if around < 0 || f.Line <= 0 || path == "" {
+		return nil
+	}
+
+	lines := getSourceFile(path)
+	if f.Line > len(lines) {
+		return nil
+	}
+
+	from, to := f.Line-around, f.Line+around
+	if from < 1 {
+		from = 1
+	}
+	if to > len(lines) {
+		to = len(lines)
+	}
+
+	context := make([]SourceLine, 0, to-from+1)
+	for i := from; i <= to; i++ {
+		context = append(context, SourceLine{
+			Line:      i,
+			Text:      lines[i-1],
+			IsCurrent: i == f.Line,
+		})
+	}
+
+	return context
+}
+
+// getSourceFile returns lines of source file by 'path' using cache,
+// loading file if it's not loaded yet. Returns nil if file is unavailable.
+func getSourceFile(path string) []string {
+
+	cachedSourceFilesMu.Lock()
+	elem, found := cachedSourceFiles[path]
+	if found {
+		cachedSourceFilesLRU.MoveToFront(elem)
+	}
+	cachedSourceFilesMu.Unlock()
+
+	if found {
+		return elem.Value.(*cachedSourceFile).lines
+	}
+
+	file := &cachedSourceFile{path: path, lines: loadSourceFile(path)}
+	file.size = len(path)
+	for _, line := range file.lines {
+		file.size += len(line) + 16 // + string header
+	}
+
+	cachedSourceFilesMu.Lock()
+	defer cachedSourceFilesMu.Unlock()
+
+	// The file might be loaded by another goroutine meanwhile.
+	if elem, found = cachedSourceFiles[path]; found {
+		cachedSourceFilesLRU.MoveToFront(elem)
+		return elem.Value.(*cachedSourceFile).lines
+	}
+
+	cachedSourceFiles[path] = cachedSourceFilesLRU.PushFront(file)
+	cachedSourceFilesSize += file.size
+
+	// The just loaded file is kept anyway.
+	for cachedSourceFilesSize > SOURCE_CACHE_MAX_SIZE && cachedSourceFilesLRU.Len() > 1 {
+		evicted := cachedSourceFilesLRU.Remove(cachedSourceFilesLRU.Back()).(*cachedSourceFile)
+		delete(cachedSourceFiles, evicted.path)
+		cachedSourceFilesSize -= evicted.size
+	}
+
+	return file.lines
+}
+
+// loadSourceFile reads source file by 'path' and splits it to the lines.
+// Returns nil if file does not exist, is not a regular file or is too big.
+func loadSourceFile(path string) []string {
+
+	stat, err := os.Stat(path)
+	if err != nil || !stat.Mode().IsRegular() || stat.Size() > SOURCE_FILE_MAX_SIZE {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	rawLines := bytes.Split(data, []byte{'\n'})
+	lines := make([]string, len(rawLines))
+
+	for i, rawLine := range rawLines {
+		lines[i] = string(bytes.TrimSuffix(rawLine, []byte{'\r'}))
+	}
+
+	return lines
+}
@@ -0,0 +1,59 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekasys
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackFrame_SourceContextTrimmed(t *testing.T) {
+
+	s := GetStackTrace(0, 1)
+	require.Len(t, s, 1)
+
+	// Trimmed path is relative and does not exist from the working directory.
+	filter := new(StackFrameFilter).TrimPaths(filepath.Dir(filepath.Dir(s[0].File)))
+	filtered, _ := filter.Apply(s, nil)
+
+	require.Equal(t, "ekasys/stackframe_source_test.go", filtered[0].File)
+
+	lines := filtered[0].SourceContext(0)
+	require.Len(t, lines, 1)
+	require.Contains(t, lines[0].Text, "GetStackTrace(0, 1)")
+}
+
+func TestStackFrame_SourceContextCacheLimit(t *testing.T) {
+
+	var (
+		dir   = t.TempDir()
+		line  = strings.Repeat("x", 999) + "\n"
+		data  = []byte(strings.Repeat(line, SOURCE_FILE_MAX_SIZE/len(line)-1))
+		paths []string
+	)
+
+	for i := 0; i*len(data) <= SOURCE_CACHE_MAX_SIZE; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("%d.go", i))
+		require.NoError(t, ioutil.WriteFile(path, data, 0644))
+		paths = append(paths, path)
+
+		frame := StackFrame{sourceFile: path}
+		frame.Line = 1
+		require.Len(t, frame.SourceContext(0), 1)
+	}
+
+	cachedSourceFilesMu.Lock()
+	defer cachedSourceFilesMu.Unlock()
+
+	require.LessOrEqual(t, cachedSourceFilesSize, SOURCE_CACHE_MAX_SIZE)
+	require.NotContains(t, cachedSourceFiles, paths[0])
+	require.Contains(t, cachedSourceFiles, paths[len(paths)-1])
+}
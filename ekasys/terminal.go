@@ -0,0 +1,152 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekasys
+
+import (
+	"io"
+	"os"
+	"strings"
+)
+
+// ColorSupport describes what colors are supported by the terminal
+// (or by some io.Writer). See COLOR_SUPPORT_... constants.
+// Greater value means more colors.
+type ColorSupport uint8
+
+//noinspection GoSnakeCaseUsage
+const (
+	// COLOR_SUPPORT_NONE means that colors (ANSI escape sequences)
+	// must not be written at all.
+	COLOR_SUPPORT_NONE ColorSupport = iota
+
+	// COLOR_SUPPORT_16 means that only 16 basic colors are supported
+	// (SGR 30-37, 40-47, 90-97, 100-107).
+	COLOR_SUPPORT_16
+
+	// COLOR_SUPPORT_256 means that xterm 256 colors are supported
+	// (SGR 38;5;<n>, 48;5;<n>).
+	COLOR_SUPPORT_256
+
+	// COLOR_SUPPORT_TRUECOLOR means that 24-bit RGB colors are supported
+	// (SGR 38;2;<r>;<g>;<b>, 48;2;<r>;<g>;<b>).
+	COLOR_SUPPORT_TRUECOLOR
+)
+
+// String returns the name of color support level.
+func (cs ColorSupport) String() string {
+	switch cs {
+	case COLOR_SUPPORT_NONE:      return "none"
+	case COLOR_SUPPORT_16:        return "16 colors"
+	case COLOR_SUPPORT_256:       return "256 colors"
+	case COLOR_SUPPORT_TRUECOLOR: return "truecolor"
+	default:                      return "unknown"
+	}
+}
+
+// IsTerminal reports whether file descriptor 'fd' refers to a terminal (TTY).
+// Always returns false on the platforms, there is no way to check it.
+func IsTerminal(fd uintptr) bool {
+	return isTerminal(fd)
+}
+
+// ColorsEnabledFor reports whether colored output (ANSI escape sequences)
+// should be written to 'w' at all. It's the same as
+// ColorSupportFor(w) != COLOR_SUPPORT_NONE.
+func ColorsEnabledFor(w io.Writer) bool {
+	return ColorSupportFor(w) != COLOR_SUPPORT_NONE
+}
+
+// ColorSupportFor returns what colors are supported by 'w'.
+// The rules are (first matched wins):
+//
+//   - NO_COLOR env var is set and not empty: COLOR_SUPPORT_NONE
+//     (https://no-color.org),
+//   - FORCE_COLOR env var is set and not empty: COLOR_SUPPORT_NONE
+//     if it's "0" or "false", COLOR_SUPPORT_256 if it's "2",
+//     COLOR_SUPPORT_TRUECOLOR if it's "3", the detected by TERM, COLORTERM
+//     (but at least COLOR_SUPPORT_16) otherwise,
+//   - 'w' is not a terminal (it must have Fd() uintptr method, like *os.File
+//     or IStdSynced, to be checked) or TERM env var is "dumb": COLOR_SUPPORT_NONE,
+//   - detected by TERM, COLORTERM env vars otherwise (see ColorSupportByEnv()).
+func ColorSupportFor(w io.Writer) ColorSupport {
+
+	if os.Getenv("NO_COLOR") != "" {
+		return COLOR_SUPPORT_NONE
+	}
+
+	switch force := os.Getenv("FORCE_COLOR"); {
+
+	case force == "":
+		// not forced, check below
+
+	case force == "0" || strings.EqualFold(force, "false"):
+		return COLOR_SUPPORT_NONE
+
+	case force == "2":
+		return COLOR_SUPPORT_256
+
+	case force == "3":
+		return COLOR_SUPPORT_TRUECOLOR
+
+	default:
+		if support := ColorSupportByEnv(); support > COLOR_SUPPORT_16 {
+			return support
+		}
+		return COLOR_SUPPORT_16
+	}
+
+	if os.Getenv("TERM") == "dumb" || !IsTerminalWriter(w) {
+		return COLOR_SUPPORT_NONE
+	}
+
+	return ColorSupportByEnv()
+}
+
+// ColorSupportByEnv returns what colors are supported by the terminal
+// according with TERM, COLORTERM env vars:
+//
+//   - COLORTERM is "truecolor" or "24bit", or TERM ends with "-direct"
+//     or "-truecolor": COLOR_SUPPORT_TRUECOLOR,
+//   - TERM contains "256color": COLOR_SUPPORT_256,
+//   - TERM is "dumb": COLOR_SUPPORT_NONE,
+//   - COLOR_SUPPORT_16 otherwise.
+//
+// It does not check whether the output is a terminal.
+func ColorSupportByEnv() ColorSupport {
+
+	term := strings.ToLower(os.Getenv("TERM"))
+
+	switch colorTerm := strings.ToLower(os.Getenv("COLORTERM")); {
+
+	case colorTerm == "truecolor" || colorTerm == "24bit",
+		strings.HasSuffix(term, "-direct"), strings.HasSuffix(term, "-truecolor"):
+		return COLOR_SUPPORT_TRUECOLOR
+
+	case strings.Contains(term, "256color"):
+		return COLOR_SUPPORT_256
+
+	case term == "dumb":
+		return COLOR_SUPPORT_NONE
+
+	default:
+		return COLOR_SUPPORT_16
+	}
+}
+
+// IsTerminalWriter reports whether 'w' is a terminal (TTY).
+// 'w' must have Fd() uintptr method (like *os.File or IStdSynced)
+// to be checked, false is returned otherwise.
+func IsTerminalWriter(w io.Writer) bool {
+
+	switch typed := w.(type) {
+	case IStdSynced:
+		return typed.IsTerminal()
+	case interface{ Fd() uintptr }:
+		return IsTerminal(typed.Fd())
+	default:
+		return false
+	}
+}
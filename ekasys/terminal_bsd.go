@@ -0,0 +1,25 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+//go:build darwin || freebsd || netbsd || openbsd || dragonfly
+// +build darwin freebsd netbsd openbsd dragonfly
+
+package ekasys
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// isTerminal reports whether 'fd' is a terminal,
+// requesting its attributes by ioctl(TIOCGETA).
+func isTerminal(fd uintptr) bool {
+
+	var termios syscall.Termios
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL,
+		fd, syscall.TIOCGETA, uintptr(unsafe.Pointer(&termios)))
+
+	return errno == 0
+}
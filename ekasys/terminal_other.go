@@ -0,0 +1,15 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly
+
+package ekasys
+
+// isTerminal always returns false, because there is no way to check
+// whether 'fd' is a terminal on the current platform.
+func isTerminal(_ uintptr) bool {
+	return false
+}
@@ -6,6 +6,7 @@
 package ekatime
 
 import (
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -61,10 +62,10 @@ type (
 
 		today unsafe.Pointer // *Today object, protected by atomic operations
 
-		newDayTimer *time.Timer // timer that fires when new day's midnight has come
+		newDayTimer ClockTimer // timer that fires when new day's midnight has come
 		// timer that fires after N seconds since new event has been added or
 		// and old one has been removed
-		logPendingEventsChangedTimer *time.Timer
+		logPendingEventsChangedTimer ClockTimer
 
 		mu sync.Mutex // all fields named "pending..." protector
 		wg sync.WaitGroup // graceful shutdown for user's callback and ekadeath
@@ -72,6 +73,9 @@ type (
 		isStarted bool      // flag: RunAsync() must be called only once
 		disableLogging bool // flag: whether logging must be disabled
 
+		loc *time.Location // time zone "today" is determined in, see SetLocation()
+		clock Clock // source of the current time and timers, see SetClock()
+
 		// All next fields (except counters) has 2 variants: pending and confirmed.
 		// By default, when any Calendar's setter is called, the pending related
 		// field is changed.
@@ -88,6 +92,23 @@ type (
 		confirmedEvents []Event // user defined events that may change month counters
 		pendingEvents []Event
 
+		// user defined recurring events, expanded when they're used
+		confirmedRecurringEvents []RecurringEvent
+		pendingRecurringEvents []RecurringEvent
+
+		// user defined callbacks when recurring date has come
+		confirmedRecurringCallbacks []calRecurringCallback
+		pendingRecurringCallbacks []calRecurringCallback
+
+		// Weekend that is used with events. 0 means WEEKEND_DEFAULT,
+		// otherwise it's stored with _WEEKEND_IS_SET flag.
+		confirmedWeekend Weekend
+		pendingWeekend Weekend
+
+		// confirmedWorkdays is a business day arithmetic based on
+		// confirmed events and weekend. Rebuilt when pending changes are confirmed.
+		confirmedWorkdays *Workdays
+
 		confirmedTodayEncoderJson TodayEncoder
 		pendingTodayEncoderJson TodayEncoder
 
@@ -141,6 +162,85 @@ func (c *Calendar) DisableLogging() *Calendar {
 	return c
 }
 
+// SetLocation sets the time zone the "today" is determined in,
+// meaning that a new day comes at the midnight of that location
+// (callback registered by WhenNewDay() is called at the local midnight).
+// DST is respected. Nil 'loc' means UTC (the default).
+// Nil safe. Thread-safety.
+//
+// DOES NOTHING IF CALENDAR ALREADY RUNNING.
+// CALL THIS METHOD BEFORE RunAsync() IS CALLED!
+func (c *Calendar) SetLocation(loc *time.Location) *Calendar {
+
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.isStarted {
+		c.loc = loc
+	}
+
+	return c
+}
+
+// SetClock sets the Clock the current time and timers are taken from.
+// Nil 'clock' means DefaultClock() (the default).
+// Use FakeClock to test new day's callbacks.
+// Nil safe. Thread-safety.
+//
+// DOES NOTHING IF CALENDAR ALREADY RUNNING.
+// CALL THIS METHOD BEFORE RunAsync() IS CALLED!
+func (c *Calendar) SetClock(clock Clock) *Calendar {
+
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.isStarted {
+		c.clock = clock
+	}
+
+	return c
+}
+
+// Location returns the time zone the "today" is determined in
+// (that is set by SetLocation()). Never returns nil, UTC is used by default.
+// Nil safe (returns UTC). Thread-safety.
+func (c *Calendar) Location() *time.Location {
+
+	if c == nil {
+		return time.UTC
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return normalizeLocation(c.loc)
+}
+
+// SetWeekend sets days of week that are day offs if there is no Event for them.
+// WEEKEND_DEFAULT (Saturday, Sunday) is used by default.
+// The Weekend will be applied when new day will come.
+// Nil safe. Thread-safety.
+func (c *Calendar) SetWeekend(weekend Weekend) *Calendar {
+
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pendingWeekend = weekend & _WEEKEND_MASK | _WEEKEND_IS_SET
+	return c
+}
+
 // EventAdd adds a new event to the Calendar that will be applied when new day will come.
 // If the same day event already exists, does nothing (remove it before).
 // Nil safe. Thread-safety.
@@ -237,6 +337,96 @@ func (c *Calendar) EventWalk(eventWalker func(i int, event Event)) *Calendar {
 	return c
 }
 
+// RecurringEventAdd adds a new recurring event to the Calendar that will be applied
+// when new day will come. Recurring events are expanded to the Event s
+// when they are used (see EventsBetween()). Event added by EventAdd()
+// has a priority over the recurring one of the same day.
+// Does nothing if 're' has no RRule.
+// Nil safe. Thread-safety.
+func (c *Calendar) RecurringEventAdd(re RecurringEvent) *Calendar {
+
+	if c == nil || re.Rule == nil {
+		return c
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pendingRecurringEvents = append(c.pendingRecurringEvents, re)
+	return c
+}
+
+// RecurringEventRemoveAll removes all pending recurring events from the Calendar
+// when new day will come. Nil safe. Thread-safety.
+func (c *Calendar) RecurringEventRemoveAll() *Calendar {
+
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pendingRecurringEvents = nil
+	return c
+}
+
+// EventsBetween returns all pending (as EventWalk() does) Event s
+// and recurring events expanded to the Event s that are in [from, to] range,
+// in ascending order. Use it to get events for any requested year or month:
+//
+// 		c.EventsBetween(ekatime.NewDate(2021, 1, 1), ekatime.NewDate(2021, 12, 31))
+//
+// Nil safe. Thread-safety.
+func (c *Calendar) EventsBetween(from, to Date) []Event {
+
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	events := c.eventsBetween(from, to, c.pendingEvents, c.pendingRecurringEvents)
+
+	var (
+		fromCmp, toCmp = from.ToCmp(), to.ToCmp()
+		filtered       = make([]Event, 0, len(events))
+	)
+
+	for _, event := range events {
+		if date := event.Date().ToCmp(); fromCmp <= date && date <= toCmp {
+			filtered = append(filtered, event)
+		}
+	}
+
+	sort.Slice(filtered, func(i, j int) bool {
+		return filtered[i].Date().ToCmp() < filtered[j].Date().ToCmp()
+	})
+
+	return filtered
+}
+
+// WhenRecurs registers the 'cb' as callback that will be called when new day
+// has come and 'rule' recurs on that day, like WhenNewDay() does.
+// Many callbacks may be registered. Does nothing if 'rule' or 'cb' is nil.
+// Nil safe. Thread-safety.
+//
+// The same guarantees and recommendations as WhenNewDay() has, are applied.
+func (c *Calendar) WhenRecurs(rule *RRule, cb func(*Today)) *Calendar {
+
+	if c == nil || rule == nil || cb == nil {
+		return c
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pendingRecurringCallbacks = append(c.pendingRecurringCallbacks,
+		calRecurringCallback{rule: rule, cb: cb})
+	return c
+}
+
 // WhenNewDay registers the 'cb' as callback that will be called when new day has come.
 // Keep in mind, you must call RunAsync() method to start the internal goroutine of Calendar.
 // Does nothing if RunAsync() has been called before.
@@ -255,7 +445,7 @@ func (c *Calendar) WhenNewDay(cb func(*Today)) *Calendar {
 	defer c.mu.Unlock()
 
 	c.pendingNewDayCallback = cb // just overwrite
-	return nil
+	return c
 }
 
 // RegJsonEncoder registers 'encoder' as a new JSON *Today's object encoder,
@@ -333,9 +523,14 @@ func (c *Calendar) RunAsync() {
 		return
 	}
 
+	c.isStarted = true
+
+	// Calendar keeps using the same Clock even if DefaultClock() is changed.
+	c.clock = c.getClock()
+
 	if !c.disableLogging {
 		c.logPendingEventsChangedTimer =
-			time.AfterFunc(_CAL_PENDING_LOG_STAT_DELAY, c.deferredLoggingOfPendingStat)
+			c.getClock().AfterFunc(_CAL_PENDING_LOG_STAT_DELAY, c.deferredLoggingOfPendingStat)
 		c.logPendingEventsChangedTimer.Stop()
 	}
 
@@ -349,7 +544,7 @@ func (c *Calendar) RunAsync() {
 	c.pendingEventsRemoveTotalCounter = 0
 
 	ekadeath.Reg(c.destructor)
-	c.newDayTimer = time.AfterFunc(TillNextMidnight(), c.newDayHasCome)
+	c.newDayTimer = c.getClock().AfterFunc(c.now().TillNextMidnightIn(c.loc), c.newDayHasCome)
 }
 
 // WorkdaysFor normalizes the passed dd Date, splitting it to the Year, Month, Day
@@ -392,10 +587,70 @@ func (c *Calendar) WorkdaysFor(dd Date, d1 Day) (current, total Day) {
 		return -1, -1
 	}
 
+	y, m, _ := normalizeDate(dd.Split())
+
 	c.mu.Lock()
-	confirmedEvents := append(c.confirmedEvents[:0:0], c.confirmedEvents...)
+	confirmedEvents := c.eventsBetween(
+		NewDate(y, m, 1), NewDate(y, m, DaysInMonth(y, m)),
+		append(c.confirmedEvents[:0:0], c.confirmedEvents...), c.confirmedRecurringEvents,
+	)
+	weekend := c.confirmedWorkdays.Weekend()
 	c.mu.Unlock()
 
-	current, total, _ = workdaysFor(dd, d1, confirmedEvents, nil)
+	current, total, _ = workdaysFor(dd, d1, confirmedEvents, weekend, nil)
 	return current, total
 }
+
+// Workdays returns a business day arithmetic object based on confirmed
+// (!!!, not pending) events and weekend of the current Calendar.
+// Returned object is immutable and can be used even after the next day comes.
+//
+// Returns nil (that is valid Workdays w/o events and with WEEKEND_DEFAULT)
+// if RunAsync() has not been called yet.
+// Use NewWorkdays() if you need business day arithmetic w/o running Calendar.
+// Nil safe. Thread-safety.
+func (c *Calendar) Workdays() *Workdays {
+
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.confirmedWorkdays
+}
+
+// IsWorkday reports whether 'dd' is workday according with confirmed events
+// and weekend. See Workdays.IsWorkday() for more info. Nil safe. Thread-safety.
+func (c *Calendar) IsWorkday(dd Date) bool {
+	return c.Workdays().IsWorkday(dd)
+}
+
+// NextWorkday returns the nearest workday that is after 'dd' according with
+// confirmed events and weekend. See Workdays.NextWorkday() for more info.
+// Nil safe. Thread-safety.
+func (c *Calendar) NextWorkday(dd Date) Date {
+	return c.Workdays().NextWorkday(dd)
+}
+
+// PrevWorkday returns the nearest workday that is before 'dd' according with
+// confirmed events and weekend. See Workdays.PrevWorkday() for more info.
+// Nil safe. Thread-safety.
+func (c *Calendar) PrevWorkday(dd Date) Date {
+	return c.Workdays().PrevWorkday(dd)
+}
+
+// AddWorkdays returns a Date that is 'n' workdays after 'dd' (or before if 'n' < 0)
+// according with confirmed events and weekend.
+// See Workdays.AddWorkdays() for more info. Nil safe. Thread-safety.
+func (c *Calendar) AddWorkdays(dd Date, n int) Date {
+	return c.Workdays().AddWorkdays(dd, n)
+}
+
+// WorkdaysBetween returns the number of workdays in (d1, d2] according with
+// confirmed events and weekend. See Workdays.WorkdaysBetween() for more info.
+// Nil safe. Thread-safety.
+func (c *Calendar) WorkdaysBetween(d1, d2 Date) int {
+	return c.Workdays().WorkdaysBetween(d1, d2)
+}
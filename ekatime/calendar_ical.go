@@ -0,0 +1,170 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekatime
+
+import (
+	"io"
+	"time"
+
+	"github.com/qioalice/ekago/v2/ekaerr"
+)
+
+type (
+	// ICalCategory is what Event a VEVENT of iCalendar (RFC 5545) becomes,
+	// when it's imported. See ICalMapping for more info.
+	ICalCategory struct {
+		ID       uint16 // Event's ID
+		IsDayOff bool   // true if Event is day off, false if it's workday
+	}
+
+	// ICalMapping describes how VEVENTs of iCalendar (RFC 5545) are converted
+	// to Event s and back. Used by ParseICal(), WriteICal() and corresponding
+	// Calendar's methods.
+	//
+	// Nil ICalMapping means that all VEVENTs are day offs with 0 ID.
+	ICalMapping struct {
+
+		// Categories maps VEVENT's category (a value of CATEGORIES property)
+		// to the Event's ID and day off flag. Keys are case-insensitive.
+		// The first VEVENT's category that is presented in the map is used.
+		//
+		// When Event s are exported, the category is the key of the map
+		// which value has the same ID and day off flag as Event has.
+		Categories map[string]ICalCategory
+
+		// Default is used for VEVENTs w/o any category from Categories.
+		// If it's nil, such VEVENTs are skipped.
+		Default *ICalCategory
+
+		// Location is a time zone VEVENTs' days are determined in,
+		// if they are not all-day events. Also used for "floating" date-time
+		// (w/o UTC suffix and TZID). Nil means UTC.
+		Location *time.Location
+	}
+)
+
+//noinspection GoSnakeCaseUsage
+const (
+	// ICAL_EVENT_MAX_DAYS is a max number of days the one VEVENT may last.
+	// ParseICal() returns an error for longer VEVENTs.
+	ICAL_EVENT_MAX_DAYS = 366
+
+	// ICAL_PRODID is a PRODID property WriteICal() writes to the VCALENDAR.
+	ICAL_PRODID = "-//qioalice//ekago ekatime//EN"
+)
+
+// ParseICal reads iCalendar (RFC 5545) stream from 'r' and returns Event s
+// its VEVENTs are converted to by 'mapping'.
+//
+// Both of all-day VEVENTs (DTSTART;VALUE=DATE) and date-time ones are supported,
+// as well as DTEND and DURATION properties. Multi-day VEVENTs are expanded
+// into per-day Event s (DTEND is exclusive). Cancelled VEVENTs are skipped.
+// Recurring VEVENTs (with RRULE) are skipped too,
+// use ParseICalWithRecurring() to get them.
+//
+// Returns an error if stream can't be read or it's malformed.
+func ParseICal(r io.Reader, mapping *ICalMapping) ([]Event, *ekaerr.Error) {
+
+	events, _, err := ParseICalWithRecurring(r, mapping)
+	if err.IsNotNil() {
+		return nil, err.Throw()
+	}
+
+	return events, nil
+}
+
+// ParseICalWithRecurring is the same as ParseICal() but also returns
+// recurring VEVENTs (with RRULE) as RecurringEvent s.
+// Only the first day of multi-day recurring VEVENT is used.
+// See RRule for supported RRULE parts.
+func ParseICalWithRecurring(
+	r io.Reader,
+	mapping *ICalMapping,
+) ([]Event, []RecurringEvent, *ekaerr.Error) {
+
+	lines, err := icalReadLines(r)
+	if err.IsNotNil() {
+		return nil, nil, err.Throw()
+	}
+
+	return icalParse(lines, mapping)
+}
+
+// WriteICal writes 'events' as iCalendar (RFC 5545) VCALENDAR to 'w'
+// converting them to the all-day VEVENTs by 'mapping'.
+//
+// Event s with the same ID and day off flag of consecutive days
+// are merged to the one multi-day VEVENT.
+//
+// Returns an error if 'w' returns it.
+func WriteICal(w io.Writer, events []Event, mapping *ICalMapping) *ekaerr.Error {
+	return WriteICalWithRecurring(w, events, nil, mapping)
+}
+
+// WriteICalWithRecurring is the same as WriteICal() but also writes
+// 'recurring' events as all-day VEVENTs with RRULE.
+func WriteICalWithRecurring(
+	w io.Writer,
+	events []Event,
+	recurring []RecurringEvent,
+	mapping *ICalMapping,
+) *ekaerr.Error {
+
+	encoded := icalEncode(events, recurring, mapping, Now())
+	if _, legacyErr := io.WriteString(w, encoded); legacyErr != nil {
+		return ekaerr.ExternalError.
+			Wrap(legacyErr, "ekatime: failed to write iCalendar").
+			Throw()
+	}
+
+	return nil
+}
+
+// EventsImportICal reads iCalendar (RFC 5545) stream from 'r' and adds
+// all its VEVENTs as Event s to the Calendar using EventAdd()
+// and recurring VEVENTs using RecurringEventAdd().
+// See ParseICalWithRecurring() for more info.
+//
+// Nothing is added if error is returned.
+// Nil safe. Thread-safety.
+func (c *Calendar) EventsImportICal(r io.Reader, mapping *ICalMapping) *ekaerr.Error {
+
+	if c == nil {
+		return nil
+	}
+
+	events, recurring, err := ParseICalWithRecurring(r, mapping)
+	if err.IsNotNil() {
+		return err.Throw()
+	}
+
+	for _, event := range events {
+		c.EventAdd(event)
+	}
+	for _, re := range recurring {
+		c.RecurringEventAdd(re)
+	}
+
+	return nil
+}
+
+// EventsExportICal writes all pending Event s and recurring events of Calendar
+// (see EventWalk()) to 'w' as iCalendar (RFC 5545) VCALENDAR.
+// See WriteICalWithRecurring() for more info.
+// Nil safe. Thread-safety.
+func (c *Calendar) EventsExportICal(w io.Writer, mapping *ICalMapping) *ekaerr.Error {
+
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	events := append(c.pendingEvents[:0:0], c.pendingEvents...)
+	recurring := append(c.pendingRecurringEvents[:0:0], c.pendingRecurringEvents...)
+	c.mu.Unlock()
+
+	return WriteICalWithRecurring(w, events, recurring, mapping)
+}
@@ -0,0 +1,599 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekatime
+
+import (
+	"bufio"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/qioalice/ekago/v2/ekaerr"
+)
+
+type (
+	// icalLine is an one unfolded content line of iCalendar stream,
+	// like "DTSTART;VALUE=DATE:20200101".
+	icalLine struct {
+		num    int               // line's number in the stream (for errors)
+		name   string            // property's name in upper case
+		params map[string]string // property's params, names are in upper case
+		value  string            // raw property's value
+	}
+
+	// icalEvent is a VEVENT's properties ParseICal() is interested in.
+	icalEvent struct {
+		line       int // line's number of BEGIN:VEVENT
+		start, end *icalLine
+		duration   *icalLine
+		rrule      *icalLine
+		categories []string
+		isCanceled bool
+	}
+)
+
+//noinspection GoSnakeCaseUsage
+const (
+	_ICAL_DATE_LAYOUT      = "20060102"
+	_ICAL_DATE_TIME_LAYOUT = "20060102T150405"
+
+	// Max len of content line in octets (w/o line break).
+	_ICAL_LINE_MAX_LEN = 75
+)
+
+// icalReadLines reads iCalendar stream from 'r' and returns its unfolded
+// and parsed content lines. Empty lines are skipped.
+func icalReadLines(r io.Reader) ([]icalLine, *ekaerr.Error) {
+
+	var (
+		lines   []icalLine
+		raw     strings.Builder
+		rawNum  int
+		scanner = bufio.NewScanner(r)
+		num     int
+	)
+
+	scanner.Buffer(nil, 1<<20)
+
+	flush := func() *ekaerr.Error {
+		if raw.Len() == 0 {
+			return nil
+		}
+		line, err := icalParseLine(raw.String(), rawNum)
+		if err.IsNotNil() {
+			return err.Throw()
+		}
+		lines = append(lines, line)
+		raw.Reset()
+		return nil
+	}
+
+	for scanner.Scan() {
+		num++
+		text := strings.TrimSuffix(scanner.Text(), "\r")
+
+		// Folded line is continued by the line that starts with space or tab.
+		if text != "" && (text[0] == ' ' || text[0] == '\t') && raw.Len() > 0 {
+			raw.WriteString(text[1:])
+			continue
+		}
+
+		if err := flush(); err.IsNotNil() {
+			return nil, err.Throw()
+		}
+		raw.WriteString(text)
+		rawNum = num
+	}
+
+	if legacyErr := scanner.Err(); legacyErr != nil {
+		return nil, ekaerr.DataUnavailable.
+			Wrap(legacyErr, "ekatime: failed to read iCalendar").
+			Throw()
+	}
+
+	if err := flush(); err.IsNotNil() {
+		return nil, err.Throw()
+	}
+
+	return lines, nil
+}
+
+// icalParseLine parses unfolded content line 's'.
+func icalParseLine(s string, num int) (icalLine, *ekaerr.Error) {
+
+	line := icalLine{num: num}
+
+	i := strings.IndexAny(s, ";:")
+	if i <= 0 {
+		return line, ekaerr.IllegalFormat.
+			New("ekatime: malformed iCalendar content line", "line", num).
+			Throw()
+	}
+	line.name = strings.ToUpper(s[:i])
+
+	// Params: ;NAME=VALUE[,VALUE] where value may be quoted.
+	for s = s[i:]; s != "" && s[0] == ';'; {
+		s = s[1:]
+
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 {
+			return line, ekaerr.IllegalFormat.
+				New("ekatime: malformed iCalendar property's param", "line", num).
+				Throw()
+		}
+		name := strings.ToUpper(s[:eq])
+		s = s[eq+1:]
+
+		var value strings.Builder
+		for isQuoted := false; s != ""; s = s[1:] {
+			if s[0] == '"' {
+				isQuoted = !isQuoted
+				continue
+			}
+			if !isQuoted && (s[0] == ';' || s[0] == ':') {
+				break
+			}
+			value.WriteByte(s[0])
+		}
+
+		if line.params == nil {
+			line.params = make(map[string]string)
+		}
+		line.params[name] = value.String()
+	}
+
+	if s == "" || s[0] != ':' {
+		return line, ekaerr.IllegalFormat.
+			New("ekatime: iCalendar property has no value", "line", num).
+			Throw()
+	}
+
+	line.value = s[1:]
+	return line, nil
+}
+
+// icalParse converts VEVENTs from 'lines' to Event s using 'mapping'.
+// VEVENTs with RRULE are converted to RecurringEvent s.
+func icalParse(lines []icalLine, mapping *ICalMapping) ([]Event, []RecurringEvent, *ekaerr.Error) {
+
+	var (
+		events    []Event
+		recurring []RecurringEvent
+		current   *icalEvent
+		nested    []string // components that are nested to VEVENT (like VALARM)
+		loc       = mapping.location()
+	)
+
+	for i := range lines {
+		line := &lines[i]
+
+		switch value := strings.ToUpper(line.value); {
+
+		case line.name == "BEGIN" && current == nil && value == "VEVENT":
+			current = &icalEvent{line: line.num}
+
+		case line.name == "BEGIN" && current != nil:
+			nested = append(nested, value)
+
+		case line.name == "END" && current != nil && len(nested) > 0:
+			nested = nested[:len(nested)-1]
+
+		case line.name == "END" && current != nil && value == "VEVENT":
+			category, found := mapping.categoryOf(current.categories)
+			if found && !current.isCanceled {
+				dates, err := current.dates(loc)
+				if err.IsNotNil() {
+					return nil, nil, err.Throw()
+				}
+				if current.rrule != nil && len(dates) > 0 {
+					rule, err := ParseRRule(dates[0], current.rrule.value)
+					if err.IsNotNil() {
+						return nil, nil, err.AddFields("line", current.rrule.num).Throw()
+					}
+					recurring = append(recurring, RecurringEvent{
+						Rule:     rule,
+						ID:       category.ID,
+						IsDayOff: category.IsDayOff,
+					})
+				} else {
+					for _, date := range dates {
+						events = append(events, NewEvent(date, category.ID, category.IsDayOff))
+					}
+				}
+			}
+			current = nil
+
+		case current == nil || len(nested) > 0:
+			// not a VEVENT's property
+
+		case line.name == "DTSTART":
+			current.start = line
+
+		case line.name == "DTEND":
+			current.end = line
+
+		case line.name == "DURATION":
+			current.duration = line
+
+		case line.name == "RRULE":
+			current.rrule = line
+
+		case line.name == "STATUS":
+			current.isCanceled = value == "CANCELLED"
+
+		case line.name == "CATEGORIES":
+			for _, category := range icalSplitList(line.value) {
+				if category = strings.TrimSpace(category); category != "" {
+					current.categories = append(current.categories, category)
+				}
+			}
+		}
+	}
+
+	if current != nil {
+		return nil, nil, ekaerr.IllegalFormat.
+			New("ekatime: iCalendar VEVENT is not closed", "line", current.line).
+			Throw()
+	}
+
+	return events, recurring, nil
+}
+
+// dates returns the dates VEVENT lasts in the 'loc' location.
+func (e *icalEvent) dates(loc *time.Location) ([]Date, *ekaerr.Error) {
+
+	if e.start == nil {
+		return nil, ekaerr.IllegalFormat.
+			New("ekatime: iCalendar VEVENT has no DTSTART", "line", e.line).
+			Throw()
+	}
+
+	start, isAllDay, err := icalParseDateTime(e.start, loc)
+	if err.IsNotNil() {
+		return nil, err.Throw()
+	}
+
+	// For all-day events 'end' is exclusive date,
+	// for others it's exclusive moment.
+	var end time.Time
+	switch {
+
+	case e.end != nil:
+		if end, _, err = icalParseDateTime(e.end, loc); err.IsNotNil() {
+			return nil, err.Throw()
+		}
+
+	case e.duration != nil:
+		duration, ok := icalParseDuration(e.duration.value)
+		if !ok {
+			return nil, ekaerr.IllegalFormat.
+				New("ekatime: malformed iCalendar DURATION", "line", e.duration.num).
+				Throw()
+		}
+		end = start.Add(duration)
+
+	case isAllDay:
+		end = start.AddDate(0, 0, 1)
+
+	default:
+		end = start.Add(time.Second)
+	}
+
+	if !end.After(start) {
+		end = start.Add(time.Second)
+	}
+
+	var (
+		first = NewDate(icalDateOf(start))
+		last  = NewDate(icalDateOf(end.Add(-time.Second)))
+		dates []Date
+	)
+
+	for d := first; d.ToCmp() <= last.ToCmp(); d = d.AddDays(1) {
+		if len(dates) == ICAL_EVENT_MAX_DAYS {
+			return nil, ekaerr.IllegalArgument.
+				New("ekatime: iCalendar VEVENT lasts too long", "line", e.line).
+				Throw()
+		}
+		dates = append(dates, d)
+	}
+
+	return dates, nil
+}
+
+// icalParseDateTime parses DTSTART or DTEND 'line'. All-day (date) values
+// are returned as midnight in UTC, others are returned in the 'loc' location.
+func icalParseDateTime(line *icalLine, loc *time.Location) (time.Time, bool, *ekaerr.Error) {
+
+	var (
+		value     = strings.TrimSpace(line.value)
+		t         time.Time
+		legacyErr error
+	)
+
+	switch {
+
+	case line.params["VALUE"] == "DATE" || len(value) == len(_ICAL_DATE_LAYOUT):
+		t, legacyErr = time.ParseInLocation(_ICAL_DATE_LAYOUT, value, time.UTC)
+		if legacyErr == nil {
+			return t, true, nil
+		}
+
+	case strings.HasSuffix(value, "Z"):
+		t, legacyErr = time.ParseInLocation(_ICAL_DATE_TIME_LAYOUT, value[:len(value)-1], time.UTC)
+
+	default:
+		valueLoc := loc
+		if tzID := line.params["TZID"]; tzID != "" {
+			if tz, err := time.LoadLocation(strings.TrimPrefix(tzID, "/")); err == nil {
+				valueLoc = tz
+			}
+		}
+		t, legacyErr = time.ParseInLocation(_ICAL_DATE_TIME_LAYOUT, value, valueLoc)
+	}
+
+	if legacyErr != nil {
+		return time.Time{}, false, ekaerr.IllegalFormat.
+			Wrap(legacyErr, "ekatime: malformed iCalendar date-time", "line", line.num).
+			Throw()
+	}
+
+	return t.In(loc), false, nil
+}
+
+// icalParseDuration parses iCalendar's DURATION value, like "P1D", "PT12H", "P2W".
+func icalParseDuration(s string) (time.Duration, bool) {
+
+	s = strings.TrimPrefix(strings.TrimSpace(s), "+")
+	if !strings.HasPrefix(s, "P") || len(s) < 3 {
+		return 0, false
+	}
+
+	var (
+		d      time.Duration
+		isTime bool
+		num    = -1
+	)
+
+	for _, c := range s[1:] {
+		switch {
+		case c >= '0' && c <= '9':
+			if num == -1 {
+				num = 0
+			}
+			num = num*10 + int(c-'0')
+			continue
+		case c == 'T' && num == -1 && !isTime:
+			isTime = true
+			continue
+		case num == -1:
+			return 0, false
+		case c == 'W' && !isTime:
+			d += time.Duration(num) * 7 * 24 * time.Hour
+		case c == 'D' && !isTime:
+			d += time.Duration(num) * 24 * time.Hour
+		case c == 'H' && isTime:
+			d += time.Duration(num) * time.Hour
+		case c == 'M' && isTime:
+			d += time.Duration(num) * time.Minute
+		case c == 'S' && isTime:
+			d += time.Duration(num) * time.Second
+		default:
+			return 0, false
+		}
+		num = -1
+	}
+
+	return d, num == -1
+}
+
+// icalDateOf returns 't' date parts.
+func icalDateOf(t time.Time) (Year, Month, Day) {
+	y, m, d := t.Date()
+	return Year(y), Month(m), Day(d)
+}
+
+// icalSplitList splits iCalendar's list value by not escaped commas
+// and unescapes its items.
+func icalSplitList(s string) []string {
+
+	var (
+		items []string
+		item  strings.Builder
+	)
+
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s):
+			i++
+			if s[i] == 'n' || s[i] == 'N' {
+				item.WriteByte('\n')
+			} else {
+				item.WriteByte(s[i])
+			}
+		case s[i] == ',':
+			items = append(items, item.String())
+			item.Reset()
+		default:
+			item.WriteByte(s[i])
+		}
+	}
+
+	return append(items, item.String())
+}
+
+// icalEscape escapes iCalendar's text value 's'.
+func icalEscape(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`, `;`, `\;`, `,`, `\,`, "\n", `\n`,
+	).Replace(s)
+}
+
+// icalFold returns content line 's' folded by _ICAL_LINE_MAX_LEN octets
+// (w/o breaking UTF-8 characters), ended by CRLF.
+func icalFold(s string) string {
+
+	var b strings.Builder
+	for limit := _ICAL_LINE_MAX_LEN; len(s) > limit; limit = _ICAL_LINE_MAX_LEN - 1 {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+	}
+	b.WriteString(s)
+	b.WriteString("\r\n")
+
+	return b.String()
+}
+
+// icalEncode returns 'events' and 'recurring' events encoded as VCALENDAR
+// using 'mapping'. 'now' is used as VEVENTs' DTSTAMP.
+func icalEncode(events []Event, recurring []RecurringEvent, mapping *ICalMapping, now Timestamp) string {
+
+	events = append(events[:0:0], events...)
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Date().ToCmp() < events[j].Date().ToCmp()
+	})
+
+	var (
+		b       strings.Builder
+		dtStamp = now.Std().Format(_ICAL_DATE_TIME_LAYOUT) + "Z"
+	)
+
+	b.WriteString(icalFold("BEGIN:VCALENDAR"))
+	b.WriteString(icalFold("VERSION:2.0"))
+	b.WriteString(icalFold("PRODID:" + ICAL_PRODID))
+	b.WriteString(icalFold("CALSCALE:GREGORIAN"))
+
+	for i := 0; i < len(events); {
+
+		// Merge consecutive days of the same kind.
+		var (
+			first = events[i]
+			last  = first.Date()
+		)
+		for i++; i < len(events); i++ {
+			next := events[i]
+			if next.ID() != first.ID() || next.IsDayOff() != first.IsDayOff() ||
+				!next.Date().Equal(last.AddDays(1)) {
+				break
+			}
+			last = next.Date()
+		}
+
+		var (
+			start = first.Date().WithTime(0, 0, 0).Std().Format(_ICAL_DATE_LAYOUT)
+			end   = last.AddDays(1).WithTime(0, 0, 0).Std().Format(_ICAL_DATE_LAYOUT)
+		)
+
+		summary := "Workday"
+		if first.IsDayOff() {
+			summary = "Day off"
+		}
+
+		b.WriteString(icalFold("BEGIN:VEVENT"))
+		b.WriteString(icalFold("UID:" + start + "-" + strconv.Itoa(int(first.ID())) + "@ekatime"))
+		b.WriteString(icalFold("DTSTAMP:" + dtStamp))
+		b.WriteString(icalFold("DTSTART;VALUE=DATE:" + start))
+		b.WriteString(icalFold("DTEND;VALUE=DATE:" + end))
+		b.WriteString(icalFold("SUMMARY:" + summary))
+		if category := mapping.categoryFor(first); category != "" {
+			b.WriteString(icalFold("CATEGORIES:" + icalEscape(category)))
+		}
+		b.WriteString(icalFold("TRANSP:TRANSPARENT"))
+		b.WriteString(icalFold("END:VEVENT"))
+	}
+
+	for i, re := range recurring {
+		if re.Rule == nil {
+			continue
+		}
+
+		var (
+			start = re.Rule.Start.WithTime(0, 0, 0).Std().Format(_ICAL_DATE_LAYOUT)
+			end   = re.Rule.Start.AddDays(1).WithTime(0, 0, 0).Std().Format(_ICAL_DATE_LAYOUT)
+			event = NewEvent(re.Rule.Start, re.ID, re.IsDayOff)
+		)
+
+		summary := "Workday"
+		if re.IsDayOff {
+			summary = "Day off"
+		}
+
+		b.WriteString(icalFold("BEGIN:VEVENT"))
+		b.WriteString(icalFold("UID:" + start + "-" + strconv.Itoa(int(re.ID)) +
+			"-r" + strconv.Itoa(i) + "@ekatime"))
+		b.WriteString(icalFold("DTSTAMP:" + dtStamp))
+		b.WriteString(icalFold("DTSTART;VALUE=DATE:" + start))
+		b.WriteString(icalFold("DTEND;VALUE=DATE:" + end))
+		b.WriteString(icalFold("RRULE:" + re.Rule.String()))
+		b.WriteString(icalFold("SUMMARY:" + summary))
+		if category := mapping.categoryFor(event); category != "" {
+			b.WriteString(icalFold("CATEGORIES:" + icalEscape(category)))
+		}
+		b.WriteString(icalFold("TRANSP:TRANSPARENT"))
+		b.WriteString(icalFold("END:VEVENT"))
+	}
+
+	b.WriteString(icalFold("END:VCALENDAR"))
+	return b.String()
+}
+
+// location returns a time zone VEVENTs' days are determined in. Nil safe.
+func (m *ICalMapping) location() *time.Location {
+	if m == nil {
+		return time.UTC
+	}
+	return normalizeLocation(m.Location)
+}
+
+// categoryOf returns the ICalCategory the VEVENT with 'categories' is converted to
+// and true or false if VEVENT must be skipped. Nil safe.
+func (m *ICalMapping) categoryOf(categories []string) (ICalCategory, bool) {
+
+	if m == nil {
+		return ICalCategory{IsDayOff: true}, true
+	}
+
+	for _, category := range categories {
+		for name, mapped := range m.Categories {
+			if strings.EqualFold(name, category) {
+				return mapped, true
+			}
+		}
+	}
+
+	if m.Default != nil {
+		return *m.Default, true
+	}
+
+	return ICalCategory{}, false
+}
+
+// categoryFor returns a category 'event' is exported with or "". Nil safe.
+// If there are many suitable categories, the least one is returned.
+func (m *ICalMapping) categoryFor(event Event) string {
+
+	if m == nil {
+		return ""
+	}
+
+	found := ""
+	for name, mapped := range m.Categories {
+		if mapped.ID == event.ID() && mapped.IsDayOff == event.IsDayOff() &&
+			(found == "" || name < found) {
+			found = name
+		}
+	}
+
+	return found
+}
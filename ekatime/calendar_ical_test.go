@@ -0,0 +1,123 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekatime_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/qioalice/ekago/v2/ekatime"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testICal = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"PRODID:-//test//test//EN\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:1\r\n" +
+	"DTSTART;VALUE=DATE:20210101\r\n" +
+	"DTEND;VALUE=DATE:20210104\r\n" +
+	"SUMMARY:New Year\r\n" +
+	"  holidays\r\n" +
+	"CATEGORIES:Holiday,Public\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"DTSTART:20201231T120000Z\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:2\r\n" +
+	"DTSTART;TZID=Europe/Moscow:20210220T090000\r\n" +
+	"DURATION:PT8H\r\n" +
+	"CATEGORIES:transfer\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:3\r\n" +
+	"DTSTART:20210308\r\n" +
+	"CATEGORIES:Birthday\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:4\r\n" +
+	"DTSTART;VALUE=DATE:20210309\r\n" +
+	"STATUS:CANCELLED\r\n" +
+	"CATEGORIES:Holiday\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseICal(t *testing.T) {
+
+	loadLocation(t, "Europe/Moscow")
+
+	mapping := &ekatime.ICalMapping{
+		Categories: map[string]ekatime.ICalCategory{
+			"HOLIDAY":  {ID: 1, IsDayOff: true},
+			"Transfer": {ID: 2, IsDayOff: false},
+		},
+	}
+
+	events, err := ekatime.ParseICal(strings.NewReader(testICal), mapping)
+	require.True(t, err.IsNil())
+
+	require.Equal(t, []ekatime.Event{
+		ekatime.NewEvent(ekatime.NewDate(2021, 1, 1), 1, true),
+		ekatime.NewEvent(ekatime.NewDate(2021, 1, 2), 1, true),
+		ekatime.NewEvent(ekatime.NewDate(2021, 1, 3), 1, true),
+		ekatime.NewEvent(ekatime.NewDate(2021, 2, 20), 2, false),
+	}, events)
+
+	// Default category makes VEVENTs w/o known category to be imported.
+	mapping.Default = &ekatime.ICalCategory{ID: 3, IsDayOff: true}
+
+	events, err = ekatime.ParseICal(strings.NewReader(testICal), mapping)
+	require.True(t, err.IsNil())
+	require.Len(t, events, 5)
+	require.Equal(t, ekatime.NewEvent(ekatime.NewDate(2021, 3, 8), 3, true), events[4])
+
+	_, err = ekatime.ParseICal(strings.NewReader("BEGIN:VEVENT\r\nDTSTART:2021\r\nEND:VEVENT\r\n"), nil)
+	require.True(t, err.IsNotNil())
+
+	_, err = ekatime.ParseICal(strings.NewReader("BEGIN:VEVENT\r\n"), nil)
+	require.True(t, err.IsNotNil())
+}
+
+func TestCalendar_EventsExportICal(t *testing.T) {
+
+	mapping := &ekatime.ICalMapping{
+		Categories: map[string]ekatime.ICalCategory{
+			"Holiday":  {ID: 1, IsDayOff: true},
+			"Transfer": {ID: 2, IsDayOff: false},
+		},
+	}
+
+	c := new(ekatime.Calendar).
+		EventAdd(ekatime.NewEvent(ekatime.NewDate(2021, 1, 2), 1, true)).
+		EventAdd(ekatime.NewEvent(ekatime.NewDate(2021, 1, 1), 1, true)).
+		EventAdd(ekatime.NewEvent(ekatime.NewDate(2021, 2, 20), 2, false))
+
+	var b bytes.Buffer
+	require.True(t, c.EventsExportICal(&b, mapping).IsNil())
+
+	ics := b.String()
+	require.Equal(t, 2, strings.Count(ics, "BEGIN:VEVENT\r\n"))
+	require.Contains(t, ics, "DTSTART;VALUE=DATE:20210101\r\nDTEND;VALUE=DATE:20210103\r\n")
+	require.Contains(t, ics, "CATEGORIES:Transfer\r\n")
+
+	// Exported calendar must be imported back as is.
+	imported := new(ekatime.Calendar)
+	require.True(t, imported.EventsImportICal(&b, mapping).IsNil())
+
+	var events []ekatime.Event
+	imported.EventWalk(func(_ int, event ekatime.Event) {
+		events = append(events, event)
+	})
+
+	require.Equal(t, []ekatime.Event{
+		ekatime.NewEvent(ekatime.NewDate(2021, 1, 1), 1, true),
+		ekatime.NewEvent(ekatime.NewDate(2021, 1, 2), 1, true),
+		ekatime.NewEvent(ekatime.NewDate(2021, 2, 20), 2, false),
+	}, events)
+}
@@ -21,6 +21,14 @@ const (
 	_CAL_PENDING_LOG_STAT_DELAY = 30 * time.Second
 )
 
+type (
+	// calRecurringCallback is a callback registered by Calendar.WhenRecurs().
+	calRecurringCallback struct {
+		rule *RRule
+		cb   func(*Today)
+	}
+)
+
 // confirmPending flushes all Calendar's pending changes into confirmed.
 // Do nothing if nothing changed.
 func (c *Calendar) confirmPending() *Calendar {
@@ -32,6 +40,20 @@ func (c *Calendar) confirmPending() *Calendar {
 		c.confirmedEvents = append(c.pendingEvents[:0:0], c.pendingEvents...)
 	}
 
+	c.confirmedRecurringEvents = append(c.pendingRecurringEvents[:0:0], c.pendingRecurringEvents...)
+	c.confirmedRecurringCallbacks = append(c.pendingRecurringCallbacks[:0:0], c.pendingRecurringCallbacks...)
+
+	c.confirmedWeekend = c.pendingWeekend
+	weekend := WEEKEND_DEFAULT
+	if c.confirmedWeekend & _WEEKEND_IS_SET != 0 {
+		weekend = c.confirmedWeekend &^ _WEEKEND_IS_SET
+	}
+
+	// Recurring events are checked by Workdays lazily for any requested day.
+	c.confirmedWorkdays = NewWorkdays(c.confirmedEvents).
+		WithRecurring(c.confirmedRecurringEvents).
+		WithWeekend(weekend)
+
 	c.confirmedNewDayCallback = c.pendingNewDayCallback
 	c.confirmedTodayEncoderJson = c.pendingTodayEncoderJson
 	c.confirmedTodayEncoderCustom1 = c.pendingTodayEncoderCustom1
@@ -77,7 +99,7 @@ func (c *Calendar) newDayHasCome() {
 	c.mu.Unlock()
 
 	newToday := c.updateToday()
-	c.newDayTimer.Reset(TillNextMidnight())
+	c.newDayTimer.Reset(c.now().TillNextMidnightIn(c.loc))
 
 	if !disableLogging && (addTotalCounter != 0 || removeTotalCounter != 0) {
 		ekalog.Debug("ekatime.Calendar has been updated, and event list has been changed.",
@@ -85,11 +107,20 @@ func (c *Calendar) newDayHasCome() {
 			"c_removed_old_events_count?", removeTotalCounter)
 	}
 
-	if c.confirmedNewDayCallback != nil {
+	if c.confirmedNewDayCallback != nil || len(c.confirmedRecurringCallbacks) > 0 {
 		c.wg.Add(1)
 		defer c.wg.Done()
+	}
+
+	if c.confirmedNewDayCallback != nil {
 		c.confirmedNewDayCallback(newToday)
 	}
+
+	for _, recurringCallback := range c.confirmedRecurringCallbacks {
+		if recurringCallback.rule.Matches(newToday.Date) {
+			recurringCallback.cb(newToday)
+		}
+	}
 }
 
 // updateToday creates a new Today object, fills them, stores them into the
@@ -99,9 +130,9 @@ func (c *Calendar) updateToday() *Today {
 	newToday := new(Today)
 	newToday.c = c
 
-	newToday.Timestamp = Now()
+	newToday.Timestamp = c.now()
 
-	newToday.Date, newToday.Time                    = newToday.Timestamp.Split()
+	newToday.Date, newToday.Time                    = newToday.Timestamp.SplitIn(c.loc)
 	newToday.Year, newToday.Month, newToday.Day     = newToday.Date.Split()
 	newToday.Hour, newToday.Minute, newToday.Second = newToday.Time.Split()
 	newToday.Weekday                                = newToday.Date.Weekday()
@@ -114,7 +145,12 @@ func (c *Calendar) updateToday() *Today {
 		workdaysFor(
 			NewDate(newToday.Year, newToday.Month, 1),
 			newToday.Day,
-			c.confirmedEvents,
+			c.eventsBetween(
+				NewDate(newToday.Year, newToday.Month, 1),
+				NewDate(newToday.Year, newToday.Month, newToday.DaysInMonth),
+				c.confirmedEvents, c.confirmedRecurringEvents,
+			),
+			c.confirmedWorkdays.Weekend(),
 			&newToday.WorkDays,
 		)
 
@@ -150,12 +186,53 @@ func (c *Calendar) updateToday() *Today {
 	return newToday
 }
 
+// getClock returns the Clock that is set by SetClock() or DefaultClock().
+func (c *Calendar) getClock() Clock {
+	if c.clock == nil {
+		return DefaultClock()
+	}
+	return c.clock
+}
+
+// now returns the current Timestamp using Calendar's Clock.
+func (c *Calendar) now() Timestamp {
+	return UnixFromStd(c.getClock().Now())
+}
+
 // destructor provides you a graceful shutdown for user defined new day callback
 // (by WhenNewDay() method) allowing to pause shutting down until callback is done their work.
 func (c *Calendar) destructor() {
 	c.wg.Wait()
 }
 
+// eventsBetween returns 'events' and 'recurring' events expanded
+// for [from, to] range. Events from 'events' have a priority over the recurring
+// ones that are in the same day (recurring events are dropped then).
+// All 'events' are returned, even they are out of range.
+func (_ *Calendar) eventsBetween(from, to Date, events []Event, recurring []RecurringEvent) []Event {
+
+	if len(recurring) == 0 {
+		return events
+	}
+
+	dates := make(map[Date]struct{}, len(events))
+	for _, event := range events {
+		dates[event.Date().ToCmp()] = struct{}{}
+	}
+
+	var merged []Event
+	for _, re := range recurring {
+		for _, event := range re.Between(from, to) {
+			if _, found := dates[event.Date().ToCmp()]; !found {
+				dates[event.Date().ToCmp()] = struct{}{}
+				merged = append(merged, event)
+			}
+		}
+	}
+
+	return append(merged, events...)
+}
+
 // pendingEventIdx returns the idx of 'event' in the pending event's slice
 // in the current Calendar's object or -1 if not found.
 func (c *Calendar) pendingEventIdx(event Event) int {
@@ -172,7 +249,7 @@ func (c *Calendar) pendingEventIdx(event Event) int {
 // See Calendar.WorkdaysFor docs.
 // isDayOff reports whether d1 is day off.
 // workDaysOut will contain working days if it's not nil.
-func workdaysFor(dd Date, d1 Day, events []Event, workDaysOut *[]Day) (current, total Day, isDayOff bool) {
+func workdaysFor(dd Date, d1 Day, events []Event, weekend Weekend, workDaysOut *[]Day) (current, total Day, isDayOff bool) {
 
 	y, m, d := normalizeDate(dd.Split())
 	dd = NewDate(y, m, d)
@@ -189,7 +266,7 @@ func workdaysFor(dd Date, d1 Day, events []Event, workDaysOut *[]Day) (current,
 	var workDaysBitSet ekamath.Flags32
 
 	for d, wd := d, dd.Weekday(); d <= daysInMonth; d++ {
-		if !wd.IsDayOff() {
+		if !weekend.Has(wd) {
 			total++
 			workDaysBitSet |= 1 << (d-1)
 			if d <= d1 {
@@ -200,9 +277,9 @@ func workdaysFor(dd Date, d1 Day, events []Event, workDaysOut *[]Day) (current,
 	}
 
 	if d1 != d {
-		isDayOff = NewDate(y, m, d1).Weekday().IsDayOff()
+		isDayOff = weekend.Has(NewDate(y, m, d1).Weekday())
 	} else {
-		isDayOff = dd.Weekday().IsDayOff()
+		isDayOff = weekend.Has(dd.Weekday())
 	}
 
 	for _, ce := range events {
@@ -212,18 +289,18 @@ func workdaysFor(dd Date, d1 Day, events []Event, workDaysOut *[]Day) (current,
 		if ce.Day() == d1 {
 			isDayOff = ce.IsDayOff()
 		}
-		if ce.Weekday().IsDayOff() == ce.IsDayOff() {
+		if weekend.Has(ce.Weekday()) == ce.IsDayOff() {
 			continue
 		}
 		if ce.IsDayOff() {
 			total--
-			workDaysBitSet &^= 1 << (d-1)
+			workDaysBitSet &^= 1 << (ce.Day()-1)
 			if ce.Day() <= d1 {
 				current--
 			}
 		} else {
 			total++
-			workDaysBitSet |= 1 << (d-1)
+			workDaysBitSet |= 1 << (ce.Day()-1)
 			if ce.Day() <= d1 {
 				current++
 			}
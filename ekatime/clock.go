@@ -0,0 +1,206 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekatime
+
+import (
+	"sync"
+	"time"
+)
+
+type (
+	// Clock is a source of the current time and timers.
+	//
+	// All ekatime's functions and objects that depend on the current time
+	// (Now(), TillNext...(), OnceIn<period>, Calendar, Cron) use Clock,
+	// that is SystemClock() by default. Replace it by FakeClock in tests
+	// to control the time (see SetDefaultClock(), Calendar.SetClock(),
+	// Cron.WithClock()).
+	Clock interface {
+
+		// Now returns the current time.
+		Now() time.Time
+
+		// NewTimer creates a new ClockTimer that sends the current time
+		// to its channel after at least 'd' duration.
+		NewTimer(d time.Duration) ClockTimer
+
+		// AfterFunc creates a new ClockTimer that calls 'f'
+		// after at least 'd' duration.
+		AfterFunc(d time.Duration, f func()) ClockTimer
+	}
+
+	// ClockTimer is a timer that is created by Clock.
+	// It has the same semantic as time.Timer has.
+	ClockTimer interface {
+
+		// C returns a channel the current time is sent to when timer fires.
+		// Returns nil for timers created by Clock.AfterFunc().
+		C() <-chan time.Time
+
+		// Stop prevents the timer from firing. Reports whether the timer
+		// has been active.
+		Stop() bool
+
+		// Reset changes the timer to fire after 'd' duration.
+		// Reports whether the timer has been active.
+		Reset(d time.Duration) bool
+	}
+
+	// FakeClock is a Clock which time is changed manually only,
+	// using Set(), Advance() or its helpers. Timers are fired deterministically
+	// in the order of their deadlines when the time is changed,
+	// in the goroutine that changes the time.
+	//
+	// Useful in tests for simulating a midnight, month's end, leap day, etc:
+	//
+	// 		fc := ekatime.NewFakeClock(ekatime.UnixFrom(2020, 2, 28, 23, 59, 59).Std())
+	// 		c := new(ekatime.Calendar).SetClock(fc)
+	// 		c.RunAsync()
+	//
+	// 		fc.Advance(time.Second) // new day callbacks are called; Feb 29
+	//
+	// Callbacks of timers created by AfterFunc() are called synchronously,
+	// so FakeClock's methods that change the time return when they are done.
+	// Times are not sent to the channels of timers created by NewTimer()
+	// if previous ones are not received yet.
+	FakeClock struct {
+		mu     sync.Mutex
+		now    time.Time
+		timers []*fakeTimer // active timers
+		seq    uint64       // sequence number of the last started timer
+	}
+)
+
+// SystemClock returns Clock that is based on the time package's
+// time.Now(), time.NewTimer(), time.AfterFunc().
+func SystemClock() Clock {
+	return systemClock{}
+}
+
+// SetDefaultClock sets 'clock' as a Clock that is used by Now(), TillNext...(),
+// OnceIn<period>, and by Calendar, Cron that have no their own Clock.
+// Nil means SystemClock().
+//
+// OnceIn<period> objects are restarted with the new Clock.
+// Calendar s, Cron s that are running already keep the previous Clock.
+func SetDefaultClock(clock Clock) {
+	if clock == nil {
+		clock = SystemClock()
+	}
+	defaultClock.Store(clockHolder{clock})
+	initOnceIn()
+}
+
+// DefaultClock returns Clock that is set by SetDefaultClock()
+// or SystemClock() if it has not been set.
+func DefaultClock() Clock {
+	if holder, ok := defaultClock.Load().(clockHolder); ok {
+		return holder.Clock
+	}
+	return SystemClock()
+}
+
+// NewFakeClock creates a new FakeClock which current time is 'now'.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now returns FakeClock's current time. Nil safe (returns zero time).
+func (fc *FakeClock) Now() time.Time {
+
+	if fc == nil {
+		return time.Time{}
+	}
+
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	return fc.now
+}
+
+// NewTimer creates a new ClockTimer that sends FakeClock's current time
+// to its channel when FakeClock's time reaches its deadline.
+func (fc *FakeClock) NewTimer(d time.Duration) ClockTimer {
+	t := &fakeTimer{clock: fc, c: make(chan time.Time, 1)}
+	t.Reset(d)
+	return t
+}
+
+// AfterFunc creates a new ClockTimer that calls 'f' when FakeClock's time
+// reaches its deadline.
+func (fc *FakeClock) AfterFunc(d time.Duration, f func()) ClockTimer {
+	t := &fakeTimer{clock: fc, f: f}
+	t.Reset(d)
+	return t
+}
+
+// Set sets FakeClock's current time to 't', firing all timers which deadlines
+// are before or equal 't' one by one, with FakeClock's time set to
+// their deadlines. The time may be set to the past, timers are not fired then.
+// Nil safe.
+func (fc *FakeClock) Set(t time.Time) {
+
+	if fc == nil {
+		return
+	}
+
+	for {
+		fc.mu.Lock()
+		timer := fc.popExpired(t)
+		if timer == nil {
+			fc.now = t
+			fc.mu.Unlock()
+			return
+		}
+		if timer.deadline.After(fc.now) {
+			fc.now = timer.deadline
+		}
+		now := fc.now
+		fc.mu.Unlock()
+
+		timer.fire(now)
+	}
+}
+
+// Advance moves FakeClock's current time forward by 'd'. See Set(). Nil safe.
+func (fc *FakeClock) Advance(d time.Duration) {
+	if fc != nil {
+		fc.Set(fc.Now().Add(d))
+	}
+}
+
+// AdvanceToNextMidnightIn moves FakeClock's current time forward
+// to the next midnight in the 'loc' time zone (nil means UTC). See Set().
+// Nil safe.
+func (fc *FakeClock) AdvanceToNextMidnightIn(loc *time.Location) {
+	if fc != nil {
+		ts := UnixFromStd(fc.Now())
+		fc.Set(ts.Std().Add(ts.TillNextMidnightIn(loc)))
+	}
+}
+
+// AdvanceToNextMonthIn moves FakeClock's current time forward to the
+// midnight of the 1st day of next month in the 'loc' time zone (nil means UTC).
+// See Set(). Nil safe.
+func (fc *FakeClock) AdvanceToNextMonthIn(loc *time.Location) {
+	if fc != nil {
+		y, m, _ := UnixFromStd(fc.Now()).DateIn(loc).Split()
+		fc.Set(NewDate(y, m, 1).Add(0, 1, 0).WithTimeIn(0, 0, 0, loc).Std())
+	}
+}
+
+// TimersNum returns the number of FakeClock's active timers. Nil safe.
+func (fc *FakeClock) TimersNum() int {
+
+	if fc == nil {
+		return 0
+	}
+
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	return len(fc.timers)
+}
@@ -0,0 +1,150 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekatime
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// systemClock is a Clock that is based on the time package.
+	systemClock struct{}
+
+	// systemTimer is a ClockTimer that is based on time.Timer.
+	systemTimer struct {
+		t *time.Timer
+	}
+
+	// clockHolder is a wrapper of Clock that allows to store different
+	// Clock s implementations in the atomic.Value.
+	clockHolder struct {
+		Clock
+	}
+
+	// fakeTimer is a ClockTimer that is created by FakeClock.
+	// All its fields are protected by FakeClock's mutex.
+	fakeTimer struct {
+		clock    *FakeClock
+		deadline time.Time
+		seq      uint64 // to fire timers with the same deadline in the order of starting
+		f        func()
+		c        chan time.Time
+		isActive bool
+	}
+)
+
+var (
+	// defaultClock is a Clock that is set by SetDefaultClock() (clockHolder).
+	defaultClock atomic.Value
+)
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTimer(d time.Duration) ClockTimer {
+	return &systemTimer{time.NewTimer(d)}
+}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) ClockTimer {
+	return &systemTimer{time.AfterFunc(d, f)}
+}
+
+func (t *systemTimer) C() <-chan time.Time {
+	return t.t.C
+}
+
+func (t *systemTimer) Stop() bool {
+	return t.t.Stop()
+}
+
+func (t *systemTimer) Reset(d time.Duration) bool {
+	return t.t.Reset(d)
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	wasActive := t.isActive
+	t.clock.remove(t)
+
+	return wasActive
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+
+	fc := t.clock
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	wasActive := t.isActive
+	if !wasActive {
+		fc.timers = append(fc.timers, t)
+	}
+
+	fc.seq++
+	t.deadline, t.seq, t.isActive = fc.now.Add(d), fc.seq, true
+
+	return wasActive
+}
+
+// fire calls fakeTimer's callback or sends 'now' to its channel
+// if it's not full.
+func (t *fakeTimer) fire(now time.Time) {
+
+	if t.f != nil {
+		t.f()
+		return
+	}
+
+	select {
+	case t.c <- now:
+	default:
+	}
+}
+
+// popExpired removes and returns FakeClock's active timer which deadline
+// is the earliest one and is before or equal 't' or nil if there is no such timer.
+// FakeClock's mutex must be locked.
+func (fc *FakeClock) popExpired(t time.Time) *fakeTimer {
+
+	var expired *fakeTimer
+	for _, timer := range fc.timers {
+		if timer.deadline.After(t) {
+			continue
+		}
+		if expired == nil || timer.deadline.Before(expired.deadline) ||
+			timer.deadline.Equal(expired.deadline) && timer.seq < expired.seq {
+			expired = timer
+		}
+	}
+
+	if expired != nil {
+		fc.remove(expired)
+	}
+
+	return expired
+}
+
+// remove removes 'timer' from FakeClock's active timers and marks it inactive.
+// FakeClock's mutex must be locked.
+func (fc *FakeClock) remove(timer *fakeTimer) {
+
+	timer.isActive = false
+	for i, t := range fc.timers {
+		if t == timer {
+			fc.timers = append(fc.timers[:i], fc.timers[i+1:]...)
+			return
+		}
+	}
+}
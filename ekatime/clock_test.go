@@ -0,0 +1,125 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekatime_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/qioalice/ekago/v2/ekaerr"
+	"github.com/qioalice/ekago/v2/ekatime"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeClock(t *testing.T) {
+
+	start := ekatime.UnixFrom(2021, 1, 1, 12, 0, 0).Std()
+	fc := ekatime.NewFakeClock(start)
+
+	var fired []string
+	fc.AfterFunc(2*time.Second, func() { fired = append(fired, "2s") })
+	fc.AfterFunc(time.Second, func() {
+		fired = append(fired, "1s")
+		require.Equal(t, start.Add(time.Second), fc.Now())
+	})
+	stopped := fc.AfterFunc(time.Second, func() { fired = append(fired, "stopped") })
+	timer := fc.NewTimer(3 * time.Second)
+
+	require.Equal(t, 4, fc.TimersNum())
+	require.True(t, stopped.Stop())
+	require.False(t, stopped.Stop())
+
+	fc.Advance(2 * time.Second)
+	require.Equal(t, []string{"1s", "2s"}, fired)
+	require.Equal(t, start.Add(2*time.Second), fc.Now())
+
+	select {
+	case <-timer.C():
+		t.Fatal("timer must not be fired yet")
+	default:
+	}
+
+	fc.Advance(time.Hour)
+	require.Equal(t, start.Add(3*time.Second), <-timer.C())
+	require.Equal(t, 0, fc.TimersNum())
+
+	require.False(t, timer.Reset(time.Second))
+	fc.Set(start) // to the past, nothing is fired
+	require.Equal(t, 1, fc.TimersNum())
+
+	fc.AdvanceToNextMidnightIn(nil)
+	require.Equal(t, ekatime.UnixFrom(2021, 1, 2, 0, 0, 0).Std(), fc.Now())
+
+	fc.AdvanceToNextMonthIn(nil)
+	require.Equal(t, ekatime.UnixFrom(2021, 2, 1, 0, 0, 0).Std(), fc.Now())
+}
+
+func TestCalendar_SetClock(t *testing.T) {
+
+	fc := ekatime.NewFakeClock(ekatime.UnixFrom(2020, 2, 28, 23, 59, 59).Std())
+
+	var days []ekatime.Date
+	c := new(ekatime.Calendar).
+		DisableLogging().
+		SetClock(fc).
+		WhenNewDay(func(today *ekatime.Today) {
+			days = append(days, today.Date.ToCmp())
+		})
+
+	c.RunAsync()
+	require.True(t, c.Today().Date.Equal(ekatime.NewDate(2020, 2, 28)))
+	require.Equal(t, ekatime.Day(20), c.Today().WorkDayTotal)
+
+	fc.Advance(time.Second) // leap day
+	require.True(t, c.Today().Date.Equal(ekatime.NewDate(2020, 2, 29)))
+	require.Equal(t, ekatime.Day(29), c.Today().DaysInMonth)
+
+	fc.AdvanceToNextMidnightIn(nil) // month's end
+	require.True(t, c.Today().Date.Equal(ekatime.NewDate(2020, 3, 1)))
+	require.Equal(t, ekatime.Day(22), c.Today().WorkDayTotal)
+
+	require.Equal(t, []ekatime.Date{
+		ekatime.NewDate(2020, 2, 29),
+		ekatime.NewDate(2020, 3, 1),
+	}, days)
+}
+
+func TestSetDefaultClock(t *testing.T) {
+
+	fc := ekatime.NewFakeClock(ekatime.UnixFrom(2020, 12, 31, 23, 59, 30).Std())
+
+	ekatime.SetDefaultClock(fc)
+	defer ekatime.SetDefaultClock(nil)
+
+	require.Equal(t, ekatime.UnixFrom(2020, 12, 31, 23, 59, 30), ekatime.Now())
+	require.Equal(t, 30*time.Second, ekatime.TillNextMinute())
+	require.Equal(t, ekatime.UnixFrom(2020, 12, 31, 23, 59, 30), ekatime.OnceInMinute.Now())
+
+	fc.Advance(30 * time.Second)
+	require.Equal(t, ekatime.UnixFrom(2021, 1, 1, 0, 0, 0), ekatime.OnceInMinute.Now())
+	require.True(t, ekatime.OnceInDay.Date().Equal(ekatime.NewDate(2021, 1, 1)))
+
+	ran := make(chan ekatime.Timestamp, 1)
+	cron := ekatime.NewCron().DisableLogging()
+	_, err := cron.Add("hourly", "@hourly", func(_ context.Context, ts ekatime.Timestamp) *ekaerr.Error {
+		ran <- ts
+		return nil
+	}, nil)
+	require.True(t, err.IsNil())
+
+	cron.RunAsync()
+	defer cron.Stop(nil)
+
+	fc.Advance(time.Hour)
+	select {
+	case ts := <-ran:
+		require.Equal(t, ekatime.UnixFrom(2021, 1, 1, 1, 0, 0), ts)
+	case <-time.After(time.Second):
+		t.Fatal("cron job has not been run")
+	}
+}
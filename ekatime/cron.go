@@ -0,0 +1,439 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekatime
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qioalice/ekago/v2/ekadeath"
+	"github.com/qioalice/ekago/v2/ekaerr"
+)
+
+type (
+	// CronExpr is a parsed cron expression. See ParseCron().
+	CronExpr struct {
+		second uint64 // bit per second, 0..59
+		minute uint64 // bit per minute, 0..59
+		hour   uint32 // bit per hour, 0..23
+		dom    uint32 // bit per day of month, 1..31
+		month  uint16 // bit per month, 1..12
+		dow    uint8  // bit per day of week, 0..6, 0 is Sunday
+
+		// Day of month and day of week are ORed if both of them are restricted
+		// (standard cron behaviour), ANDed otherwise.
+		isDomStar bool
+		isDowStar bool
+
+		spec string
+	}
+
+	// CronFunc is a job's function that is called by Cron.
+	// 'ts' is a moment job has been planned to be run at (w/o jitter).
+	// 'ctx' is cancelled when job's timeout is elapsed or Cron is stopped
+	// and there is no time to wait anymore.
+	//
+	// Returned error (as well as panic that is converted to the error)
+	// is logged using ekalog's package logger.
+	CronFunc func(ctx context.Context, ts Timestamp) *ekaerr.Error
+
+	// CronJobOptions are options of job that is added to the Cron.
+	// The zero value (or nil) means: no overlapping, no jitter, no timeout,
+	// run at any day.
+	CronJobOptions struct {
+
+		// AllowOverlap allows job to be run even if its previous run is not
+		// finished yet. Otherwise the run is skipped.
+		AllowOverlap bool
+
+		// WorkdaysOnly marks that job must be run only at workdays
+		// according with Cron's Calendar (see Cron.WithCalendar())
+		// or only at Monday-Friday if there is no Calendar.
+		WorkdaysOnly bool
+
+		// Jitter is a max random delay job is run after its planned time with.
+		// Useful to avoid thundering herd. Ignored if it's not positive.
+		Jitter time.Duration
+
+		// Timeout is a time job's context is cancelled after
+		// since job has been run. Ignored if it's not positive.
+		Timeout time.Duration
+	}
+
+	// CronJob is a job that is added to the Cron. See Cron.Add().
+	CronJob struct {
+		name string
+		expr *CronExpr
+		f    CronFunc
+		opts CronJobOptions
+		cron *Cron
+
+		timer     ClockTimer  // nil until Cron is started
+		next      Timestamp   // planned time of the next run, protected by Cron's mutex
+		isRemoved bool        // protected by Cron's mutex
+		isRunning int32       // atomic, 1 if job is running right now
+	}
+
+	// Cron is a jobs scheduler that runs jobs at the moments that are
+	// described by cron expressions (see ParseCron()).
+	//
+	// It's a flexible alternative of OnceIn<period>.Call() that allows
+	// not only fixed periods, but also "at 09:30 on workdays" and so on:
+	//
+	// 		c := ekatime.NewCron().WithCalendar(calendar)
+	// 		_, err := c.Add("report", "30 9 * * *", sendReport,
+	// 			&ekatime.CronJobOptions{WorkdaysOnly: true, Timeout: 5 * time.Minute})
+	// 		c.RunAsync()
+	//
+	// When Cron is started, it's stopped automatically at the ekadeath's
+	// PHASE_DRAIN shutdown phase, waiting for running jobs to be finished.
+	// Use Stop() to stop it manually.
+	Cron struct {
+		mu sync.Mutex
+		wg sync.WaitGroup // running jobs
+
+		jobs []*CronJob
+
+		loc            *time.Location // time zone cron expressions are in
+		clock          Clock          // nil means DefaultClock()
+		calendar       *Calendar      // for CronJobOptions.WorkdaysOnly
+		disableLogging bool
+
+		ctx    context.Context // parent of jobs' contexts, cancelled when Stop() gives up
+		cancel context.CancelFunc
+
+		isStarted bool
+		isStopped bool
+
+		deathHandle *ekadeath.Handle
+	}
+)
+
+//noinspection GoSnakeCaseUsage
+const (
+	// CRON_SEARCH_MAX_YEARS is a max number of years CronExpr.Next() looks
+	// through to find the next matched moment.
+	CRON_SEARCH_MAX_YEARS = 8
+)
+
+// ParseCron parses cron expression 'spec' and returns CronExpr.
+//
+// Both of standard 5 fields (minute, hour, day of month, month, day of week)
+// and 6 fields (second is the first one) expressions are supported.
+// Each field may be "*", a number, a range "1-5", a step "*/15", "1-30/2",
+// a list of them "1,15,30-40". Months and days of week may be also
+// their 3 letters names (JAN-DEC, SUN-SAT). Sunday is both of 0 and 7.
+// "?" is the same as "*".
+//
+// Macros @yearly (@annually), @monthly, @weekly, @daily (@midnight), @hourly
+// are supported as well.
+//
+// Returns an error if 'spec' is malformed.
+func ParseCron(spec string) (*CronExpr, *ekaerr.Error) {
+
+	spec = strings.TrimSpace(spec)
+	fields := strings.Fields(spec)
+
+	if len(fields) == 1 && strings.HasPrefix(fields[0], "@") {
+		macro, found := _CronMacros[strings.ToLower(fields[0])]
+		if !found {
+			return nil, ekaerr.IllegalFormat.
+				New("ekatime: unknown cron macro", "cron_spec", spec).
+				Throw()
+		}
+		fields = strings.Fields(macro)
+	}
+
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, ekaerr.IllegalFormat.
+			New("ekatime: cron expression must have 5 or 6 fields", "cron_spec", spec).
+			Throw()
+	}
+
+	e := &CronExpr{spec: spec}
+	if err := e.parse(fields); err.IsNotNil() {
+		return nil, err.AddFields("cron_spec", spec).Throw()
+	}
+
+	return e, nil
+}
+
+// String returns cron expression the current CronExpr is parsed from. Nil safe.
+func (e *CronExpr) String() string {
+	if e == nil {
+		return ""
+	}
+	return e.spec
+}
+
+// Matches reports whether 'ts' in the 'loc' time zone (nil means UTC)
+// matches the current CronExpr. Nil safe.
+func (e *CronExpr) Matches(ts Timestamp, loc *time.Location) bool {
+	return e != nil && e.matches(ts.In(normalizeLocation(loc)))
+}
+
+// Next returns the first moment that is after 'ts' and matches the current CronExpr
+// in the 'loc' time zone (nil means UTC).
+// Returns 0 if there is no such moment in the next CRON_SEARCH_MAX_YEARS years
+// (e.g. "0 0 30 2 *"). Nil safe.
+func (e *CronExpr) Next(ts Timestamp, loc *time.Location) Timestamp {
+	if e == nil {
+		return 0
+	}
+	return e.next(ts, normalizeLocation(loc))
+}
+
+// NewCron creates a new Cron w/o jobs, w/o Calendar and with UTC time zone.
+// It's the same as new(Cron).
+func NewCron() *Cron {
+	return new(Cron)
+}
+
+// WithLocation sets the time zone cron expressions are in. Nil means UTC.
+// Must be called before RunAsync(). Nil safe.
+func (c *Cron) WithLocation(loc *time.Location) *Cron {
+	if c != nil {
+		c.mu.Lock()
+		if !c.isStarted {
+			c.loc = loc
+		}
+		c.mu.Unlock()
+	}
+	return c
+}
+
+// WithClock sets the Clock the current time and timers are taken from.
+// Nil means DefaultClock() (the default). Must be called before RunAsync().
+// Nil safe.
+func (c *Cron) WithClock(clock Clock) *Cron {
+	if c != nil {
+		c.mu.Lock()
+		if !c.isStarted {
+			c.clock = clock
+		}
+		c.mu.Unlock()
+	}
+	return c
+}
+
+// WithCalendar sets Calendar that is used to determine workdays
+// for jobs with CronJobOptions.WorkdaysOnly. Nil safe.
+func (c *Cron) WithCalendar(calendar *Calendar) *Cron {
+	if c != nil {
+		c.mu.Lock()
+		c.calendar = calendar
+		c.mu.Unlock()
+	}
+	return c
+}
+
+// DisableLogging disables logging of jobs' errors, panics and skipped runs.
+// Nil safe.
+func (c *Cron) DisableLogging() *Cron {
+	if c != nil {
+		c.mu.Lock()
+		c.disableLogging = true
+		c.mu.Unlock()
+	}
+	return c
+}
+
+// Add adds a new job with the 'name' (used for logging), that must be run
+// at the moments 'spec' cron expression describes (see ParseCron()),
+// calling 'f' with 'opts' options (nil means the default ones).
+// Job may be added when Cron is running, it's planned immediately then.
+//
+// Returns an error if 'spec' is malformed, 'f' is nil or Cron is stopped.
+// Nil safe (returns an error).
+func (c *Cron) Add(name, spec string, f CronFunc, opts *CronJobOptions) (*CronJob, *ekaerr.Error) {
+
+	switch {
+	case c == nil:
+		return nil, ekaerr.IllegalArgument.
+			New("ekatime: Cron is nil").
+			Throw()
+
+	case f == nil:
+		return nil, ekaerr.IllegalArgument.
+			New("ekatime: cron job's function is nil", "cron_job_name", name).
+			Throw()
+	}
+
+	expr, err := ParseCron(spec)
+	if err.IsNotNil() {
+		return nil, err.AddFields("cron_job_name", name).Throw()
+	}
+
+	j := &CronJob{name: name, expr: expr, f: f, cron: c}
+	if opts != nil {
+		j.opts = *opts
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.isStopped {
+		return nil, ekaerr.RejectedOperation.
+			New("ekatime: Cron is stopped", "cron_job_name", name).
+			Throw()
+	}
+
+	c.jobs = append(c.jobs, j)
+	if c.isStarted {
+		j.plan(c.now())
+	}
+
+	return j, nil
+}
+
+// Jobs returns jobs that are added to the current Cron and are not removed.
+// Nil safe. Thread-safety.
+func (c *Cron) Jobs() []*CronJob {
+
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append(c.jobs[:0:0], c.jobs...)
+}
+
+// RunAsync starts the Cron planning all its jobs and registers ekadeath's
+// destructor that stops Cron at PHASE_DRAIN phase (see Stop()).
+// There is no-op if Cron is started or stopped already. Nil safe. Thread-safety.
+func (c *Cron) RunAsync() {
+
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.isStarted || c.isStopped {
+		return
+	}
+
+	c.isStarted = true
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+
+	// Cron keeps using the same Clock even if DefaultClock() is changed.
+	if c.clock == nil {
+		c.clock = DefaultClock()
+	}
+
+	now := c.now()
+	for _, j := range c.jobs {
+		j.plan(now)
+	}
+
+	c.deathHandle = ekadeath.RegPhaseNamed("", ekadeath.PHASE_DRAIN, 0, c.destructor)
+}
+
+// Stop stops the Cron: no new jobs' runs are started and Stop() waits until
+// running jobs are finished or 'ctx' is done (nil means to wait forever).
+// In the last case running jobs' contexts are cancelled and an error is returned.
+//
+// Cron can't be restarted. Nil safe. Thread-safety.
+func (c *Cron) Stop(ctx context.Context) *ekaerr.Error {
+
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	wasStopped := c.isStopped
+	c.isStopped = true
+	for _, j := range c.jobs {
+		if j.timer != nil {
+			j.timer.Stop()
+		}
+	}
+	deathHandle := c.deathHandle
+	c.deathHandle = nil
+	c.mu.Unlock()
+
+	if !wasStopped {
+		deathHandle.Unregister()
+	}
+
+	if err := c.wait(ctx); err.IsNotNil() {
+		return err.Throw()
+	}
+
+	return nil
+}
+
+// Name returns a name the current CronJob has been added with. Nil safe.
+func (j *CronJob) Name() string {
+	if j == nil {
+		return ""
+	}
+	return j.name
+}
+
+// Expr returns a cron expression the current CronJob is run by. Nil safe.
+func (j *CronJob) Expr() *CronExpr {
+	if j == nil {
+		return nil
+	}
+	return j.expr
+}
+
+// Next returns a moment the current CronJob is planned to be run at
+// (w/o jitter). Returns 0 if Cron is not started or job is removed.
+// Nil safe. Thread-safety.
+func (j *CronJob) Next() Timestamp {
+
+	if j == nil {
+		return 0
+	}
+
+	j.cron.mu.Lock()
+	defer j.cron.mu.Unlock()
+
+	if j.isRemoved || j.cron.isStopped {
+		return 0
+	}
+	return j.next
+}
+
+// Remove removes the current CronJob from its Cron. It won't be run anymore,
+// but the running one is not interrupted. Nil safe. Thread-safety.
+func (j *CronJob) Remove() {
+
+	if j == nil {
+		return
+	}
+
+	c := j.cron
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if j.isRemoved {
+		return
+	}
+
+	j.isRemoved = true
+	if j.timer != nil {
+		j.timer.Stop()
+	}
+
+	for i, job := range c.jobs {
+		if job == j {
+			c.jobs = append(c.jobs[:i], c.jobs[i+1:]...)
+			break
+		}
+	}
+}
@@ -0,0 +1,412 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekatime
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/qioalice/ekago/v2/ekaerr"
+	"github.com/qioalice/ekago/v2/ekalog"
+)
+
+var (
+	// _CronMacros are cron expressions' macros and their 6 fields equivalents.
+	_CronMacros = map[string]string{
+		"@yearly":   "0 0 0 1 1 *",
+		"@annually": "0 0 0 1 1 *",
+		"@monthly":  "0 0 0 1 * *",
+		"@weekly":   "0 0 0 * * 0",
+		"@daily":    "0 0 0 * * *",
+		"@midnight": "0 0 0 * * *",
+		"@hourly":   "0 0 * * * *",
+	}
+
+	// _CronMonths, _CronWeekdays are names of months and days of week
+	// that may be used in cron expressions.
+	_CronMonths = map[string]int{
+		"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+		"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+	}
+	_CronWeekdays = map[string]int{
+		"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+	}
+)
+
+// parse parses 6 'fields' of cron expression (second is the first one)
+// and fills the current CronExpr.
+func (e *CronExpr) parse(fields []string) *ekaerr.Error {
+
+	var (
+		bits [6]uint64
+		err  *ekaerr.Error
+	)
+
+	bounds := [6]struct {
+		min, max int
+		names    map[string]int
+	}{
+		{0, 59, nil}, {0, 59, nil}, {0, 23, nil},
+		{1, 31, nil}, {1, 12, _CronMonths}, {0, 7, _CronWeekdays},
+	}
+
+	for i, field := range fields {
+		bits[i], err = cronParseField(field, bounds[i].min, bounds[i].max, bounds[i].names)
+		if err.IsNotNil() {
+			return err.AddFields("cron_field", field).Throw()
+		}
+	}
+
+	// Sunday is both of 0 and 7.
+	if bits[5]&(1<<7) != 0 {
+		bits[5] |= 1
+	}
+
+	e.second, e.minute = bits[0], bits[1]
+	e.hour, e.dom = uint32(bits[2]), uint32(bits[3])
+	e.month, e.dow = uint16(bits[4]), uint8(bits[5]&0x7F)
+
+	e.isDomStar = fields[3] == "*" || fields[3] == "?"
+	e.isDowStar = fields[5] == "*" || fields[5] == "?"
+
+	return nil
+}
+
+// cronParseField parses one field of cron expression, which values must be
+// in [min, max] range, returning a bitset of values.
+func cronParseField(field string, min, max int, names map[string]int) (uint64, *ekaerr.Error) {
+
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+
+		var (
+			from, to, step = min, max, 1
+			err            *ekaerr.Error
+		)
+
+		if slash := strings.IndexByte(part, '/'); slash != -1 {
+			step, err = cronParseValue(part[slash+1:], 1, max, nil)
+			if err.IsNotNil() {
+				return 0, err.Throw()
+			}
+			part = part[:slash]
+		}
+
+		switch dash := strings.IndexByte(part, '-'); {
+
+		case part == "*" || part == "?":
+
+		case dash != -1:
+			if from, err = cronParseValue(part[:dash], min, max, names); err.IsNotNil() {
+				return 0, err.Throw()
+			}
+			if to, err = cronParseValue(part[dash+1:], min, max, names); err.IsNotNil() {
+				return 0, err.Throw()
+			}
+
+		default:
+			if from, err = cronParseValue(part, min, max, names); err.IsNotNil() {
+				return 0, err.Throw()
+			}
+			if step == 1 {
+				to = from
+			}
+		}
+
+		if from > to {
+			return 0, ekaerr.IllegalFormat.
+				New("ekatime: cron field's range is reversed", "cron_part", part).
+				Throw()
+		}
+
+		for v := from; v <= to; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+
+	return bits, nil
+}
+
+// cronParseValue parses one value of cron expression's field
+// that must be in [min, max] range or be one of 'names'.
+func cronParseValue(s string, min, max int, names map[string]int) (int, *ekaerr.Error) {
+
+	if v, found := names[strings.ToUpper(s)]; found {
+		return v, nil
+	}
+
+	v, legacyErr := strconv.Atoi(s)
+	if legacyErr != nil || v < min || v > max {
+		return 0, ekaerr.IllegalFormat.
+			New("ekatime: cron field's value is invalid or out of range",
+				"cron_value", s, "cron_value_min", min, "cron_value_max", max).
+			Throw()
+	}
+
+	return v, nil
+}
+
+// matches reports whether 't' matches the current CronExpr.
+func (e *CronExpr) matches(t time.Time) bool {
+	return e.second&(1<<uint(t.Second())) != 0 &&
+		e.minute&(1<<uint(t.Minute())) != 0 &&
+		e.hour&(1<<uint(t.Hour())) != 0 &&
+		e.month&(1<<uint(t.Month())) != 0 &&
+		e.isDayOK(t)
+}
+
+// isDayOK reports whether the day of 't' matches day of month and day of week
+// of the current CronExpr.
+func (e *CronExpr) isDayOK(t time.Time) bool {
+
+	var (
+		isDomOK = e.dom&(1<<uint(t.Day())) != 0
+		isDowOK = e.dow&(1<<uint(t.Weekday())) != 0
+	)
+
+	if e.isDomStar || e.isDowStar {
+		return isDomOK && isDowOK
+	}
+	return isDomOK || isDowOK
+}
+
+// next returns the first moment that is after 'ts' and matches the current
+// CronExpr in the 'loc' time zone or 0.
+//
+// Moves the fields from the largest to the smallest one. Days and months are
+// moved using time.Date() (it normalizes them), while hours, minutes
+// and seconds are moved by the absolute time to be DST-correct.
+func (e *CronExpr) next(ts Timestamp, loc *time.Location) Timestamp {
+
+	t := (ts + 1).In(loc)
+	yearLimit := t.Year() + CRON_SEARCH_MAX_YEARS
+
+WRAP:
+	if t.Year() > yearLimit {
+		return 0
+	}
+
+	for e.month&(1<<uint(t.Month())) == 0 {
+		t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		if t.Year() > yearLimit {
+			return 0
+		}
+	}
+
+	for !e.isDayOK(t) {
+		month := t.Month()
+		t = time.Date(t.Year(), month, t.Day()+1, 0, 0, 0, 0, loc)
+		if t.Month() != month {
+			goto WRAP
+		}
+	}
+
+	for e.hour&(1<<uint(t.Hour())) == 0 {
+		day := t.Day()
+		t = t.Add(-time.Duration(t.Minute())*time.Minute - time.Duration(t.Second())*time.Second + time.Hour)
+		if t.Day() != day {
+			goto WRAP
+		}
+	}
+
+	for e.minute&(1<<uint(t.Minute())) == 0 {
+		hour := t.Hour()
+		t = t.Add(-time.Duration(t.Second())*time.Second + time.Minute)
+		if t.Hour() != hour {
+			goto WRAP
+		}
+	}
+
+	for e.second&(1<<uint(t.Second())) == 0 {
+		minute := t.Minute()
+		t = t.Add(time.Second)
+		if t.Minute() != minute {
+			goto WRAP
+		}
+	}
+
+	return UnixFromStd(t)
+}
+
+// plan calculates the next moment the current CronJob must be run at
+// that is after 'after' and starts (or resets) the job's timer.
+// Cron's mutex must be locked.
+func (j *CronJob) plan(after Timestamp) {
+
+	j.next = j.expr.next(after, normalizeLocation(j.cron.loc))
+	if j.next == 0 {
+		return
+	}
+
+	clock := j.cron.clock
+	d := j.next.Std().Sub(clock.Now())
+	if j.timer == nil {
+		j.timer = clock.AfterFunc(d, j.fire)
+	} else {
+		j.timer.Reset(d)
+	}
+}
+
+// fire is called when the current CronJob's timer is triggered.
+// Plans the next run and starts the current one in a new goroutine.
+func (j *CronJob) fire() {
+
+	c := j.cron
+	c.mu.Lock()
+
+	if j.isRemoved || c.isStopped {
+		c.mu.Unlock()
+		return
+	}
+
+	ts := j.next
+	if now := c.now(); now > ts {
+		j.plan(now)
+	} else {
+		j.plan(ts)
+	}
+
+	var (
+		calendar       = c.calendar
+		loc            = c.loc
+		clock          = c.clock
+		disableLogging = c.disableLogging
+		ctx            = c.ctx
+	)
+
+	c.wg.Add(1)
+	c.mu.Unlock()
+
+	go func() {
+		defer c.wg.Done()
+		j.run(ctx, ts, calendar, loc, clock, disableLogging)
+	}()
+}
+
+// run runs the current CronJob planned at 'ts' if it's allowed
+// by its options, logging an error if it's occurred.
+func (j *CronJob) run(
+	ctx context.Context,
+	ts Timestamp,
+	calendar *Calendar,
+	loc *time.Location,
+	clock Clock,
+	disableLogging bool,
+) {
+
+	if j.opts.WorkdaysOnly && !calendar.IsWorkday(ts.DateIn(loc)) {
+		return
+	}
+
+	if !j.opts.AllowOverlap {
+		if !atomic.CompareAndSwapInt32(&j.isRunning, 0, 1) {
+			if !disableLogging {
+				ekalog.Warn("ekatime.Cron job's run is skipped, because the previous one is not finished yet.",
+					"cron_job_name", j.name,
+					"cron_job_ts", ts.I64())
+			}
+			return
+		}
+		defer atomic.StoreInt32(&j.isRunning, 0)
+	}
+
+	if j.opts.Jitter > 0 {
+		jitterTimer := clock.NewTimer(time.Duration(rand.Int63n(int64(j.opts.Jitter))))
+		select {
+		case <-jitterTimer.C():
+		case <-ctx.Done():
+			jitterTimer.Stop()
+			return
+		}
+	}
+
+	if j.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, j.opts.Timeout)
+		defer cancel()
+	}
+
+	err := j.call(ctx, ts)
+	if err.IsNil() && ctx.Err() == context.DeadlineExceeded {
+		err = ekaerr.TimeoutElapsed.
+			New("ekatime: cron job's timeout is elapsed", "cron_job_timeout", j.opts.Timeout.String()).
+			Throw()
+	}
+
+	if err.IsNotNil() && !disableLogging {
+		err.AddFields("cron_job_name", j.name, "cron_job_ts", ts.I64()).
+			LogAsError("ekatime.Cron job has failed.")
+	}
+}
+
+// call calls the current CronJob's function, converting its panic to the error.
+func (j *CronJob) call(ctx context.Context, ts Timestamp) (err *ekaerr.Error) {
+
+	defer func() {
+		if panicObj := recover(); panicObj != nil {
+			err = ekaerr.InternalError.
+				New("ekatime: cron job has panicked", "cron_job_panic", fmt.Sprint(panicObj)).
+				Throw()
+		}
+	}()
+
+	return j.f(ctx, ts)
+}
+
+// now returns the current Timestamp using Cron's Clock.
+// Cron's mutex must be locked.
+func (c *Cron) now() Timestamp {
+	if c.clock == nil {
+		return Now()
+	}
+	return UnixFromStd(c.clock.Now())
+}
+
+// wait waits until all running jobs are finished or 'ctx' is done.
+// In the last case, jobs' context is cancelled and an error is returned.
+func (c *Cron) wait(ctx context.Context) *ekaerr.Error {
+
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	select {
+	case <-done:
+		return nil
+
+	case <-ctx.Done():
+		c.mu.Lock()
+		if c.cancel != nil {
+			c.cancel()
+		}
+		c.mu.Unlock()
+
+		return ekaerr.TimeoutElapsed.
+			New("ekatime: Cron's running jobs are not finished in time").
+			Throw()
+	}
+}
+
+// destructor is the ekadeath's destructor that stops the Cron
+// when service is going to die.
+func (c *Cron) destructor(ctx context.Context) error {
+	if err := c.Stop(ctx); err.IsNotNil() {
+		return ctx.Err()
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekatime_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/qioalice/ekago/v2/ekaerr"
+	"github.com/qioalice/ekago/v2/ekatime"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCronExpr_Next(t *testing.T) {
+
+	tests := []struct {
+		spec     string
+		from     ekatime.Timestamp
+		expected ekatime.Timestamp
+	}{
+		{"*/15 * * * *", ekatime.UnixFrom(2021, 1, 1, 10, 7, 30), ekatime.UnixFrom(2021, 1, 1, 10, 15, 0)},
+		{"30 9 * * MON-FRI", ekatime.UnixFrom(2021, 1, 1, 10, 0, 0), ekatime.UnixFrom(2021, 1, 4, 9, 30, 0)},
+		{"0 0 0 29 2 *", ekatime.UnixFrom(2021, 3, 1, 0, 0, 0), ekatime.UnixFrom(2024, 2, 29, 0, 0, 0)},
+		{"@monthly", ekatime.UnixFrom(2021, 1, 31, 12, 0, 0), ekatime.UnixFrom(2021, 2, 1, 0, 0, 0)},
+		{"0 12 13 * FRI", ekatime.UnixFrom(2021, 1, 1, 12, 0, 0), ekatime.UnixFrom(2021, 1, 8, 12, 0, 0)},
+		{"0 0 * * 7", ekatime.UnixFrom(2021, 1, 1, 0, 0, 0), ekatime.UnixFrom(2021, 1, 3, 0, 0, 0)},
+		{"0 0 30 2 *", ekatime.UnixFrom(2021, 1, 1, 0, 0, 0), 0},
+	}
+
+	for _, test := range tests {
+		e, err := ekatime.ParseCron(test.spec)
+		require.True(t, err.IsNil(), test.spec)
+		require.Equal(t, test.expected, e.Next(test.from, nil), test.spec)
+		if test.expected != 0 {
+			require.True(t, e.Matches(test.expected, nil), test.spec)
+		}
+	}
+
+	// 2021-03-14 02:30 does not exist in New York (DST starts).
+	ny := loadLocation(t, "America/New_York")
+	e, err := ekatime.ParseCron("30 2 * * *")
+	require.True(t, err.IsNil())
+	require.Equal(t,
+		ekatime.UnixFromIn(2021, 3, 15, 2, 30, 0, ny),
+		e.Next(ekatime.UnixFromIn(2021, 3, 13, 3, 0, 0, ny), ny))
+
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "5-1 * * * *", "@foo", "* * * JANUARY *"} {
+		_, err := ekatime.ParseCron(spec)
+		require.True(t, err.IsNotNil(), spec)
+	}
+}
+
+func TestCron(t *testing.T) {
+
+	var (
+		counter  int32
+		panicked int32
+	)
+
+	c := ekatime.NewCron().DisableLogging()
+
+	_, err := c.Add("counter", "* * * * * *", func(_ context.Context, _ ekatime.Timestamp) *ekaerr.Error {
+		atomic.AddInt32(&counter, 1)
+		return nil
+	}, nil)
+	require.True(t, err.IsNil())
+
+	_, err = c.Add("panic", "* * * * * *", func(_ context.Context, _ ekatime.Timestamp) *ekaerr.Error {
+		atomic.AddInt32(&panicked, 1)
+		panic("oops")
+	}, nil)
+	require.True(t, err.IsNil())
+
+	_, err = c.Add("invalid", "* * *", func(_ context.Context, _ ekatime.Timestamp) *ekaerr.Error {
+		return nil
+	}, nil)
+	require.True(t, err.IsNotNil())
+
+	c.RunAsync()
+
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&counter) >= 2 && atomic.LoadInt32(&panicked) >= 2
+	}, 4*time.Second, 50*time.Millisecond)
+
+	require.True(t, c.Stop(nil).IsNil())
+	stopped := atomic.LoadInt32(&counter)
+
+	time.Sleep(1100 * time.Millisecond)
+	require.Equal(t, stopped, atomic.LoadInt32(&counter))
+}
+
+func TestCron_StopTimeout(t *testing.T) {
+
+	var (
+		started   = make(chan struct{}, 1)
+		cancelled = make(chan struct{})
+		runs      int32
+	)
+
+	c := ekatime.NewCron().DisableLogging()
+
+	_, err := c.Add("blocked", "* * * * * *", func(ctx context.Context, _ ekatime.Timestamp) *ekaerr.Error {
+		if atomic.AddInt32(&runs, 1) == 1 {
+			started <- struct{}{}
+			<-ctx.Done()
+			close(cancelled)
+		}
+		return nil
+	}, nil)
+	require.True(t, err.IsNil())
+
+	c.RunAsync()
+
+	select {
+	case <-started:
+	case <-time.After(3 * time.Second):
+		t.Fatal("cron job has not been started")
+	}
+
+	// Next runs must be skipped while the first one is running (no overlap).
+	time.Sleep(1100 * time.Millisecond)
+	require.Equal(t, int32(1), atomic.LoadInt32(&runs))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	require.True(t, c.Stop(ctx).IsNotNil())
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("cron job's context has not been cancelled")
+	}
+}
@@ -219,6 +219,51 @@ func (dd Date) ISOWeek() WeekNumber {
 	return WeekNumber(1 + math.Floor(doy / 7))
 }
 
+// ISOYearWeek returns an ISO year and ISO week of the current Date.
+// ISO year may differ from the Date's year for the first and the last days
+// of year: e.g. 01 Jan 2021 is 53rd week of 2020 ISO year,
+// 30 Dec 2024 is 1st week of 2025 ISO year.
+//
+// Read more:
+// https://en.wikipedia.org/wiki/ISO_week_date
+func (dd Date) ISOYearWeek() (Year, WeekNumber) {
+	y, m, _ := dd.Split()
+	w := dd.ISOWeek()
+	switch {
+	case m == MONTH_JANUARY && w >= 52:
+		y--
+	case m == MONTH_DECEMBER && w == 1:
+		y++
+	}
+	return y, w
+}
+
+// NewDateFromISOWeek creates a new Date object using provided ISO year,
+// ISO week and day of week (e.g. 2020-W05-3 is 29 Jan 2020).
+// Like NewDate() the date is shifted if week is not in its valid range
+// (e.g. 53rd week of 2021 ISO year is 1st week of 2022 ISO year).
+//
+// Read more:
+// https://en.wikipedia.org/wiki/ISO_week_date
+func NewDateFromISOWeek(y Year, w WeekNumber, wd Weekday) Date {
+
+	// 04 Jan is always in the 1st ISO week.
+	jan4 := workdaysDayNum(NewDate(y, MONTH_JANUARY, 4))
+	monday := jan4 - Timestamp(workdaysWeekday(jan4).toISO() - 1)
+
+	return workdaysDate(monday + Timestamp(w-1) * 7 + Timestamp(wd.toISO() - 1))
+}
+
+// NewDateFromOrdinal creates a new Date object using provided year
+// and day of year (e.g. 2020-036 is 05 Feb 2020).
+// Like NewDate() the date is shifted if day of year is not in its valid range.
+//
+// Read more:
+// https://en.wikipedia.org/wiki/ISO_8601#Ordinal_dates
+func NewDateFromOrdinal(y Year, days Days) Date {
+	return NewDate(y, MONTH_JANUARY, 1).AddDays(days - 1)
+}
+
 // Split returns the year number, month number and day number the current Date
 // includes which.
 // It's just like a separate Year(), Month(), Day() calls.
@@ -59,6 +59,12 @@ func normalizeDate(y Year, m Month, d Day) (Year, Month, Day) {
 	return y, m, d
 }
 
+// isoWeeksInYear returns a number of ISO weeks (52 or 53) in the 'y' ISO year.
+func isoWeeksInYear(y Year) WeekNumber {
+	// 28 Dec is always in the last ISO week.
+	return NewDate(y, MONTH_DECEMBER, 28).ISOWeek()
+}
+
 // ensureWeekdayExist returns the current Date w/o modifications if it already
 // has a weekday or adds it and to and returns a copy.
 func (dd Date) ensureWeekdayExist() Date {
@@ -53,7 +53,7 @@ func initTable5() {
 	_Table5UpperBound = Year(time.Now().Year()) + 10
 	_Table5 = make([][12]Timestamp, _Table5UpperBound - 1970 +1)
 
-	d := time.Unix(1, 0)
+	d := time.Unix(0, 0).UTC()
 	for i, n := 0, len(_Table5); i < n; i++ {
 		for j := 0; j < 12; j++ {
 			_Table5[i][j] = Timestamp(d.Unix())
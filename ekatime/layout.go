@@ -0,0 +1,288 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekatime
+
+import (
+	"errors"
+	"time"
+
+	"github.com/qioalice/ekago/v2/ekaerr"
+)
+
+type (
+	// Layout is a compiled format of Date, Time or Timestamp string representation
+	// that is used to format and parse them.
+	//
+	// Layout may be created from Go's reference layout (see NewLayout())
+	// or from strftime-like format (see NewStrftimeLayout()).
+	// Compile Layout once and then use it as many times as you want:
+	// formatting and parsing using Layout do not allocate memory
+	// (if the destination buffer has enough space).
+	//
+	// Zero Layout is an empty layout: nothing is appended and only an empty
+	// string is parsed.
+	Layout struct {
+		elems  []layoutElem
+		source string
+	}
+)
+
+//goland:noinspection GoSnakeCaseUsage
+var (
+	_ERR_LAYOUT_MISMATCH   = errors.New("value does not match the layout")
+	_ERR_LAYOUT_EXTRA_TEXT = errors.New("extra text after the value parsed by the layout")
+	_ERR_BAD_YEAR_DAY      = errors.New("day of year must be in the range [1..365] or [1..366] for leap year")
+	_ERR_BAD_ISO_WEEK      = errors.New("ISO week must be in the range [1..52] or [1..53], depends on ISO year")
+	_ERR_BAD_WEEKDAY       = errors.New("day of week must be in the range [1..7] (ISO) or [0..6] (Sunday is 0)")
+	_ERR_BAD_OFFSET        = errors.New("time zone offset must be Z or in the range [-23:59..+23:59]")
+	_ERR_UNKNOWN_ZONE      = errors.New("unknown time zone abbreviation")
+)
+
+//goland:noinspection GoSnakeCaseUsage
+var (
+	// LAYOUT_ISO8601_DATE is "YYYY-MM-DD" layout, e.g: "2020-02-05".
+	LAYOUT_ISO8601_DATE = NewLayout("2006-01-02")
+
+	// LAYOUT_ISO8601_TIME is "hh:mm:ss" layout, e.g: "12:13:14".
+	LAYOUT_ISO8601_TIME = NewLayout("15:04:05")
+
+	// LAYOUT_ISO8601_WEEK_DATE is ISO 8601 week date layout "YYYY-Www-D",
+	// where YYYY is ISO year, ww is ISO week, D is ISO day of week
+	// (Monday is 1, Sunday is 7), e.g: "2020-W05-3".
+	LAYOUT_ISO8601_WEEK_DATE = Layout{
+		elems: []layoutElem{
+			{kind: _LAYOUT_ELEM_ISO_YEAR}, {lit: "-W"},
+			{kind: _LAYOUT_ELEM_ISO_WEEK}, {lit: "-"},
+			{kind: _LAYOUT_ELEM_WEEKDAY_ISO},
+		},
+		source: "%G-W%V-%u",
+	}
+
+	// LAYOUT_ISO8601_ORDINAL_DATE is ISO 8601 ordinal date layout "YYYY-DDD",
+	// where DDD is day of year, e.g: "2020-036".
+	LAYOUT_ISO8601_ORDINAL_DATE = Layout{
+		elems: []layoutElem{
+			{kind: _LAYOUT_ELEM_YEAR}, {lit: "-"},
+			{kind: _LAYOUT_ELEM_YEAR_DAY},
+		},
+		source: "%Y-%j",
+	}
+
+	// LAYOUT_RFC3339 is RFC 3339 layout "YYYY-MM-DDThh:mm:ss±hh:mm",
+	// e.g: "2020-02-05T12:13:14Z", "2020-02-05T15:13:14+03:00".
+	// Fractional seconds are accepted (and truncated) when parsing.
+	LAYOUT_RFC3339 = NewLayout("2006-01-02T15:04:05Z07:00")
+)
+
+// NewLayout compiles Go's reference 'layout' (like time.Time.Format() uses),
+// e.g: "2006-01-02 15:04:05", "Mon, 02 Jan 2006 15:04:05 -0700", "Jan _2 3:04PM".
+//
+// Supported elements:
+//   "2006", "06"          - year (4 and 2 digits),
+//   "01", "1"             - month (zero padded and not),
+//   "January", "Jan"      - month's name (full and short),
+//   "02", "2", "_2"       - day of month (zero, not and space padded),
+//   "002"                 - day of year (zero padded, 3 digits),
+//   "Monday", "Mon"       - day of week's name (full and short),
+//   "15", "03", "3"       - hour (24h, 12h zero padded and not),
+//   "04", "4"             - minute (zero padded and not),
+//   "05", "5"             - second (zero padded and not),
+//   "PM", "pm"            - AM/PM mark (upper and lower case),
+//   "MST"                 - time zone abbreviation,
+//   "Z07:00", "Z0700", "Z07" - time zone offset or Z for UTC,
+//   "-07:00", "-0700", "-07" - time zone offset.
+//
+// All other bytes are literals. Fractional seconds are not supported
+// (because of Timestamp's precision), but they are accepted (and truncated)
+// when parsing if they follow the second.
+func NewLayout(layout string) Layout {
+	return Layout{
+		elems:  layoutCompileGo(layout),
+		source: layout,
+	}
+}
+
+// NewStrftimeLayout compiles strftime-like 'format', e.g: "%Y-%m-%d %H:%M:%S".
+// Returns an error if 'format' contains unknown or incomplete directive.
+//
+// Supported directives:
+//   %Y, %y     - year (4 and 2 digits),
+//   %G         - ISO year (4 digits, see Date.ISOYearWeek()),
+//   %m         - month (zero padded),
+//   %B, %b, %h - month's name (full and short),
+//   %d, %e     - day of month (zero and space padded),
+//   %j         - day of year (zero padded, 3 digits),
+//   %A, %a     - day of week's name (full and short),
+//   %u, %w     - day of week's number (Monday is 1, Sunday is 7; Sunday is 0),
+//   %V         - ISO week (zero padded),
+//   %H, %I     - hour (24h and 12h, zero padded),
+//   %M, %S     - minute, second (zero padded),
+//   %p, %P     - AM/PM mark (upper and lower case),
+//   %Z         - time zone abbreviation,
+//   %z, %:z    - time zone offset ("-0700" and "-07:00"),
+//   %F, %T, %R, %D - the same as "%Y-%m-%d", "%H:%M:%S", "%H:%M", "%m/%d/%y",
+//   %n, %t, %% - new line, tab and '%' literals.
+func NewStrftimeLayout(format string) (Layout, *ekaerr.Error) {
+
+	elems, err := layoutCompileStrftime(format)
+	if err.IsNotNil() {
+		return Layout{}, err.Throw()
+	}
+
+	return Layout{elems: elems, source: format}, nil
+}
+
+// String returns the source Go's reference layout or strftime-like format
+// the current Layout has been compiled from.
+func (l Layout) String() string {
+	return l.source
+}
+
+// AppendLayout appends the current Date's string representation
+// formatted by 'l' to 'b' and returns an extended slice.
+// Time's elements are formatted as midnight UTC.
+func (dd Date) AppendLayout(b []byte, l Layout) []byte {
+	y, m, d := normalizeDate(dd.Split())
+	return l.appendTo(b, &layoutValue{y: y, m: m, d: d, zone: "UTC"})
+}
+
+// Format returns the current Date's string representation formatted by 'l'.
+func (dd Date) Format(l Layout) string {
+	return string(dd.AppendLayout(make([]byte, 0, 32), l))
+}
+
+// ParseLayout parses 'b' using 'l' layout and saves the parsed date
+// into the current Date object. Skips leading spaces, but 'b' must not contain
+// any data after the value.
+//
+// Date may be specified by year, month, day of month or by year, day of year
+// or by ISO year, ISO week, day of week. Absent year is 1970,
+// absent month or day is 1. Time's elements are parsed but ignored.
+func (dd *Date) ParseLayout(b []byte, l Layout) error {
+
+	if dd == nil {
+		return _ERR_NIL_DATE_RECEIVER
+	}
+
+	var p layoutParsed
+	if err := l.parse(b, &p); err != nil {
+		return err
+	}
+
+	date, err := p.date()
+	if err != nil {
+		return err
+	}
+
+	*dd = date
+	return nil
+}
+
+// AppendLayout appends the current Time's string representation
+// formatted by 'l' to 'b' and returns an extended slice.
+// Date's elements are formatted as 01 Jan 1970 UTC.
+func (t Time) AppendLayout(b []byte, l Layout) []byte {
+	hh, mm, ss := t.Split()
+	return l.appendTo(b, &layoutValue{
+		y: 1970, m: MONTH_JANUARY, d: 1, hh: hh, mm: mm, ss: ss, zone: "UTC"})
+}
+
+// Format returns the current Time's string representation formatted by 'l'.
+func (t Time) Format(l Layout) string {
+	return string(t.AppendLayout(make([]byte, 0, 32), l))
+}
+
+// ParseLayout parses 'b' using 'l' layout and saves the parsed time
+// into the current Time object. Skips leading spaces, but 'b' must not contain
+// any data after the value. Absent hour, minute or second is 0.
+// Date's and time zone's elements are parsed but ignored.
+func (t *Time) ParseLayout(b []byte, l Layout) error {
+
+	if t == nil {
+		return _ERR_NIL_TIME_RECEIVER
+	}
+
+	var p layoutParsed
+	if err := l.parse(b, &p); err != nil {
+		return err
+	}
+
+	parsed, err := p.time()
+	if err != nil {
+		return err
+	}
+
+	*t = parsed
+	return nil
+}
+
+// AppendLayout is the same as AppendLayoutIn() with UTC time zone.
+func (ts Timestamp) AppendLayout(b []byte, l Layout) []byte {
+	return ts.AppendLayoutIn(b, l, nil)
+}
+
+// AppendLayoutIn appends the current Timestamp's string representation
+// in the 'loc' time zone formatted by 'l' to 'b' and returns an extended slice.
+// Nil 'loc' means UTC.
+func (ts Timestamp) AppendLayoutIn(b []byte, l Layout, loc *time.Location) []byte {
+
+	t := ts.In(loc)
+	y, m, d := t.Date()
+	hh, mm, ss := t.Clock()
+	zone, offset := t.Zone()
+
+	return l.appendTo(b, &layoutValue{
+		y: Year(y), m: Month(m), d: Day(d),
+		hh: Hour(hh), mm: Minute(mm), ss: Second(ss),
+		offset: offset, zone: zone,
+	})
+}
+
+// Format returns the current Timestamp's string representation
+// in UTC formatted by 'l'.
+func (ts Timestamp) Format(l Layout) string {
+	return ts.FormatIn(l, nil)
+}
+
+// FormatIn returns the current Timestamp's string representation
+// in the 'loc' time zone formatted by 'l'. Nil 'loc' means UTC.
+func (ts Timestamp) FormatIn(l Layout, loc *time.Location) string {
+	return string(ts.AppendLayoutIn(make([]byte, 0, 32), l, loc))
+}
+
+// ParseLayout is the same as ParseLayoutIn() with UTC time zone.
+func (ts *Timestamp) ParseLayout(b []byte, l Layout) error {
+	return ts.ParseLayoutIn(b, l, nil)
+}
+
+// ParseLayoutIn parses 'b' using 'l' layout and saves the parsed timestamp
+// into the current Timestamp object. Skips leading spaces, but 'b' must not
+// contain any data after the value. See Date.ParseLayout()
+// and Time.ParseLayout() for the absent elements' values.
+//
+// If 'b' contains time zone offset, it's used. Otherwise date and time
+// are considered as the local ones in the 'loc' time zone (nil means UTC).
+// If 'b' contains only time zone abbreviation, it must be either "UTC", "GMT"
+// or the abbreviation 'loc' has at the parsed moment.
+func (ts *Timestamp) ParseLayoutIn(b []byte, l Layout, loc *time.Location) error {
+
+	if ts == nil {
+		return _ERR_NIL_TIMESTAMP_RECEIVER
+	}
+
+	var p layoutParsed
+	if err := l.parse(b, &p); err != nil {
+		return err
+	}
+
+	parsed, err := p.timestamp(normalizeLocation(loc))
+	if err != nil {
+		return err
+	}
+
+	*ts = parsed
+	return nil
+}
@@ -0,0 +1,844 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekatime
+
+import (
+	"strings"
+	"time"
+
+	"github.com/qioalice/ekago/v2/ekaerr"
+)
+
+type (
+	// layoutElemKind is a kind of Layout's element.
+	layoutElemKind uint8
+
+	// layoutElem is a compiled element of Layout: either a literal
+	// or a date, time, time zone's part.
+	layoutElem struct {
+		kind layoutElemKind
+		lit  string
+	}
+
+	// layoutValue is a set of values that are formatted by Layout.
+	layoutValue struct {
+		y      Year
+		m      Month
+		d      Day
+		hh     Hour
+		mm     Minute
+		ss     Second
+		offset int // seconds east of UTC
+		zone   string
+	}
+
+	// layoutParsed is a set of values that are parsed using Layout.
+	// Days of week are stored as ISO days of week (Monday is 1, Sunday is 7).
+	layoutParsed struct {
+		y, m, d, yearDay          int
+		isoYear, isoWeek, weekday int
+		hh, mm, ss, offset        int
+		zone                      []byte
+
+		hasYear, hasMonth, hasDay, hasYearDay bool
+		hasISOYear, hasISOWeek, hasWeekday    bool
+		hasHour12, isPM, hasOffset            bool
+	}
+)
+
+//noinspection GoSnakeCaseUsage
+const (
+	_LAYOUT_ELEM_LITERAL layoutElemKind = iota
+	_LAYOUT_ELEM_YEAR
+	_LAYOUT_ELEM_YEAR2
+	_LAYOUT_ELEM_ISO_YEAR
+	_LAYOUT_ELEM_MONTH
+	_LAYOUT_ELEM_MONTH_NOPAD
+	_LAYOUT_ELEM_MONTH_SHORT
+	_LAYOUT_ELEM_MONTH_LONG
+	_LAYOUT_ELEM_DAY
+	_LAYOUT_ELEM_DAY_NOPAD
+	_LAYOUT_ELEM_DAY_SPACE
+	_LAYOUT_ELEM_YEAR_DAY
+	_LAYOUT_ELEM_WEEKDAY_SHORT
+	_LAYOUT_ELEM_WEEKDAY_LONG
+	_LAYOUT_ELEM_WEEKDAY_ISO
+	_LAYOUT_ELEM_WEEKDAY_06
+	_LAYOUT_ELEM_ISO_WEEK
+	_LAYOUT_ELEM_HOUR
+	_LAYOUT_ELEM_HOUR12
+	_LAYOUT_ELEM_HOUR12_NOPAD
+	_LAYOUT_ELEM_MINUTE
+	_LAYOUT_ELEM_MINUTE_NOPAD
+	_LAYOUT_ELEM_SECOND
+	_LAYOUT_ELEM_SECOND_NOPAD
+	_LAYOUT_ELEM_PM
+	_LAYOUT_ELEM_PM_LOWER
+	_LAYOUT_ELEM_ZONE
+	_LAYOUT_ELEM_OFFSET_Z_COLON // Z07:00
+	_LAYOUT_ELEM_OFFSET_Z       // Z0700
+	_LAYOUT_ELEM_OFFSET_Z_HH    // Z07
+	_LAYOUT_ELEM_OFFSET_COLON   // -07:00
+	_LAYOUT_ELEM_OFFSET         // -0700
+	_LAYOUT_ELEM_OFFSET_HH      // -07
+)
+
+var (
+	// _LayoutMonths is just English names of months.
+	_LayoutMonths = [...]string{
+		"January", "February", "March", "April", "May", "June", "July",
+		"August", "September", "October", "November", "December",
+	}
+
+	// _LayoutGoChunks are Go's reference layout elements.
+	// The order matters: the longest elements must be checked first.
+	_LayoutGoChunks = [...]struct {
+		chunk string
+		kind  layoutElemKind
+	}{
+		{"January", _LAYOUT_ELEM_MONTH_LONG}, {"Jan", _LAYOUT_ELEM_MONTH_SHORT},
+		{"Monday", _LAYOUT_ELEM_WEEKDAY_LONG}, {"Mon", _LAYOUT_ELEM_WEEKDAY_SHORT},
+		{"MST", _LAYOUT_ELEM_ZONE},
+		{"2006", _LAYOUT_ELEM_YEAR}, {"002", _LAYOUT_ELEM_YEAR_DAY},
+		{"01", _LAYOUT_ELEM_MONTH}, {"02", _LAYOUT_ELEM_DAY}, {"03", _LAYOUT_ELEM_HOUR12},
+		{"04", _LAYOUT_ELEM_MINUTE}, {"05", _LAYOUT_ELEM_SECOND}, {"06", _LAYOUT_ELEM_YEAR2},
+		{"15", _LAYOUT_ELEM_HOUR}, {"1", _LAYOUT_ELEM_MONTH_NOPAD},
+		{"2", _LAYOUT_ELEM_DAY_NOPAD}, {"_2", _LAYOUT_ELEM_DAY_SPACE},
+		{"3", _LAYOUT_ELEM_HOUR12_NOPAD}, {"4", _LAYOUT_ELEM_MINUTE_NOPAD},
+		{"5", _LAYOUT_ELEM_SECOND_NOPAD},
+		{"PM", _LAYOUT_ELEM_PM}, {"pm", _LAYOUT_ELEM_PM_LOWER},
+		{"Z07:00", _LAYOUT_ELEM_OFFSET_Z_COLON}, {"Z0700", _LAYOUT_ELEM_OFFSET_Z},
+		{"Z07", _LAYOUT_ELEM_OFFSET_Z_HH},
+		{"-07:00", _LAYOUT_ELEM_OFFSET_COLON}, {"-0700", _LAYOUT_ELEM_OFFSET},
+		{"-07", _LAYOUT_ELEM_OFFSET_HH},
+	}
+
+	// _LayoutStrftime are strftime-like directives (w/o leading '%')
+	// and the elements they are compiled to.
+	_LayoutStrftime = map[byte][]layoutElem{
+		'Y': {{kind: _LAYOUT_ELEM_YEAR}},
+		'y': {{kind: _LAYOUT_ELEM_YEAR2}},
+		'G': {{kind: _LAYOUT_ELEM_ISO_YEAR}},
+		'm': {{kind: _LAYOUT_ELEM_MONTH}},
+		'B': {{kind: _LAYOUT_ELEM_MONTH_LONG}},
+		'b': {{kind: _LAYOUT_ELEM_MONTH_SHORT}},
+		'h': {{kind: _LAYOUT_ELEM_MONTH_SHORT}},
+		'd': {{kind: _LAYOUT_ELEM_DAY}},
+		'e': {{kind: _LAYOUT_ELEM_DAY_SPACE}},
+		'j': {{kind: _LAYOUT_ELEM_YEAR_DAY}},
+		'A': {{kind: _LAYOUT_ELEM_WEEKDAY_LONG}},
+		'a': {{kind: _LAYOUT_ELEM_WEEKDAY_SHORT}},
+		'u': {{kind: _LAYOUT_ELEM_WEEKDAY_ISO}},
+		'w': {{kind: _LAYOUT_ELEM_WEEKDAY_06}},
+		'V': {{kind: _LAYOUT_ELEM_ISO_WEEK}},
+		'H': {{kind: _LAYOUT_ELEM_HOUR}},
+		'I': {{kind: _LAYOUT_ELEM_HOUR12}},
+		'M': {{kind: _LAYOUT_ELEM_MINUTE}},
+		'S': {{kind: _LAYOUT_ELEM_SECOND}},
+		'p': {{kind: _LAYOUT_ELEM_PM}},
+		'P': {{kind: _LAYOUT_ELEM_PM_LOWER}},
+		'Z': {{kind: _LAYOUT_ELEM_ZONE}},
+		'z': {{kind: _LAYOUT_ELEM_OFFSET}},
+		'F': {
+			{kind: _LAYOUT_ELEM_YEAR}, {lit: "-"},
+			{kind: _LAYOUT_ELEM_MONTH}, {lit: "-"},
+			{kind: _LAYOUT_ELEM_DAY},
+		},
+		'T': {
+			{kind: _LAYOUT_ELEM_HOUR}, {lit: ":"},
+			{kind: _LAYOUT_ELEM_MINUTE}, {lit: ":"},
+			{kind: _LAYOUT_ELEM_SECOND},
+		},
+		'R': {
+			{kind: _LAYOUT_ELEM_HOUR}, {lit: ":"},
+			{kind: _LAYOUT_ELEM_MINUTE},
+		},
+		'D': {
+			{kind: _LAYOUT_ELEM_MONTH}, {lit: "/"},
+			{kind: _LAYOUT_ELEM_DAY}, {lit: "/"},
+			{kind: _LAYOUT_ELEM_YEAR2},
+		},
+		'n': {{lit: "\n"}},
+		't': {{lit: "\t"}},
+		'%': {{lit: "%"}},
+	}
+)
+
+// layoutCompileGo compiles Go's reference 'layout' to the Layout's elements.
+func layoutCompileGo(layout string) []layoutElem {
+
+	var (
+		elems []layoutElem
+		lit   = 0 // start of not yet saved literal
+	)
+
+	for i, n := 0, len(layout); i < n; {
+		kind, l := layoutGoChunk(layout[i:])
+		if l == 0 {
+			i++
+			continue
+		}
+		if lit < i {
+			elems = append(elems, layoutElem{lit: layout[lit:i]})
+		}
+		elems = append(elems, layoutElem{kind: kind})
+		i += l
+		lit = i
+	}
+
+	if lit < len(layout) {
+		elems = append(elems, layoutElem{lit: layout[lit:]})
+	}
+
+	return elems
+}
+
+// layoutGoChunk returns the kind and the length of Go's reference layout
+// element 's' is started with or 0 length if it's a literal.
+func layoutGoChunk(s string) (layoutElemKind, int) {
+
+	// "_2006" is a literal '_' followed by the year, like Go does.
+	if strings.HasPrefix(s, "_2006") {
+		return _LAYOUT_ELEM_LITERAL, 0
+	}
+
+	for i, n := 0, len(_LayoutGoChunks); i < n; i++ {
+		if strings.HasPrefix(s, _LayoutGoChunks[i].chunk) {
+			return _LayoutGoChunks[i].kind, len(_LayoutGoChunks[i].chunk)
+		}
+	}
+
+	return _LAYOUT_ELEM_LITERAL, 0
+}
+
+// layoutCompileStrftime compiles strftime-like 'format' to the Layout's elements.
+func layoutCompileStrftime(format string) ([]layoutElem, *ekaerr.Error) {
+
+	var (
+		elems []layoutElem
+		lit   = 0 // start of not yet saved literal
+	)
+
+	for i, n := 0, len(format); i < n; i++ {
+
+		if format[i] != '%' {
+			continue
+		}
+		if lit < i {
+			elems = append(elems, layoutElem{lit: format[lit:i]})
+		}
+
+		switch {
+		case i+1 == n:
+			return nil, ekaerr.IllegalFormat.
+				New("ekatime: strftime format has incomplete directive", "layout", format).
+				Throw()
+
+		case format[i+1] == ':' && i+2 < n && format[i+2] == 'z':
+			elems = append(elems, layoutElem{kind: _LAYOUT_ELEM_OFFSET_COLON})
+			i += 2
+
+		default:
+			directive, found := _LayoutStrftime[format[i+1]]
+			if !found {
+				return nil, ekaerr.IllegalFormat.
+					New("ekatime: strftime format has unknown directive",
+						"layout", format, "layout_directive", format[i:i+2]).
+					Throw()
+			}
+			elems = append(elems, directive...)
+			i++
+		}
+
+		lit = i + 1
+	}
+
+	if lit < len(format) {
+		elems = append(elems, layoutElem{lit: format[lit:]})
+	}
+
+	return elems, nil
+}
+
+// appendTo appends 'v' formatted by the current Layout to 'b'
+// and returns an extended slice.
+func (l Layout) appendTo(b []byte, v *layoutValue) []byte {
+
+	for i, n := 0, len(l.elems); i < n; i++ {
+		switch e := &l.elems[i]; e.kind {
+
+		case _LAYOUT_ELEM_LITERAL:
+			b = append(b, e.lit...)
+
+		case _LAYOUT_ELEM_YEAR:
+			b = layoutAppendInt(b, int(v.y), 4, '0')
+
+		case _LAYOUT_ELEM_YEAR2:
+			b = layoutAppendInt(b, int(v.y)%100, 2, '0')
+
+		case _LAYOUT_ELEM_ISO_YEAR:
+			y, _ := NewDate(v.y, v.m, v.d).ISOYearWeek()
+			b = layoutAppendInt(b, int(y), 4, '0')
+
+		case _LAYOUT_ELEM_MONTH:
+			b = layoutAppendInt(b, int(v.m), 2, '0')
+
+		case _LAYOUT_ELEM_MONTH_NOPAD:
+			b = layoutAppendInt(b, int(v.m), 1, '0')
+
+		case _LAYOUT_ELEM_MONTH_SHORT:
+			b = append(b, _LayoutMonths[v.m-1][:3]...)
+
+		case _LAYOUT_ELEM_MONTH_LONG:
+			b = append(b, _LayoutMonths[v.m-1]...)
+
+		case _LAYOUT_ELEM_DAY:
+			b = layoutAppendInt(b, int(v.d), 2, '0')
+
+		case _LAYOUT_ELEM_DAY_NOPAD:
+			b = layoutAppendInt(b, int(v.d), 1, '0')
+
+		case _LAYOUT_ELEM_DAY_SPACE:
+			b = layoutAppendInt(b, int(v.d), 2, ' ')
+
+		case _LAYOUT_ELEM_YEAR_DAY:
+			b = layoutAppendInt(b, int(NewDate(v.y, v.m, v.d).Days()), 3, '0')
+
+		case _LAYOUT_ELEM_WEEKDAY_SHORT:
+			b = append(b, _WeekdayStr[NewDate(v.y, v.m, v.d).Weekday()+1][:3]...)
+
+		case _LAYOUT_ELEM_WEEKDAY_LONG:
+			b = append(b, _WeekdayStr[NewDate(v.y, v.m, v.d).Weekday()+1]...)
+
+		case _LAYOUT_ELEM_WEEKDAY_ISO:
+			b = layoutAppendInt(b, int(NewDate(v.y, v.m, v.d).Weekday().toISO()), 1, '0')
+
+		case _LAYOUT_ELEM_WEEKDAY_06:
+			b = layoutAppendInt(b, int(NewDate(v.y, v.m, v.d).Weekday().To06()), 1, '0')
+
+		case _LAYOUT_ELEM_ISO_WEEK:
+			_, w := NewDate(v.y, v.m, v.d).ISOYearWeek()
+			b = layoutAppendInt(b, int(w), 2, '0')
+
+		case _LAYOUT_ELEM_HOUR:
+			b = layoutAppendInt(b, int(v.hh), 2, '0')
+
+		case _LAYOUT_ELEM_HOUR12:
+			b = layoutAppendInt(b, layoutHour12(v.hh), 2, '0')
+
+		case _LAYOUT_ELEM_HOUR12_NOPAD:
+			b = layoutAppendInt(b, layoutHour12(v.hh), 1, '0')
+
+		case _LAYOUT_ELEM_MINUTE:
+			b = layoutAppendInt(b, int(v.mm), 2, '0')
+
+		case _LAYOUT_ELEM_MINUTE_NOPAD:
+			b = layoutAppendInt(b, int(v.mm), 1, '0')
+
+		case _LAYOUT_ELEM_SECOND:
+			b = layoutAppendInt(b, int(v.ss), 2, '0')
+
+		case _LAYOUT_ELEM_SECOND_NOPAD:
+			b = layoutAppendInt(b, int(v.ss), 1, '0')
+
+		case _LAYOUT_ELEM_PM:
+			if v.hh >= 12 {
+				b = append(b, "PM"...)
+			} else {
+				b = append(b, "AM"...)
+			}
+
+		case _LAYOUT_ELEM_PM_LOWER:
+			if v.hh >= 12 {
+				b = append(b, "pm"...)
+			} else {
+				b = append(b, "am"...)
+			}
+
+		case _LAYOUT_ELEM_ZONE:
+			if v.zone != "" {
+				b = append(b, v.zone...)
+			} else {
+				// Like Go does, if there is no abbreviation, use offset.
+				b = layoutAppendOffset(b, v.offset, _LAYOUT_ELEM_OFFSET)
+			}
+
+		default:
+			b = layoutAppendOffset(b, v.offset, e.kind)
+		}
+	}
+
+	return b
+}
+
+// layoutAppendInt appends 'v' to 'b', padding it by 'pad'
+// up to 'width' bytes and returns an extended slice.
+func layoutAppendInt(b []byte, v, width int, pad byte) []byte {
+
+	var (
+		buf [20]byte
+		i   = len(buf)
+	)
+
+	if v < 0 {
+		b = append(b, '-')
+		v = -v
+	}
+
+	for {
+		i--
+		buf[i] = byte('0' + v%10)
+		if v /= 10; v == 0 {
+			break
+		}
+	}
+
+	for w := len(buf) - i; w < width; w++ {
+		b = append(b, pad)
+	}
+
+	return append(b, buf[i:]...)
+}
+
+// layoutAppendOffset appends time zone 'offset' (in seconds east of UTC)
+// in the format of 'kind' element to 'b' and returns an extended slice.
+func layoutAppendOffset(b []byte, offset int, kind layoutElemKind) []byte {
+
+	isZ := kind == _LAYOUT_ELEM_OFFSET_Z_COLON ||
+		kind == _LAYOUT_ELEM_OFFSET_Z || kind == _LAYOUT_ELEM_OFFSET_Z_HH
+
+	if isZ && offset == 0 {
+		return append(b, 'Z')
+	}
+
+	sign := byte('+')
+	if offset < 0 {
+		sign = '-'
+		offset = -offset
+	}
+
+	b = append(b, sign)
+	b = layoutAppendInt(b, offset/3600, 2, '0')
+
+	switch kind {
+	case _LAYOUT_ELEM_OFFSET_Z_HH, _LAYOUT_ELEM_OFFSET_HH:
+		return b
+	case _LAYOUT_ELEM_OFFSET_Z_COLON, _LAYOUT_ELEM_OFFSET_COLON:
+		b = append(b, ':')
+	}
+
+	return layoutAppendInt(b, offset/60%60, 2, '0')
+}
+
+// layoutHour12 returns 'hh' in 12h format.
+func layoutHour12(hh Hour) int {
+	if hh %= 12; hh == 0 {
+		return 12
+	}
+	return int(hh)
+}
+
+// parse parses 'b' using the current Layout saving parsed values into 'p'.
+func (l Layout) parse(b []byte, p *layoutParsed) error {
+
+	var i = 0
+	for n := len(b); i < n && b[i] <= ' '; i++ { }
+
+	for k, ne := 0, len(l.elems); k < ne; k++ {
+
+		var (
+			e = &l.elems[k]
+			s = b[i:]
+			v = 0
+			n = 0
+		)
+
+		switch e.kind {
+
+		case _LAYOUT_ELEM_LITERAL:
+			if layoutHasPrefixFold(s, e.lit) {
+				n = len(e.lit)
+			}
+
+		case _LAYOUT_ELEM_YEAR:
+			p.y, n = layoutParseInt(s, 4, 4)
+			p.hasYear = true
+
+		case _LAYOUT_ELEM_YEAR2:
+			if v, n = layoutParseInt(s, 2, 2); v >= 69 {
+				p.y = 1900 + v
+			} else {
+				p.y = 2000 + v
+			}
+			p.hasYear = true
+
+		case _LAYOUT_ELEM_ISO_YEAR:
+			p.isoYear, n = layoutParseInt(s, 4, 4)
+			p.hasISOYear = true
+
+		case _LAYOUT_ELEM_MONTH:
+			p.m, n = layoutParseInt(s, 2, 2)
+			p.hasMonth = true
+
+		case _LAYOUT_ELEM_MONTH_NOPAD:
+			p.m, n = layoutParseInt(s, 1, 2)
+			p.hasMonth = true
+
+		case _LAYOUT_ELEM_MONTH_SHORT, _LAYOUT_ELEM_MONTH_LONG:
+			v, n = layoutParseName(s, _LayoutMonths[:], e.kind == _LAYOUT_ELEM_MONTH_SHORT)
+			p.m, p.hasMonth = v+1, true
+
+		case _LAYOUT_ELEM_DAY:
+			p.d, n = layoutParseInt(s, 2, 2)
+			p.hasDay = true
+
+		case _LAYOUT_ELEM_DAY_NOPAD:
+			p.d, n = layoutParseInt(s, 1, 2)
+			p.hasDay = true
+
+		case _LAYOUT_ELEM_DAY_SPACE:
+			if len(s) > 0 && s[0] == ' ' {
+				if p.d, n = layoutParseInt(s[1:], 1, 1); n != 0 {
+					n++
+				}
+			} else {
+				p.d, n = layoutParseInt(s, 1, 2)
+			}
+			p.hasDay = true
+
+		case _LAYOUT_ELEM_YEAR_DAY:
+			p.yearDay, n = layoutParseInt(s, 3, 3)
+			p.hasYearDay = true
+
+		case _LAYOUT_ELEM_WEEKDAY_SHORT, _LAYOUT_ELEM_WEEKDAY_LONG:
+			v, n = layoutParseName(s, _WeekdayStr[1:], e.kind == _LAYOUT_ELEM_WEEKDAY_SHORT)
+			p.weekday, p.hasWeekday = int(Weekday(v).toISO()), true
+
+		case _LAYOUT_ELEM_WEEKDAY_ISO:
+			if v, n = layoutParseInt(s, 1, 1); n != 0 && (v < 1 || v > 7) {
+				return _ERR_BAD_WEEKDAY
+			}
+			p.weekday, p.hasWeekday = v, true
+
+		case _LAYOUT_ELEM_WEEKDAY_06:
+			if v, n = layoutParseInt(s, 1, 1); n != 0 && v > 6 {
+				return _ERR_BAD_WEEKDAY
+			}
+			p.weekday, p.hasWeekday = int(WeekdayFrom06(int8(v)).toISO()), true
+
+		case _LAYOUT_ELEM_ISO_WEEK:
+			p.isoWeek, n = layoutParseInt(s, 2, 2)
+			p.hasISOWeek = true
+
+		case _LAYOUT_ELEM_HOUR:
+			p.hh, n = layoutParseInt(s, 2, 2)
+
+		case _LAYOUT_ELEM_HOUR12:
+			p.hh, n = layoutParseInt(s, 2, 2)
+			p.hasHour12 = true
+
+		case _LAYOUT_ELEM_HOUR12_NOPAD:
+			p.hh, n = layoutParseInt(s, 1, 2)
+			p.hasHour12 = true
+
+		case _LAYOUT_ELEM_MINUTE:
+			p.mm, n = layoutParseInt(s, 2, 2)
+
+		case _LAYOUT_ELEM_MINUTE_NOPAD:
+			p.mm, n = layoutParseInt(s, 1, 2)
+
+		case _LAYOUT_ELEM_SECOND, _LAYOUT_ELEM_SECOND_NOPAD:
+			if e.kind == _LAYOUT_ELEM_SECOND {
+				p.ss, n = layoutParseInt(s, 2, 2)
+			} else {
+				p.ss, n = layoutParseInt(s, 1, 2)
+			}
+			if n != 0 {
+				n += layoutSkipFraction(s[n:], l.elems[k+1:])
+			}
+
+		case _LAYOUT_ELEM_PM, _LAYOUT_ELEM_PM_LOWER:
+			switch {
+			case layoutHasPrefixFold(s, "PM"):
+				n, p.isPM = 2, true
+			case layoutHasPrefixFold(s, "AM"):
+				n, p.isPM = 2, false
+			}
+
+		case _LAYOUT_ELEM_ZONE:
+			for n < len(s) && n < 5 && s[n] >= 'A' && s[n] <= 'Z' {
+				n++
+			}
+			if n < 3 {
+				n = 0
+			}
+			p.zone = s[:n]
+
+		default:
+			var isValid bool
+			if p.offset, n, isValid = layoutParseOffset(s, e.kind); n != 0 && !isValid {
+				return _ERR_BAD_OFFSET
+			}
+			p.hasOffset = true
+		}
+
+		if n == 0 {
+			return _ERR_LAYOUT_MISMATCH
+		}
+		i += n
+	}
+
+	if i < len(b) {
+		return _ERR_LAYOUT_EXTRA_TEXT
+	}
+
+	return nil
+}
+
+// layoutParseInt parses an unsigned integer that has
+// [minDigits..maxDigits] digits from the beginning of 's'.
+// Returns the parsed integer and the number of parsed digits
+// (0 if there is less than 'minDigits' digits).
+func layoutParseInt(s []byte, minDigits, maxDigits int) (int, int) {
+
+	v, n := 0, 0
+	for ; n < len(s) && n < maxDigits && s[n] >= '0' && s[n] <= '9'; n++ {
+		v = v*10 + int(s[n]-'0')
+	}
+
+	if n < minDigits {
+		return 0, 0
+	}
+	return v, n
+}
+
+// layoutParseName returns an index of the name from 'names' (or its 3 bytes
+// prefix if 'short' is true) 's' is started with (case insensitive)
+// and its length, or 0 length if there is no such name.
+func layoutParseName(s []byte, names []string, short bool) (int, int) {
+	for i, name := range names {
+		if short {
+			name = name[:3]
+		}
+		if layoutHasPrefixFold(s, name) {
+			return i, len(name)
+		}
+	}
+	return 0, 0
+}
+
+// layoutHasPrefixFold reports whether 's' is started with 'prefix',
+// ignoring the case of ASCII letters.
+func layoutHasPrefixFold(s []byte, prefix string) bool {
+
+	if len(s) < len(prefix) {
+		return false
+	}
+
+	for i, n := 0, len(prefix); i < n; i++ {
+		c1, c2 := s[i], prefix[i]
+		if c1 != c2 && !(c1|0x20 == c2|0x20 && c1|0x20 >= 'a' && c1|0x20 <= 'z') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// layoutSkipFraction returns the length of fractional seconds ('.' or ','
+// followed by digits) 's' is started with, or 0 if there is no one
+// or 'next' Layout's elements are expecting the same separator.
+func layoutSkipFraction(s []byte, next []layoutElem) int {
+
+	if len(s) < 2 || (s[0] != '.' && s[0] != ',') || s[1] < '0' || s[1] > '9' {
+		return 0
+	}
+	if len(next) > 0 && next[0].kind == _LAYOUT_ELEM_LITERAL && next[0].lit[0] == s[0] {
+		return 0
+	}
+
+	n := 1
+	for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+		n++
+	}
+	return n
+}
+
+// layoutParseOffset parses time zone offset in the format of 'kind' element
+// from the beginning of 's'. Returns the offset in seconds east of UTC,
+// its length (0 if 's' is not started with an offset)
+// and whether the offset is in its valid range.
+func layoutParseOffset(s []byte, kind layoutElemKind) (int, int, bool) {
+
+	isZ := kind == _LAYOUT_ELEM_OFFSET_Z_COLON ||
+		kind == _LAYOUT_ELEM_OFFSET_Z || kind == _LAYOUT_ELEM_OFFSET_Z_HH
+
+	if isZ && len(s) > 0 && (s[0] == 'Z' || s[0] == 'z') {
+		return 0, 1, true
+	}
+	if len(s) == 0 || (s[0] != '+' && s[0] != '-') {
+		return 0, 0, false
+	}
+
+	hh, n := layoutParseInt(s[1:], 2, 2)
+	if n == 0 {
+		return 0, 0, false
+	}
+
+	mm := 0
+	n = 3
+
+	switch kind {
+	case _LAYOUT_ELEM_OFFSET_Z_COLON, _LAYOUT_ELEM_OFFSET_COLON:
+		if n >= len(s) || s[n] != ':' {
+			return 0, 0, false
+		}
+		n++
+		fallthrough
+
+	case _LAYOUT_ELEM_OFFSET_Z, _LAYOUT_ELEM_OFFSET:
+		var l int
+		if mm, l = layoutParseInt(s[n:], 2, 2); l == 0 {
+			return 0, 0, false
+		}
+		n += l
+	}
+
+	offset := hh*3600 + mm*60
+	if s[0] == '-' {
+		offset = -offset
+	}
+
+	return offset, n, hh <= 23 && mm <= 59
+}
+
+// date returns the Date the current layoutParsed represents
+// or an error if parsed values are not in their valid ranges.
+func (p *layoutParsed) date() (Date, error) {
+
+	y := 1970
+	if p.hasYear {
+		y = p.y
+	}
+
+	if p.hasISOWeek {
+		if p.hasISOYear {
+			y = p.isoYear
+		}
+		if y > int(_YEAR_MAX) {
+			return 0, _ERR_BAD_YEAR
+		}
+		if p.isoWeek < 1 || p.isoWeek > int(isoWeeksInYear(Year(y))) {
+			return 0, _ERR_BAD_ISO_WEEK
+		}
+		w := WEEKDAY_MONDAY
+		if p.hasWeekday {
+			w = weekdayFromISO(int8(p.weekday))
+		}
+		return NewDateFromISOWeek(Year(y), WeekNumber(p.isoWeek), w), nil
+	}
+
+	if y > int(_YEAR_MAX) {
+		return 0, _ERR_BAD_YEAR
+	}
+
+	if p.hasYearDay {
+		if p.yearDay < 1 || p.yearDay > 365 && !(p.yearDay == 366 && IsLeap(Year(y))) {
+			return 0, _ERR_BAD_YEAR_DAY
+		}
+		dd := NewDateFromOrdinal(Year(y), Days(p.yearDay))
+		if p.hasMonth && Month(p.m) != dd.Month() || p.hasDay && Day(p.d) != dd.Day() {
+			return 0, _ERR_BAD_CORRESP_DATE
+		}
+		return dd, nil
+	}
+
+	m, d := MONTH_JANUARY, Day(1)
+	if p.hasMonth {
+		m = Month(p.m)
+	}
+	if p.hasDay {
+		d = Day(p.d)
+	}
+
+	switch {
+	case m < MONTH_JANUARY || m > MONTH_DECEMBER:
+		return 0, _ERR_BAD_MONTH
+	case d < 1 || d > 31:
+		return 0, _ERR_BAD_DAY
+	case !IsValidDate(Year(y), m, d):
+		return 0, _ERR_BAD_CORRESP_DATE
+	}
+
+	return NewDate(Year(y), m, d), nil
+}
+
+// time returns the Time the current layoutParsed represents
+// or an error if parsed values are not in their valid ranges.
+func (p *layoutParsed) time() (Time, error) {
+
+	hh := p.hh
+	if p.hasHour12 {
+		if hh < 1 || hh > 12 {
+			return 0, _ERR_BAD_HOUR
+		}
+		switch {
+		case p.isPM && hh < 12:
+			hh += 12
+		case !p.isPM && hh == 12:
+			hh = 0
+		}
+	}
+
+	switch {
+	case hh > 23:
+		return 0, _ERR_BAD_HOUR
+	case p.mm > 59:
+		return 0, _ERR_BAD_MINUTE
+	case p.ss > 59:
+		return 0, _ERR_BAD_SECOND
+	}
+
+	return NewTime(Hour(hh), Minute(p.mm), Second(p.ss)), nil
+}
+
+// timestamp returns the Timestamp the current layoutParsed represents
+// (considering it's in the 'loc' time zone if there is no parsed offset)
+// or an error if parsed values are not in their valid ranges.
+func (p *layoutParsed) timestamp(loc *time.Location) (Timestamp, error) {
+
+	dd, err := p.date()
+	if err != nil {
+		return 0, err
+	}
+
+	t, err := p.time()
+	if err != nil {
+		return 0, err
+	}
+
+	y, m, d := dd.Split()
+	hh, mm, ss := t.Split()
+
+	switch zone := p.zone; {
+
+	case p.hasOffset:
+		return UnixFrom(y, m, d, hh, mm, ss) - Timestamp(p.offset), nil
+
+	case string(zone) == "UTC" || string(zone) == "GMT":
+		return UnixFrom(y, m, d, hh, mm, ss), nil
+
+	case len(zone) != 0:
+		ts := UnixFromIn(y, m, d, hh, mm, ss, loc)
+		if name, _ := ts.In(loc).Zone(); name != string(zone) {
+			return 0, _ERR_UNKNOWN_ZONE
+		}
+		return ts, nil
+
+	default:
+		return UnixFromIn(y, m, d, hh, mm, ss, loc), nil
+	}
+}
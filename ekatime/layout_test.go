@@ -0,0 +1,222 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekatime_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/qioalice/ekago/v2/ekatime"
+
+	"github.com/stretchr/testify/require"
+)
+
+func mustStrftime(t *testing.T, format string) ekatime.Layout {
+	l, err := ekatime.NewStrftimeLayout(format)
+	require.True(t, err.IsNil(), format)
+	return l
+}
+
+func TestLayout_Format(t *testing.T) {
+
+	ts := ekatime.UnixFrom(2020, 2, 5, 15, 4, 5)
+	moscow := loadLocation(t, "Europe/Moscow")
+
+	tests := []struct {
+		layout   ekatime.Layout
+		expected string
+	}{
+		{ekatime.NewLayout("2006-01-02 15:04:05"), "2020-02-05 15:04:05"},
+		{ekatime.NewLayout("Mon, 02 Jan 2006 15:04:05 -0700"), "Wed, 05 Feb 2020 15:04:05 +0000"},
+		{ekatime.NewLayout("Monday, January _2 '06 3:4:5pm MST"), "Wednesday, February  5 '20 3:4:5pm UTC"},
+		{ekatime.NewLayout("2006.002 03PM Z07:00"), "2020.036 03PM Z"},
+		{ekatime.NewLayout("_2006/1/2"), "_2020/2/5"},
+		{mustStrftime(t, "%F %T %z"), "2020-02-05 15:04:05 +0000"},
+		{mustStrftime(t, "%a %b %e %I:%M %p %%Y"), "Wed Feb  5 03:04 PM %Y"},
+		{mustStrftime(t, "%G-W%V-%u %j %w %D"), "2020-W06-3 036 3 02/05/20"},
+		{ekatime.LAYOUT_ISO8601_WEEK_DATE, "2020-W06-3"},
+		{ekatime.LAYOUT_ISO8601_ORDINAL_DATE, "2020-036"},
+		{ekatime.LAYOUT_RFC3339, "2020-02-05T15:04:05Z"},
+	}
+
+	for _, test := range tests {
+		require.Equal(t, test.expected, ts.Format(test.layout), test.layout.String())
+	}
+
+	require.Equal(t, "2020-02-05T18:04:05+03:00", ts.FormatIn(ekatime.LAYOUT_RFC3339, moscow))
+	require.Equal(t, "18:04 MSK +03 +03:00", ts.FormatIn(ekatime.NewLayout("15:04 MST -07 -07:00"), moscow))
+	require.Equal(t, "18:04 MSK +0300", ts.FormatIn(mustStrftime(t, "%R %Z %z"), moscow))
+
+	require.Equal(t, "2020-W53-5", ekatime.NewDate(2021, 1, 1).Format(ekatime.LAYOUT_ISO8601_WEEK_DATE))
+	require.Equal(t, "2025-W01-1", ekatime.NewDate(2024, 12, 30).Format(ekatime.LAYOUT_ISO8601_WEEK_DATE))
+	require.Equal(t, "12:00 AM", ekatime.NewTime(0, 0, 0).Format(ekatime.NewLayout("03:04 PM")))
+
+	_, err := ekatime.NewStrftimeLayout("%Y-%q")
+	require.True(t, err.IsNotNil())
+	_, err = ekatime.NewStrftimeLayout("%Y%")
+	require.True(t, err.IsNotNil())
+}
+
+func TestLayout_Parse(t *testing.T) {
+
+	tests := []struct {
+		layout   ekatime.Layout
+		s        string
+		expected ekatime.Timestamp
+	}{
+		{ekatime.NewLayout("2006-01-02 15:04:05"), "2020-02-05 15:04:05", ekatime.UnixFrom(2020, 2, 5, 15, 4, 5)},
+		{ekatime.NewLayout("Mon, 02 Jan 2006 15:04:05 -0700"), "wed, 05 FEB 2020 18:04:05 +0300", ekatime.UnixFrom(2020, 2, 5, 15, 4, 5)},
+		{ekatime.NewLayout("January _2 06 3:4PM"), "February  5 20 12:4AM", ekatime.UnixFrom(2020, 2, 5, 0, 4, 0)},
+		{ekatime.NewLayout("Jan 2 3PM MST"), "Feb 5 3PM UTC", ekatime.UnixFrom(1970, 2, 5, 15, 0, 0)},
+		{mustStrftime(t, "%Y-%j"), "2020-366", ekatime.UnixFrom(2020, 12, 31, 0, 0, 0)},
+		{mustStrftime(t, "%G-W%V-%u %T"), "2020-W01-1 01:02:03", ekatime.UnixFrom(2019, 12, 30, 1, 2, 3)},
+		{mustStrftime(t, "%G-W%V"), "2020-W53", ekatime.UnixFrom(2020, 12, 28, 0, 0, 0)},
+		{mustStrftime(t, "%F %T%:z"), "2020-02-05 15:04:05-01:30", ekatime.UnixFrom(2020, 2, 5, 16, 34, 5)},
+		{ekatime.LAYOUT_RFC3339, "  2020-02-05t15:04:05.999z", ekatime.UnixFrom(2020, 2, 5, 15, 4, 5)},
+	}
+
+	for _, test := range tests {
+		var ts ekatime.Timestamp
+		require.NoError(t, ts.ParseLayout([]byte(test.s), test.layout), test.s)
+		require.Equal(t, test.expected, ts, test.s)
+	}
+
+	errTests := []struct {
+		layout ekatime.Layout
+		s      string
+	}{
+		{ekatime.LAYOUT_RFC3339, "2020-02-05T15:04:05"},
+		{ekatime.LAYOUT_RFC3339, "2020-02-05T15:04:05Z "},
+		{ekatime.LAYOUT_RFC3339, "2020-02-30T15:04:05Z"},
+		{ekatime.LAYOUT_RFC3339, "2020-02-05T24:04:05Z"},
+		{ekatime.LAYOUT_RFC3339, "2020-02-05T15:04:05+24:00"},
+		{ekatime.LAYOUT_ISO8601_ORDINAL_DATE, "2021-366"},
+		{ekatime.LAYOUT_ISO8601_WEEK_DATE, "2021-W53-1"},
+		{ekatime.LAYOUT_ISO8601_WEEK_DATE, "2021-W01-8"},
+		{ekatime.NewLayout("3PM"), "13PM"},
+		{ekatime.NewLayout("15 MST"), "10 XYZ"},
+		{mustStrftime(t, "%Y-%j %m"), "2020-036 03"},
+	}
+
+	for _, test := range errTests {
+		var ts ekatime.Timestamp
+		require.Error(t, ts.ParseLayout([]byte(test.s), test.layout), test.s)
+	}
+
+	// Local time in the time zone.
+	moscow := loadLocation(t, "Europe/Moscow")
+	var ts ekatime.Timestamp
+	require.NoError(t, ts.ParseLayoutIn([]byte("2020-02-05 18:04 MSK"), ekatime.NewLayout("2006-01-02 15:04 MST"), moscow))
+	require.Equal(t, ekatime.UnixFrom(2020, 2, 5, 15, 4, 0), ts)
+
+	var dd ekatime.Date
+	require.NoError(t, dd.ParseLayout([]byte("2020-W05-3"), ekatime.LAYOUT_ISO8601_WEEK_DATE))
+	require.True(t, dd.Equal(ekatime.NewDate(2020, 1, 29)))
+	require.NoError(t, dd.ParseLayout([]byte("5 Feb 2020 12:00"), ekatime.NewLayout("2 Jan 2006 15:04")))
+	require.True(t, dd.Equal(ekatime.NewDate(2020, 2, 5)))
+
+	var tt ekatime.Time
+	require.NoError(t, tt.ParseLayout([]byte("07:08:09 pm"), mustStrftime(t, "%I:%M:%S %P")))
+	require.Equal(t, ekatime.NewTime(19, 8, 9), tt)
+}
+
+func TestNewDateFromISOWeek(t *testing.T) {
+
+	for _, dd := range []ekatime.Date{
+		ekatime.NewDate(2019, 12, 30), ekatime.NewDate(2020, 1, 29), ekatime.NewDate(2021, 1, 3),
+		ekatime.NewDate(2024, 12, 29), ekatime.NewDate(2024, 12, 30), ekatime.NewDate(2026, 12, 31),
+	} {
+		y, w := dd.ISOYearWeek()
+		require.True(t, ekatime.NewDateFromISOWeek(y, w, dd.Weekday()).Equal(dd), dd.String())
+		require.True(t, ekatime.NewDateFromOrdinal(dd.Year(), dd.Days()).Equal(dd), dd.String())
+	}
+}
+
+func TestTimestamp_MarshalText(t *testing.T) {
+
+	ts := ekatime.UnixFrom(2020, 9, 12, 13, 14, 15)
+
+	b, err := ts.MarshalText()
+	require.NoError(t, err)
+	require.Equal(t, "2020-09-12T13:14:15Z", string(b))
+
+	var decoded ekatime.Timestamp
+	require.NoError(t, decoded.UnmarshalText([]byte("2020-09-12T16:14:15.5+03:00")))
+	require.Equal(t, ts, decoded)
+	require.Error(t, decoded.UnmarshalText([]byte("2020-09-12 13:14:15")))
+
+	// Map keys use text marshalling, JSON accepts RFC 3339 as well.
+	m := map[ekatime.Timestamp]int{ts: 1}
+	b, err = json.Marshal(m)
+	require.NoError(t, err)
+	require.Equal(t, `{"2020-09-12T13:14:15Z":1}`, string(b))
+
+	var decodedMap map[ekatime.Timestamp]int
+	require.NoError(t, json.Unmarshal(b, &decodedMap))
+	require.Equal(t, m, decodedMap)
+
+	var v struct {
+		TS ekatime.Timestamp `json:"ts"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"ts":"2020-09-12T15:14:15+02:00"}`), &v))
+	require.Equal(t, ts, v.TS)
+
+	// Struct value and struct pointer must be encoded the same way.
+	b, err = json.Marshal(v)
+	require.NoError(t, err)
+	require.Equal(t, `{"ts":"2020-09-12T13:14:15"}`, string(b))
+
+	b, err = json.Marshal(&v)
+	require.NoError(t, err)
+	require.Equal(t, `{"ts":"2020-09-12T13:14:15"}`, string(b))
+
+	v.TS = 0
+	b, err = json.Marshal(v)
+	require.NoError(t, err)
+	require.Equal(t, `{"ts":null}`, string(b))
+
+	var p struct {
+		TS *ekatime.Timestamp `json:"ts"`
+	}
+	b, err = json.Marshal(p)
+	require.NoError(t, err)
+	require.Equal(t, `{"ts":null}`, string(b))
+}
+
+func TestLayout_Allocs(t *testing.T) {
+
+	var (
+		ts     = ekatime.UnixFrom(2020, 9, 12, 13, 14, 15)
+		layout = mustStrftime(t, "%A, %d %B %Y %T %z (%G-W%V-%u, %j)")
+		b      = make([]byte, 0, 128)
+		s      = []byte("2020-09-12T16:14:15+03:00")
+	)
+
+	require.Zero(t, testing.AllocsPerRun(100, func() {
+		_ = ts.AppendLayout(b, layout)
+	}))
+	require.Zero(t, testing.AllocsPerRun(100, func() {
+		_ = ts.ParseLayout(s, ekatime.LAYOUT_RFC3339)
+	}))
+}
+
+func BenchmarkTimestamp_AppendLayout(b *testing.B) {
+	ts := ekatime.UnixFrom(2020, 9, 12, 13, 14, 15)
+	buf := make([]byte, 0, 64)
+	b.ResetTimer(); b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = ts.AppendLayout(buf, ekatime.LAYOUT_RFC3339)
+	}
+}
+
+func BenchmarkTimestamp_ParseLayout(b *testing.B) {
+	bd0 := []byte("2020-09-12T16:14:15+03:00")
+	var ts ekatime.Timestamp
+	b.ResetTimer(); b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = ts.ParseLayout(bd0, ekatime.LAYOUT_RFC3339)
+	}
+}
@@ -8,7 +8,6 @@ package ekatime
 import (
 	"sync"
 	"sync/atomic"
-	"time"
 )
 
 type (
@@ -34,7 +33,9 @@ type (
 		/* 4b */ t Time // cached current Time
 		/* -- */ cbs []OnceInCallback // callbacks that must be called when time has come
 		/* -- */ cbsMutex sync.Mutex
-		/* -- */ tm *time.Timer // timer that allows to update the cached data
+		/* -- */ tm ClockTimer // timer that allows to update the cached data
+		/* -- */ tmGeneration uint64 // incremented each time timer is restarted
+		/* -- */ tmMutex sync.Mutex // protects 'tm', 'tmGeneration', 'updateDelayInSec'
 		/* -- */ updateDelayInSec Timestamp // timer delay
 	}
 )
@@ -83,18 +84,39 @@ func (oiu *onceInUpdater) callbacksCaller() {
 
 // tick is a special method that is called when onceInUpdater's timer is triggered.
 // Updates the onceInUpdater's cached data, plans and runs timer at least one more time.
-func (oiu *onceInUpdater) tick() {
-	oiu.tm.Reset(oiu.update().TillNext(oiu.updateDelayInSec))
+// Does nothing if timer has been restarted since 'generation'.
+func (oiu *onceInUpdater) tick(generation uint64) {
+
+	oiu.tmMutex.Lock()
+	defer oiu.tmMutex.Unlock()
+
+	if oiu.tmGeneration == generation {
+		oiu.tm.Reset(oiu.update().TillNext(oiu.updateDelayInSec))
+	}
 }
 
-// run starts the onceInUpdater internal timer, fills the cached data by initial values.
+// run starts (or restarts) the onceInUpdater internal timer using DefaultClock(),
+// fills the cached data by initial values.
 func (oiu *onceInUpdater) run(delayInSec Timestamp) {
-	oiu.update()
+
+	oiu.tmMutex.Lock()
+	defer oiu.tmMutex.Unlock()
+
+	if oiu.tm != nil {
+		oiu.tm.Stop()
+	}
+
+	oiu.tmGeneration++
+	generation := oiu.tmGeneration
+
 	oiu.updateDelayInSec = delayInSec
-	oiu.tm = time.AfterFunc(Now().TillNext(delayInSec), oiu.tick)
+	oiu.tm = DefaultClock().AfterFunc(oiu.update().TillNext(delayInSec), func() {
+		oiu.tick(generation)
+	})
 }
 
-// initOnceIn initializes all package level onceInUpdater global variables.
+// initOnceIn initializes (or reinitializes after DefaultClock() is changed)
+// all package level onceInUpdater global variables.
 func initOnceIn() {
 	OnceInMinute.run(SECONDS_IN_MINUTE)
 	OnceIn10Minutes.run(SECONDS_IN_MINUTE * 10)
@@ -0,0 +1,195 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekatime
+
+import (
+	"time"
+)
+
+type (
+	// Period is an amount of time in terms of years, months, days
+	// and hours, minutes, seconds, like ISO 8601 duration "P1Y2M3DT4H5M6S".
+	//
+	// Unlike time.Duration, Period is calendar-aware: "P1M" is not
+	// a fixed number of seconds, but one calendar month.
+	// Each part may be negative. Parts are not normalized automatically,
+	// so "PT90M" remains 90 minutes (see Normalized()).
+	//
+	// Period is added to Date or Timestamp by the following rules:
+	//
+	//  1. Years and months are added first. If the day of month does not exist
+	//     in the resulting month, it's clamped to the last day of month
+	//     (31 Jan + P1M = 28 Feb or 29 Feb in leap year).
+	//  2. Days are added then (as calendar days).
+	//  3. Hours, minutes, seconds are added at last as an exact duration.
+	//     They are ignored when Period is added to Date.
+	//
+	// So, adding Period is not the same as adding its parts one by one:
+	// 30 Jan 2021 + P1M + P1M = 28 Mar 2021, but 30 Jan 2021 + P2M = 30 Mar 2021.
+	Period struct {
+		Years   int32
+		Months  int32
+		Days    int32
+		Hours   int32
+		Minutes int32
+		Seconds int32
+	}
+)
+
+// NewPeriod creates a new Period of passed years, months, days,
+// hours, minutes and seconds.
+func NewPeriod(years, months, days, hours, minutes, seconds int32) Period {
+	return Period{
+		Years:   years,
+		Months:  months,
+		Days:    days,
+		Hours:   hours,
+		Minutes: minutes,
+		Seconds: seconds,
+	}
+}
+
+// PeriodOfDuration creates a new Period of hours, minutes and seconds
+// that are the same as 'd' (rounded towards zero to seconds).
+func PeriodOfDuration(d time.Duration) Period {
+	secs := int64(d / time.Second)
+	return Period{
+		Hours:   int32(secs / 3600),
+		Minutes: int32(secs % 3600 / 60),
+		Seconds: int32(secs % 60),
+	}
+}
+
+// Between returns the Period between 'd1' and 'd2' dates in years, months
+// and days, such that d1.AddPeriod(Between(d1, d2)) == d2.
+// Period is negative (all its parts are <= 0) if 'd2' is before 'd1'.
+//
+// Examples:
+//     Between(NewDate(2020, 1, 15), NewDate(2021, 3, 20)) // P1Y2M5D
+//     Between(NewDate(2021, 1, 31), NewDate(2021, 2, 28)) // P28D
+//     Between(NewDate(2021, 3, 20), NewDate(2021, 1, 15)) // -P2M5D
+func Between(d1, d2 Date) Period {
+
+	y1, m1, dd1 := d1.Split()
+	y2, m2, dd2 := d2.Split()
+
+	var (
+		months = (int32(y2)*12 + int32(m2)) - (int32(y1)*12 + int32(m1))
+		days   = int32(dd2) - int32(dd1)
+	)
+
+	switch {
+	case months > 0 && days < 0:
+		months--
+	case months < 0 && days > 0:
+		months++
+	}
+
+	// Days are counted from the date months are added to, because the day of month
+	// may be clamped (31 Mar - 1 month is 28 Feb).
+	days = int32(workdaysDayNum(d2) - workdaysDayNum(periodAddMonths(d1, months)))
+
+	return Period{Years: months / 12, Months: months % 12, Days: days}
+}
+
+// IsZero reports whether all parts of the current Period are 0.
+func (p Period) IsZero() bool {
+	return p == Period{}
+}
+
+// IsNegative reports whether at least one part of the current Period
+// is negative.
+func (p Period) IsNegative() bool {
+	return p.Years < 0 || p.Months < 0 || p.Days < 0 ||
+		p.Hours < 0 || p.Minutes < 0 || p.Seconds < 0
+}
+
+// Negated returns the current Period with all its parts negated.
+func (p Period) Negated() Period {
+	return Period{
+		Years:   -p.Years,
+		Months:  -p.Months,
+		Days:    -p.Days,
+		Hours:   -p.Hours,
+		Minutes: -p.Minutes,
+		Seconds: -p.Seconds,
+	}
+}
+
+// Plus returns a new Period which parts are the sums of the current Period's
+// and 'other' Period's parts. Parts are not normalized.
+func (p Period) Plus(other Period) Period {
+	return Period{
+		Years:   p.Years + other.Years,
+		Months:  p.Months + other.Months,
+		Days:    p.Days + other.Days,
+		Hours:   p.Hours + other.Hours,
+		Minutes: p.Minutes + other.Minutes,
+		Seconds: p.Seconds + other.Seconds,
+	}
+}
+
+// Normalized returns the current Period with months normalized to years
+// (12 months is 1 year) and seconds, minutes normalized to minutes, hours
+// (60 seconds is 1 minute, 60 minutes is 1 hour). Days are not normalized,
+// because the number of days in month varies, as well as hours are not
+// normalized to days. Signs of parts are preserved (see Between()).
+func (p Period) Normalized() Period {
+
+	months := int64(p.Years)*12 + int64(p.Months)
+	secs := int64(p.Hours)*3600 + int64(p.Minutes)*60 + int64(p.Seconds)
+
+	return Period{
+		Years:   int32(months / 12),
+		Months:  int32(months % 12),
+		Days:    p.Days,
+		Hours:   int32(secs / 3600),
+		Minutes: int32(secs % 3600 / 60),
+		Seconds: int32(secs % 60),
+	}
+}
+
+// TimeDuration returns hours, minutes and seconds of the current Period
+// as time.Duration. Years, months and days are ignored,
+// because their durations vary.
+func (p Period) TimeDuration() time.Duration {
+	return time.Duration(p.seconds()) * time.Second
+}
+
+// AddPeriod returns a new Date that is the current Date with added Period 'p'.
+// Hours, minutes and seconds of Period are ignored.
+// See Period for the rules of adding.
+func (dd Date) AddPeriod(p Period) Date {
+	return periodAddMonths(dd, p.Years*12+p.Months).AddDays(Days(p.Days))
+}
+
+// SubPeriod is the same as AddPeriod(p.Negated()).
+func (dd Date) SubPeriod(p Period) Date {
+	return dd.AddPeriod(p.Negated())
+}
+
+// AddPeriod returns a new Timestamp that is the current Timestamp with added
+// Period 'p'. Date part is changed in UTC, keeping the time of day.
+// See Period for the rules of adding.
+func (ts Timestamp) AddPeriod(p Period) Timestamp {
+	dd, t := ts.Split()
+	return dd.AddPeriod(p).WithTime(t.Split()) + p.seconds()
+}
+
+// AddPeriodIn is the same as AddPeriod() but date part is changed
+// in the 'loc' time zone (nil means UTC), keeping the local time of day.
+// If the local time does not exist in the resulting day (DST gap)
+// or exists twice, the result is the same as Date.WithTimeIn() returns.
+func (ts Timestamp) AddPeriodIn(p Period, loc *time.Location) Timestamp {
+	dd, t := ts.SplitIn(loc)
+	hh, mm, ss := t.Split()
+	return dd.AddPeriod(p).WithTimeIn(hh, mm, ss, loc) + p.seconds()
+}
+
+// SubPeriod is the same as AddPeriod(p.Negated()).
+func (ts Timestamp) SubPeriod(p Period) Timestamp {
+	return ts.AddPeriod(p.Negated())
+}
@@ -0,0 +1,241 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekatime
+
+import (
+	"errors"
+	"math"
+	"strconv"
+
+	"github.com/qioalice/ekago/v2/internal/ekaenc"
+)
+
+//goland:noinspection GoSnakeCaseUsage
+var (
+	_ERR_NIL_PERIOD_RECEIVER = errors.New("nil ekatime.Period receiver")
+	_ERR_NOT_ISO8601_PERIOD  = errors.New("incorrect ISO8601 duration format (must be like P1Y2M3DT4H5M6S)")
+	_ERR_BAD_PERIOD_PART     = errors.New("ISO8601 duration's part is out of range")
+	_ERR_BAD_JSON_PERIOD_QUO = errors.New("bad JSON ISO8601 duration representation (forgotten quotes?)")
+)
+
+// AppendTo generates ISO8601 duration representation of Period
+// (like "P1Y2M3DT4H5M6S") and adds it to the b, returning a new slice.
+//
+// Zero parts are omitted, zero Period is "P0D".
+// If all non-zero parts are negative, Period is written with leading minus
+// and positive parts ("-P1M2D"), otherwise negative parts are written
+// with their signs ("P1M-2D").
+func (p Period) AppendTo(b []byte) []byte {
+
+	if p.IsZero() {
+		return append(b, "P0D"...)
+	}
+
+	if p.Years <= 0 && p.Months <= 0 && p.Days <= 0 &&
+		p.Hours <= 0 && p.Minutes <= 0 && p.Seconds <= 0 {
+		b = append(b, '-')
+		p = p.Negated()
+	}
+
+	f := func(b []byte, v int32, designator byte) []byte {
+		if v != 0 {
+			b = strconv.AppendInt(b, int64(v), 10)
+			b = append(b, designator)
+		}
+		return b
+	}
+
+	b = append(b, 'P')
+	b = f(b, p.Years, 'Y')
+	b = f(b, p.Months, 'M')
+	b = f(b, p.Days, 'D')
+
+	if p.Hours != 0 || p.Minutes != 0 || p.Seconds != 0 {
+		b = append(b, 'T')
+		b = f(b, p.Hours, 'H')
+		b = f(b, p.Minutes, 'M')
+		b = f(b, p.Seconds, 'S')
+	}
+
+	return b
+}
+
+// ParseFrom tries to parse b considering it's ISO8601 duration
+// in the following format: "[-]P[nY][nM][nW][nD][T[nH][nM][nS]]",
+// where each n may have its own sign (e.g. "P1M-2D"). Weeks are converted
+// to days. Fractions are not supported. Designators are case insensitive.
+//
+// Read more:
+// https://en.wikipedia.org/wiki/ISO_8601#Durations
+//
+// If success, returns nil and saves period into the current Period object.
+// Skips leading spaces.
+func (p *Period) ParseFrom(b []byte) error {
+
+	if p == nil {
+		return _ERR_NIL_PERIOD_RECEIVER
+	}
+
+	var i = 0
+	for n := len(b); i < n && b[i] <= ' '; i++ { }
+	b = b[i:]
+
+	isNegative := false
+	if len(b) > 0 && (b[0] == '-' || b[0] == '+') {
+		isNegative = b[0] == '-'
+		b = b[1:]
+	}
+
+	if len(b) < 3 || b[0] != 'P' && b[0] != 'p' {
+		return _ERR_NOT_ISO8601_PERIOD
+	}
+
+	var (
+		parsed   Period
+		order    = 0 // index of the last parsed designator in the "YMWDTHMS"
+		wasTime  = false
+		wasPart  = false
+		partsInT = 0
+	)
+
+	for i = 1; i < len(b); {
+
+		if b[i] == 'T' || b[i] == 't' {
+			if wasTime {
+				return _ERR_NOT_ISO8601_PERIOD
+			}
+			wasTime, order = true, 5
+			i++
+			continue
+		}
+
+		j := i
+		if b[j] == '-' || b[j] == '+' {
+			j++
+		}
+		for ; j < len(b) && b[j] >= '0' && b[j] <= '9'; j++ { }
+
+		if j == i || j == len(b) || b[j-1] < '0' || b[j-1] > '9' {
+			return _ERR_NOT_ISO8601_PERIOD
+		}
+
+		v, err := strconv.ParseInt(string(b[i:j]), 10, 32)
+		if err != nil {
+			return _ERR_BAD_PERIOD_PART
+		}
+
+		var (
+			ptr      *int32
+			newOrder int
+			mul      int64 = 1
+		)
+
+		switch designator := b[j] &^ 0x20; { // to upper case
+
+		case !wasTime && designator == 'Y':
+			ptr, newOrder = &parsed.Years, 1
+		case !wasTime && designator == 'M':
+			ptr, newOrder = &parsed.Months, 2
+		case !wasTime && designator == 'W':
+			ptr, newOrder, mul = &parsed.Days, 3, 7
+		case !wasTime && designator == 'D':
+			ptr, newOrder = &parsed.Days, 4
+		case wasTime && designator == 'H':
+			ptr, newOrder = &parsed.Hours, 6
+		case wasTime && designator == 'M':
+			ptr, newOrder = &parsed.Minutes, 7
+		case wasTime && designator == 'S':
+			ptr, newOrder = &parsed.Seconds, 8
+		default:
+			return _ERR_NOT_ISO8601_PERIOD
+		}
+
+		if newOrder <= order {
+			return _ERR_NOT_ISO8601_PERIOD
+		}
+
+		v = int64(*ptr) + v*mul
+		if v < math.MinInt32 || v > math.MaxInt32 {
+			return _ERR_BAD_PERIOD_PART
+		}
+
+		*ptr, order, wasPart = int32(v), newOrder, true
+		if wasTime {
+			partsInT++
+		}
+
+		i = j + 1
+	}
+
+	if !wasPart || wasTime && partsInT == 0 {
+		return _ERR_NOT_ISO8601_PERIOD
+	}
+
+	if isNegative {
+		parsed = parsed.Negated()
+	}
+
+	*p = parsed
+	return nil
+}
+
+// String returns the current Period's ISO8601 duration representation
+// like "P1Y2M3DT4H5M6S". See AppendTo() for more info.
+func (p Period) String() string {
+	return string(p.AppendTo(make([]byte, 0, 16)))
+}
+
+// MarshalJSON encodes the current Period as ISO8601 duration (quoted)
+// like "P1Y2M3DT4H5M6S", and returns it. Always returns nil as error.
+//
+// JSON null supporting:
+// - Writes JSON null if current Period receiver == nil,
+// - Writes JSON null if current Period is zero.
+func (p *Period) MarshalJSON() ([]byte, error) {
+
+	if p == nil || p.IsZero() {
+		return ekaenc.NULL_JSON_BYTES_SLICE, nil
+	}
+
+	b := make([]byte, 1, 18)
+	b[0] = '"'
+	b = p.AppendTo(b)
+	b = append(b, '"')
+
+	return b, nil
+}
+
+// UnmarshalJSON decodes b into the current Period object expecting b contains
+// ISO8601 quoted duration (see ParseFrom() for supported formats).
+//
+// JSON null supporting:
+// - It's ok if there is JSON null and receiver == nil (nothing changes)
+// - Zeroes Period if there is JSON null and receiver != nil.
+//
+// In other cases JSON parsing error or Period.ParseFrom() error is returned.
+func (p *Period) UnmarshalJSON(b []byte) error {
+
+	if ekaenc.IsNullJSON(b) {
+		if p != nil {
+			*p = Period{}
+		}
+		return nil
+	}
+
+	switch l := len(b); {
+
+	case l < 5:
+		// The min length is 5: "P0D" with quotes.
+		return _ERR_NOT_ISO8601_PERIOD
+
+	case b[0] != '"' || b[l-1] != '"':
+		// Forgotten quotes? Incorrect JSON?
+		return _ERR_BAD_JSON_PERIOD_QUO
+
+	default:
+		return p.ParseFrom(b[1:l-1])
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekatime
+
+// seconds returns hours, minutes and seconds of the current Period
+// as a number of seconds.
+func (p Period) seconds() Timestamp {
+	return Timestamp(p.Hours)*SECONDS_IN_HOUR +
+		Timestamp(p.Minutes)*SECONDS_IN_MINUTE +
+		Timestamp(p.Seconds)
+}
+
+// periodAddMonths returns 'dd' with added 'months' months,
+// clamping the day to the last day of the resulting month if it's needed.
+func periodAddMonths(dd Date, months int32) Date {
+
+	if months == 0 {
+		return dd
+	}
+
+	y, m, d := dd.Split()
+
+	total := int32(y)*12 + int32(m) - 1 + months
+	if total < int32(_YEAR_MIN)*12 {
+		total = int32(_YEAR_MIN) * 12
+	}
+	if total > int32(_YEAR_MAX)*12+11 {
+		total = int32(_YEAR_MAX)*12 + 11
+	}
+
+	y, m = Year(total/12), Month(total%12)+1
+	if daysInMonth := DaysInMonth(y, m); d > daysInMonth {
+		d = daysInMonth
+	}
+
+	return NewDate(y, m, d)
+}
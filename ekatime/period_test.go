@@ -0,0 +1,141 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekatime_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/qioalice/ekago/v2/ekatime"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeriod_ParseFrom(t *testing.T) {
+
+	tests := []struct {
+		s        string
+		expected ekatime.Period
+		str      string // expected String(), empty means the same as s
+	}{
+		{"P1Y2M3DT4H5M6S", ekatime.NewPeriod(1, 2, 3, 4, 5, 6), ""},
+		{"P2W", ekatime.NewPeriod(0, 0, 14, 0, 0, 0), "P14D"},
+		{"PT90M", ekatime.NewPeriod(0, 0, 0, 0, 90, 0), ""},
+		{"-P1M2D", ekatime.NewPeriod(0, -1, -2, 0, 0, 0), ""},
+		{"P1M-2D", ekatime.NewPeriod(0, 1, -2, 0, 0, 0), ""},
+		{"p1dt2h", ekatime.NewPeriod(0, 0, 1, 2, 0, 0), "P1DT2H"},
+		{"P0D", ekatime.Period{}, ""},
+	}
+
+	for _, test := range tests {
+		var p ekatime.Period
+		require.NoError(t, p.ParseFrom([]byte(test.s)), test.s)
+		require.Equal(t, test.expected, p, test.s)
+
+		expectedStr := test.str
+		if expectedStr == "" {
+			expectedStr = test.s
+		}
+		require.Equal(t, expectedStr, p.String(), test.s)
+	}
+
+	for _, s := range []string{"", "P", "PT", "P1DT", "1D", "P1", "PD", "P1D1Y", "PT1Y", "P1.5D", "P9999999999D"} {
+		var p ekatime.Period
+		require.Error(t, p.ParseFrom([]byte(s)), s)
+	}
+}
+
+func TestPeriod_MarshalJSON(t *testing.T) {
+
+	type T struct {
+		P1 ekatime.Period  `json:"p1"`
+		P2 ekatime.Period  `json:"p2"`
+		P3 *ekatime.Period `json:"p3"`
+	}
+
+	v := T{P1: ekatime.NewPeriod(1, 0, 0, 0, 30, 0)}
+
+	b, err := json.Marshal(&v)
+	require.NoError(t, err)
+	require.Equal(t, `{"p1":"P1YT30M","p2":null,"p3":null}`, string(b))
+
+	var decoded T
+	require.NoError(t, json.Unmarshal(b, &decoded))
+	require.Equal(t, v, decoded)
+
+	require.Error(t, json.Unmarshal([]byte(`{"p1":P1D}`), &decoded))
+}
+
+func TestDate_AddPeriod(t *testing.T) {
+
+	tests := []struct {
+		dd       ekatime.Date
+		p        ekatime.Period
+		expected ekatime.Date
+	}{
+		{ekatime.NewDate(2021, 1, 31), ekatime.NewPeriod(0, 1, 0, 0, 0, 0), ekatime.NewDate(2021, 2, 28)},
+		{ekatime.NewDate(2020, 1, 31), ekatime.NewPeriod(0, 1, 0, 0, 0, 0), ekatime.NewDate(2020, 2, 29)},
+		{ekatime.NewDate(2020, 2, 29), ekatime.NewPeriod(1, 0, 0, 0, 0, 0), ekatime.NewDate(2021, 2, 28)},
+		{ekatime.NewDate(2021, 1, 31), ekatime.NewPeriod(0, 1, 1, 0, 0, 0), ekatime.NewDate(2021, 3, 1)},
+		{ekatime.NewDate(2021, 3, 31), ekatime.NewPeriod(0, -1, 0, 0, 0, 0), ekatime.NewDate(2021, 2, 28)},
+		{ekatime.NewDate(2021, 1, 30), ekatime.NewPeriod(0, 14, 0, 0, 0, 0), ekatime.NewDate(2022, 3, 30)},
+		{ekatime.NewDate(2021, 12, 31), ekatime.NewPeriod(0, 0, 1, 23, 0, 0), ekatime.NewDate(2022, 1, 1)},
+	}
+
+	for _, test := range tests {
+		require.True(t, test.dd.AddPeriod(test.p).Equal(test.expected),
+			"%s + %s", test.dd, test.p)
+	}
+
+	require.True(t, ekatime.NewDate(2021, 3, 31).SubPeriod(ekatime.NewPeriod(0, 1, 0, 0, 0, 0)).
+		Equal(ekatime.NewDate(2021, 2, 28)))
+}
+
+func TestTimestamp_AddPeriod(t *testing.T) {
+
+	ts := ekatime.UnixFrom(2021, 1, 31, 22, 0, 0)
+	require.Equal(t, ekatime.UnixFrom(2021, 3, 1, 0, 30, 0),
+		ts.AddPeriod(ekatime.NewPeriod(0, 1, 0, 2, 30, 0)))
+	require.Equal(t, ts, ts.AddPeriod(ekatime.NewPeriod(0, 0, 1, 2, 0, 0)).
+		SubPeriod(ekatime.NewPeriod(0, 0, 1, 2, 0, 0)))
+
+	// 27 Mar 2021 12:00 CET + P1D = 28 Mar 2021 12:00 CEST (23 hours).
+	berlin := loadLocation(t, "Europe/Berlin")
+	ts = ekatime.UnixFromIn(2021, 3, 27, 12, 0, 0, berlin)
+	require.Equal(t, ts+23*ekatime.SECONDS_IN_HOUR,
+		ts.AddPeriodIn(ekatime.NewPeriod(0, 0, 1, 0, 0, 0), berlin))
+}
+
+func TestBetween(t *testing.T) {
+
+	tests := []struct {
+		d1, d2   ekatime.Date
+		expected string
+	}{
+		{ekatime.NewDate(2020, 1, 15), ekatime.NewDate(2021, 3, 20), "P1Y2M5D"},
+		{ekatime.NewDate(2021, 1, 31), ekatime.NewDate(2021, 2, 28), "P28D"},
+		{ekatime.NewDate(2021, 1, 31), ekatime.NewDate(2021, 3, 1), "P1M1D"},
+		{ekatime.NewDate(2021, 3, 20), ekatime.NewDate(2021, 1, 15), "-P2M5D"},
+		{ekatime.NewDate(2020, 2, 29), ekatime.NewDate(2021, 2, 28), "P11M30D"},
+		{ekatime.NewDate(2021, 5, 5), ekatime.NewDate(2021, 5, 5), "P0D"},
+		{ekatime.NewDate(2021, 3, 31), ekatime.NewDate(2021, 2, 1), "-P1M27D"},
+		{ekatime.NewDate(2021, 3, 30), ekatime.NewDate(2021, 1, 31), "-P1M28D"},
+	}
+
+	for _, test := range tests {
+		p := ekatime.Between(test.d1, test.d2)
+		require.Equal(t, test.expected, p.String(), "%s - %s", test.d1, test.d2)
+		require.True(t, test.d1.AddPeriod(p).Equal(test.d2), "%s - %s", test.d1, test.d2)
+	}
+
+	// Round trip must work for any dates, including the end of months.
+	from, to := ekatime.NewDate(2020, 12, 25), ekatime.NewDate(2021, 4, 5)
+	for d1 := from; !d1.Equal(to); d1 = d1.AddDays(1) {
+		for d2 := from; !d2.Equal(to); d2 = d2.AddDays(1) {
+			require.True(t, d1.AddPeriod(ekatime.Between(d1, d2)).Equal(d2), "%s - %s", d1, d2)
+		}
+	}
+}
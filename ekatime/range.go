@@ -0,0 +1,354 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekatime
+
+import (
+	"time"
+)
+
+type (
+	// DateRange is a range of dates [From..To], both of them are included.
+	// DateRange is empty if To is before From.
+	//
+	// The Dates that are returned by DateRange's methods may contain
+	// a day of week, so use Date.Equal() or Date.ToCmp() to compare them.
+	DateRange struct {
+		From Date
+		To   Date
+	}
+
+	// TimestampRange is a range of unix timestamps [From..To],
+	// both of them are included, like TimestampPair that is returned by
+	// BeginningAndEndOfMonth() and friends.
+	// TimestampRange is empty if To is less than From.
+	TimestampRange struct {
+		From Timestamp
+		To   Timestamp
+	}
+
+	// RangeUnit is a calendar unit DateRange and TimestampRange
+	// can be iterated by or split into buckets of.
+	RangeUnit uint8
+)
+
+//noinspection GoSnakeCaseUsage
+const (
+	RANGE_UNIT_DAY RangeUnit = iota
+	RANGE_UNIT_WEEK // ISO week, starts at Monday
+	RANGE_UNIT_MONTH
+	RANGE_UNIT_YEAR
+)
+
+// NewDateRange creates a new DateRange of [from..to] dates.
+func NewDateRange(from, to Date) DateRange {
+	return DateRange{From: from, To: to}
+}
+
+// IsEmpty reports whether the current DateRange contains no dates
+// (its To is before From or at least one of them is not valid).
+func (r DateRange) IsEmpty() bool {
+	return !r.From.IsValid() || !r.To.IsValid() || r.To.ToCmp() < r.From.ToCmp()
+}
+
+// Days returns a number of dates the current DateRange contains.
+func (r DateRange) Days() Days {
+	if r.IsEmpty() {
+		return 0
+	}
+	from, to := r.dayNums()
+	return Days(to - from + 1)
+}
+
+// Contains reports whether 'dd' is inside the current DateRange.
+func (r DateRange) Contains(dd Date) bool {
+	return !r.IsEmpty() && r.From.ToCmp() <= dd.ToCmp() && dd.ToCmp() <= r.To.ToCmp()
+}
+
+// ContainsRange reports whether 'other' DateRange is entirely inside
+// the current one. Empty 'other' is never contained.
+func (r DateRange) ContainsRange(other DateRange) bool {
+	return !other.IsEmpty() && r.Contains(other.From) && r.Contains(other.To)
+}
+
+// Overlaps reports whether the current and 'other' DateRanges
+// have at least one date in common.
+func (r DateRange) Overlaps(other DateRange) bool {
+	_, ok := r.Intersection(other)
+	return ok
+}
+
+// Intersection returns the DateRange of dates both of the current
+// and 'other' DateRanges contain and true, or an empty DateRange and false,
+// if they are not overlapped.
+func (r DateRange) Intersection(other DateRange) (DateRange, bool) {
+	if r.IsEmpty() || other.IsEmpty() {
+		return DateRange{}, false
+	}
+	from, to, ok := rangeIntersection(r.i64(), other.i64())
+	if !ok {
+		return DateRange{}, false
+	}
+	return dateRangeOf(from, to), true
+}
+
+// Union returns the DateRange of dates at least one of the current
+// and 'other' DateRanges contains and true, if they are overlapped
+// or adjacent (one is starting the next day after another is finished).
+// Otherwise the union is not a single range and an empty DateRange
+// and false are returned (use RangeSet for that case).
+func (r DateRange) Union(other DateRange) (DateRange, bool) {
+	switch {
+	case r.IsEmpty():
+		return other, !other.IsEmpty()
+	case other.IsEmpty():
+		return r, true
+	}
+	from, to, ok := rangeUnion(r.i64(), other.i64())
+	if !ok {
+		return DateRange{}, false
+	}
+	return dateRangeOf(from, to), true
+}
+
+// Difference returns the DateRanges (0, 1 or 2 of them) of dates
+// the current DateRange contains but 'other' doesn't.
+func (r DateRange) Difference(other DateRange) []DateRange {
+	switch {
+	case r.IsEmpty():
+		return nil
+	case other.IsEmpty():
+		return []DateRange{r}
+	}
+	parts := rangeDifference(r.i64(), other.i64())
+	ranges := make([]DateRange, len(parts))
+	for i, part := range parts {
+		ranges[i] = dateRangeOf(part[0], part[1])
+	}
+	return ranges
+}
+
+// EachDay calls 'cb' for each date of the current DateRange in order
+// until 'cb' returns false.
+func (r DateRange) EachDay(cb func(dd Date) bool) {
+	if r.IsEmpty() || cb == nil {
+		return
+	}
+	for day, to := r.dayNums(); day <= to; day++ {
+		if !cb(workdaysDate(day)) {
+			return
+		}
+	}
+}
+
+// Walk splits the current DateRange into calendar buckets of 'unit'
+// (days, ISO weeks, months or years) and calls 'cb' for each of them in order
+// until 'cb' returns false. The first and the last buckets are truncated
+// by the current DateRange's bounds.
+//
+// Example:
+//     NewDateRange(NewDate(2021, 1, 20), NewDate(2021, 3, 5)).Walk(RANGE_UNIT_MONTH, cb)
+//     // cb is called for 20 Jan..31 Jan, 01 Feb..28 Feb, 01 Mar..05 Mar.
+func (r DateRange) Walk(unit RangeUnit, cb func(bucket DateRange) bool) {
+	if r.IsEmpty() || cb == nil {
+		return
+	}
+	for day, to := r.dayNums(); day <= to; {
+		next := rangeNextBucket(unit, day)
+		last := next - 1
+		if last > to {
+			last = to
+		}
+		if !cb(DateRange{From: workdaysDate(day), To: workdaysDate(last)}) {
+			return
+		}
+		day = next
+	}
+}
+
+// Split returns the calendar buckets of 'unit' the current DateRange
+// consists of. See Walk() for more details.
+func (r DateRange) Split(unit RangeUnit) []DateRange {
+	var buckets []DateRange
+	r.Walk(unit, func(bucket DateRange) bool {
+		buckets = append(buckets, bucket)
+		return true
+	})
+	return buckets
+}
+
+// TimestampRangeIn returns the TimestampRange from the beginning
+// of the current DateRange's first day until the end of its last day
+// in the 'loc' time zone. Nil 'loc' means UTC. Respects DST.
+// Returns an empty TimestampRange if the current DateRange is empty.
+func (r DateRange) TimestampRangeIn(loc *time.Location) TimestampRange {
+	if r.IsEmpty() {
+		return TimestampRange{From: 0, To: -1}
+	}
+	loc = normalizeLocation(loc)
+	y1, m1, d1 := r.From.Split()
+	y2, m2, d2 := r.To.Split()
+	return TimestampRange{
+		From: beginningOfDayIn(y1, m1, d1, loc),
+		To:   beginningOfDayIn(y2, m2, d2+1, loc) - 1,
+	}
+}
+
+// String returns the current DateRange's string representation
+// in the following format: "YYYY/MM/DD - YYYY/MM/DD".
+func (r DateRange) String() string {
+	b := make([]byte, 0, 23)
+	b = r.From.AppendTo(b, '/')
+	b = append(b, " - "...)
+	return string(r.To.AppendTo(b, '/'))
+}
+
+// NewTimestampRange creates a new TimestampRange of [from..to] timestamps.
+func NewTimestampRange(from, to Timestamp) TimestampRange {
+	return TimestampRange{From: from, To: to}
+}
+
+// Range returns the current TimestampPair as TimestampRange.
+func (tsp TimestampPair) Range() TimestampRange {
+	return TimestampRange{From: tsp[0], To: tsp[1]}
+}
+
+// Pair returns the current TimestampRange as TimestampPair.
+func (r TimestampRange) Pair() TimestampPair {
+	return TimestampPair{r.From, r.To}
+}
+
+// IsEmpty reports whether the current TimestampRange contains no timestamps.
+func (r TimestampRange) IsEmpty() bool {
+	return r.To < r.From
+}
+
+// Duration returns a duration of the current TimestampRange.
+// Because of both bounds are included, [ts..ts] lasts 1 second.
+func (r TimestampRange) Duration() time.Duration {
+	if r.IsEmpty() {
+		return 0
+	}
+	return time.Duration(r.To - r.From + 1) * time.Second
+}
+
+// Contains reports whether 'ts' is inside the current TimestampRange.
+func (r TimestampRange) Contains(ts Timestamp) bool {
+	return r.From <= ts && ts <= r.To
+}
+
+// ContainsRange reports whether 'other' TimestampRange is entirely inside
+// the current one. Empty 'other' is never contained.
+func (r TimestampRange) ContainsRange(other TimestampRange) bool {
+	return !other.IsEmpty() && r.From <= other.From && other.To <= r.To
+}
+
+// Overlaps reports whether the current and 'other' TimestampRanges
+// have at least one timestamp in common.
+func (r TimestampRange) Overlaps(other TimestampRange) bool {
+	return !r.IsEmpty() && !other.IsEmpty() && r.From <= other.To && other.From <= r.To
+}
+
+// Intersection returns the TimestampRange of timestamps both of the current
+// and 'other' TimestampRanges contain and true, or an empty TimestampRange
+// and false, if they are not overlapped.
+func (r TimestampRange) Intersection(other TimestampRange) (TimestampRange, bool) {
+	if r.IsEmpty() || other.IsEmpty() {
+		return TimestampRange{From: 0, To: -1}, false
+	}
+	from, to, ok := rangeIntersection(r.i64(), other.i64())
+	if !ok {
+		return TimestampRange{From: 0, To: -1}, false
+	}
+	return TimestampRange{From: Timestamp(from), To: Timestamp(to)}, true
+}
+
+// Union returns the TimestampRange of timestamps at least one of the current
+// and 'other' TimestampRanges contains and true, if they are overlapped
+// or adjacent. Otherwise the union is not a single range and an empty
+// TimestampRange and false are returned (use RangeSet for that case).
+func (r TimestampRange) Union(other TimestampRange) (TimestampRange, bool) {
+	switch {
+	case r.IsEmpty():
+		return other, !other.IsEmpty()
+	case other.IsEmpty():
+		return r, true
+	}
+	from, to, ok := rangeUnion(r.i64(), other.i64())
+	if !ok {
+		return TimestampRange{From: 0, To: -1}, false
+	}
+	return TimestampRange{From: Timestamp(from), To: Timestamp(to)}, true
+}
+
+// Difference returns the TimestampRanges (0, 1 or 2 of them) of timestamps
+// the current TimestampRange contains but 'other' doesn't.
+func (r TimestampRange) Difference(other TimestampRange) []TimestampRange {
+	switch {
+	case r.IsEmpty():
+		return nil
+	case other.IsEmpty():
+		return []TimestampRange{r}
+	}
+	parts := rangeDifference(r.i64(), other.i64())
+	ranges := make([]TimestampRange, len(parts))
+	for i, part := range parts {
+		ranges[i] = TimestampRange{From: Timestamp(part[0]), To: Timestamp(part[1])}
+	}
+	return ranges
+}
+
+// WalkIn splits the current TimestampRange into calendar buckets of 'unit'
+// (days, ISO weeks, months or years) in the 'loc' time zone and calls 'cb'
+// for each of them in order until 'cb' returns false. Nil 'loc' means UTC.
+// The first and the last buckets are truncated by the current
+// TimestampRange's bounds. Respects DST, so the day's bucket may last
+// 23 or 25 hours.
+func (r TimestampRange) WalkIn(unit RangeUnit, loc *time.Location, cb func(bucket TimestampRange) bool) {
+	if r.IsEmpty() || cb == nil {
+		return
+	}
+	loc = normalizeLocation(loc)
+	for from := r.From; from <= r.To; {
+		to := rangeBucketEndIn(unit, from, loc)
+		if to > r.To {
+			to = r.To
+		}
+		if !cb(TimestampRange{From: from, To: to}) {
+			return
+		}
+		from = to + 1
+	}
+}
+
+// SplitIn returns the calendar buckets of 'unit' in the 'loc' time zone
+// the current TimestampRange consists of. See WalkIn() for more details.
+func (r TimestampRange) SplitIn(unit RangeUnit, loc *time.Location) []TimestampRange {
+	var buckets []TimestampRange
+	r.WalkIn(unit, loc, func(bucket TimestampRange) bool {
+		buckets = append(buckets, bucket)
+		return true
+	})
+	return buckets
+}
+
+// DateRangeIn returns the DateRange of dates the current TimestampRange
+// touches in the 'loc' time zone. Nil 'loc' means UTC.
+// Returns an empty DateRange if the current TimestampRange is empty.
+func (r TimestampRange) DateRangeIn(loc *time.Location) DateRange {
+	if r.IsEmpty() {
+		return DateRange{}
+	}
+	return DateRange{From: r.From.DateIn(loc), To: r.To.DateIn(loc)}
+}
+
+// String returns the current TimestampRange's string representation
+// in the following format: "YYYY/MM/DD hh:mm:ss - YYYY/MM/DD hh:mm:ss".
+func (r TimestampRange) String() string {
+	b := make([]byte, 0, 41)
+	b = r.From.AppendTo(b, '/', ':')
+	b = append(b, " - "...)
+	return string(r.To.AppendTo(b, '/', ':'))
+}
@@ -0,0 +1,127 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekatime
+
+import (
+	"sort"
+	"time"
+)
+
+// i64 returns the current DateRange's bounds as day numbers
+// (see workdaysDayNum()).
+func (r DateRange) i64() [2]int64 {
+	from, to := r.dayNums()
+	return [2]int64{from.I64(), to.I64()}
+}
+
+// dayNums returns day numbers of the current DateRange's bounds.
+// See workdaysDayNum().
+func (r DateRange) dayNums() (Timestamp, Timestamp) {
+	return workdaysDayNum(r.From), workdaysDayNum(r.To)
+}
+
+// i64 returns the current TimestampRange's bounds as int64.
+func (r TimestampRange) i64() [2]int64 {
+	return [2]int64{r.From.I64(), r.To.I64()}
+}
+
+// dateRangeOf returns a DateRange of [from..to] day numbers.
+func dateRangeOf(from, to int64) DateRange {
+	return DateRange{From: workdaysDate(Timestamp(from)), To: workdaysDate(Timestamp(to))}
+}
+
+// rangeIntersection returns the intersection of non-empty [a..a] and [b..b]
+// ranges (both bounds are included) and true or false if there is no one.
+func rangeIntersection(a, b [2]int64) (int64, int64, bool) {
+	from, to := a[0], a[1]
+	if b[0] > from {
+		from = b[0]
+	}
+	if b[1] < to {
+		to = b[1]
+	}
+	return from, to, from <= to
+}
+
+// rangeUnion returns the union of non-empty [a..a] and [b..b] ranges
+// (both bounds are included) and true or false if they are neither
+// overlapped nor adjacent.
+func rangeUnion(a, b [2]int64) (int64, int64, bool) {
+	if a[0]-1 > b[1] || b[0]-1 > a[1] {
+		return 0, 0, false
+	}
+	from, to := a[0], a[1]
+	if b[0] < from {
+		from = b[0]
+	}
+	if b[1] > to {
+		to = b[1]
+	}
+	return from, to, true
+}
+
+// rangeDifference returns the parts of non-empty [a..a] range
+// that are not in the non-empty [b..b] range (both bounds are included).
+func rangeDifference(a, b [2]int64) [][2]int64 {
+	if b[1] < a[0] || b[0] > a[1] {
+		return [][2]int64{a}
+	}
+	parts := make([][2]int64, 0, 2)
+	if a[0] < b[0] {
+		parts = append(parts, [2]int64{a[0], b[0] - 1})
+	}
+	if b[1] < a[1] {
+		parts = append(parts, [2]int64{b[1] + 1, a[1]})
+	}
+	return parts
+}
+
+// rangeNextBucket returns the day number of the first day of the 'unit'
+// calendar bucket that follows the one the 'day' number belongs to.
+func rangeNextBucket(unit RangeUnit, day Timestamp) Timestamp {
+	switch unit {
+
+	case RANGE_UNIT_WEEK:
+		w := Timestamp(workdaysWeekday(day) - WEEKDAY_MONDAY)
+		if w < 0 {
+			w += 7
+		}
+		return day + 7 - w
+
+	case RANGE_UNIT_MONTH:
+		y, m, d := workdaysDate(day).Split()
+		return day + Timestamp(DaysInMonth(y, m) - d) + 1
+
+	case RANGE_UNIT_YEAR:
+		return workdaysDayNum(NewDate(workdaysDate(day).Year()+1, MONTH_JANUARY, 1))
+
+	default:
+		return day + 1
+	}
+}
+
+// rangeBucketEndIn returns the last Timestamp of the 'unit' calendar bucket
+// in the 'loc' time zone the 'ts' belongs to.
+func rangeBucketEndIn(unit RangeUnit, ts Timestamp, loc *time.Location) Timestamp {
+	next := workdaysDate(rangeNextBucket(unit, workdaysDayNum(ts.DateIn(loc))))
+	y, m, d := next.Split()
+	return beginningOfDayIn(y, m, d, loc) - 1
+}
+
+// search returns an index of the range that may contain 'ts'
+// (the first one which To >= ts) or -1. Nil safe.
+func (rs *RangeSet) search(ts Timestamp) int {
+	if rs == nil {
+		return -1
+	}
+	i := sort.Search(len(rs.ranges), func(i int) bool {
+		return rs.ranges[i].To >= ts
+	})
+	if i == len(rs.ranges) {
+		return -1
+	}
+	return i
+}
@@ -0,0 +1,182 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekatime
+
+import (
+	"sort"
+	"time"
+)
+
+type (
+	// RangeSet is a set of unix timestamps that is stored as sorted
+	// non-overlapping and non-adjacent TimestampRanges.
+	// Added ranges are merged with the overlapped and adjacent ones,
+	// removed ranges cut the existing ones.
+	//
+	// Use DateRange.TimestampRangeIn() to add or remove a range of dates.
+	//
+	// RangeSet is not thread-safe. A nil RangeSet is an empty set,
+	// its methods are nil safe.
+	RangeSet struct {
+		ranges []TimestampRange
+	}
+)
+
+// NewRangeSet creates a new RangeSet and adds all 'ranges' to it.
+func NewRangeSet(ranges ...TimestampRange) *RangeSet {
+	rs := new(RangeSet)
+	for _, r := range ranges {
+		rs.Add(r)
+	}
+	return rs
+}
+
+// Add adds 'r' to the current RangeSet, merging it with the overlapped
+// and adjacent ranges. Empty 'r' is ignored. Nil safe.
+func (rs *RangeSet) Add(r TimestampRange) *RangeSet {
+
+	if rs == nil || r.IsEmpty() {
+		return rs
+	}
+
+	// The first range that is not before 'r' and is not adjacent to it.
+	i := sort.Search(len(rs.ranges), func(i int) bool {
+		return rs.ranges[i].To >= r.From-1
+	})
+
+	// The first range that is after 'r' and is not adjacent to it.
+	j := i
+	for ; j < len(rs.ranges) && rs.ranges[j].From-1 <= r.To; j++ {
+		r, _ = r.Union(rs.ranges[j])
+	}
+
+	rs.ranges = append(rs.ranges[:i], append([]TimestampRange{r}, rs.ranges[j:]...)...)
+	return rs
+}
+
+// Remove removes 'r' from the current RangeSet, cutting the ranges
+// that are overlapped with it. Empty 'r' is ignored. Nil safe.
+func (rs *RangeSet) Remove(r TimestampRange) *RangeSet {
+
+	if rs == nil || r.IsEmpty() {
+		return rs
+	}
+
+	ranges := make([]TimestampRange, 0, len(rs.ranges)+1)
+	for _, existing := range rs.ranges {
+		ranges = append(ranges, existing.Difference(r)...)
+	}
+
+	rs.ranges = ranges
+	return rs
+}
+
+// Contains reports whether 'ts' is inside the current RangeSet. Nil safe.
+func (rs *RangeSet) Contains(ts Timestamp) bool {
+	if i := rs.search(ts); i != -1 {
+		return rs.ranges[i].Contains(ts)
+	}
+	return false
+}
+
+// ContainsRange reports whether 'r' is entirely inside the current RangeSet.
+// Empty 'r' is never contained. Nil safe.
+func (rs *RangeSet) ContainsRange(r TimestampRange) bool {
+	if i := rs.search(r.From); i != -1 {
+		return rs.ranges[i].ContainsRange(r)
+	}
+	return false
+}
+
+// Overlaps reports whether the current RangeSet and 'r' have at least
+// one timestamp in common. Nil safe.
+func (rs *RangeSet) Overlaps(r TimestampRange) bool {
+	if rs == nil || r.IsEmpty() {
+		return false
+	}
+	i := sort.Search(len(rs.ranges), func(i int) bool {
+		return rs.ranges[i].To >= r.From
+	})
+	return i < len(rs.ranges) && rs.ranges[i].Overlaps(r)
+}
+
+// Ranges returns a copy of the current RangeSet's sorted non-overlapping
+// and non-adjacent ranges. Nil safe.
+func (rs *RangeSet) Ranges() []TimestampRange {
+	if rs == nil || len(rs.ranges) == 0 {
+		return nil
+	}
+	return append([]TimestampRange(nil), rs.ranges...)
+}
+
+// Len returns a number of ranges the current RangeSet consists of. Nil safe.
+func (rs *RangeSet) Len() int {
+	if rs == nil {
+		return 0
+	}
+	return len(rs.ranges)
+}
+
+// IsEmpty reports whether the current RangeSet contains no timestamps. Nil safe.
+func (rs *RangeSet) IsEmpty() bool {
+	return rs.Len() == 0
+}
+
+// Duration returns a total duration of the current RangeSet's ranges. Nil safe.
+func (rs *RangeSet) Duration() time.Duration {
+	var d time.Duration
+	for i, n := 0, rs.Len(); i < n; i++ {
+		d += rs.ranges[i].Duration()
+	}
+	return d
+}
+
+// Union returns a new RangeSet that contains the timestamps at least one of
+// the current and 'other' RangeSets contains. Nil safe.
+func (rs *RangeSet) Union(other *RangeSet) *RangeSet {
+	union := NewRangeSet(rs.Ranges()...)
+	for i, n := 0, other.Len(); i < n; i++ {
+		union.Add(other.ranges[i])
+	}
+	return union
+}
+
+// Intersection returns a new RangeSet that contains the timestamps both of
+// the current and 'other' RangeSets contain. Nil safe.
+func (rs *RangeSet) Intersection(other *RangeSet) *RangeSet {
+
+	intersection := new(RangeSet)
+
+	for i, j := 0, 0; i < rs.Len() && j < other.Len(); {
+		a, b := rs.ranges[i], other.ranges[j]
+		if r, ok := a.Intersection(b); ok {
+			intersection.ranges = append(intersection.ranges, r)
+		}
+		if a.To < b.To {
+			i++
+		} else {
+			j++
+		}
+	}
+
+	return intersection
+}
+
+// Difference returns a new RangeSet that contains the timestamps
+// the current RangeSet contains but 'other' doesn't. Nil safe.
+func (rs *RangeSet) Difference(other *RangeSet) *RangeSet {
+	difference := NewRangeSet(rs.Ranges()...)
+	for i, n := 0, other.Len(); i < n; i++ {
+		difference.Remove(other.ranges[i])
+	}
+	return difference
+}
+
+// Gaps returns the ranges inside 'within' that the current RangeSet
+// does not contain. Nil safe.
+func (rs *RangeSet) Gaps(within TimestampRange) []TimestampRange {
+	return NewRangeSet(within).Difference(rs).Ranges()
+}
@@ -0,0 +1,170 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekatime_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qioalice/ekago/v2/ekatime"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDateRange(t *testing.T) {
+
+	r := ekatime.NewDateRange(ekatime.NewDate(2021, 1, 20), ekatime.NewDate(2021, 3, 5))
+	require.Equal(t, ekatime.Days(45), r.Days())
+	require.True(t, r.Contains(ekatime.NewDate(2021, 2, 28)))
+	require.False(t, r.Contains(ekatime.NewDate(2021, 3, 6)))
+	require.True(t, ekatime.NewDateRange(ekatime.NewDate(2021, 2, 1), ekatime.NewDate(2021, 1, 31)).IsEmpty())
+
+	other := ekatime.NewDateRange(ekatime.NewDate(2021, 3, 1), ekatime.NewDate(2021, 4, 1))
+	require.True(t, r.Overlaps(other))
+
+	intersection, ok := r.Intersection(other)
+	require.True(t, ok)
+	require.Equal(t, "2021/03/01 - 2021/03/05", intersection.String())
+
+	union, ok := r.Union(other)
+	require.True(t, ok)
+	require.Equal(t, "2021/01/20 - 2021/04/01", union.String())
+
+	// Adjacent ranges.
+	adjacent := ekatime.NewDateRange(ekatime.NewDate(2021, 3, 6), ekatime.NewDate(2021, 3, 7))
+	require.False(t, r.Overlaps(adjacent))
+	union, ok = r.Union(adjacent)
+	require.True(t, ok)
+	require.Equal(t, "2021/01/20 - 2021/03/07", union.String())
+
+	_, ok = r.Union(ekatime.NewDateRange(ekatime.NewDate(2021, 3, 7), ekatime.NewDate(2021, 3, 8)))
+	require.False(t, ok)
+
+	var diff []string
+	for _, part := range r.Difference(ekatime.NewDateRange(ekatime.NewDate(2021, 2, 1), ekatime.NewDate(2021, 2, 28))) {
+		diff = append(diff, part.String())
+	}
+	require.Equal(t, []string{"2021/01/20 - 2021/01/31", "2021/03/01 - 2021/03/05"}, diff)
+	require.Empty(t, r.Difference(union))
+
+	days := 0
+	r.EachDay(func(dd ekatime.Date) bool {
+		days++
+		return !dd.Equal(ekatime.NewDate(2021, 1, 31))
+	})
+	require.Equal(t, 12, days)
+}
+
+func TestDateRange_Split(t *testing.T) {
+
+	toStrings := func(ranges []ekatime.DateRange) []string {
+		s := make([]string, len(ranges))
+		for i, r := range ranges {
+			s[i] = r.String()
+		}
+		return s
+	}
+
+	r := ekatime.NewDateRange(ekatime.NewDate(2020, 12, 30), ekatime.NewDate(2021, 2, 3))
+
+	require.Equal(t, []string{
+		"2020/12/30 - 2020/12/31",
+		"2021/01/01 - 2021/01/31",
+		"2021/02/01 - 2021/02/03",
+	}, toStrings(r.Split(ekatime.RANGE_UNIT_MONTH)))
+
+	require.Equal(t, []string{
+		"2020/12/30 - 2020/12/31",
+		"2021/01/01 - 2021/02/03",
+	}, toStrings(r.Split(ekatime.RANGE_UNIT_YEAR)))
+
+	weeks := r.Split(ekatime.RANGE_UNIT_WEEK)
+	require.Len(t, weeks, 6)
+	require.Equal(t, "2020/12/30 - 2021/01/03", weeks[0].String()) // Wed..Sun
+	require.Equal(t, "2021/01/04 - 2021/01/10", weeks[1].String()) // Mon..Sun
+	require.Equal(t, "2021/02/01 - 2021/02/03", weeks[5].String())
+
+	require.Len(t, r.Split(ekatime.RANGE_UNIT_DAY), 36)
+}
+
+func TestTimestampRange(t *testing.T) {
+
+	month := ekatime.BeginningAndEndOfMonth(2021, 2).Range()
+	require.Equal(t, 28*24*time.Hour, month.Duration())
+	require.Equal(t, ekatime.BeginningAndEndOfMonth(2021, 2), month.Pair())
+	require.True(t, month.Contains(ekatime.UnixFrom(2021, 2, 28, 23, 59, 59)))
+	require.False(t, month.Contains(ekatime.UnixFrom(2021, 3, 1, 0, 0, 0)))
+
+	days := ekatime.NewDateRange(ekatime.NewDate(2021, 2, 1), ekatime.NewDate(2021, 2, 28))
+	require.Equal(t, month, days.TimestampRangeIn(nil))
+	require.True(t, month.DateRangeIn(nil).From.Equal(days.From))
+	require.True(t, month.DateRangeIn(nil).To.Equal(days.To))
+
+	var diff []ekatime.TimestampRange
+	diff = month.Difference(ekatime.BeginningAndEndOfMonth(2021, 3).Range())
+	require.Equal(t, []ekatime.TimestampRange{month}, diff)
+
+	// DST: 28 Mar 2021 lasts 23 hours in Berlin.
+	berlin := loadLocation(t, "Europe/Berlin")
+	r := ekatime.NewTimestampRange(
+		ekatime.UnixFromIn(2021, 3, 27, 12, 0, 0, berlin),
+		ekatime.UnixFromIn(2021, 3, 29, 12, 0, 0, berlin),
+	)
+
+	buckets := r.SplitIn(ekatime.RANGE_UNIT_DAY, berlin)
+	require.Len(t, buckets, 3)
+	require.Equal(t, 12*time.Hour, buckets[0].Duration())
+	require.Equal(t, 23*time.Hour, buckets[1].Duration())
+	require.Equal(t, 12*time.Hour+time.Second, buckets[2].Duration())
+	require.Equal(t, ekatime.UnixFromIn(2021, 3, 28, 0, 0, 0, berlin), buckets[1].From)
+	require.Equal(t, r, ekatime.NewTimestampRange(buckets[0].From, buckets[2].To))
+}
+
+func TestRangeSet(t *testing.T) {
+
+	ts := func(d ekatime.Day, hh ekatime.Hour) ekatime.Timestamp {
+		return ekatime.UnixFrom(2021, 1, d, hh, 0, 0)
+	}
+	r := func(d1 ekatime.Day, hh1 ekatime.Hour, d2 ekatime.Day, hh2 ekatime.Hour) ekatime.TimestampRange {
+		return ekatime.NewTimestampRange(ts(d1, hh1), ts(d2, hh2)-1)
+	}
+
+	rs := ekatime.NewRangeSet(r(1, 10, 1, 12), r(1, 14, 1, 16), r(1, 8, 1, 9))
+	require.Equal(t, 3, rs.Len())
+
+	rs.Add(r(1, 12, 1, 14)) // adjacent to both, merges them
+	require.Equal(t, []ekatime.TimestampRange{r(1, 8, 1, 9), r(1, 10, 1, 16)}, rs.Ranges())
+
+	rs.Add(r(1, 7, 1, 11))
+	require.Equal(t, []ekatime.TimestampRange{r(1, 7, 1, 16)}, rs.Ranges())
+	require.Equal(t, 9*time.Hour, rs.Duration())
+
+	rs.Remove(r(1, 12, 1, 13))
+	require.Equal(t, []ekatime.TimestampRange{r(1, 7, 1, 12), r(1, 13, 1, 16)}, rs.Ranges())
+	require.True(t, rs.Contains(ts(1, 7)))
+	require.False(t, rs.Contains(ts(1, 12)))
+	require.True(t, rs.ContainsRange(r(1, 8, 1, 12)))
+	require.False(t, rs.ContainsRange(r(1, 8, 1, 14)))
+	require.True(t, rs.Overlaps(r(1, 11, 1, 14)))
+	require.False(t, rs.Overlaps(r(1, 12, 1, 13)))
+
+	require.Equal(t, []ekatime.TimestampRange{r(1, 0, 1, 7), r(1, 12, 1, 13), r(1, 16, 2, 0)},
+		rs.Gaps(r(1, 0, 2, 0)))
+
+	other := ekatime.NewRangeSet(r(1, 10, 1, 14), r(1, 15, 1, 20))
+	require.Equal(t, []ekatime.TimestampRange{r(1, 10, 1, 12), r(1, 13, 1, 14), r(1, 15, 1, 16)},
+		rs.Intersection(other).Ranges())
+	require.Equal(t, []ekatime.TimestampRange{r(1, 7, 1, 20)},
+		rs.Union(other).Ranges())
+	require.Equal(t, []ekatime.TimestampRange{r(1, 7, 1, 10), r(1, 14, 1, 15)},
+		rs.Difference(other).Ranges())
+
+	var nilSet *ekatime.RangeSet
+	require.Nil(t, nilSet.Add(r(1, 0, 1, 1)))
+	require.False(t, nilSet.Contains(ts(1, 0)))
+	require.True(t, nilSet.IsEmpty())
+	require.Equal(t, rs.Ranges(), rs.Union(nilSet).Ranges())
+}
@@ -0,0 +1,224 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekatime
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/qioalice/ekago/v2/ekaerr"
+)
+
+type (
+	// RRuleFreq is a frequency of RRule. See RRULE_FREQ_... constants.
+	RRuleFreq uint8
+
+	// RRuleWeekday is a day of week RRule recurs on (BYDAY part of RRULE).
+	// N is an occurrence of the day of week inside a month (or year):
+	// 0 means each one, 1 means the first one, -1 means the last one, etc.
+	RRuleWeekday struct {
+		Weekday Weekday
+		N       int8
+	}
+
+	// RRule is a recurrence rule of dates, a subset of RFC 5545 RRULE.
+	// It allows to describe recurring Event s, like "every year on Jan 1",
+	// "the last Friday of each month" or "every 2nd Monday".
+	//
+	// Supported RRULE parts: FREQ (DAILY, WEEKLY, MONTHLY, YEARLY),
+	// INTERVAL, COUNT, UNTIL, BYMONTH, BYMONTHDAY, BYDAY. Weeks start on Monday.
+	// Use ParseRRule() to create RRule from its string representation
+	// or fill the fields manually.
+	//
+	// RRule must not be changed after it's passed to someone.
+	RRule struct {
+		Start    Date // the first date rule may recur on (DTSTART)
+		Freq     RRuleFreq
+		Interval int  // rule recurs every Interval periods, 0 means 1
+		Count    int  // rule recurs no more than Count times, 0 means unlimited
+		Until    Date // rule recurs until this date (including), 0 means unlimited
+
+		ByMonth    []Month
+		ByMonthDay []Day // negative values are from the end of month, -1 is the last day
+		ByDay      []RRuleWeekday
+	}
+
+	// RecurringEvent is an Event that recurs on the dates of its RRule,
+	// like a holiday that is each year on the same date.
+	// See Calendar.RecurringEventAdd().
+	RecurringEvent struct {
+		Rule     *RRule
+		ID       uint16
+		IsDayOff bool
+	}
+)
+
+//noinspection GoSnakeCaseUsage
+const (
+	RRULE_FREQ_DAILY RRuleFreq = 1 + iota
+	RRULE_FREQ_WEEKLY
+	RRULE_FREQ_MONTHLY
+	RRULE_FREQ_YEARLY
+)
+
+// String returns RRULE's FREQ value of the current RRuleFreq, like "MONTHLY".
+func (f RRuleFreq) String() string {
+	switch f {
+	case RRULE_FREQ_DAILY:   return "DAILY"
+	case RRULE_FREQ_WEEKLY:  return "WEEKLY"
+	case RRULE_FREQ_MONTHLY: return "MONTHLY"
+	case RRULE_FREQ_YEARLY:  return "YEARLY"
+	default:                 return "UNKNOWN"
+	}
+}
+
+// ParseRRule parses 's' that is RFC 5545 RRULE value (like "FREQ=MONTHLY;BYDAY=-1FR",
+// "RRULE:" prefix is allowed) and returns RRule that starts at 'start'.
+// Returns an error if 's' is malformed or contains unsupported parts.
+func ParseRRule(start Date, s string) (*RRule, *ekaerr.Error) {
+
+	r := &RRule{Start: start.ToCmp()}
+
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+
+		eq := strings.IndexByte(part, '=')
+		if eq <= 0 {
+			return nil, ekaerr.IllegalFormat.
+				New("ekatime: malformed RRULE part", "part", part).
+				Throw()
+		}
+
+		if err := r.parsePart(strings.ToUpper(part[:eq]), strings.ToUpper(part[eq+1:])); err.IsNotNil() {
+			return nil, err.Throw()
+		}
+	}
+
+	if r.Freq == 0 {
+		return nil, ekaerr.IllegalFormat.
+			New("ekatime: RRULE has no FREQ", "rrule", s).
+			Throw()
+	}
+
+	return r, nil
+}
+
+// String returns RFC 5545 RRULE value of the current RRule (w/o "RRULE:" prefix),
+// like "FREQ=MONTHLY;BYDAY=-1FR". Start date is not a part of it. Nil safe.
+func (r *RRule) String() string {
+
+	if r == nil {
+		return ""
+	}
+
+	parts := []string{"FREQ=" + r.Freq.String()}
+
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != 0 {
+		y, m, d := r.Until.Split()
+		parts = append(parts, "UNTIL="+UnixFrom(y, m, d, 0, 0, 0).Std().Format(_ICAL_DATE_LAYOUT))
+	}
+	if len(r.ByMonth) > 0 {
+		values := make([]string, len(r.ByMonth))
+		for i, m := range r.ByMonth {
+			values[i] = strconv.Itoa(int(m))
+		}
+		parts = append(parts, "BYMONTH="+strings.Join(values, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		values := make([]string, len(r.ByMonthDay))
+		for i, d := range r.ByMonthDay {
+			values[i] = strconv.Itoa(int(d))
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(values, ","))
+	}
+	if len(r.ByDay) > 0 {
+		values := make([]string, len(r.ByDay))
+		for i, wd := range r.ByDay {
+			values[i] = wd.String()
+		}
+		parts = append(parts, "BYDAY="+strings.Join(values, ","))
+	}
+
+	return strings.Join(parts, ";")
+}
+
+// String returns RRULE's BYDAY value of the current RRuleWeekday, like "-1FR".
+func (wd RRuleWeekday) String() string {
+	s := _RRuleWeekdays[wd.Weekday.To06()]
+	if wd.N != 0 {
+		s = strconv.Itoa(int(wd.N)) + s
+	}
+	return s
+}
+
+// Between returns dates the current RRule recurs on that are in [from, to] range.
+// Nil safe.
+func (r *RRule) Between(from, to Date) []Date {
+
+	if r == nil {
+		return nil
+	}
+
+	var dates []Date
+	r.iterate(workdaysDayNum(from), workdaysDayNum(to), func(day Timestamp) bool {
+		dates = append(dates, workdaysDate(day))
+		return true
+	})
+
+	return dates
+}
+
+// Matches reports whether the current RRule recurs on 'dd'. Nil safe.
+func (r *RRule) Matches(dd Date) bool {
+	return r.matchesDay(workdaysDayNum(dd))
+}
+
+// Next returns the first date the current RRule recurs on that is after 'dd'.
+// Returns 0 if there is no such date (in the next 100 years). Nil safe.
+func (r *RRule) Next(dd Date) Date {
+
+	if r == nil {
+		return 0
+	}
+
+	var (
+		next Date
+		from = workdaysDayNum(dd) + 1
+	)
+
+	r.iterate(from, from + _RRULE_SEARCH_MAX_DAYS, func(day Timestamp) bool {
+		next = workdaysDate(day)
+		return false
+	})
+
+	return next
+}
+
+// Between returns Event s the current RecurringEvent recurs on
+// that are in [from, to] range.
+func (re RecurringEvent) Between(from, to Date) []Event {
+
+	dates := re.Rule.Between(from, to)
+	if len(dates) == 0 {
+		return nil
+	}
+
+	events := make([]Event, len(dates))
+	for i, date := range dates {
+		events[i] = NewEvent(date, re.ID, re.IsDayOff)
+	}
+
+	return events
+}
@@ -0,0 +1,429 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekatime
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/qioalice/ekago/v2/ekaerr"
+)
+
+//noinspection GoSnakeCaseUsage
+const (
+	// _RRULE_SEARCH_MAX_DAYS is how much days RRule.Next() looks through.
+	_RRULE_SEARCH_MAX_DAYS = 100 * 366
+)
+
+var (
+	// _RRuleWeekdays is RRULE's days of week, indexed by Weekday.To06().
+	_RRuleWeekdays = [7]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+)
+
+// parsePart parses RRULE's 'name'='value' part, saving it to the current RRule.
+func (r *RRule) parsePart(name, value string) *ekaerr.Error {
+
+	var (
+		values    = strings.Split(value, ",")
+		legacyErr error
+	)
+
+	switch name {
+
+	case "FREQ":
+		for f := RRULE_FREQ_DAILY; f <= RRULE_FREQ_YEARLY; f++ {
+			if f.String() == value {
+				r.Freq = f
+			}
+		}
+		if r.Freq == 0 {
+			return ekaerr.UnsupportedOperation.
+				New("ekatime: unsupported RRULE FREQ", "freq", value).
+				Throw()
+		}
+
+	case "INTERVAL":
+		r.Interval, legacyErr = strconv.Atoi(value)
+
+	case "COUNT":
+		r.Count, legacyErr = strconv.Atoi(value)
+
+	case "UNTIL":
+		if len(value) < len(_ICAL_DATE_LAYOUT) {
+			return ekaerr.IllegalFormat.
+				New("ekatime: malformed RRULE UNTIL", "until", value).
+				Throw()
+		}
+		var y, m, d int
+		if y, legacyErr = strconv.Atoi(value[:4]); legacyErr == nil {
+			if m, legacyErr = strconv.Atoi(value[4:6]); legacyErr == nil {
+				d, legacyErr = strconv.Atoi(value[6:8])
+			}
+		}
+		r.Until = NewDate(Year(y), Month(m), Day(d))
+
+	case "BYMONTH":
+		for _, v := range values {
+			var m int
+			if m, legacyErr = strconv.Atoi(v); legacyErr != nil {
+				break
+			}
+			if m < 1 || m > 12 {
+				return ekaerr.IllegalFormat.
+					New("ekatime: RRULE BYMONTH is out of range", "month", v).
+					Throw()
+			}
+			r.ByMonth = append(r.ByMonth, Month(m))
+		}
+
+	case "BYMONTHDAY":
+		for _, v := range values {
+			var d int
+			if d, legacyErr = strconv.Atoi(v); legacyErr != nil {
+				break
+			}
+			if d == 0 || d < -31 || d > 31 {
+				return ekaerr.IllegalFormat.
+					New("ekatime: RRULE BYMONTHDAY is out of range", "day", v).
+					Throw()
+			}
+			r.ByMonthDay = append(r.ByMonthDay, Day(d))
+		}
+
+	case "BYDAY":
+		for _, v := range values {
+			wd, ok := rruleParseWeekday(v)
+			if !ok {
+				return ekaerr.IllegalFormat.
+					New("ekatime: malformed RRULE BYDAY", "day", v).
+					Throw()
+			}
+			r.ByDay = append(r.ByDay, wd)
+		}
+
+	case "WKST":
+		if value != "MO" {
+			return ekaerr.UnsupportedOperation.
+				New("ekatime: only Monday is supported as RRULE WKST", "wkst", value).
+				Throw()
+		}
+
+	default:
+		return ekaerr.UnsupportedOperation.
+			New("ekatime: unsupported RRULE part", "part", name).
+			Throw()
+	}
+
+	if legacyErr != nil || r.Interval < 0 || r.Count < 0 {
+		return ekaerr.IllegalFormat.
+			New("ekatime: malformed RRULE value", "part", name, "value", value).
+			Throw()
+	}
+
+	return nil
+}
+
+// rruleParseWeekday parses RRULE's BYDAY value, like "MO", "1MO", "-1FR".
+func rruleParseWeekday(s string) (RRuleWeekday, bool) {
+
+	if len(s) < 2 {
+		return RRuleWeekday{}, false
+	}
+
+	wd := RRuleWeekday{Weekday: -1}
+	for i, name := range _RRuleWeekdays {
+		if s[len(s)-2:] == name {
+			wd.Weekday = WeekdayFrom06(int8(i))
+		}
+	}
+
+	if n := s[:len(s)-2]; n != "" {
+		nn, err := strconv.Atoi(n)
+		if err != nil || nn == 0 || nn < -53 || nn > 53 {
+			return RRuleWeekday{}, false
+		}
+		wd.N = int8(nn)
+	}
+
+	return wd, wd.Weekday != -1
+}
+
+// matchesDay reports whether the current RRule recurs on the day with 'day' number
+// (see workdaysDayNum()). Nil safe.
+func (r *RRule) matchesDay(day Timestamp) bool {
+
+	if r == nil {
+		return false
+	}
+
+	matched := false
+	r.iterate(day, day, func(_ Timestamp) bool {
+		matched = true
+		return false
+	})
+
+	return matched
+}
+
+// iterate calls 'cb' for each day's number (see workdaysDayNum())
+// the current RRule recurs on in the [from, to] range in ascending order,
+// until 'cb' returns false.
+func (r *RRule) iterate(from, to Timestamp, cb func(day Timestamp) bool) {
+
+	start := workdaysDayNum(r.Start)
+	if from < start {
+		from = start
+	}
+	if r.Until != 0 {
+		if until := workdaysDayNum(r.Until); to > until {
+			to = until
+		}
+	}
+	if from > to || r.Freq < RRULE_FREQ_DAILY || r.Freq > RRULE_FREQ_YEARLY {
+		return
+	}
+
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+
+	// If there is no COUNT, periods before 'from' may be skipped.
+	k := 0
+	if r.Count == 0 {
+		k = r.periodOf(from) / interval * interval
+	}
+
+	for count := 0; ; k += interval {
+		periodStart, days, ok := r.period(k)
+		if !ok || periodStart > to {
+			return
+		}
+		for _, day := range days {
+			if day < start {
+				continue
+			}
+			if count++; r.Count > 0 && count > r.Count || day > to {
+				return
+			}
+			if day >= from && !cb(day) {
+				return
+			}
+		}
+	}
+}
+
+// periodOf returns an index of period (since the period of RRule.Start)
+// 'day' is in.
+func (r *RRule) periodOf(day Timestamp) int {
+
+	start := workdaysDayNum(r.Start)
+	switch r.Freq {
+
+	case RRULE_FREQ_DAILY:
+		return int(day - start)
+
+	case RRULE_FREQ_WEEKLY:
+		return int(rruleMonday(day) - rruleMonday(start)) / 7
+
+	case RRULE_FREQ_MONTHLY:
+		y1, m1, _ := r.Start.Split()
+		y2, m2, _ := workdaysDate(day).Split()
+		return (int(y2) - int(y1)) * 12 + int(m2) - int(m1)
+
+	default:
+		return int(workdaysDate(day).Year() - r.Start.Year())
+	}
+}
+
+// period returns the first day's number of 'k' period (since the period
+// of RRule.Start) and days' numbers the current RRule recurs on in that period
+// in ascending order. Returns false if period is out of supported years.
+func (r *RRule) period(k int) (Timestamp, []Timestamp, bool) {
+
+	start := workdaysDayNum(r.Start)
+	switch r.Freq {
+
+	case RRULE_FREQ_DAILY:
+		day := start + Timestamp(k)
+		dd := workdaysDate(day)
+		if !r.isMonthOK(dd.Month()) || !r.isMonthDayOK(dd) || !r.isWeekdayOK(dd.Weekday()) {
+			return day, nil, dd.Year() <= _YEAR_MAX
+		}
+		return day, []Timestamp{day}, dd.Year() <= _YEAR_MAX
+
+	case RRULE_FREQ_WEEKLY:
+		var (
+			monday = rruleMonday(start) + Timestamp(k) * 7
+			days   []Timestamp
+		)
+		for day := monday; day < monday + 7; day++ {
+			dd := workdaysDate(day)
+			if !r.isMonthOK(dd.Month()) {
+				continue
+			}
+			if len(r.ByDay) > 0 && r.isWeekdayOK(dd.Weekday()) ||
+				len(r.ByDay) == 0 && dd.Weekday() == r.Start.Weekday() {
+				days = append(days, day)
+			}
+		}
+		return monday, days, workdaysDate(monday).Year() <= _YEAR_MAX
+
+	case RRULE_FREQ_MONTHLY:
+		y, m, _ := r.Start.Split()
+		months := int(m) - 1 + k
+		y, m = y + Year(months / 12), Month(months % 12) + 1
+		if y > _YEAR_MAX {
+			return 0, nil, false
+		}
+		periodStart := workdaysDayNum(NewDate(y, m, 1))
+		if !r.isMonthOK(m) {
+			return periodStart, nil, true
+		}
+		return periodStart, r.monthDays(y, m), true
+
+	default:
+		y := r.Start.Year() + Year(k)
+		if y > _YEAR_MAX {
+			return 0, nil, false
+		}
+		return workdaysDayNum(NewDate(y, MONTH_JANUARY, 1)), r.yearDays(y), true
+	}
+}
+
+// monthDays returns days' numbers of 'y' year 'm' month
+// the current RRule recurs on (using BYMONTHDAY, BYDAY) in ascending order.
+func (r *RRule) monthDays(y Year, m Month) []Timestamp {
+
+	var (
+		first = workdaysDayNum(NewDate(y, m, 1))
+		n     = Timestamp(DaysInMonth(y, m))
+		days  []Timestamp
+	)
+
+	if len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+		if d := Timestamp(r.Start.Day()); d <= n {
+			days = append(days, first + d - 1)
+		}
+		return days
+	}
+
+	for day := first; day < first + n; day++ {
+		dd := workdaysDate(day)
+		if r.isMonthDayOK(dd) && r.isWeekdayNOK(day, first, first + n - 1) {
+			days = append(days, day)
+		}
+	}
+
+	return days
+}
+
+// yearDays returns days' numbers of 'y' year the current RRule recurs on
+// in ascending order.
+func (r *RRule) yearDays(y Year) []Timestamp {
+
+	var days []Timestamp
+
+	switch {
+
+	case len(r.ByMonth) > 0 || len(r.ByMonthDay) > 0:
+		// Each month (or each of BYMONTH months).
+		for m := MONTH_JANUARY; m <= MONTH_DECEMBER; m++ {
+			if r.isMonthOK(m) {
+				days = append(days, r.monthDays(y, m)...)
+			}
+		}
+
+	case len(r.ByDay) > 0:
+		// Days of week inside the whole year.
+		first := workdaysDayNum(NewDate(y, MONTH_JANUARY, 1))
+		last := workdaysDayNum(NewDate(y, MONTH_DECEMBER, 31))
+		for day := first; day <= last; day++ {
+			if r.isWeekdayNOK(day, first, last) {
+				days = append(days, day)
+			}
+		}
+
+	default:
+		m, d := r.Start.Month(), r.Start.Day()
+		if d <= DaysInMonth(y, m) {
+			days = append(days, workdaysDayNum(NewDate(y, m, d)))
+		}
+	}
+
+	sort.Slice(days, func(i, j int) bool { return days[i] < days[j] })
+	return days
+}
+
+// isMonthOK reports whether 'm' passes BYMONTH filter.
+func (r *RRule) isMonthOK(m Month) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, m_ := range r.ByMonth {
+		if m_ == m {
+			return true
+		}
+	}
+	return false
+}
+
+// isMonthDayOK reports whether 'dd' passes BYMONTHDAY filter.
+func (r *RRule) isMonthDayOK(dd Date) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	d, n := dd.Day(), dd.DaysInMonth()
+	for _, d_ := range r.ByMonthDay {
+		if d_ == d || d_ < 0 && n + 1 + d_ == d {
+			return true
+		}
+	}
+	return false
+}
+
+// isWeekdayOK reports whether 'w' passes BYDAY filter, ignoring occurrences.
+func (r *RRule) isWeekdayOK(w Weekday) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, wd := range r.ByDay {
+		if wd.Weekday == w {
+			return true
+		}
+	}
+	return false
+}
+
+// isWeekdayNOK reports whether 'day' passes BYDAY filter respecting occurrences
+// inside the [first, last] days range (month or year).
+func (r *RRule) isWeekdayNOK(day, first, last Timestamp) bool {
+
+	if len(r.ByDay) == 0 {
+		return true
+	}
+
+	w := workdaysWeekday(day)
+	for _, wd := range r.ByDay {
+		switch {
+		case wd.Weekday != w:
+		case wd.N == 0:
+			return true
+		case wd.N > 0 && (day - first) / 7 + 1 == Timestamp(wd.N):
+			return true
+		case wd.N < 0 && (last - day) / 7 + 1 == Timestamp(-wd.N):
+			return true
+		}
+	}
+
+	return false
+}
+
+// rruleMonday returns day's number of Monday of the week 'day' is in.
+func rruleMonday(day Timestamp) Timestamp {
+	return day - Timestamp((workdaysWeekday(day).To06() + 6) % 7)
+}
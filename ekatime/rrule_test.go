@@ -0,0 +1,149 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekatime_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/qioalice/ekago/v2/ekatime"
+
+	"github.com/stretchr/testify/require"
+)
+
+// toCmp returns 'dates' w/o weekdays (see Date.ToCmp()).
+func toCmp(dates []ekatime.Date) []ekatime.Date {
+	for i := range dates {
+		dates[i] = dates[i].ToCmp()
+	}
+	return dates
+}
+
+func TestParseRRule(t *testing.T) {
+
+	tests := []struct {
+		start    ekatime.Date
+		rrule    string
+		from, to ekatime.Date
+		expected []ekatime.Date
+	}{
+		{ // each year on Jan 1
+			start: ekatime.NewDate(2020, 1, 1),
+			rrule: "FREQ=YEARLY",
+			from:  ekatime.NewDate(2019, 6, 1),
+			to:    ekatime.NewDate(2022, 12, 31),
+			expected: []ekatime.Date{
+				ekatime.NewDate(2020, 1, 1),
+				ekatime.NewDate(2021, 1, 1),
+				ekatime.NewDate(2022, 1, 1),
+			},
+		},
+		{ // last Friday of month
+			start: ekatime.NewDate(2021, 1, 1),
+			rrule: "FREQ=MONTHLY;BYDAY=-1FR",
+			from:  ekatime.NewDate(2021, 1, 1),
+			to:    ekatime.NewDate(2021, 4, 30),
+			expected: []ekatime.Date{
+				ekatime.NewDate(2021, 1, 29),
+				ekatime.NewDate(2021, 2, 26),
+				ekatime.NewDate(2021, 3, 26),
+				ekatime.NewDate(2021, 4, 30),
+			},
+		},
+		{ // second Monday of month
+			start: ekatime.NewDate(2021, 1, 1),
+			rrule: "RRULE:FREQ=MONTHLY;BYDAY=2MO",
+			from:  ekatime.NewDate(2021, 1, 1),
+			to:    ekatime.NewDate(2021, 3, 31),
+			expected: []ekatime.Date{
+				ekatime.NewDate(2021, 1, 11),
+				ekatime.NewDate(2021, 2, 8),
+				ekatime.NewDate(2021, 3, 8),
+			},
+		},
+		{ // every other Monday, 3 times
+			start: ekatime.NewDate(2021, 1, 4),
+			rrule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO;COUNT=3",
+			from:  ekatime.NewDate(2021, 1, 1),
+			to:    ekatime.NewDate(2021, 12, 31),
+			expected: []ekatime.Date{
+				ekatime.NewDate(2021, 1, 4),
+				ekatime.NewDate(2021, 1, 18),
+				ekatime.NewDate(2021, 2, 1),
+			},
+		},
+		{ // last day of month until Mar
+			start: ekatime.NewDate(2021, 1, 1),
+			rrule: "FREQ=MONTHLY;BYMONTHDAY=-1;UNTIL=20210331",
+			from:  ekatime.NewDate(2021, 1, 1),
+			to:    ekatime.NewDate(2021, 12, 31),
+			expected: []ekatime.Date{
+				ekatime.NewDate(2021, 1, 31),
+				ekatime.NewDate(2021, 2, 28),
+				ekatime.NewDate(2021, 3, 31),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		rule, err := ekatime.ParseRRule(test.start, test.rrule)
+		require.True(t, err.IsNil(), test.rrule)
+		require.Equal(t, toCmp(test.expected), toCmp(rule.Between(test.from, test.to)), test.rrule)
+
+		// String() must be parsed back to the same rule.
+		parsed, err := ekatime.ParseRRule(test.start, rule.String())
+		require.True(t, err.IsNil(), test.rrule)
+		require.Equal(t, rule, parsed, test.rrule)
+	}
+
+	for _, rrule := range []string{"", "INTERVAL=2", "FREQ=HOURLY", "FREQ=DAILY;BYDAY=XX"} {
+		_, err := ekatime.ParseRRule(ekatime.NewDate(2021, 1, 1), rrule)
+		require.True(t, err.IsNotNil(), rrule)
+	}
+}
+
+func TestRRule_Next(t *testing.T) {
+
+	rule, err := ekatime.ParseRRule(ekatime.NewDate(2021, 1, 1), "FREQ=MONTHLY;BYDAY=-1FR")
+	require.True(t, err.IsNil())
+
+	require.True(t, rule.Matches(ekatime.NewDate(2021, 1, 29)))
+	require.False(t, rule.Matches(ekatime.NewDate(2021, 1, 22)))
+	require.Equal(t, ekatime.NewDate(2021, 2, 26), rule.Next(ekatime.NewDate(2021, 1, 29)).ToCmp())
+}
+
+func TestCalendar_EventsBetween(t *testing.T) {
+
+	newYear, err := ekatime.ParseRRule(ekatime.NewDate(2020, 1, 1), "FREQ=YEARLY")
+	require.True(t, err.IsNil())
+
+	c := new(ekatime.Calendar).
+		RecurringEventAdd(ekatime.RecurringEvent{Rule: newYear, ID: 1, IsDayOff: true}).
+		EventAdd(ekatime.NewEvent(ekatime.NewDate(2022, 1, 1), 2, true))
+
+	// Explicit event overrides the recurring one.
+	require.Equal(t, []ekatime.Event{
+		ekatime.NewEvent(ekatime.NewDate(2021, 1, 1), 1, true),
+		ekatime.NewEvent(ekatime.NewDate(2022, 1, 1), 2, true),
+	}, c.EventsBetween(ekatime.NewDate(2020, 6, 1), ekatime.NewDate(2022, 6, 1)))
+
+	mapping := &ekatime.ICalMapping{
+		Categories: map[string]ekatime.ICalCategory{
+			"Holiday":  {ID: 1, IsDayOff: true},
+			"Birthday": {ID: 2, IsDayOff: true},
+		},
+	}
+
+	var b bytes.Buffer
+	require.True(t, c.EventsExportICal(&b, mapping).IsNil())
+	require.Contains(t, b.String(), "RRULE:FREQ=YEARLY\r\n")
+
+	imported := new(ekatime.Calendar)
+	require.True(t, imported.EventsImportICal(&b, mapping).IsNil())
+	require.Equal(t,
+		c.EventsBetween(ekatime.NewDate(2020, 6, 1), ekatime.NewDate(2022, 6, 1)),
+		imported.EventsBetween(ekatime.NewDate(2020, 6, 1), ekatime.NewDate(2022, 6, 1)))
+}
@@ -0,0 +1,254 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekatime
+
+import (
+	"time"
+)
+
+type (
+	// ScheduleInterval is an one interval of working hours [From, To)
+	// inside a day, like 09:00 - 13:00. 'To' of 00:00:00 means the end of day.
+	ScheduleInterval struct {
+		From Time
+		To   Time
+	}
+
+	// Schedule is a working hours schedule (business hours) on top of
+	// Workdays (or Calendar) that determines what days are day offs.
+	//
+	// Working hours of workdays can be set for all days, for days of week,
+	// for days that have Event s with some ID (e.g. shortened pre-holiday days)
+	// or for specific dates:
+	//
+	// 		s := ekatime.NewSchedule().
+	// 			WithWorkdays(ekatime.NewWorkdays(events)).
+	// 			WithHours(
+	// 				ekatime.NewScheduleInterval(ekatime.NewTime(9, 0, 0), ekatime.NewTime(13, 0, 0)),
+	// 				ekatime.NewScheduleInterval(ekatime.NewTime(14, 0, 0), ekatime.NewTime(18, 0, 0))).
+	// 			WithHoursForEvent(PRE_HOLIDAY_EVENT_ID,
+	// 				ekatime.NewScheduleInterval(ekatime.NewTime(9, 0, 0), ekatime.NewTime(16, 0, 0)))
+	//
+	// 		deadline := s.AddBusinessDuration(ekatime.Now(), 8 * time.Hour)
+	//
+	// Priority of working hours (from highest): specific date, Event's ID,
+	// day of week, all days. Day offs have no working hours
+	// (except specific dates overriding).
+	//
+	// Schedule must not be changed after it's passed to someone
+	// or it's used by many goroutines.
+	Schedule struct {
+		hours        []ScheduleInterval
+		hoursWeekday [7][]ScheduleInterval // nil if not set
+		hoursEvent   map[uint16][]ScheduleInterval
+		hoursDate    map[Timestamp][]ScheduleInterval // day's number -> hours
+
+		workdays *Workdays
+		calendar *Calendar // if not nil, its Workdays() is used instead of 'workdays'
+		loc      *time.Location
+	}
+)
+
+//noinspection GoSnakeCaseUsage
+const (
+	// SCHEDULE_SEARCH_MAX_DAYS is a max number of days Schedule looks through
+	// to find the next opening or to add business duration.
+	SCHEDULE_SEARCH_MAX_DAYS = 3660
+)
+
+// NewScheduleInterval creates a new ScheduleInterval [from, to).
+func NewScheduleInterval(from, to Time) ScheduleInterval {
+	return ScheduleInterval{From: from, To: to}
+}
+
+// NewSchedule creates a new Schedule w/o working hours,
+// nil Workdays (no events, WEEKEND_DEFAULT) and UTC time zone.
+// It's the same as new(Schedule).
+func NewSchedule() *Schedule {
+	return new(Schedule)
+}
+
+// WithWorkdays sets Workdays that determines what days are day offs
+// and what Event s days have. Overrides WithCalendar(). Nil safe.
+func (s *Schedule) WithWorkdays(w *Workdays) *Schedule {
+	if s != nil {
+		s.workdays, s.calendar = w, nil
+	}
+	return s
+}
+
+// WithCalendar marks that Calendar's confirmed events and weekend
+// must be used to determine day offs (see Calendar.Workdays()),
+// so Schedule follows Calendar's changes. Overrides WithWorkdays(). Nil safe.
+func (s *Schedule) WithCalendar(c *Calendar) *Schedule {
+	if s != nil {
+		s.workdays, s.calendar = nil, c
+	}
+	return s
+}
+
+// WithLocation sets the time zone working hours are in. Nil means UTC.
+// Nil safe.
+func (s *Schedule) WithLocation(loc *time.Location) *Schedule {
+	if s != nil {
+		s.loc = loc
+	}
+	return s
+}
+
+// WithHours sets working hours of all workdays. Nil safe.
+func (s *Schedule) WithHours(intervals ...ScheduleInterval) *Schedule {
+	if s != nil {
+		s.hours = scheduleNormalizeIntervals(intervals)
+	}
+	return s
+}
+
+// WithHoursFor sets working hours of workdays that are 'w' day of week,
+// overriding WithHours() for them. Nil safe.
+//
+// Also used for transferred workdays (like Saturday that is workday),
+// so you may want to set WithHours() in that case.
+func (s *Schedule) WithHoursFor(w Weekday, intervals ...ScheduleInterval) *Schedule {
+	if s != nil && w >= 0 && w <= 6 {
+		s.hoursWeekday[w] = scheduleNormalizeIntervals(intervals)
+	}
+	return s
+}
+
+// WithHoursForEvent sets working hours of workdays that have an Event
+// with 'id' ID, like shortened pre-holiday days. Nil safe.
+func (s *Schedule) WithHoursForEvent(id uint16, intervals ...ScheduleInterval) *Schedule {
+	if s != nil {
+		if s.hoursEvent == nil {
+			s.hoursEvent = make(map[uint16][]ScheduleInterval)
+		}
+		s.hoursEvent[id] = scheduleNormalizeIntervals(intervals)
+	}
+	return s
+}
+
+// WithHoursForDate sets working hours of 'dd' day, regardless whether it's
+// workday or day off. No intervals means that 'dd' is closed. Nil safe.
+func (s *Schedule) WithHoursForDate(dd Date, intervals ...ScheduleInterval) *Schedule {
+	if s != nil {
+		if s.hoursDate == nil {
+			s.hoursDate = make(map[Timestamp][]ScheduleInterval)
+		}
+		s.hoursDate[workdaysDayNum(dd)] = scheduleNormalizeIntervals(intervals)
+	}
+	return s
+}
+
+// HoursFor returns working hours of 'dd' day. Returns nil if it's closed.
+// Nil safe.
+func (s *Schedule) HoursFor(dd Date) []ScheduleInterval {
+	if s == nil {
+		return nil
+	}
+	return s.hoursForDay(s.getWorkdays(), workdaysDayNum(dd))
+}
+
+// IsOpenAt reports whether 'ts' is inside working hours. Nil safe.
+func (s *Schedule) IsOpenAt(ts Timestamp) bool {
+
+	if s == nil {
+		return false
+	}
+
+	for _, interval := range s.dayIntervals(s.getWorkdays(), s.dayNumOf(ts)) {
+		if interval[0] <= ts && ts < interval[1] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NextOpening returns 'ts' if it's inside working hours or the beginning
+// of the next working hours interval otherwise.
+// Returns 0 if there are no working hours in SCHEDULE_SEARCH_MAX_DAYS days.
+// Nil safe.
+func (s *Schedule) NextOpening(ts Timestamp) Timestamp {
+
+	if s == nil {
+		return 0
+	}
+
+	w := s.getWorkdays()
+	for day, i := s.dayNumOf(ts), 0; i < SCHEDULE_SEARCH_MAX_DAYS; day, i = day+1, i+1 {
+		for _, interval := range s.dayIntervals(w, day) {
+			if ts < interval[1] {
+				return maxTimestamp(ts, interval[0])
+			}
+		}
+	}
+
+	return 0
+}
+
+// BusinessDurationBetween returns how much working time is in [ts1, ts2).
+// Returns negative duration if 'ts2' is before 'ts1'. Nil safe.
+func (s *Schedule) BusinessDurationBetween(ts1, ts2 Timestamp) time.Duration {
+
+	if s == nil {
+		return 0
+	}
+
+	sign := time.Duration(1)
+	if ts1 > ts2 {
+		ts1, ts2, sign = ts2, ts1, -1
+	}
+
+	var (
+		w     = s.getWorkdays()
+		total Timestamp
+	)
+
+	for day, last := s.dayNumOf(ts1), s.dayNumOf(ts2); day <= last; day++ {
+		for _, interval := range s.dayIntervals(w, day) {
+			from, to := maxTimestamp(ts1, interval[0]), minTimestamp(ts2, interval[1])
+			if from < to {
+				total += to - from
+			}
+		}
+	}
+
+	return time.Duration(total) * time.Second * sign
+}
+
+// AddBusinessDuration returns the moment 'd' of working time will elapse
+// since 'ts', like SLA deadline. Precision is second.
+// Returns 'ts' if 'd' <= 0 or 0 if there are not enough working hours
+// in SCHEDULE_SEARCH_MAX_DAYS days. Nil safe.
+func (s *Schedule) AddBusinessDuration(ts Timestamp, d time.Duration) Timestamp {
+
+	if s == nil {
+		return 0
+	}
+
+	remaining := Timestamp(d / time.Second)
+	if remaining <= 0 {
+		return ts
+	}
+
+	w := s.getWorkdays()
+	for day, i := s.dayNumOf(ts), 0; i < SCHEDULE_SEARCH_MAX_DAYS; day, i = day+1, i+1 {
+		for _, interval := range s.dayIntervals(w, day) {
+			from := maxTimestamp(ts, interval[0])
+			if from >= interval[1] {
+				continue
+			}
+			if available := interval[1] - from; remaining <= available {
+				return from + remaining
+			} else {
+				remaining -= available
+			}
+		}
+	}
+
+	return 0
+}
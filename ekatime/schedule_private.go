@@ -0,0 +1,123 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekatime
+
+import (
+	"sort"
+)
+
+// getWorkdays returns Workdays that determines day offs.
+func (s *Schedule) getWorkdays() *Workdays {
+	if s.calendar != nil {
+		return s.calendar.Workdays()
+	}
+	return s.workdays
+}
+
+// dayNumOf returns a number of day 'ts' is in Schedule's time zone.
+func (s *Schedule) dayNumOf(ts Timestamp) Timestamp {
+	return workdaysDayNum(ts.DateIn(s.loc))
+}
+
+// hoursForDay returns working hours of the day with 'day' number
+// using 'w' to determine day offs and events.
+func (s *Schedule) hoursForDay(w *Workdays, day Timestamp) []ScheduleInterval {
+
+	if hours, found := s.hoursDate[day]; found {
+		return hours
+	}
+
+	if !w.isWorkday(day) {
+		return nil
+	}
+
+	if event, found := w.EventFor(workdaysDate(day)); found {
+		if hours, found := s.hoursEvent[event.ID()]; found {
+			return hours
+		}
+	}
+
+	if hours := s.hoursWeekday[workdaysWeekday(day)]; hours != nil {
+		return hours
+	}
+
+	return s.hours
+}
+
+// dayIntervals returns working hours of the day with 'day' number
+// as [from, to) Timestamp pairs in Schedule's time zone.
+func (s *Schedule) dayIntervals(w *Workdays, day Timestamp) []TimestampPair {
+
+	hours := s.hoursForDay(w, day)
+	if len(hours) == 0 {
+		return nil
+	}
+
+	var (
+		y, m, d   = workdaysDate(day).Split()
+		loc       = normalizeLocation(s.loc)
+		intervals = make([]TimestampPair, 0, len(hours))
+	)
+
+	for _, interval := range hours {
+		from := UnixFromIn(y, m, d, interval.From.Hour(), interval.From.Minute(), interval.From.Second(), loc)
+
+		var to Timestamp
+		if interval.To.secondsOfDay() == 0 {
+			to = beginningOfDayIn(y, m, d+1, loc)
+		} else {
+			to = UnixFromIn(y, m, d, interval.To.Hour(), interval.To.Minute(), interval.To.Second(), loc)
+		}
+
+		if from < to {
+			intervals = append(intervals, TimestampPair{from, to})
+		}
+	}
+
+	return intervals
+}
+
+// scheduleNormalizeIntervals returns a copy of 'intervals' w/o empty ones,
+// sorted by their beginning. Returns not nil slice even if it's empty.
+func scheduleNormalizeIntervals(intervals []ScheduleInterval) []ScheduleInterval {
+
+	normalized := make([]ScheduleInterval, 0, len(intervals))
+	for _, interval := range intervals {
+		from, to := interval.From.secondsOfDay(), interval.To.secondsOfDay()
+		if from < to || to == 0 {
+			normalized = append(normalized, interval)
+		}
+	}
+
+	sort.Slice(normalized, func(i, j int) bool {
+		return normalized[i].From.secondsOfDay() < normalized[j].From.secondsOfDay()
+	})
+
+	return normalized
+}
+
+// secondsOfDay returns how much seconds have been passed since midnight
+// until the current Time.
+func (t Time) secondsOfDay() Timestamp {
+	hh, mm, ss := t.Split()
+	return Timestamp(hh)*SECONDS_IN_HOUR + Timestamp(mm)*SECONDS_IN_MINUTE + Timestamp(ss)
+}
+
+// minTimestamp returns the least of 'a' and 'b'.
+func minTimestamp(a, b Timestamp) Timestamp {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// maxTimestamp returns the greatest of 'a' and 'b'.
+func maxTimestamp(a, b Timestamp) Timestamp {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,76 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekatime_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qioalice/ekago/v2/ekatime"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedule(t *testing.T) {
+
+	// 8 Mar 2021 (Mo) is holiday, 5 Mar 2021 (Fr) is shortened pre-holiday day.
+	const PRE_HOLIDAY = 2
+
+	workdays := ekatime.NewWorkdays([]ekatime.Event{
+		ekatime.NewEvent(ekatime.NewDate(2021, 3, 5), PRE_HOLIDAY, false),
+		ekatime.NewEvent(ekatime.NewDate(2021, 3, 8), 1, true),
+	})
+
+	s := ekatime.NewSchedule().
+		WithWorkdays(workdays).
+		WithHours(
+			ekatime.NewScheduleInterval(ekatime.NewTime(14, 0, 0), ekatime.NewTime(18, 0, 0)),
+			ekatime.NewScheduleInterval(ekatime.NewTime(9, 0, 0), ekatime.NewTime(13, 0, 0))).
+		WithHoursForEvent(PRE_HOLIDAY,
+			ekatime.NewScheduleInterval(ekatime.NewTime(9, 0, 0), ekatime.NewTime(16, 0, 0)))
+
+	require.True(t, s.IsOpenAt(ekatime.UnixFrom(2021, 3, 4, 9, 0, 0)))
+	require.False(t, s.IsOpenAt(ekatime.UnixFrom(2021, 3, 4, 13, 30, 0)))
+	require.False(t, s.IsOpenAt(ekatime.UnixFrom(2021, 3, 4, 18, 0, 0)))
+	require.True(t, s.IsOpenAt(ekatime.UnixFrom(2021, 3, 5, 15, 0, 0)))
+	require.False(t, s.IsOpenAt(ekatime.UnixFrom(2021, 3, 5, 16, 0, 0)))
+	require.False(t, s.IsOpenAt(ekatime.UnixFrom(2021, 3, 8, 10, 0, 0)))
+
+	ts := ekatime.UnixFrom(2021, 3, 4, 10, 0, 0)
+	require.Equal(t, ts, s.NextOpening(ts))
+	require.Equal(t, ekatime.UnixFrom(2021, 3, 4, 14, 0, 0), s.NextOpening(ekatime.UnixFrom(2021, 3, 4, 13, 0, 0)))
+	require.Equal(t, ekatime.UnixFrom(2021, 3, 9, 9, 0, 0), s.NextOpening(ekatime.UnixFrom(2021, 3, 5, 16, 0, 0)))
+
+	// Thu: 3h + 4h, Fri: 7h, Mon: holiday, Tue: 1h.
+	from, to := ekatime.UnixFrom(2021, 3, 4, 10, 0, 0), ekatime.UnixFrom(2021, 3, 9, 10, 0, 0)
+	require.Equal(t, 15*time.Hour, s.BusinessDurationBetween(from, to))
+	require.Equal(t, -15*time.Hour, s.BusinessDurationBetween(to, from))
+
+	require.Equal(t, to, s.AddBusinessDuration(from, 15*time.Hour))
+	require.Equal(t, ekatime.UnixFrom(2021, 3, 4, 15, 0, 0), s.AddBusinessDuration(from, 4*time.Hour))
+	require.Equal(t, ekatime.UnixFrom(2021, 3, 4, 13, 0, 0), s.AddBusinessDuration(from, 3*time.Hour))
+
+	// Specific date overrides even day off.
+	s.WithHoursForDate(ekatime.NewDate(2021, 3, 8),
+		ekatime.NewScheduleInterval(ekatime.NewTime(10, 0, 0), ekatime.NewTime(0, 0, 0)))
+	require.True(t, s.IsOpenAt(ekatime.UnixFrom(2021, 3, 8, 23, 59, 59)))
+	require.Len(t, s.HoursFor(ekatime.NewDate(2021, 3, 8)), 1)
+	require.Nil(t, s.HoursFor(ekatime.NewDate(2021, 3, 7)))
+}
+
+func TestSchedule_WithLocation(t *testing.T) {
+
+	msk := loadLocation(t, "Europe/Moscow")
+
+	s := ekatime.NewSchedule().
+		WithLocation(msk).
+		WithHours(ekatime.NewScheduleInterval(ekatime.NewTime(9, 0, 0), ekatime.NewTime(18, 0, 0)))
+
+	// 09:00 MSK is 06:00 UTC.
+	require.False(t, s.IsOpenAt(ekatime.UnixFrom(2021, 3, 4, 5, 59, 59)))
+	require.True(t, s.IsOpenAt(ekatime.UnixFrom(2021, 3, 4, 6, 0, 0)))
+	require.Equal(t, ekatime.UnixFrom(2021, 3, 5, 6, 0, 0), s.NextOpening(ekatime.UnixFrom(2021, 3, 4, 20, 0, 0)))
+}
@@ -123,9 +123,10 @@ func (ts Timestamp) Weekday() Weekday {
 	return Weekday(((ts + SECONDS_IN_DAY) % SECONDS_IN_WEEK) / SECONDS_IN_DAY)
 }
 
-// Now is just the same as time.Now().
+// Now returns the current Timestamp using DefaultClock()
+// (it's just the same as time.Now() by default).
 func Now() Timestamp {
-	return UnixFromStd(time.Now())
+	return UnixFromStd(DefaultClock().Now())
 }
 
 // UnixFrom creates and returns Timestamp object from the presented Date 'd'
@@ -17,6 +17,7 @@ var (
 	_ERR_NOT_ISO8601_TIMESTAMP   = errors.New("incorrect ISO8601 timestamp format (must be YYYY-MM-DDThh:mm:ss)")
 	_ERR_BAD_TIMESTAMP_SEPARATOR = errors.New("ISO8601 require using 'T' as date time separator")
 	_ERR_BAD_JSON_TIMESTAMP_QUO  = errors.New("bad JSON ISO8601 timestamp representation (forgotten quotes?)")
+	_ERR_NOT_RFC3339_TIMESTAMP   = errors.New("incorrect RFC3339 timestamp format (must be YYYY-MM-DDThh:mm:ss±hh:mm or YYYY-MM-DDThh:mm:ssZ)")
 )
 
 // AppendTo generates a string representation of Timestamp and adds it to the b,
@@ -119,8 +120,11 @@ func (ts Timestamp) String() string {
 // "YYYY-MM-DDThh:mm:ss", and returns it. Always returns nil as error.
 //
 // JSON null supporting:
-// - Writes JSON null if current Timestamp receiver == nil.
 // - Writes JSON null if current Timestamp == 0 (*).
+// - encoding/json writes JSON null itself for nil *Timestamp.
+//
+// It's a value receiver (as MarshalText() has), so the Timestamp is encoded
+// the same way regardless whether it's a part of struct value or struct pointer.
 //
 // -----
 //
@@ -129,9 +133,9 @@ func (ts Timestamp) String() string {
 // this date and it's not a null-like value?
 // And, of course, you always may use 01 Jan 1970 00:00:01 and it will be marshalled
 // correctly.
-func (ts *Timestamp) MarshalJSON() ([]byte, error) {
+func (ts Timestamp) MarshalJSON() ([]byte, error) {
 
-	if ts == nil || *ts == 0 {
+	if ts == 0 {
 		return ekaenc.NULL_JSON_BYTES_SLICE, nil
 	}
 
@@ -156,6 +160,7 @@ func (ts *Timestamp) MarshalJSON() ([]byte, error) {
 // ISO8601 quoted date with time in the one of the following formats:
 //   "YYYY-MM-DDThh:mm:ss" (recommended),
 //   "YYYYMMDDThh:mm:ss", "YYYY-MM-DDThhmmss", "YYYYMMDDThhmmss"
+// or RFC 3339 quoted timestamp with time zone offset (see UnmarshalText()).
 //
 // JSON null supporting:
 // - It's ok if there is JSON null and receiver == nil (nothing changes)
@@ -188,6 +193,10 @@ func (ts *Timestamp) UnmarshalJSON(b []byte) error {
 
 	switch l := len(b); {
 
+	case l > 21 && b[0] == '"' && b[l-1] == '"':
+		// RFC 3339 with time zone offset or fractional seconds.
+		return ts.UnmarshalText(b[1:l-1])
+
 	case !(l >= 15 && l <= 19) && l != 21:
 		return _ERR_NOT_ISO8601_TIMESTAMP
 
@@ -198,3 +207,33 @@ func (ts *Timestamp) UnmarshalJSON(b []byte) error {
 		return ts.ParseFrom(b[1:l-1])
 	}
 }
+
+// MarshalText encodes the current Timestamp as RFC 3339 timestamp in UTC
+// "YYYY-MM-DDThh:mm:ssZ", and returns it. Always returns nil as error.
+// Use AppendLayoutIn() with LAYOUT_RFC3339 to encode it with time zone offset.
+//
+// Unlike MarshalJSON(), 01 Jan 1970 00:00:00 is encoded as is.
+func (ts Timestamp) MarshalText() ([]byte, error) {
+	return ts.AppendLayout(make([]byte, 0, 20), LAYOUT_RFC3339), nil
+}
+
+// UnmarshalText decodes b into the current Timestamp object expecting b contains
+// RFC 3339 timestamp with time zone offset in the one of the following formats:
+//   "YYYY-MM-DDThh:mm:ssZ", "YYYY-MM-DDThh:mm:ss±hh:mm",
+// fractional seconds are allowed (and truncated), e.g:
+//   "2020-09-12T13:14:15.123+03:00".
+//
+// Returns an error if b is not RFC 3339 timestamp or receiver is nil.
+func (ts *Timestamp) UnmarshalText(b []byte) error {
+
+	if ts == nil {
+		return _ERR_NIL_TIMESTAMP_RECEIVER
+	}
+
+	switch err := ts.ParseLayout(b, LAYOUT_RFC3339); err {
+	case _ERR_LAYOUT_MISMATCH, _ERR_LAYOUT_EXTRA_TEXT:
+		return _ERR_NOT_RFC3339_TIMESTAMP
+	default:
+		return err
+	}
+}
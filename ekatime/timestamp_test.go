@@ -27,6 +27,22 @@ func TestDate_Equal(t *testing.T) {
 	require.True(t, d1.Equal(d2))
 }
 
+func TestBeginningOfMonth(t *testing.T) {
+
+	// Both cached and not cached years must be the same as UnixFrom() returns,
+	// even if the local time zone is not UTC.
+	for _, y := range []ekatime.Year{1970, 2021, ekatime.Now().Year(), ekatime.Now().Year() + 20} {
+		for m := ekatime.Month(1); m <= 12; m++ {
+			bm := ekatime.UnixFrom(y, m, 1, 0, 0, 0)
+			em := ekatime.NewDate(y, m, 1).AddDays(ekatime.Days(ekatime.DaysInMonth(y, m))).WithTime(0, 0, 0) - 1
+
+			require.Equal(t, bm, ekatime.BeginningOfMonth(y, m), "%d/%d", y, m)
+			require.Equal(t, em, ekatime.EndOfMonth(y, m), "%d/%d", y, m)
+			require.Equal(t, ekatime.TimestampPair{bm, em}, ekatime.BeginningAndEndOfMonth(y, m), "%d/%d", y, m)
+		}
+	}
+}
+
 // =========================================================================== //
 // =========================================================================== //
 // =========================================================================== //
@@ -0,0 +1,106 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekatime
+
+import (
+	"time"
+)
+
+// In returns standard Golang's time.Time object with the same values
+// as current Timestamp have, but in the 'loc' location.
+// Nil 'loc' means UTC (it's the same as Std() call then).
+func (ts Timestamp) In(loc *time.Location) time.Time {
+	return time.Unix(ts.I64(), 0).In(normalizeLocation(loc))
+}
+
+// DateIn returns the Date object, the current Timestamp includes which
+// in the 'loc' location. Nil 'loc' means UTC.
+//
+// Unlike Date(), never returns 0/0/0 Date.
+func (ts Timestamp) DateIn(loc *time.Location) Date {
+	y, m, d := ts.In(loc).Date()
+	return NewDate(Year(y), Month(m), Day(d)).ensureWeekdayExist()
+}
+
+// TimeIn returns the Time object, the current Timestamp includes which
+// in the 'loc' location. Nil 'loc' means UTC.
+func (ts Timestamp) TimeIn(loc *time.Location) Time {
+	hh, mm, ss := ts.In(loc).Clock()
+	return NewTime(Hour(hh), Minute(mm), Second(ss))
+}
+
+// SplitIn returns the Date and Time, the current Timestamp includes which
+// in the 'loc' location. It's just like a separate DateIn(), TimeIn() calls.
+func (ts Timestamp) SplitIn(loc *time.Location) (d Date, t Time) {
+	return ts.DateIn(loc), ts.TimeIn(loc)
+}
+
+// BeginningOfDayIn returns the day beginning of the current timestamp 'ts'
+// in the 'loc' location. Nil 'loc' means UTC.
+//
+// Respects DST: if there is no midnight in the day (clock has been moved
+// forward at midnight), the first existing moment of the day is returned.
+//
+// E.g: 12/11/2019, 15:46:40 (3:46:40 PM) MSK -> 12/11/2019 00:00:00 (12:00:00 AM) MSK.
+func (ts Timestamp) BeginningOfDayIn(loc *time.Location) Timestamp {
+	y, m, d := ts.In(loc).Date()
+	return beginningOfDayIn(Year(y), Month(m), Day(d), normalizeLocation(loc))
+}
+
+// EndOfDayIn returns the day ending of the current timestamp 'ts'
+// in the 'loc' location. Nil 'loc' means UTC.
+//
+// Respects DST, so the day may last 23 or 25 hours (or even other)
+// in the locations with DST.
+//
+// E.g: 12/11/2019, 15:46:40 (3:46:40 PM) MSK -> 12/11/2019 23:59:59 (11:59:59 PM) MSK.
+func (ts Timestamp) EndOfDayIn(loc *time.Location) Timestamp {
+	return ts.BeginningAndEndOfDayIn(loc)[1]
+}
+
+// BeginningAndEndOfDayIn is the same as BeginningOfDayIn() and EndOfDayIn() calls.
+func (ts Timestamp) BeginningAndEndOfDayIn(loc *time.Location) TimestampPair {
+	loc = normalizeLocation(loc)
+	y, m, d := ts.In(loc).Date()
+	return TimestampPair{
+		beginningOfDayIn(Year(y), Month(m), Day(d), loc),
+		beginningOfDayIn(Year(y), Month(m), Day(d+1), loc) - 1,
+	}
+}
+
+// TillNextMidnightIn returns how much ns (as time.Duration) must be passed until
+// next midnight (12.00 AM) in the 'loc' location (for the current Timestamp 'ts')
+// will came. Respects DST. Nil 'loc' means UTC.
+func (ts Timestamp) TillNextMidnightIn(loc *time.Location) time.Duration {
+	return time.Duration(ts.EndOfDayIn(loc) + 1 - ts) * time.Second
+}
+
+// TillNextMidnightIn is the same as Timestamp.TillNextMidnightIn()
+// but for current time.
+func TillNextMidnightIn(loc *time.Location) time.Duration {
+	return Now().TillNextMidnightIn(loc)
+}
+
+// WithTimeIn returns the current Date with the presented Time's hour, minute, second
+// in the 'loc' location as a new Timestamp object. Nil 'loc' means UTC.
+//
+// If such time does not exist (clock has been moved forward because of DST)
+// or exists twice (clock has been moved backward), the result is the same
+// as time.Date() returns for that case.
+func (dd Date) WithTimeIn(hh Hour, mm Minute, ss Second, loc *time.Location) Timestamp {
+	y, m, d := dd.Split()
+	return UnixFromIn(y, m, d, hh, mm, ss, loc)
+}
+
+// UnixFromIn is the same as UnixFrom() but presented Date and Time
+// are in the 'loc' location. Nil 'loc' means UTC.
+func UnixFromIn(y Year, m Month, d Day, hh Hour, mm Minute, ss Second, loc *time.Location) Timestamp {
+	if y > 4095 {
+		y = 4095
+	}
+	tt := time.Date(int(y), time.Month(m), int(d), int(hh), int(mm), int(ss), 0, normalizeLocation(loc))
+	return Timestamp(tt.Unix())
+}
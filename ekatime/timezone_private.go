@@ -0,0 +1,58 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekatime
+
+import (
+	"time"
+)
+
+// normalizeLocation returns 'loc' or time.UTC if 'loc' is nil.
+func normalizeLocation(loc *time.Location) *time.Location {
+	if loc == nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// beginningOfDayIn returns a Timestamp of the first moment of 'y', 'm', 'd' day
+// in the 'loc' location. 'd' may be out of month's range (it's normalized).
+//
+// Usually it's a midnight, but if the clock has been moved forward at midnight
+// because of DST, there is no midnight in that day, and the first existing
+// moment is returned then.
+func beginningOfDayIn(y Year, m Month, d Day, loc *time.Location) Timestamp {
+
+	// Normalize the day, avoiding DST effects.
+	y_, m_, d_ := time.Date(int(y), time.Month(m), int(d), 0, 0, 0, 0, time.UTC).Date()
+
+	// Fast path. Midnight exists and it's the first moment of the day.
+	midnight := time.Date(y_, m_, d_, 0, 0, 0, 0, loc)
+	if ym, mm, dm := midnight.Date(); ym == y_ && mm == m_ && dm == d_ &&
+			midnight.Hour() == 0 && midnight.Minute() == 0 && midnight.Second() == 0 {
+		if yb, mb, db := midnight.Add(-time.Second).Date(); yb != y_ || mb != m_ || db != d_ {
+			return Timestamp(midnight.Unix())
+		}
+	}
+
+	// Find the first moment of the day using binary search.
+	// Time zones' offsets are in the range [-12h..+14h],
+	// so the day is started in that range around the UTC midnight.
+
+	utcMidnight := time.Date(y_, m_, d_, 0, 0, 0, 0, time.UTC).Unix()
+	lo, hi := utcMidnight - 15*int64(SECONDS_IN_HOUR), utcMidnight + 13*int64(SECONDS_IN_HOUR)
+
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		yc, mc, dc := time.Unix(mid, 0).In(loc).Date()
+		if yc < y_ || yc == y_ && (mc < m_ || mc == m_ && dc < d_) {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+
+	return Timestamp(lo)
+}
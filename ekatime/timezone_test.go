@@ -0,0 +1,81 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekatime_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qioalice/ekago/v2/ekatime"
+
+	"github.com/stretchr/testify/require"
+)
+
+func loadLocation(t *testing.T, name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone %s is unavailable: %s", name, err)
+	}
+	return loc
+}
+
+func TestTimestamp_In(t *testing.T) {
+
+	msk := loadLocation(t, "Europe/Moscow")
+
+	// 12 Nov 2019 22:30:00 UTC is 13 Nov 2019 01:30:00 MSK.
+	ts := ekatime.UnixFrom(2019, 11, 12, 22, 30, 0)
+
+	require.True(t, ts.DateIn(msk).Equal(ekatime.NewDate(2019, 11, 13)))
+	require.Equal(t, ekatime.NewTime(1, 30, 0), ts.TimeIn(msk))
+	require.Equal(t, ekatime.WEEKDAY_WEDNESDAY, ts.DateIn(msk).Weekday())
+	require.True(t, ts.DateIn(nil).Equal(ekatime.NewDate(2019, 11, 12)))
+
+	require.Equal(t, ekatime.UnixFrom(2019, 11, 12, 21, 0, 0), ts.BeginningOfDayIn(msk))
+	require.Equal(t, ekatime.UnixFrom(2019, 11, 13, 20, 59, 59), ts.EndOfDayIn(msk))
+	require.Equal(t, 22*time.Hour+30*time.Minute, ts.TillNextMidnightIn(msk))
+
+	require.Equal(t, ts, ekatime.NewDate(2019, 11, 13).WithTimeIn(1, 30, 0, msk))
+	require.Equal(t, ts.BeginningOfDay(), ts.BeginningOfDayIn(nil))
+}
+
+func TestTimestamp_BeginningAndEndOfDayIn_DST(t *testing.T) {
+
+	berlin := loadLocation(t, "Europe/Berlin")
+
+	// 29 Mar 2020 in Berlin lasts 23 hours, 25 Oct 2020 lasts 25 hours.
+	ts := ekatime.UnixFromIn(2020, 3, 29, 12, 0, 0, berlin)
+	b, e := ts.BeginningAndEndOfDayIn(berlin).Split()
+	require.EqualValues(t, 23*ekatime.SECONDS_IN_HOUR-1, e-b)
+
+	ts = ekatime.UnixFromIn(2020, 10, 25, 12, 0, 0, berlin)
+	b, e = ts.BeginningAndEndOfDayIn(berlin).Split()
+	require.EqualValues(t, 25*ekatime.SECONDS_IN_HOUR-1, e-b)
+
+	// 4 Nov 2018 in Sao Paulo has no midnight, the day starts at 01:00.
+	saoPaulo := loadLocation(t, "America/Sao_Paulo")
+
+	ts = ekatime.UnixFromIn(2018, 11, 4, 12, 0, 0, saoPaulo)
+	beginning := ts.BeginningOfDayIn(saoPaulo)
+	require.Equal(t, ekatime.NewTime(1, 0, 0), beginning.TimeIn(saoPaulo))
+	require.True(t, beginning.DateIn(saoPaulo).Equal(ekatime.NewDate(2018, 11, 4)))
+	require.True(t, (beginning - 1).DateIn(saoPaulo).Equal(ekatime.NewDate(2018, 11, 3)))
+}
+
+func TestCalendar_SetLocation(t *testing.T) {
+
+	msk := loadLocation(t, "Europe/Moscow")
+
+	c := new(ekatime.Calendar).DisableLogging().SetLocation(msk)
+	c.RunAsync()
+
+	require.Equal(t, msk, c.Location())
+	require.True(t, c.Today().Date.Equal(ekatime.Now().DateIn(msk)))
+
+	// Location can't be changed when Calendar is running.
+	c.SetLocation(time.UTC)
+	require.Equal(t, msk, c.Location())
+}
@@ -56,3 +56,18 @@ func initWeekday() {
 		_WeekdayBytes[i] = []byte("\"" + _WeekdayStr[i] + "\"")
 	}
 }
+
+// toISO returns the current Weekday as ISO day of week,
+// where 1 - Monday, 7 - Sunday.
+func (w Weekday) toISO() int8 {
+	if d := w.To06(); d != 0 {
+		return d
+	}
+	return 7
+}
+
+// weekdayFromISO returns a Weekday by ISO day of week 'd',
+// where 1 - Monday, 7 - Sunday.
+func weekdayFromISO(d int8) Weekday {
+	return WeekdayFrom06(d % 7)
+}
@@ -0,0 +1,243 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekatime
+
+type (
+	// Weekend is a set of days of week that are day offs by default
+	// (if there is no Event for that day). Each bit is a Weekday.
+	// Use NewWeekend() or predefined constants to create it.
+	Weekend uint8
+
+	// Workdays is a business day arithmetic based on the Event s
+	// (holidays, transferred workdays) and Weekend.
+	// It allows to add N workdays to some date, find next or previous workday,
+	// count workdays between two dates, etc. across months and years.
+	//
+	// Unlike Calendar, Workdays does not require RunAsync() to be called
+	// and works with an explicit list of events:
+	//
+	// 		w := ekatime.NewWorkdays(events).WithWeekend(ekatime.WEEKEND_DEFAULT)
+	// 		paymentDate := w.AddWorkdays(ekatime.NewDate(2021, 1, 1), 5)
+	//
+	// Nil Workdays is a valid object that has no events and WEEKEND_DEFAULT.
+	// Workdays is immutable and can be used by many goroutines after it's created.
+	Workdays struct {
+		events    map[Timestamp]Event // day's number -> event of that day
+		recurring []RecurringEvent
+		weekend   Weekend
+	}
+)
+
+//noinspection GoSnakeCaseUsage
+const (
+	// WEEKEND_DEFAULT is Saturday and Sunday.
+	WEEKEND_DEFAULT Weekend = 1<<WEEKDAY_SATURDAY | 1<<WEEKDAY_SUNDAY
+
+	// WEEKEND_NONE means that all days of week are workdays by default.
+	WEEKEND_NONE Weekend = 0
+)
+
+// NewWeekend creates and returns a new Weekend of passed days of week.
+// Invalid days of week are ignored.
+func NewWeekend(days ...Weekday) Weekend {
+	var weekend Weekend
+	for _, w := range days {
+		if w >= 0 && w <= 6 {
+			weekend |= 1 << w
+		}
+	}
+	return weekend
+}
+
+// Has reports whether 'w' is day off in the current Weekend.
+func (wk Weekend) Has(w Weekday) bool {
+	return w >= 0 && w <= 6 && wk & (1 << w) != 0
+}
+
+// NewWorkdays creates a new Workdays object based on 'events'
+// with WEEKEND_DEFAULT as Weekend. If there are many events for the same day,
+// the last one is used.
+func NewWorkdays(events []Event) *Workdays {
+
+	w := &Workdays{
+		events:  make(map[Timestamp]Event, len(events)),
+		weekend: WEEKEND_DEFAULT,
+	}
+
+	for _, event := range events {
+		w.events[workdaysDayNum(event.Date())] = event
+	}
+
+	return w
+}
+
+// WithWeekend returns a copy of the current Workdays with 'weekend' as Weekend.
+// Weekend of all 7 days is not allowed, WEEKEND_DEFAULT is used instead then.
+// Nil safe.
+func (w *Workdays) WithWeekend(weekend Weekend) *Workdays {
+
+	if weekend & _WEEKEND_MASK == _WEEKEND_MASK {
+		weekend = WEEKEND_DEFAULT
+	}
+
+	cp := NewWorkdays(nil)
+	if w != nil {
+		cp.events = w.events
+		cp.recurring = w.recurring
+	}
+	cp.weekend = weekend & _WEEKEND_MASK
+
+	return cp
+}
+
+// WithRecurring returns a copy of the current Workdays with 'recurring' events.
+// Recurring events are checked lazily for each day there is no explicit Event for,
+// so they are taken into account at any year. If many recurring events recur
+// on the same day, the first one is used. Nil safe.
+func (w *Workdays) WithRecurring(recurring []RecurringEvent) *Workdays {
+
+	cp := NewWorkdays(nil)
+	if w != nil {
+		cp.events = w.events
+		cp.weekend = w.weekend
+	}
+	cp.recurring = append(recurring[:0:0], recurring...)
+
+	return cp
+}
+
+// Weekend returns Weekend of the current Workdays. Nil safe.
+func (w *Workdays) Weekend() Weekend {
+	if w == nil {
+		return WEEKEND_DEFAULT
+	}
+	return w.weekend
+}
+
+// EventFor returns an Event of 'dd' day and true or false if there is no Event
+// for that day. An explicit Event has a priority over the recurring ones.
+// Nil safe.
+func (w *Workdays) EventFor(dd Date) (Event, bool) {
+	return w.eventFor(workdaysDayNum(dd))
+}
+
+// IsWorkday reports whether 'dd' is workday.
+// Event for that day has a priority over the Weekend. Nil safe.
+func (w *Workdays) IsWorkday(dd Date) bool {
+	return w.isWorkday(workdaysDayNum(dd))
+}
+
+// NextWorkday returns the nearest workday that is after 'dd' (never 'dd' itself).
+// Nil safe.
+func (w *Workdays) NextWorkday(dd Date) Date {
+	return w.AddWorkdays(dd, 1)
+}
+
+// PrevWorkday returns the nearest workday that is before 'dd' (never 'dd' itself).
+// Nil safe.
+func (w *Workdays) PrevWorkday(dd Date) Date {
+	return w.AddWorkdays(dd, -1)
+}
+
+// AddWorkdays returns a Date that is 'n' workdays after 'dd'
+// (or before if 'n' < 0). 'dd' itself is not counted, even if it's workday.
+// Returns 'dd' as is if 'n' == 0. Nil safe.
+//
+// Examples (no events, WEEKEND_DEFAULT):
+//
+// 		AddWorkdays(Friday, 1)   // -> next Monday
+// 		AddWorkdays(Saturday, 1) // -> next Monday
+// 		AddWorkdays(Monday, -1)  // -> prev Friday
+func (w *Workdays) AddWorkdays(dd Date, n int) Date {
+
+	if n == 0 {
+		return dd
+	}
+
+	step := Timestamp(1)
+	if n < 0 {
+		step, n = -1, -n
+	}
+
+	day := workdaysDayNum(dd)
+	for n > 0 {
+		day += step
+		if w.isWorkday(day) {
+			n--
+		}
+	}
+
+	return workdaysDate(day)
+}
+
+// WorkdaysBetween returns the number of workdays that are after 'd1'
+// and not after 'd2' (d1, d2]. Returns negative number if 'd2' is before 'd1'.
+// So, AddWorkdays(d1, WorkdaysBetween(d1, d2)) == d2 if 'd2' is workday.
+// Nil safe.
+func (w *Workdays) WorkdaysBetween(d1, d2 Date) int {
+
+	from, to := workdaysDayNum(d1), workdaysDayNum(d2)
+
+	sign := 1
+	if from > to {
+		// (d2, d1] must be counted
+		from, to, sign = to, from, -1
+	}
+
+	// Count by weeks first, then the rest of days, then apply events.
+	var (
+		weekend  = w.Weekend()
+		days     = to - from
+		weeks    = days / 7
+		workdays = int(weeks) * (7 - weekend.daysCount())
+	)
+
+	for day := from + weeks*7 + 1; day <= to; day++ {
+		if !weekend.Has(workdaysWeekday(day)) {
+			workdays++
+		}
+	}
+
+	if w != nil {
+		for day, event := range w.events {
+			isDayOff := event.IsDayOff()
+			if day <= from || day > to || isDayOff != !weekend.Has(workdaysWeekday(day)) {
+				continue
+			}
+			if isDayOff {
+				workdays--
+			} else {
+				workdays++
+			}
+		}
+
+		// Recurring events are applied to the days w/o explicit events only,
+		// and only the first recurring event of each day counts.
+		applied := make(map[Timestamp]struct{})
+		for _, re := range w.recurring {
+			re.Rule.iterate(from+1, to, func(day Timestamp) bool {
+				if _, found := w.events[day]; found {
+					return true
+				}
+				if _, found := applied[day]; found {
+					return true
+				}
+				applied[day] = struct{}{}
+				if re.IsDayOff != !weekend.Has(workdaysWeekday(day)) {
+					return true
+				}
+				if re.IsDayOff {
+					workdays--
+				} else {
+					workdays++
+				}
+				return true
+			})
+		}
+	}
+
+	return workdays * sign
+}
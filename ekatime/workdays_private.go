@@ -0,0 +1,82 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekatime
+
+//noinspection GoSnakeCaseUsage
+const (
+	// _WEEKEND_MASK is a mask of all days of week Weekend may contain.
+	_WEEKEND_MASK Weekend = 0x7F
+
+	// _WEEKEND_IS_SET is a flag that is stored with Weekend in Calendar,
+	// to distinguish WEEKEND_NONE and not set Weekend.
+	_WEEKEND_IS_SET Weekend = 0x80
+)
+
+// daysCount returns a number of days of week in the current Weekend.
+func (wk Weekend) daysCount() int {
+	n := 0
+	for w := Weekday(0); w <= 6; w++ {
+		if wk.Has(w) {
+			n++
+		}
+	}
+	return n
+}
+
+// isWorkday reports whether day with 'day' number is workday. Nil safe.
+func (w *Workdays) isWorkday(day Timestamp) bool {
+	if event, found := w.eventFor(day); found {
+		return event.IsWorkday()
+	}
+	return !w.Weekend().Has(workdaysWeekday(day))
+}
+
+// eventFor returns an Event of day with 'day' number and true
+// or false if there is no Event for that day. Explicit events are checked first,
+// then the recurring ones. Nil safe.
+func (w *Workdays) eventFor(day Timestamp) (Event, bool) {
+
+	if w == nil {
+		return 0, false
+	}
+
+	if event, found := w.events[day]; found {
+		return event, true
+	}
+
+	for _, re := range w.recurring {
+		if re.Rule.matchesDay(day) {
+			return NewEvent(workdaysDate(day), re.ID, re.IsDayOff), true
+		}
+	}
+
+	return 0, false
+}
+
+// workdaysDayNum returns a number of days that have been passed
+// since 01 Jan 1970 until 'dd'.
+func workdaysDayNum(dd Date) Timestamp {
+	ts := dd.WithTime(0, 0, 0)
+	if ts < 0 {
+		return (ts - SECONDS_IN_DAY + 1) / SECONDS_IN_DAY
+	}
+	return ts / SECONDS_IN_DAY
+}
+
+// workdaysDate returns a Date by its 'day' number. See workdaysDayNum().
+func workdaysDate(day Timestamp) Date {
+	return NewDate(dateFromUnix(day * SECONDS_IN_DAY)).ensureWeekdayExist()
+}
+
+// workdaysWeekday returns a Weekday of day with 'day' number.
+func workdaysWeekday(day Timestamp) Weekday {
+	// 01 Jan 1970 is Thursday, WEEKDAY_WEDNESDAY is 0.
+	w := (day + 1) % 7
+	if w < 0 {
+		w += 7
+	}
+	return Weekday(w)
+}
@@ -0,0 +1,123 @@
+// Copyright © 2020. All rights reserved.
+// Author: Ilya Stroy.
+// Contacts: qioalice@gmail.com, https://github.com/qioalice
+// License: https://opensource.org/licenses/MIT
+
+package ekatime_test
+
+import (
+	"testing"
+
+	"github.com/qioalice/ekago/v2/ekatime"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkdays(t *testing.T) {
+
+	// December 2020 - January 2021:
+	//     Mo   Tu   We   Th   Fr   Sa   Su
+	//     28   29   30   31   1    2    3
+	//     4    5    6    7    8    9    10
+	//     11   12   13   14   15   16   17
+	//
+	// Events: 31 Dec - 8 Jan are day offs, 9 Jan (Sa) is workday.
+
+	var events []ekatime.Event
+	for d := ekatime.NewDate(2020, 12, 31); !d.Equal(ekatime.NewDate(2021, 1, 9)); d = d.AddDays(1) {
+		events = append(events, ekatime.NewEvent(d, 1, true))
+	}
+	events = append(events, ekatime.NewEvent(ekatime.NewDate(2021, 1, 9), 2, false))
+
+	w := ekatime.NewWorkdays(events)
+
+	require.True(t, w.IsWorkday(ekatime.NewDate(2020, 12, 30)))
+	require.False(t, w.IsWorkday(ekatime.NewDate(2021, 1, 4)))
+	require.True(t, w.IsWorkday(ekatime.NewDate(2021, 1, 9)))
+	require.False(t, w.IsWorkday(ekatime.NewDate(2021, 1, 10)))
+
+	require.True(t, w.NextWorkday(ekatime.NewDate(2020, 12, 30)).Equal(ekatime.NewDate(2021, 1, 9)))
+	require.True(t, w.PrevWorkday(ekatime.NewDate(2021, 1, 9)).Equal(ekatime.NewDate(2020, 12, 30)))
+	require.True(t, w.AddWorkdays(ekatime.NewDate(2020, 12, 29), 3).Equal(ekatime.NewDate(2021, 1, 11)))
+	require.True(t, w.AddWorkdays(ekatime.NewDate(2021, 1, 11), -3).Equal(ekatime.NewDate(2020, 12, 29)))
+	require.True(t, w.AddWorkdays(ekatime.NewDate(2021, 1, 10), 0).Equal(ekatime.NewDate(2021, 1, 10)))
+
+	require.Equal(t, 3, w.WorkdaysBetween(ekatime.NewDate(2020, 12, 29), ekatime.NewDate(2021, 1, 11)))
+	require.Equal(t, -3, w.WorkdaysBetween(ekatime.NewDate(2021, 1, 11), ekatime.NewDate(2020, 12, 29)))
+	require.Equal(t, 0, w.WorkdaysBetween(ekatime.NewDate(2021, 1, 11), ekatime.NewDate(2021, 1, 11)))
+
+	// Between must be consistent with AddWorkdays for the long ranges.
+	from := ekatime.NewDate(2020, 11, 17)
+	for _, n := range []int{1, 7, 30, 260, 1000} {
+		to := w.AddWorkdays(from, n)
+		require.Equal(t, n, w.WorkdaysBetween(from, to))
+	}
+
+	// Nil Workdays has no events and default weekend.
+	var nilW *ekatime.Workdays
+	require.True(t, nilW.NextWorkday(ekatime.NewDate(2021, 1, 1)).Equal(ekatime.NewDate(2021, 1, 4)))
+	require.Equal(t, 5, nilW.WorkdaysBetween(ekatime.NewDate(2021, 1, 1), ekatime.NewDate(2021, 1, 8)))
+
+	// Friday and Saturday weekend.
+	w = w.WithWeekend(ekatime.NewWeekend(ekatime.WEEKDAY_FRIDAY, ekatime.WEEKDAY_SATURDAY))
+	require.True(t, w.IsWorkday(ekatime.NewDate(2021, 1, 10)))
+	require.True(t, w.IsWorkday(ekatime.NewDate(2021, 1, 9)))
+	require.False(t, w.IsWorkday(ekatime.NewDate(2021, 1, 15)))
+}
+
+func TestCalendar_AddWorkdays(t *testing.T) {
+
+	c := new(ekatime.Calendar).
+		DisableLogging().
+		SetWeekend(ekatime.NewWeekend(ekatime.WEEKDAY_SUNDAY)).
+		EventAdd(ekatime.NewEvent(ekatime.NewDate(2021, 1, 1), 1, true))
+
+	// Confirmed events are used only, so there are no events before RunAsync().
+	require.True(t, c.IsWorkday(ekatime.NewDate(2021, 1, 1)))
+	require.False(t, c.IsWorkday(ekatime.NewDate(2021, 1, 2)))
+
+	c.RunAsync()
+
+	require.False(t, c.IsWorkday(ekatime.NewDate(2021, 1, 1)))
+	require.True(t, c.IsWorkday(ekatime.NewDate(2021, 1, 2)))
+
+	require.True(t, c.NextWorkday(ekatime.NewDate(2020, 12, 31)).Equal(ekatime.NewDate(2021, 1, 2)))
+	require.True(t, c.PrevWorkday(ekatime.NewDate(2021, 1, 4)).Equal(ekatime.NewDate(2021, 1, 2)))
+	require.True(t, c.AddWorkdays(ekatime.NewDate(2020, 12, 31), 2).Equal(ekatime.NewDate(2021, 1, 4)))
+	require.Equal(t, 2, c.WorkdaysBetween(ekatime.NewDate(2020, 12, 31), ekatime.NewDate(2021, 1, 4)))
+}
+
+func TestWorkdays_Recurring(t *testing.T) {
+
+	newYear, err := ekatime.ParseRRule(ekatime.NewDate(2020, 1, 1), "FREQ=YEARLY")
+	require.True(t, err.IsNil())
+
+	c := new(ekatime.Calendar).
+		DisableLogging().
+		RecurringEventAdd(ekatime.RecurringEvent{Rule: newYear, ID: 1, IsDayOff: true}).
+		EventAdd(ekatime.NewEvent(ekatime.NewDate(2031, 1, 1), 2, false))
+
+	c.RunAsync()
+
+	// Recurring events must be applied at any year, not only around the current one.
+	// 01 Jan 2030 is Tuesday, 01 Jan 2031 is Wednesday, but there is an explicit event.
+	require.False(t, c.IsWorkday(ekatime.NewDate(2030, 1, 1)))
+	require.True(t, c.IsWorkday(ekatime.NewDate(2031, 1, 1)))
+
+	event, found := c.Workdays().EventFor(ekatime.NewDate(2030, 1, 1))
+	require.True(t, found)
+	require.Equal(t, ekatime.NewEvent(ekatime.NewDate(2030, 1, 1), 1, true), event)
+
+	require.True(t, c.NextWorkday(ekatime.NewDate(2029, 12, 31)).Equal(ekatime.NewDate(2030, 1, 2)))
+	require.Equal(t, 1, c.WorkdaysBetween(ekatime.NewDate(2029, 12, 31), ekatime.NewDate(2030, 1, 2)))
+	require.Equal(t, 2, c.WorkdaysBetween(ekatime.NewDate(2030, 12, 31), ekatime.NewDate(2031, 1, 2)))
+
+	// Between must be consistent with AddWorkdays across many years.
+	w := c.Workdays()
+	from := ekatime.NewDate(2025, 11, 17)
+	for _, n := range []int{1, 30, 260, 1000, 3000} {
+		to := w.AddWorkdays(from, n)
+		require.Equal(t, n, w.WorkdaysBetween(from, to))
+		require.Equal(t, -n, w.WorkdaysBetween(to, from))
+	}
+}
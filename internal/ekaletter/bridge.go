@@ -46,6 +46,21 @@ var (
 	BridgeLogErr2 func(logger unsafe.Pointer, level uint8, errLetter *Letter, errArgs []interface{})
 	BridgeLogwErr2 func(logger unsafe.Pointer, level uint8, errLetter *Letter, errMessage string, errFields []ekafield.Field)
 
+	// BridgeLogwMessage is a function that is initialized in the ekalog package
+	// and used in the ekadeath package (that can not import ekalog, because
+	// ekalog depends on ekadeath).
+	//
+	// This function must log a 'message' with 'fields' with log level 'level'
+	// using standard package's logger.
+	BridgeLogwMessage func(level uint8, message string, fields []ekafield.Field)
+
+	// BridgeLogGoroutines is a function that is initialized in the ekalog package
+	// and used in the ekadeath package.
+	//
+	// This function must log the states and stacktraces of all goroutines
+	// with log level 'level' using standard package's logger.
+	BridgeLogGoroutines func(level uint8)
+
 	// GErrRelease is a function that is initialized in the ekaerr package
 	// and used in the ekalog package.
 	//